@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Утилита встраивает текстовые файлы каталога в Go исходник в виде map[string]string
+// Используется через go:generate, например:
+//     //go:generate go run ../../cmd/embedfiles -dir sql -ext .sql -pkg migrate -var sqlFiles -out sql_files.go
+
+// входные флаги утилиты
+var (
+	dirFlag = flag.String("dir", "", "Source directory")
+	extFlag = flag.String("ext", "", "File extension filter, for example .sql")
+	pkgFlag = flag.String("pkg", "", "Go package name")
+	varFlag = flag.String("var", "", "Go variable name")
+	outFlag = flag.String("out", "", "Output Go file name")
+)
+
+// main function
+func main() {
+	flag.Parse()
+
+	if *dirFlag == "" || *pkgFlag == "" || *varFlag == "" || *outFlag == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	infos, err := ioutil.ReadDir(*dirFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// стабильный порядок файлов для воспроизводимой генерации
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDir() || (*extFlag != "" && !strings.HasSuffix(info.Name(), *extFlag)) {
+			continue
+		}
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by embedfiles from directory %s; DO NOT EDIT.\n\n", filepath.ToSlash(*dirFlag))
+	fmt.Fprintf(buf, "package %s\n\n", *pkgFlag)
+	fmt.Fprintf(buf, "// %s represent embedded files of directory %s\n", *varFlag, filepath.ToSlash(*dirFlag))
+	fmt.Fprintf(buf, "var %s = map[string]string{\n", *varFlag)
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(*dirFlag, name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(buf, "%q: %q,\n", name, string(data))
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(*outFlag, src, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,3 +1,3 @@
-.\httpserver.exe -httpcfg httpserver.cfg -l 127.0.0.1:3000 -httpu roman -httppwd Welcome1 -jwtk qwerlkc8SFlwe -d DEBUG -log .\log\httpserver_%%s.log 
+.\httpserver.exe serve -httpcfg httpserver.cfg -l 127.0.0.1:3000 -httpu roman -httppwd Welcome1 -jwtk qwerlkc8SFlwe -d DEBUG -log .\log\httpserver_%%s.log 
 
 pause
\ No newline at end of file
@@ -1 +1 @@
-.\httpserver.exe -httpcfg httpserver.cfg -l 127.0.0.1:3000 -httpu roman -httppwd Welcome1 -jwtk qwerlkc8SFlwe -d INFO -log .\log\httpserver_%%s.log 
\ No newline at end of file
+.\httpserver.exe serve -httpcfg httpserver.cfg -l 127.0.0.1:3000 -httpu roman -httppwd Welcome1 -jwtk qwerlkc8SFlwe -d INFO -log .\log\httpserver_%%s.log 
\ No newline at end of file
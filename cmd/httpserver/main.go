@@ -13,7 +13,9 @@ import (
 
 	"github.com/romapres2010/httpserver/daemon"
 	myerror "github.com/romapres2010/httpserver/error"
+	myjwt "github.com/romapres2010/httpserver/jwt"
 	mylog "github.com/romapres2010/httpserver/log"
+	"github.com/romapres2010/httpserver/users"
 )
 
 // Параметры, подменяемые компилятором при сборке бинарника
@@ -32,48 +34,61 @@ var (
 	httpUserIDFlag     string
 	httpUserPwdFlag    string
 	jwtKeyFlag         string
+	configFormatFlag   string
+	jwtUserFlag        string
+	jwtExpiresFlag     int
+	usersFileFlag      string
+	userReplaceFlag    bool
+	migrateDownFlag    bool
+	migrateStepsFlag   int
 )
 
-// входные флаги программы
-var flags = []cli.Flag{
-	cli.StringFlag{
+// общие флаги команд
+var (
+	httpConfigFlag = cli.StringFlag{
 		Name:        "httpconfig, httpcfg",
-		Usage:       "HTTP Config file name",
+		Usage:       "HTTP Config file name (INI, YAML or TOML)",
 		Required:    true,
 		Destination: &httpConfigFileFlag,
-	},
+	}
+	debugLevelFlag = cli.StringFlag{
+		Name:        "debug, d",
+		Usage:       "Debug mode: DEBUG, INFO, ERROR",
+		Required:    false,
+		Destination: &debugFlag,
+		Value:       "INFO",
+	}
+)
+
+// входные флаги команды serve
+var serveFlags = []cli.Flag{
+	httpConfigFlag,
 	cli.StringFlag{
 		Name:        "listenstring, l",
 		Usage:       "Listen string in format <host>:<port>",
-		Required:    true,
+		Required:    false,
 		Destination: &listenStringFlag,
 		Value:       "localhost:3000",
 	},
 	cli.StringFlag{
 		Name:        "httpuser, httpu",
-		Usage:       "User name for access to HTTP server",
-		Required:    true,
+		Usage:       "User name for access to HTTP server (AuthType = INTERNAL)",
+		Required:    false,
 		Destination: &httpUserIDFlag,
 	},
 	cli.StringFlag{
 		Name:        "httppassword, httppwd",
-		Usage:       "User password for access to HTTP server",
-		Required:    true,
+		Usage:       "User password for access to HTTP server (AuthType = INTERNAL)",
+		Required:    false,
 		Destination: &httpUserPwdFlag,
 	},
 	cli.StringFlag{
 		Name:        "jwtkey, jwtk",
-		Usage:       "JSON web token secret key",
+		Usage:       "JSON web token secret key (UseJWT = true)",
 		Required:    false,
 		Destination: &jwtKeyFlag,
 	},
-	cli.StringFlag{
-		Name:        "debug, d",
-		Usage:       "Debug mode: DEBUG, INFO, ERROR",
-		Required:    false,
-		Destination: &debugFlag,
-		Value:       "INFO",
-	},
+	debugLevelFlag,
 	cli.StringFlag{
 		Name:        "logfile, log",
 		Usage:       "Log file name",
@@ -82,99 +97,395 @@ var flags = []cli.Flag{
 	},
 }
 
-//main function
-func main() {
-	// Create new Application
-	app := cli.NewApp()
-	app.Name = "HTTP Server"
-	app.Version = fmt.Sprintf("%s, commit '%s', build time '%s'", version, commit, buildTime)
-	app.Author = "Roman Presnyakov"
-	app.Email = "romapres@mail.ru"
-	app.Flags = flags // присваиваем ранее определенные флаги
-	app.Writer = os.Stderr
+// входные флаги команды config print
+var configPrintFlags = []cli.Flag{
+	httpConfigFlag,
+	cli.StringFlag{
+		Name:        "format, f",
+		Usage:       "Output format: yaml, toml, ini",
+		Required:    false,
+		Destination: &configFormatFlag,
+		Value:       "yaml",
+	},
+}
 
-	// Определяем действие - запуск демона
-	app.Action = func(ctx *cli.Context) (myerr error) {
+// входные флаги команды db migrate
+var dbMigrateFlags = []cli.Flag{
+	httpConfigFlag,
+	debugLevelFlag,
+	cli.BoolFlag{
+		Name:        "down",
+		Usage:       "Roll back applied migrations instead of applying pending ones",
+		Destination: &migrateDownFlag,
+	},
+	cli.IntFlag{
+		Name:        "steps, n",
+		Usage:       "Number of migrations to apply or roll back, 0 - all pending for apply, 1 for roll back",
+		Required:    false,
+		Destination: &migrateStepsFlag,
+	},
+}
 
-		// настраиваем параллельное логирование в файл
-		if logFileFlag != "" {
-			// добавляем в имя лог файла дату и время
-			logFileFlag = strings.Replace(logFileFlag, "%s", time.Now().Format("2006_01_02_150405"), 1)
+// входные флаги команды jwt issue
+var jwtIssueFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:        "jwtkey, jwtk",
+		Usage:       "JSON web token secret key",
+		Required:    true,
+		Destination: &jwtKeyFlag,
+	},
+	cli.StringFlag{
+		Name:        "user, u",
+		Usage:       "User name to put into token",
+		Required:    true,
+		Destination: &jwtUserFlag,
+	},
+	cli.IntFlag{
+		Name:        "expires, e",
+		Usage:       "Token expiration time in seconds, 0 - without expiration",
+		Required:    false,
+		Destination: &jwtExpiresFlag,
+		Value:       3600,
+	},
+}
 
-			// открываем лог файл на запись
-			logFile, err := os.OpenFile(logFileFlag, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-			if err != nil {
-				myerr = myerror.WithCause("6020", "Error open log file: Filename", err, logFileFlag)
-				mylog.PrintfErrorMsg(fmt.Sprintf("%+v", myerr))
-				return
-			}
+// входные флаги команды user add
+var userAddFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:        "usersfile, uf",
+		Usage:       "Users file name for AuthType = INTERNAL",
+		Required:    true,
+		Destination: &usersFileFlag,
+	},
+	cli.StringFlag{
+		Name:        "user, u",
+		Usage:       "User name",
+		Required:    true,
+		Destination: &httpUserIDFlag,
+	},
+	cli.StringFlag{
+		Name:        "password, p",
+		Usage:       "User password",
+		Required:    true,
+		Destination: &httpUserPwdFlag,
+	},
+	cli.BoolFlag{
+		Name:        "replace",
+		Usage:       "Replace password of existing user",
+		Destination: &userReplaceFlag,
+	},
+}
 
-			// закрываем лог файл по выходу
-			defer func() {
-				if logFile != nil {
+// initServiceLogger init logger for service commands - only errors are written into os.Stderr
+func initServiceLogger() error {
+	mylog.InitLogger(os.Stderr)
+	return setLogLevel("ERROR")
+}
 
-					defer logFile.Close() // ошибку закрытия игнорируем
+// setLogLevel set log level filter
+func setLogLevel(level string) error {
+	switch level {
+	case "DEBUG", "ERROR", "INFO":
+		mylog.SetFilter(level)
+		return nil
+	default:
+		return myerror.New("9001", "Incorrect debugFlag. Only avaliable: DEBUG, INFO, ERROR.", level)
+	}
+}
 
-					// flushing write buffers out to disks
-					err := logFile.Sync()
+// exitError convert error into exit error of command
+func exitError(err error) error {
+	return cli.NewExitError(fmt.Sprintf("%v", err), 1)
+}
 
-					if err != nil {
-						// ошибку через закрытие передаем на уровень выше
-						myerr = myerror.WithCause("6020", "Error sync log file before closing", err).PrintfInfo()
-					}
-				}
-			}()
+// serveAction start HTTP server daemon and wait for shutdown
+func serveAction(ctx *cli.Context) (myerr error) {
 
-			// Параллельно пишем в os.Stderr и файл
-			wrt := io.MultiWriter(os.Stderr, logFile)
+	// настраиваем параллельное логирование в файл
+	if logFileFlag != "" {
+		// добавляем в имя лог файла дату и время
+		logFileFlag = strings.Replace(logFileFlag, "%s", time.Now().Format("2006_01_02_150405"), 1)
 
-			// Переопределяем глобальный логер на кастомный
-			mylog.InitLogger(wrt)
-		} else {
-			mylog.InitLogger(os.Stderr)
+		// открываем лог файл на запись
+		logFile, err := os.OpenFile(logFileFlag, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			myerr = myerror.WithCause("6020", "Error open log file: Filename", err, logFileFlag)
+			mylog.PrintfErrorMsg(fmt.Sprintf("%+v", myerr))
+			return
 		}
 
-		mylog.PrintfInfoMsg("Server is starting up: Version, Logfile", app.Version, logFileFlag)
-
-		// Установим фильтр логирования
-		if debugFlag != "" {
-			mylog.PrintfInfoMsg("Set log level", debugFlag)
-			switch debugFlag {
-			case "DEBUG", "ERROR", "INFO":
-				mylog.SetFilter(debugFlag)
-			default:
-				myerr = myerror.New("9001", "Incorrect debugFlag. Only avaliable: DEBUG, INFO, ERROR.", debugFlag)
-				mylog.PrintfErrorMsg(fmt.Sprintf("%+v", myerr))
-				return
+		// закрываем лог файл по выходу
+		defer func() {
+			if logFile != nil {
+
+				defer logFile.Close() // ошибку закрытия игнорируем
+
+				// flushing write buffers out to disks
+				err := logFile.Sync()
+
+				if err != nil {
+					// ошибку через закрытие передаем на уровень выше
+					myerr = myerror.WithCause("6020", "Error sync log file before closing", err).PrintfInfo()
+				}
 			}
-		}
+		}()
 
-		// Создаем конфигурацию демона
-		daemonCfg := &daemon.Config{
-			ConfigFileName: httpConfigFileFlag,
-			ListenSpec:     listenStringFlag,
-			JwtKey:         []byte(jwtKeyFlag),
-			HTTPUserID:     httpUserIDFlag,
-			HTTPUserPwd:    httpUserPwdFlag,
-		}
+		// Параллельно пишем в os.Stderr и файл
+		wrt := io.MultiWriter(os.Stderr, logFile)
 
-		// Создаем демон
-		daemon, myerr := daemon.New(context.Background(), daemonCfg)
-		if myerr != nil {
-			mylog.PrintfErrorMsg(fmt.Sprintf("%+v", myerr)) // верхний уровень логирования с трассировкой
-			return
-		}
+		// Переопределяем глобальный логер на кастомный
+		mylog.InitLogger(wrt)
+	} else {
+		mylog.InitLogger(os.Stderr)
+	}
+
+	mylog.PrintfInfoMsg("Server is starting up: Version, Logfile", ctx.App.Version, logFileFlag)
 
-		// Стартуем демон и ожидаем завершения
-		if myerr = daemon.Run(); myerr != nil {
-			mylog.PrintfErrorMsg(fmt.Sprintf("%+v", myerr)) // верхний уровень логирования с трассировкой
+	// Установим фильтр логирования
+	if debugFlag != "" {
+		mylog.PrintfInfoMsg("Set log level", debugFlag)
+		if myerr = setLogLevel(debugFlag); myerr != nil {
+			mylog.PrintfErrorMsg(fmt.Sprintf("%+v", myerr))
 			return
 		}
+	}
+
+	// Создаем конфигурацию демона
+	daemonCfg := &daemon.Config{
+		ConfigFileName: httpConfigFileFlag,
+		ListenSpec:     listenStringFlag,
+		JwtKey:         []byte(jwtKeyFlag),
+		HTTPUserID:     httpUserIDFlag,
+		HTTPUserPwd:    httpUserPwdFlag,
+	}
 
-		mylog.PrintfInfoMsg("Server is shutdown")
+	// Создаем демон
+	daemon, myerr := daemon.New(context.Background(), daemonCfg)
+	if myerr != nil {
+		mylog.PrintfErrorMsg(fmt.Sprintf("%+v", myerr)) // верхний уровень логирования с трассировкой
 		return
 	}
 
+	// Стартуем демон и ожидаем завершения
+	if myerr = daemon.Run(); myerr != nil {
+		mylog.PrintfErrorMsg(fmt.Sprintf("%+v", myerr)) // верхний уровень логирования с трассировкой
+		return
+	}
+
+	mylog.PrintfInfoMsg("Server is shutdown")
+	return
+}
+
+// configValidateAction validate configuration file and print all problems
+func configValidateAction(ctx *cli.Context) error {
+	if err := initServiceLogger(); err != nil {
+		return exitError(err)
+	}
+
+	_, problems, err := daemon.ValidateConfigFile(httpConfigFileFlag)
+	if err != nil {
+		return exitError(err)
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stdout, problem.String())
+		}
+		return cli.NewExitError(fmt.Sprintf("Config file '%s' is invalid: %v problem(s) found", httpConfigFileFlag, len(problems)), 1)
+	}
+
+	fmt.Fprintf(os.Stdout, "Config file '%s' is valid\n", httpConfigFileFlag)
+	return nil
+}
+
+// configPrintAction print effective configuration with defaults applied and secrets masked
+func configPrintAction(ctx *cli.Context) error {
+	if err := initServiceLogger(); err != nil {
+		return exitError(err)
+	}
+
+	if err := daemon.PrintConfig(httpConfigFileFlag, configFormatFlag, os.Stdout); err != nil {
+		return exitError(err)
+	}
+	return nil
+}
+
+// dbPingAction check connection to DB
+func dbPingAction(ctx *cli.Context) error {
+	mylog.InitLogger(os.Stderr)
+	if err := setLogLevel(debugFlag); err != nil {
+		return exitError(err)
+	}
+
+	version, err := daemon.PingDB(httpConfigFileFlag)
+	if err != nil {
+		return exitError(err)
+	}
+
+	fmt.Fprintln(os.Stdout, version)
+	return nil
+}
+
+// dbMigrateAction apply DB schema migrations
+func dbMigrateAction(ctx *cli.Context) error {
+	mylog.InitLogger(os.Stderr)
+	if err := setLogLevel(debugFlag); err != nil {
+		return exitError(err)
+	}
+
+	// откатываем по умолчанию только последнюю миграцию
+	if migrateDownFlag && migrateStepsFlag == 0 {
+		migrateStepsFlag = 1
+	}
+
+	count, err := daemon.MigrateDB(httpConfigFileFlag, migrateDownFlag, migrateStepsFlag)
+	if err != nil {
+		return exitError(err)
+	}
+
+	if migrateDownFlag {
+		fmt.Fprintf(os.Stdout, "%v migration(s) rolled back\n", count)
+	} else {
+		fmt.Fprintf(os.Stdout, "%v migration(s) applied\n", count)
+	}
+	return nil
+}
+
+// jwtIssueAction create JSON web token for testing
+func jwtIssueAction(ctx *cli.Context) error {
+	if err := initServiceLogger(); err != nil {
+		return exitError(err)
+	}
+
+	var expirationTime *time.Time
+	if jwtExpiresFlag > 0 {
+		t := time.Now().Add(time.Duration(jwtExpiresFlag) * time.Second)
+		expirationTime = &t
+	}
+
+	token, err := myjwt.CreateJWT(&myjwt.Claims{Username: jwtUserFlag}, expirationTime, []byte(jwtKeyFlag))
+	if err != nil {
+		return exitError(err)
+	}
+
+	fmt.Fprintln(os.Stdout, token)
+	return nil
+}
+
+// userAddAction add user into users file for AuthType = INTERNAL
+func userAddAction(ctx *cli.Context) error {
+	if err := initServiceLogger(); err != nil {
+		return exitError(err)
+	}
+
+	store, err := users.Load(usersFileFlag)
+	if err != nil {
+		return exitError(err)
+	}
+
+	if err = store.Add(httpUserIDFlag, httpUserPwdFlag, userReplaceFlag); err != nil {
+		return exitError(err)
+	}
+
+	if err = store.Save(); err != nil {
+		return exitError(err)
+	}
+
+	fmt.Fprintf(os.Stdout, "User '%s' is saved into '%s'\n", httpUserIDFlag, usersFileFlag)
+	return nil
+}
+
+// versionAction print version information injected at build time
+func versionAction(ctx *cli.Context) error {
+	fmt.Fprintf(os.Stdout, "version: %s\ncommit: %s\nbuildTime: %s\n", version, commit, buildTime)
+	return nil
+}
+
+//main function
+func main() {
+	// Create new Application
+	app := cli.NewApp()
+	app.Name = "HTTP Server"
+	app.Version = fmt.Sprintf("%s, commit '%s', build time '%s'", version, commit, buildTime)
+	app.Author = "Roman Presnyakov"
+	app.Email = "romapres@mail.ru"
+	app.Writer = os.Stderr
+
+	// Определяем команды
+	app.Commands = []cli.Command{
+		{
+			Name:   "serve",
+			Usage:  "Start HTTP server",
+			Flags:  serveFlags,
+			Action: serveAction,
+		},
+		{
+			Name:  "config",
+			Usage: "Configuration file commands",
+			Subcommands: []cli.Command{
+				{
+					Name:   "validate",
+					Usage:  "Validate configuration file (INI, YAML or TOML) and report all problems",
+					Flags:  []cli.Flag{httpConfigFlag},
+					Action: configValidateAction,
+				},
+				{
+					Name:   "print",
+					Usage:  "Print effective configuration with defaults applied and secrets masked",
+					Flags:  configPrintFlags,
+					Action: configPrintAction,
+				},
+			},
+		},
+		{
+			Name:  "db",
+			Usage: "Database commands",
+			Subcommands: []cli.Command{
+				{
+					Name:   "migrate",
+					Usage:  "Apply or roll back DB schema migrations",
+					Flags:  dbMigrateFlags,
+					Action: dbMigrateAction,
+				},
+				{
+					Name:   "ping",
+					Usage:  "Check connection to DB and print DB server version",
+					Flags:  []cli.Flag{httpConfigFlag, debugLevelFlag},
+					Action: dbPingAction,
+				},
+			},
+		},
+		{
+			Name:  "jwt",
+			Usage: "JSON web token commands",
+			Subcommands: []cli.Command{
+				{
+					Name:   "issue",
+					Usage:  "Issue JSON web token for testing",
+					Flags:  jwtIssueFlags,
+					Action: jwtIssueAction,
+				},
+			},
+		},
+		{
+			Name:  "user",
+			Usage: "Users file commands for AuthType = INTERNAL",
+			Subcommands: []cli.Command{
+				{
+					Name:   "add",
+					Usage:  "Add user or replace password of existing user",
+					Flags:  userAddFlags,
+					Action: userAddAction,
+				},
+			},
+		},
+		{
+			Name:   "version",
+			Usage:  "Print version, commit and build time",
+			Action: versionAction,
+		},
+	}
+
 	// Запускаем приложение
 	if err := app.Run(os.Args); err != nil {
 		log.Printf("%+v", err)
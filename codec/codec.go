@@ -0,0 +1,260 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/xml"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"gopkg.in/guregu/null.v4"
+)
+
+// Codec represent encoding of objects for HTTP body.
+// Marshal copy result into buf, if buf is too small new buffer is allocated
+type Codec interface {
+	ContentType() string // значение заголовка Content-Type ответа
+	Marshal(v interface{}, buf []byte) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Поддерживаемые кодеки
+var (
+	JSON    Codec = jsonCodec{}
+	XML     Codec = xmlCodec{}
+	MsgPack Codec = msgpackCodec{}
+)
+
+// Default represent codec for requests without Content-Type or Accept
+var Default = JSON
+
+// mediaTypes represent mapping of media types to codecs
+var mediaTypes = map[string]Codec{
+	"application/json":        JSON,
+	"application/xml":         XML,
+	"text/xml":                XML,
+	"application/msgpack":     MsgPack,
+	"application/x-msgpack":   MsgPack,
+	"application/vnd.msgpack": MsgPack,
+}
+
+// ByContentType return codec for Content-Type of request, empty Content-Type is Default
+func ByContentType(contentType string) (Codec, bool) {
+	if strings.TrimSpace(contentType) == "" {
+		return Default, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	c, ok := mediaTypes[mediaType]
+	return c, ok
+}
+
+// acceptRange represent one media range of Accept header
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// wildcardCodecs represent order of codecs for media ranges */* and application/*
+var wildcardCodecs = []Codec{JSON, XML, MsgPack}
+
+// ByAccept return most preferred codec for Accept header of request, empty Accept is Default.
+// Media type with q=0 is not acceptable, wildcard selects codec which is not excluded in such a way
+func ByAccept(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return Default, true
+	}
+
+	var ranges []acceptRange
+	excluded := map[Codec]bool{} // кодеки, явно запрещенные q=0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qStr, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qStr, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType, q})
+		} else if c, ok := mediaTypes[mediaType]; ok {
+			excluded[c] = true
+		}
+	}
+	// при равном весе сохраняется порядок заголовка
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		if c, ok := mediaTypes[r.mediaType]; ok && !excluded[c] {
+			return c, true
+		}
+		if r.mediaType == "*/*" || r.mediaType == "application/*" {
+			if !excluded[Default] {
+				return Default, true
+			}
+			for _, c := range wildcardCodecs {
+				if !excluded[c] {
+					return c, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// jsonCodec - JSON with EasyJSON, objects must implement easyjson.Marshaler and easyjson.Unmarshaler
+type jsonCodec struct{}
+
+// ContentType return Content-Type of JSON
+func (jsonCodec) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+// Marshal encode v into JSON
+func (jsonCodec) Marshal(v interface{}, buf []byte) ([]byte, error) {
+	m, ok := v.(easyjson.Marshaler)
+	if !ok {
+		return nil, &unsupportedTypeError{"JSON", v}
+	}
+	w := jwriter.Writer{} // подготовим EasyJSON Writer
+	m.MarshalEasyJSON(&w) // сформируем JSON во внутренний буфер EasyJSON Writer
+	if w.Error != nil {
+		return nil, w.Error
+	}
+	// Скопируем из внутреннего буфера EasyJSON Writer во внешний буфер
+	return w.Buffer.BuildBytes(buf), nil
+}
+
+// Unmarshal decode JSON into v
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	u, ok := v.(easyjson.Unmarshaler)
+	if !ok {
+		return &unsupportedTypeError{"JSON", v}
+	}
+	l := jlexer.Lexer{Data: data}
+	u.UnmarshalEasyJSON(&l)
+	return l.Error()
+}
+
+// xmlCodec - XML with encoding/xml
+type xmlCodec struct{}
+
+// ContentType return Content-Type of XML
+func (xmlCodec) ContentType() string {
+	return "application/xml; charset=utf-8"
+}
+
+// Marshal encode v into XML
+func (xmlCodec) Marshal(v interface{}, buf []byte) ([]byte, error) {
+	b := bytes.NewBuffer(buf[:0]) // если буфера не хватит, то будет выделен новый
+	if err := xml.NewEncoder(b).Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Unmarshal decode XML into v
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// msgpackCodec - MessagePack, имена полей совпадают с именами полей JSON
+type msgpackCodec struct{}
+
+// ContentType return Content-Type of MessagePack
+func (msgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+// Marshal encode v into MessagePack
+func (msgpackCodec) Marshal(v interface{}, buf []byte) ([]byte, error) {
+	b := bytes.NewBuffer(buf[:0]) // если буфера не хватит, то будет выделен новый
+	enc := msgpack.NewEncoder(b)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Unmarshal decode MessagePack into v
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// unsupportedTypeError represent error of encoding type which is not supported by codec
+type unsupportedTypeError struct {
+	codec string
+	v     interface{}
+}
+
+// Error supports error interface
+func (e *unsupportedTypeError) Error() string {
+	return e.codec + " codec does not support type " + reflect.TypeOf(e.v).String()
+}
+
+// null типы кодируются в MessagePack как значение или nil
+func init() {
+	msgpack.Register(null.String{},
+		func(e *msgpack.Encoder, v reflect.Value) error {
+			s := v.Interface().(null.String)
+			if !s.Valid {
+				return e.EncodeNil()
+			}
+			return e.EncodeString(s.String)
+		},
+		func(d *msgpack.Decoder, v reflect.Value) error {
+			s := null.String{}
+			if c, err := d.PeekCode(); err != nil {
+				return err
+			} else if c == msgpcode.Nil {
+				err = d.DecodeNil()
+				v.Set(reflect.ValueOf(s))
+				return err
+			}
+			str, err := d.DecodeString()
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(null.StringFrom(str)))
+			return nil
+		})
+	msgpack.Register(null.Int{},
+		func(e *msgpack.Encoder, v reflect.Value) error {
+			i := v.Interface().(null.Int)
+			if !i.Valid {
+				return e.EncodeNil()
+			}
+			return e.EncodeInt(i.Int64)
+		},
+		func(d *msgpack.Decoder, v reflect.Value) error {
+			i := null.Int{}
+			if c, err := d.PeekCode(); err != nil {
+				return err
+			} else if c == msgpcode.Nil {
+				err = d.DecodeNil()
+				v.Set(reflect.ValueOf(i))
+				return err
+			}
+			n, err := d.DecodeInt64()
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(null.IntFrom(n)))
+			return nil
+		})
+}
@@ -0,0 +1,82 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	model "github.com/romapres2010/httpserver/model"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	in := &model.Dept{
+		Deptno:  10,
+		Dname:   "ACCOUNTING",
+		Loc:     null.StringFrom("NEW YORK"),
+		Version: 3,
+		Emps: []*model.Emp{
+			{Empno: 7839, Ename: null.StringFrom("KING"), Hiredate: null.StringFrom("1981-11-17"), Sal: null.IntFrom(5000), Deptno: null.IntFrom(10)},
+			{Empno: 7934, Mgr: null.IntFrom(7839)},
+		},
+	}
+
+	for _, c := range []Codec{JSON, XML, MsgPack} {
+		t.Run(c.ContentType(), func(t *testing.T) {
+			buf, err := c.Marshal(in, make([]byte, 0, 16))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			out := &model.Dept{}
+			if err = c.Unmarshal(buf, out); err != nil {
+				t.Fatalf("Unmarshal() error = %v, data = %s", err, buf)
+			}
+			if !reflect.DeepEqual(in, out) {
+				t.Errorf("Unmarshal() = %+v, want %+v", out, in)
+			}
+		})
+	}
+}
+
+func TestByAccept(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Codec
+		ok     bool
+	}{
+		{"", JSON, true},
+		{"*/*", JSON, true},
+		{"application/xml", XML, true},
+		{"text/html, application/msgpack;q=0.5, application/xml;q=0.9", XML, true},
+		{"application/xml;q=0, application/*", JSON, true},
+		{"application/json;q=0, */*", XML, true},
+		{"application/json;q=0, application/xml;q=0, application/*;q=0.5", MsgPack, true},
+		{"application/json;q=0, application/xml;q=0, application/msgpack;q=0, */*", nil, false},
+		{"application/x-msgpack", MsgPack, true},
+		{"text/html", nil, false},
+	}
+	for _, tt := range tests {
+		if got, ok := ByAccept(tt.accept); got != tt.want || ok != tt.ok {
+			t.Errorf("ByAccept(%q) = %v, %v, want %v, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestByContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Codec
+		ok          bool
+	}{
+		{"", JSON, true},
+		{"application/json; charset=utf-8", JSON, true},
+		{"text/xml", XML, true},
+		{"application/msgpack", MsgPack, true},
+		{"text/plain", nil, false},
+		{"bad;;", nil, false},
+	}
+	for _, tt := range tests {
+		if got, ok := ByContentType(tt.contentType); got != tt.want || ok != tt.ok {
+			t.Errorf("ByContentType(%q) = %v, %v, want %v, %v", tt.contentType, got, ok, tt.want, tt.ok)
+		}
+	}
+}
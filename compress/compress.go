@@ -0,0 +1,209 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/andybalholm/brotli"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Поддерживаемые кодировки Content-Encoding
+const (
+	EncodingBrotli   = "br"
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate" // в HTTP deflate - это формат zlib (RFC 1950)
+	EncodingIdentity = "identity"
+)
+
+// encodings represent supported encodings in order of server preference
+var encodings = []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+
+// Config repsent compression configurations
+type Config struct {
+	MinSize      int      // минимальный размер ответа для сжатия
+	Level        int      // уровень сжатия 1 - 9
+	ContentTypes []string // типы ответа для сжатия, допускается маска "text/*"
+}
+
+// Writer represent pooled compressor
+type Writer interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Pool represent pooling of compressor writers
+type Pool struct {
+	cfg   *Config // конфигурационные параметры
+	pools map[string]*sync.Pool
+}
+
+// Represent a pool statistics for benchmarking
+var (
+	countGet uint64 // количество запросов кэша
+	countPut uint64 // количество возвратов в кэша
+	countNew uint64 // количество создания нового объекта
+)
+
+// New create new compressor writers Pool
+func New(cfg *Config) *Pool {
+	p := &Pool{
+		cfg:   cfg,
+		pools: make(map[string]*sync.Pool, len(encodings)),
+	}
+
+	level := cfg.Level
+	p.pools[EncodingBrotli] = &sync.Pool{New: func() interface{} {
+		atomic.AddUint64(&countNew, 1)
+		return brotli.NewWriterLevel(nil, level)
+	}}
+	p.pools[EncodingGzip] = &sync.Pool{New: func() interface{} {
+		atomic.AddUint64(&countNew, 1)
+		w, _ := gzip.NewWriterLevel(nil, level) // уровень проверен при загрузке конфигурации
+		return w
+	}}
+	p.pools[EncodingDeflate] = &sync.Pool{New: func() interface{} {
+		atomic.AddUint64(&countNew, 1)
+		w, _ := zlib.NewWriterLevel(nil, level) // уровень проверен при загрузке конфигурации
+		return w
+	}}
+	return p
+}
+
+// GetWriter get compressor writer for encoding from pool and reset it to w
+func (p *Pool) GetWriter(encoding string, w io.Writer) Writer {
+	pool, ok := p.pools[encoding]
+	if !ok {
+		return nil
+	}
+	atomic.AddUint64(&countGet, 1)
+	cw := pool.Get().(Writer)
+	cw.Reset(w)
+	return cw
+}
+
+// PutWriter return compressor writer into pool
+func (p *Pool) PutWriter(encoding string, cw Writer) {
+	if pool, ok := p.pools[encoding]; ok && cw != nil {
+		atomic.AddUint64(&countPut, 1)
+		cw.Reset(nil) // не удерживаем ссылку на получателя
+		pool.Put(cw)
+	}
+}
+
+// Compress compress data with encoding into buf, if buf is too small new buffer is allocated
+func (p *Pool) Compress(encoding string, data []byte, buf []byte) ([]byte, error) {
+	b := bytes.NewBuffer(buf[:0])
+	cw := p.GetWriter(encoding, b)
+	defer p.PutWriter(encoding, cw)
+
+	if _, err := cw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := cw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Compressible check if response with Content-Type and size should be compressed, size < 0 - unknown size of stream
+func (p *Pool) Compressible(contentType string, size int) bool {
+	if size >= 0 && size < p.cfg.MinSize {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range p.cfg.ContentTypes {
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate choose encoding by Accept-Encoding header, empty result - response is not compressed.
+// При равном весе выбирается кодировка в порядке предпочтения сервера: br, gzip, deflate
+func (p *Pool) Negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64, len(encodings))
+	anyWeight := -1.0 // вес "*"
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				var err error
+				if q, err = strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err != nil {
+					q = 0
+				}
+			}
+		}
+		if name == "*" {
+			anyWeight = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	candidates := make([]string, 0, len(encodings))
+	for _, encoding := range encodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = anyWeight
+		}
+		if q > 0 {
+			weights[encoding] = q
+			candidates = append(candidates, encoding)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return weights[candidates[i]] > weights[candidates[j]] })
+	return candidates[0]
+}
+
+// Supported check if request Content-Encoding can be decompressed
+func Supported(encoding string) bool {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", EncodingIdentity, EncodingBrotli, EncodingGzip, EncodingDeflate:
+		return true
+	}
+	return false
+}
+
+// NewReader create decompressor of request body with Content-Encoding
+func NewReader(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case EncodingGzip:
+		return gzip.NewReader(r)
+	case EncodingDeflate:
+		return zlib.NewReader(r)
+	case EncodingBrotli:
+		return ioutil.NopCloser(brotli.NewReader(r)), nil
+	default:
+		return ioutil.NopCloser(r), nil
+	}
+}
+
+// PrintCompressPoolStats print statistics about compressor writers pool
+func (p *Pool) PrintCompressPoolStats() {
+	mylog.PrintfInfoMsg("Usage compressor writers pool: countGet, countPut, countNew", countGet, countPut, countNew)
+}
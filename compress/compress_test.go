@@ -0,0 +1,93 @@
+package compress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	p := New(&Config{Level: 5})
+	data := bytes.Repeat([]byte(`{"deptNumber":10,"deptName":"ACCOUNTING"}`), 100)
+
+	for _, encoding := range encodings {
+		t.Run(encoding, func(t *testing.T) {
+			// повторное сжатие проверяет корректный Reset writer из pool
+			for i := 0; i < 2; i++ {
+				compressed, err := p.Compress(encoding, data, make([]byte, 0, 16))
+				if err != nil {
+					t.Fatalf("Compress() error = %v", err)
+				}
+				if len(compressed) >= len(data) {
+					t.Errorf("Compress() len = %v, want less than %v", len(compressed), len(data))
+				}
+				r, err := NewReader(encoding, bytes.NewReader(compressed))
+				if err != nil {
+					t.Fatalf("NewReader() error = %v", err)
+				}
+				out, err := ioutil.ReadAll(r)
+				if err != nil {
+					t.Fatalf("ReadAll() error = %v", err)
+				}
+				if !bytes.Equal(out, data) {
+					t.Errorf("decompressed data does not match original")
+				}
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	p := New(&Config{Level: 5})
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"deflate, gzip", "gzip"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"br;q=0, *", "gzip"},
+		{"*;q=0", ""},
+		{"compress", ""},
+	}
+	for _, tt := range tests {
+		if got := p.Negotiate(tt.acceptEncoding); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	p := New(&Config{MinSize: 100, Level: 5, ContentTypes: []string{"application/json", "text/*"}})
+	tests := []struct {
+		contentType string
+		size        int
+		want        bool
+	}{
+		{"application/json; charset=utf-8", 100, true},
+		{"application/json", 99, false},
+		{"application/json", -1, true},
+		{"text/csv; charset=utf-8", 1000, true},
+		{"application/msgpack", 1000, false},
+		{"", 1000, false},
+	}
+	for _, tt := range tests {
+		if got := p.Compressible(tt.contentType, tt.size); got != tt.want {
+			t.Errorf("Compressible(%q, %v) = %v, want %v", tt.contentType, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for _, encoding := range []string{"", "identity", "gzip", "deflate", "br", " GZIP "} {
+		if !Supported(encoding) {
+			t.Errorf("Supported(%q) = false, want true", encoding)
+		}
+	}
+	if Supported("compress") {
+		t.Errorf("Supported(%q) = true, want false", "compress")
+	}
+}
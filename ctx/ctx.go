@@ -2,8 +2,6 @@ package ctx
 
 import (
 	"context"
-
-	"github.com/jmoiron/sqlx" // https://jmoiron.github.io/sqlx/
 )
 
 // The key type for Context value
@@ -13,8 +11,9 @@ type key int
 // arbitrary.  If this package defined other context keys, they would have
 // different integer values.
 const requestIDKey key = 0
-const txKey key = 1
 const sqlKey key = 2
+const apiVersionKey key = 3
+const usernameKey key = 4
 
 // NewContextRequestID returns a new Context carrying RequestID.
 func NewContextRequestID(ctx context.Context, requestID uint64) context.Context {
@@ -32,20 +31,6 @@ func FromContextRequestID(ctx context.Context) uint64 {
 	return requestID
 }
 
-// FromContextTx extracts the Tx from ctx, if present.
-func FromContextTx(ctx context.Context) *sqlx.Tx {
-	tx, ok := ctx.Value(txKey).(*sqlx.Tx)
-	if !ok {
-		return nil
-	}
-	return tx
-}
-
-// NewContextTx returns a new Context carrying Tx.
-func NewContextTx(ctx context.Context, tx *sqlx.Tx) context.Context {
-	return context.WithValue(ctx, txKey, tx)
-}
-
 // FromContextSQLId extracts the SQL Id from ctx, if present
 func FromContextSQLId(ctx context.Context) uint64 {
 	sqlID, ok := ctx.Value(sqlKey).(uint64)
@@ -59,3 +44,25 @@ func FromContextSQLId(ctx context.Context) uint64 {
 func NewContextSQLId(ctx context.Context, sqlID uint64) context.Context {
 	return context.WithValue(ctx, sqlKey, sqlID)
 }
+
+// NewContextAPIVersion returns a new Context carrying version of API
+func NewContextAPIVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, apiVersionKey, version)
+}
+
+// FromContextAPIVersion extracts version of API from ctx, if present
+func FromContextAPIVersion(ctx context.Context) string {
+	version, _ := ctx.Value(apiVersionKey).(string)
+	return version
+}
+
+// NewContextUsername returns a new Context carrying name of authenticated user
+func NewContextUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameKey, username)
+}
+
+// FromContextUsername extracts name of authenticated user from ctx, if present
+func FromContextUsername(ctx context.Context) string {
+	username, _ := ctx.Value(usernameKey).(string)
+	return username
+}
@@ -6,12 +6,12 @@ import (
 	"os/signal"
 	"syscall"
 
-	"github.com/sasbury/mini"
-
 	"github.com/romapres2010/httpserver/db"
 	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
 	"github.com/romapres2010/httpserver/httpserver"
 	"github.com/romapres2010/httpserver/json"
+	"github.com/romapres2010/httpserver/listener"
 	mylog "github.com/romapres2010/httpserver/log"
 )
 
@@ -30,6 +30,11 @@ type Daemon struct {
 
 	jsonService      *json.Service // реализация JSON сервиса
 	jsonServiceErrCh chan error    // канал ошибок для JSON сервиса
+
+	listenerService      *listener.Service // получение изменений объектов от всех экземпляров через PostgreSQL LISTEN
+	listenerServiceErrCh chan error        // канал ошибок для listener
+
+	events *events.Broker // поток изменений объектов, nil - изменения не публикуются
 }
 
 // Config repesent daemon options
@@ -44,12 +49,14 @@ type Config struct {
 	httpServerCfg  httpserver.Config // конфигурация HTTP сервера
 	dbServiceCfg   db.Config         // конфигурация сервиса БД
 	jsonServiceCfg json.Config       // конфигурация JSON сервиса
+	eventsCfg      events.Config     // конфигурация потока изменений объектов
+	listenerCfg    listener.Config   // конфигурация listener
 }
 
 // New create Daemon
 func New(ctx context.Context, cfg *Config) (*Daemon, error) {
 	var err error
-	var config *mini.Config
+	var config *ConfigFile
 
 	mylog.PrintfInfoMsg("Create new daemon")
 
@@ -68,6 +75,8 @@ func New(ctx context.Context, cfg *Config) (*Daemon, error) {
 		httpServerErrCh:  make(chan error, 1), // канал ошибок HTTP сервера
 		dbServiceErrCh:   make(chan error, 1), // канал ошибок для PostgreSQL сервиса
 		jsonServiceErrCh: make(chan error, 1), // канал ошибок для JSON сервиса
+
+		listenerServiceErrCh: make(chan error, 1), // канал ошибок для listener
 	}
 
 	// создаем корневой контекст с отменой
@@ -82,32 +91,51 @@ func New(ctx context.Context, cfg *Config) (*Daemon, error) {
 		return nil, err
 	}
 
+	// создаем поток изменений объектов
+	var publisher events.Publisher
+	if config.Events.UseEvents {
+		loadEventsConfig(config, &daemon.cfg.eventsCfg)
+		daemon.events = events.New(&daemon.cfg.eventsCfg)
+		publisher = daemon.events
+	}
+
 	{ // создаем сервис DB
-		// Настраиваем конфигурацию HTTP Logger
-		if err = loadDBServiceConfig(config, &daemon.cfg.dbServiceCfg); err != nil {
-			return nil, err
+		// Настраиваем конфигурацию сервиса DB
+		loadDBServiceConfig(config, &daemon.cfg.dbServiceCfg)
+
+		// применяем миграции до подготовки SQL команд
+		if config.DB.AutoMigrate {
+			if _, err = migrateDB(daemon.ctx, &daemon.cfg.dbServiceCfg, false, 0); err != nil {
+				return nil, err
+			}
 		}
 
-		if daemon.dbService, err = db.New(daemon.ctx, daemon.dbServiceErrCh, &daemon.cfg.dbServiceCfg); err != nil {
+		if daemon.dbService, err = db.New(daemon.ctx, daemon.dbServiceErrCh, &daemon.cfg.dbServiceCfg, publisher); err != nil {
 			return nil, err
 		}
 	} // создаем сервис PostgreSQL
 
+	// с NOTIFY изменения публикуются в поток через listener - в том числе изменения текущего экземпляра
+	if config.Notify.UseNotify {
+		loadListenerConfig(config, daemon.cfg.dbServiceCfg.SQLCfg.ConnectString, &daemon.cfg.listenerCfg)
+
+		if daemon.listenerService, err = listener.New(daemon.ctx, daemon.listenerServiceErrCh, &daemon.cfg.listenerCfg, daemon.events); err != nil {
+			return nil, err
+		}
+	}
+
 	{ // создаем сервис JSON
 		// daemon.cfg.jsonServiceCfg. =
 
-		if daemon.jsonService, err = json.New(daemon.ctx, daemon.jsonServiceErrCh, &daemon.cfg.jsonServiceCfg, daemon.dbService, daemon.dbService); err != nil {
+		if daemon.jsonService, err = json.New(daemon.ctx, daemon.jsonServiceErrCh, &daemon.cfg.jsonServiceCfg, daemon.dbService, daemon.dbService, daemon.dbService, daemon.dbService, daemon.dbService); err != nil {
 			return nil, err
 		}
 	} // создаем сервис JSON
 
 	{ // создаем HTTP server
 		// Настраиваем конфигурацию HTTP server
-		if err = loadHTTPServerConfig(config, &daemon.cfg.httpServerCfg); err == nil {
-			daemon.cfg.httpServerCfg.ListenSpec = daemon.cfg.ListenSpec
-		} else {
-			return nil, err
-		}
+		loadHTTPServerConfig(config, &daemon.cfg.httpServerCfg)
+		daemon.cfg.httpServerCfg.ListenSpec = daemon.cfg.ListenSpec
 
 		{ // Настраиваем конфигурацию HTTP service
 			// Параметры из командной строки
@@ -119,23 +147,29 @@ func New(ctx context.Context, cfg *Config) (*Daemon, error) {
 			daemon.cfg.httpServerCfg.ServiceCfg.UseHSTS = daemon.cfg.httpServerCfg.UseHSTS
 			daemon.cfg.httpServerCfg.ServiceCfg.MaxBodyBytes = daemon.cfg.httpServerCfg.MaxBodyBytes
 
-			if err = loadHTTPServiceConfig(config, &daemon.cfg.httpServerCfg.ServiceCfg); err == nil {
-				// задан ли в командной строке JSON web token secret key
-				if daemon.cfg.httpServerCfg.ServiceCfg.UseJWT && daemon.cfg.JwtKey == nil {
-					return nil, myerror.New("6023", "JSON web token secret key is null").PrintfInfo()
-				}
+			loadHTTPServiceConfig(config, &daemon.cfg.httpServerCfg.ServiceCfg)
 
-				// Настраиваем конфигурацию HTTP Logger
-				if err = loadHTTPLoggerConfig(config, &daemon.cfg.httpServerCfg.ServiceCfg.LogCfg); err != nil {
-					return nil, err
+			// задан ли в командной строке JSON web token secret key
+			if daemon.cfg.httpServerCfg.ServiceCfg.UseJWT && len(daemon.cfg.JwtKey) == 0 {
+				return nil, myerror.New("6023", "JSON web token secret key is null").PrintfInfo()
+			}
+
+			// Проверим, что для режима утентификации INTERNAL задан пользователь и пароль или файл пользователей
+			if daemon.cfg.httpServerCfg.ServiceCfg.AuthType == "INTERNAL" && daemon.cfg.httpServerCfg.ServiceCfg.UsersFile == "" {
+				if daemon.cfg.HTTPUserID == "" {
+					return nil, myerror.New("6021", "User name for access to HTTP server is null").PrintfInfo()
+				}
+				if daemon.cfg.HTTPUserPwd == "" {
+					return nil, myerror.New("6021", "User password for access to HTTP server is null").PrintfInfo()
 				}
-			} else {
-				return nil, err
 			}
+
+			// Настраиваем конфигурацию HTTP Logger
+			loadHTTPLoggerConfig(config, &daemon.cfg.httpServerCfg.ServiceCfg.LogCfg)
 		} // Настраиваем конфигурацию HTTP service
 
 		// Создаем HTTP server
-		if daemon.httpServer, err = httpserver.New(daemon.ctx, daemon.httpServerErrCh, &daemon.cfg.httpServerCfg, daemon.jsonService); err != nil {
+		if daemon.httpServer, err = httpserver.New(daemon.ctx, daemon.httpServerErrCh, &daemon.cfg.httpServerCfg, daemon.jsonService, daemon.events); err != nil {
 			return nil, err
 		}
 	} // создаем HTTP server
@@ -184,6 +218,18 @@ func (d *Daemon) Shutdown() {
 		mylog.PrintfErrorInfo(myerr) // дополнительно логируем результат остановки
 	}
 
+	// Останавливаем listener до закрытия потока изменений объектов
+	if d.listenerService != nil {
+		if myerr := d.listenerService.Shutdown(); myerr != nil {
+			mylog.PrintfErrorInfo(myerr) // дополнительно логируем результат
+		}
+	}
+
+	// Отключаем подписчиков потока изменений объектов
+	if d.events != nil {
+		d.events.Close()
+	}
+
 	// Останавливаем JSON сервис
 	if myerr := d.jsonService.Shutdown(); myerr != nil {
 		mylog.PrintfErrorInfo(myerr) // дополнительно логируем результат
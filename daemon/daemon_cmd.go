@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"context"
+	"io"
+
+	"github.com/romapres2010/httpserver/db"
+	"github.com/romapres2010/httpserver/db/migrate"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+	mysql "github.com/romapres2010/httpserver/sqlxx"
+)
+
+// PrintConfig load and validate config file, then print effective configuration in requested format
+func PrintConfig(fileName string, format string, w io.Writer) error {
+	config, err := loadConfigFile(fileName)
+	if err != nil {
+		return err
+	}
+	return config.Print(w, format)
+}
+
+// PingDB load config file, connect to DB and return DB server version
+func PingDB(fileName string) (string, error) {
+	config, err := loadConfigFile(fileName)
+	if err != nil {
+		return "", err
+	}
+
+	dbCfg := &db.Config{}
+	loadDBServiceConfig(config, dbCfg)
+
+	// подключение без подготовки SQL команд
+	sqlDB, err := mysql.New(&dbCfg.SQLCfg, nil)
+	if err != nil {
+		return "", err
+	}
+	defer sqlDB.Close()
+
+	var version string
+	if err = sqlDB.QueryRowx("SELECT version()").Scan(&version); err != nil {
+		return "", myerror.WithCause("4003", "Error get DB server version", err).PrintfInfo()
+	}
+
+	mylog.PrintfInfoMsg("DB server is available: Version", version)
+	return version, nil
+}
+
+// MigrateDB load config file and apply (down = false) or roll back (down = true) DB schema migrations
+func MigrateDB(fileName string, down bool, steps int) (int, error) {
+	config, err := loadConfigFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+
+	dbCfg := &db.Config{}
+	loadDBServiceConfig(config, dbCfg)
+
+	return migrateDB(context.Background(), dbCfg, down, steps)
+}
+
+// migrateDB connect to DB and apply or roll back DB schema migrations
+func migrateDB(ctx context.Context, dbCfg *db.Config, down bool, steps int) (int, error) {
+	// подключение без подготовки SQL команд - таблиц может еще не быть
+	sqlDB, err := mysql.New(&dbCfg.SQLCfg, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer sqlDB.Close()
+
+	migrator, err := migrate.New(sqlDB.DB)
+	if err != nil {
+		return 0, err
+	}
+
+	if down {
+		return migrator.Down(ctx, steps)
+	}
+	return migrator.Up(ctx, steps)
+}
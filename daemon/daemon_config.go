@@ -2,298 +2,215 @@ package daemon
 
 import (
 	"crypto/tls"
-	"os"
-	"strconv"
 	"strings"
 
 	"github.com/romapres2010/httpserver/db"
-	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
 	"github.com/romapres2010/httpserver/httpserver"
 	"github.com/romapres2010/httpserver/httpserver/httplog"
 	"github.com/romapres2010/httpserver/httpserver/httpservice"
-	mylog "github.com/romapres2010/httpserver/log"
-	"github.com/sasbury/mini"
+	"github.com/romapres2010/httpserver/listener"
 	auth "gopkg.in/korylprince/go-ad-auth.v2"
 )
 
-// loadConfigFile load confiuration file
-func loadConfigFile(fileName string) (*mini.Config, error) {
-	if fileName == "" {
-		return nil, myerror.New("6013", "Config file is null").PrintfInfo()
-	}
-	mylog.PrintfInfoMsg("Loading HTTP Server config from file: FileName", fileName)
-
-	// Считать информацию о файле
-	_, err := os.Stat(fileName)
-	if os.IsNotExist(err) {
-		return nil, myerror.New("5003", "Config file does not exist: FileName", fileName).PrintfInfo()
-	}
-
-	// Считать конфигурацию из файла
-	config, err := mini.LoadConfiguration(fileName)
-	if err != nil {
-		return nil, myerror.WithCause("5004", "Error load config file: FileName", err, fileName).PrintfInfo()
-	}
-
-	return config, nil
+// tlsVersions represent mapping of TLS version names
+var tlsVersions = map[string]uint16{
+	"VersionTLS13": tls.VersionTLS13,
+	"VersionTLS12": tls.VersionTLS12,
+	"VersionTLS11": tls.VersionTLS11,
+	"VersionTLS10": tls.VersionTLS10,
+	"VersionSSL30": tls.VersionSSL30,
 }
 
-// loadHTTPServerConfig load HTTP server confiuration from file
-func loadHTTPServerConfig(config *mini.Config, cfg *httpserver.Config) error {
-	var myerr error
+// msadSecurities represent mapping of MS AD security names
+var msadSecurities = map[string]auth.SecurityType{
+	"SecurityNone":     auth.SecurityNone,
+	"SecurityTLS":      auth.SecurityTLS,
+	"SecurityStartTLS": auth.SecurityStartTLS,
+}
 
-	{ // секция с основными параметрами HTTP сервера
-		sectionName := "HTTP_SERVER"
+// loadHTTPServerConfig load HTTP server confiuration from config tree
+func loadHTTPServerConfig(config *ConfigFile, cfg *httpserver.Config) {
 
-		if cfg.ReadTimeout, myerr = loadIntFromSection(sectionName, config, "ReadTimeout", true, "60"); myerr != nil {
-			return myerr
-		}
-		if cfg.WriteTimeout, myerr = loadIntFromSection(sectionName, config, "WriteTimeout", true, "60"); myerr != nil {
-			return myerr
-		}
-		if cfg.IdleTimeout, myerr = loadIntFromSection(sectionName, config, "IdleTimeout", true, "60"); myerr != nil {
-			return myerr
-		}
-		if cfg.MaxHeaderBytes, myerr = loadIntFromSection(sectionName, config, "MaxHeaderBytes", true, "0"); myerr != nil {
-			return myerr
-		}
-		if cfg.MaxBodyBytes, myerr = loadIntFromSection(sectionName, config, "MaxBodyBytes", true, "0"); myerr != nil {
-			return myerr
-		}
-		if cfg.UseProfile, myerr = loadBoolFromSection(sectionName, config, "UseProfile", true, "false"); myerr != nil {
-			return myerr
-		}
-		if cfg.ShutdownTimeout, myerr = loadIntFromSection(sectionName, config, "ShutdownTimeout", true, "30"); myerr != nil {
-			return myerr
-		}
+	{ // секция с основными параметрами HTTP сервера
+		cfg.ReadTimeout = config.HTTPServer.ReadTimeout
+		cfg.WriteTimeout = config.HTTPServer.WriteTimeout
+		cfg.IdleTimeout = config.HTTPServer.IdleTimeout
+		cfg.MaxHeaderBytes = config.HTTPServer.MaxHeaderBytes
+		cfg.MaxBodyBytes = config.HTTPServer.MaxBodyBytes
+		cfg.UseProfile = config.HTTPServer.UseProfile
+		cfg.ShutdownTimeout = config.HTTPServer.ShutdownTimeout
 	} // секция с основными параметрами HTTP сервера
 
 	{ // секция с настройками TLS
-		sectionName := "TLS"
-
-		if cfg.UseTLS, myerr = loadBoolFromSection(sectionName, config, "UseTLS", true, "false"); myerr != nil {
-			return myerr
-		}
+		cfg.UseTLS = config.TLS.UseTLS
 
 		if cfg.UseTLS {
-			{ // параметр TLSCertFile
-				if cfg.TLSCertFile, myerr = loadStringFromSection(sectionName, config, "TLSCertFile", true, ""); myerr != nil {
-					return myerr
-				} else if cfg.TLSCertFile != "" {
-					// Считать информацию о файле или каталоге
-					_, err := os.Stat(cfg.TLSCertFile)
-					// Если файл не существует
-					if os.IsNotExist(err) {
-						return myerror.New("5010", "Sertificate file does not exist: FileName", cfg.TLSCertFile).PrintfInfo()
-					}
-				}
-			}
-			{ // параметр TLSKeyFile
-				if cfg.TLSKeyFile, myerr = loadStringFromSection(sectionName, config, "TLSKeyFile", true, ""); myerr != nil {
-					return myerr
-				} else if cfg.TLSKeyFile != "" {
-					// Считать информацию о файле или каталоге
-					_, err := os.Stat(cfg.TLSKeyFile)
-					// Если файл не существует
-					if os.IsNotExist(err) {
-						return myerror.New("5011", "Private key file does not exist: FileName", cfg.TLSKeyFile).PrintfInfo()
-					}
-				}
-			}
-			{ // параметр TLSMinVersion
-				if _TLSMinVersion, myerr := loadStringFromSection(sectionName, config, "TLSMinVersion", true, "VersionSSL30"); myerr != nil {
-					return myerr
-				} else if _TLSMinVersion != "" {
-					switch _TLSMinVersion {
-					case "VersionTLS13":
-						cfg.TLSMinVersion = tls.VersionTLS13
-					case "VersionTLS12":
-						cfg.TLSMinVersion = tls.VersionTLS12
-					case "VersionTLS11":
-						cfg.TLSMinVersion = tls.VersionTLS11
-					case "VersionTLS10":
-						cfg.TLSMinVersion = tls.VersionTLS10
-					default:
-						return myerror.New("5012", "Incorrect TLSMinVersion, only avaliable 'VersionTLS13', 'VersionTLS12', 'VersionTLS11', 'VersionTLS10', 'VersionSSL30'", _TLSMinVersion).PrintfInfo()
-					}
-				}
-			}
-			{ // параметр TLSMaxVersion
-				if _TLSMaxVersion, myerr := loadStringFromSection(sectionName, config, "TLSMaxVersion", true, "VersionTLS13"); myerr != nil {
-					return myerr
-				} else if _TLSMaxVersion != "" {
-					switch _TLSMaxVersion {
-					case "VersionTLS13":
-						cfg.TLSMaxVersion = tls.VersionTLS13
-					case "VersionTLS12":
-						cfg.TLSMaxVersion = tls.VersionTLS12
-					case "VersionTLS11":
-						cfg.TLSMaxVersion = tls.VersionTLS11
-					case "VersionTLS10":
-						cfg.TLSMaxVersion = tls.VersionTLS10
-					default:
-						return myerror.New("5013", "Incorrect TLSMaxVersion, only avaliable 'VersionTLS13', 'VersionTLS12', 'VersionTLS11', 'VersionTLS10', 'VersionSSL30'", _TLSMaxVersion).PrintfInfo()
-					}
-				}
-			}
-			{ // параметр UseHSTS
-				if cfg.UseHSTS, myerr = loadBoolFromSection(sectionName, config, "UseHSTS", true, "false"); myerr != nil {
-					return myerr
-				}
-			}
+			cfg.TLSCertFile = config.TLS.TLSCertFile
+			cfg.TLSKeyFile = config.TLS.TLSKeyFile
+			cfg.TLSMinVersion = tlsVersions[config.TLS.TLSMinVersion]
+			cfg.TLSMaxVersion = tlsVersions[config.TLS.TLSMaxVersion]
+			cfg.UseHSTS = config.TLS.UseHSTS
 		}
-
 	} // секция с настройками TLS
-
-	return nil
 }
 
-// loadHTTPServiceConfig load HTTP handler confiuration from file
-func loadHTTPServiceConfig(config *mini.Config, cfg *httpservice.Config) error {
-	var myerr error
+// loadHTTPServiceConfig load HTTP handler confiuration from config tree
+func loadHTTPServiceConfig(config *ConfigFile, cfg *httpservice.Config) {
 
-	{ // секция JWT
-		sectionName := "JWT"
+	{ // секция HTTP_SERVER
+		cfg.WriteTimeout = config.HTTPServer.WriteTimeout
+		cfg.MaxStreamBodyBytes = config.HTTPServer.MaxStreamBodyBytes
+		cfg.IdempotencyTTL = config.HTTPServer.IdempotencyTTL
+		cfg.IdempotencyMaxKeys = config.HTTPServer.IdempotencyMaxKeys
+		cfg.UseOpenAPI = config.HTTPServer.UseOpenAPI
+		cfg.UseDocsUI = config.HTTPServer.UseDocsUI
+	} // секция HTTP_SERVER
 
-		if cfg.UseJWT, myerr = loadBoolFromSection(sectionName, config, "UseJWT", true, "false"); myerr != nil {
-			return myerr
-		}
+	{ // секция JWT
+		cfg.UseJWT = config.JWT.UseJWT
 
 		if cfg.UseJWT {
-			if cfg.JWTExpiresAt, myerr = loadIntFromSection(sectionName, config, "JWTExpiresAt", true, "10000"); myerr != nil {
-				return myerr
-			}
+			cfg.JWTExpiresAt = config.JWT.JWTExpiresAt
 		}
 	} // секция JWT
 
 	{ // секция AUTHENTIFICATION
-		sectionName := "AUTHENTIFICATION"
-
-		{ // параметр AuthType
-			if _AuthType, myerr := loadStringFromSection(sectionName, config, "AuthType", true, "NONE"); myerr != nil {
-				return myerr
-			} else if _AuthType != "" {
-				switch _AuthType {
-				case "NONE":
-					cfg.AuthType = "NONE"
-				case "INTERNAL":
-					cfg.AuthType = "INTERNAL"
-				case "MSAD":
-					cfg.AuthType = "MSAD"
-				default:
-					return myerror.New("5015", "Incorrect AuthType, only avaliable 'NONE', 'INTERNAL', 'MSAD'", _AuthType).PrintfInfo()
-				}
-			}
-		}
+		cfg.AuthType = config.Auth.AuthType
 
-		// Проверим, что для режима утентификации INTERNAL задан пользователь и пароль
 		if cfg.AuthType == "INTERNAL" {
-			if cfg.HTTPUserID == "" {
-				return myerror.New("6021", "User name for access to HTTP server is null").PrintfInfo()
-			}
-			if cfg.HTTPUserPwd == "" {
-				return myerror.New("6021", "User password for access to HTTP server is null").PrintfInfo()
-			}
+			cfg.UsersFile = config.Auth.UsersFile
 		}
 
-		// Проверим, что для режима утентификации MSAD заданы параметры подключения
 		if cfg.AuthType == "MSAD" {
-			if cfg.MSADServer, myerr = loadStringFromSection(sectionName, config, "MSADServer", true, ""); myerr != nil {
-				return myerr
-			}
-			if cfg.MSADPort, myerr = loadIntFromSection(sectionName, config, "MSADPort", true, ""); myerr != nil {
-				return myerr
-			}
-			if cfg.MSADBaseDN, myerr = loadStringFromSection(sectionName, config, "MSADBaseDN", true, ""); myerr != nil {
-				return myerr
-			}
-
-			if _MSADSecurity, myerr := loadStringFromSection(sectionName, config, "MSADSecurity", true, "NONE"); myerr != nil {
-				return myerr
-			} else if _MSADSecurity != "" {
-				switch _MSADSecurity {
-				case "SecurityNone":
-					cfg.MSADSecurity = int(auth.SecurityNone)
-				case "SecurityTLS":
-					cfg.MSADSecurity = int(auth.SecurityTLS)
-				case "SecurityStartTLS":
-					cfg.MSADSecurity = int(auth.SecurityStartTLS)
-				default:
-					return myerror.New("5016", "Incorrect MSADSecurity, only avaliable 'SecurityNone', 'SecurityTLS', 'SecurityStartTLS'", _MSADSecurity).PrintfInfo()
-				}
-			}
+			cfg.MSADServer = config.Auth.MSADServer
+			cfg.MSADPort = config.Auth.MSADPort
+			cfg.MSADBaseDN = config.Auth.MSADBaseDN
+			cfg.MSADSecurity = int(msadSecurities[config.Auth.MSADSecurity])
 		}
-
 	} // секция AUTHENTIFICATION
 
 	{ // секция LOG
-		sectionName := "LOG"
+		cfg.HTTPLog = config.Log.HTTPLog
+		cfg.HTTPLogFileName = config.Log.HTTPLogFileName
 
-		{
-			if cfg.HTTPLog, myerr = loadBoolFromSection(sectionName, config, "HTTPLog", true, "false"); myerr != nil {
-				return myerr
-			}
+		// логировать ошибки в заголовок ответа
+		if strings.Index(config.Log.HTTPErrLog, "HEADER") >= 0 {
+			cfg.HTTPErrorLogHeader = true
+		}
 
-			if cfg.HTTPLogFileName, myerr = loadStringFromSection(sectionName, config, "HTTPLogFileName", false, ""); myerr != nil {
-				return myerr
-			}
+		// логировать ошибки в тело ответа
+		if strings.Index(config.Log.HTTPErrLog, "BODY") >= 0 {
+			cfg.HTTPErrorLogBody = true
 		}
+	} // секция LOG
 
-		{
-			httpErrLoggerType, myerr := loadStringFromSection(sectionName, config, "HTTPErrLog", false, "")
-			if myerr != nil {
-				return myerr
-			}
+	{ // секция HTTP_POOL
+		cfg.UseBufPool = config.HTTPPool.UseBufPool
 
-			// логировать ошибки в заголовок ответа
-			if strings.Index(httpErrLoggerType, "HEADER") >= 0 {
-				cfg.HTTPErrorLogHeader = true
-			}
+		if cfg.UseBufPool {
+			cfg.BufPooledSize = config.HTTPPool.BufPooledSize
+			cfg.BufPooledMaxSize = config.HTTPPool.BufPooledMaxSize
+		}
+	} // секция HTTP_POOL
 
-			// логировать ошибки в тело ответа
-			if strings.Index(httpErrLoggerType, "BODY") >= 0 {
-				cfg.HTTPErrorLogBody = true
-			}
+	{ // секция COMPRESS
+		cfg.UseCompress = config.Compress.UseCompress
+
+		if cfg.UseCompress {
+			cfg.CompressMinSize = config.Compress.MinSize
+			cfg.CompressLevel = config.Compress.Level
+			cfg.CompressTypes = config.Compress.ContentTypes
+		}
+	} // секция COMPRESS
+
+	{ // секция RATE_LIMIT
+		cfg.UseRateLimit = config.RateLimit.UseRateLimit
+
+		if cfg.UseRateLimit {
+			cfg.RateLimitKeyBy = config.RateLimit.KeyBy
+			cfg.RateLimitAPIKeyHeader = config.RateLimit.APIKeyHeader
+			cfg.RateLimitRequests = config.RateLimit.Requests
+			cfg.RateLimitPeriod = config.RateLimit.Period
+			cfg.RateLimitBurst = config.RateLimit.Burst
+			cfg.RouteRateLimits, _ = parseRouteRateLimits(config.RateLimit.RouteLimits) // формат проверен при загрузке конфигурации
 		}
 
-	} // секция LOG
+		// политика аутентификации действует независимо от UseRateLimit
+		cfg.RateLimitMaxKeys = config.RateLimit.MaxKeys
+		cfg.AuthRateRequests = config.RateLimit.AuthRequests
+		cfg.AuthRatePeriod = config.RateLimit.AuthPeriod
+		cfg.AuthRateBurst = config.RateLimit.AuthBurst
+		cfg.LockoutFailures = config.RateLimit.LockoutFailures
+		cfg.LockoutWindow = config.RateLimit.LockoutWindow
+		cfg.LockoutDuration = config.RateLimit.LockoutDuration
+	} // секция RATE_LIMIT
+
+	{ // секция CONCURRENCY
+		cfg.UseConcurrencyLimit = config.Concurrency.UseConcurrencyLimit
+
+		if cfg.UseConcurrencyLimit {
+			cfg.MaxInFlight = config.Concurrency.MaxInFlight
+			cfg.MaxQueue = config.Concurrency.MaxQueue
+			cfg.QueueTimeout = config.Concurrency.QueueTimeout
+			cfg.OverloadRetryAfter = config.Concurrency.RetryAfter
+			cfg.AdaptiveLimit = config.Concurrency.Adaptive
+			cfg.MinInFlight = config.Concurrency.MinInFlight
+			cfg.TargetLatency = config.Concurrency.TargetLatency
+			cfg.RouteMaxInFlight, _ = parseRouteLimits(config.Concurrency.RouteLimits) // формат проверен при загрузке конфигурации
+		}
+	} // секция CONCURRENCY
+
+	{ // секция CORS
+		cfg.UseCORS = config.CORS.UseCORS
+
+		if cfg.UseCORS {
+			cfg.CORSCfg.AllowedOrigins = config.CORS.AllowedOrigins
+			cfg.CORSCfg.AllowedMethods = config.CORS.AllowedMethods
+			cfg.CORSCfg.AllowedHeaders = config.CORS.AllowedHeaders
+			cfg.CORSCfg.ExposedHeaders = config.CORS.ExposedHeaders
+			cfg.CORSCfg.AllowCredentials = config.CORS.AllowCredentials
+			cfg.CORSCfg.MaxAge = config.CORS.MaxAge
+			cfg.CORSRouteOrigins, _ = parseRouteOrigins(config.CORS.RouteOrigins) // формат проверен при загрузке конфигурации
+		}
+	} // секция CORS
 
-	{ // секция HTTP_POOL
-		sectionName := "HTTP_POOL"
+	{ // секция API_VERSION
+		cfg.UseVersioning = config.APIVersion.UseVersioning
 
-		if cfg.UseBufPool, myerr = loadBoolFromSection(sectionName, config, "UseBufPool", true, "false"); myerr != nil {
-			return myerr
+		if cfg.UseVersioning {
+			cfg.APIVersions = config.APIVersion.Versions
+			cfg.DefaultVersion = config.APIVersion.DefaultVersion
+			cfg.DeprecatedVersions = config.APIVersion.Deprecated
+			cfg.VersionSunset, _ = parseSunset(config.APIVersion.Sunset) // формат проверен при загрузке конфигурации
 		}
+	} // секция API_VERSION
 
-		if cfg.UseBufPool {
-			if cfg.BufPooledSize, myerr = loadIntFromSection(sectionName, config, "BufPooledSize", true, "512"); myerr != nil {
-				return myerr
-			}
-			if cfg.BufPooledMaxSize, myerr = loadIntFromSection(sectionName, config, "BufPooledMaxSize", true, "32768"); myerr != nil {
-				return myerr
-			}
+	{ // секция EVENTS
+		if config.Events.UseEvents {
+			cfg.EventsHeartbeat = config.Events.Heartbeat
+			cfg.EventsRetry = config.Events.Retry
 		}
-	} // секция HTTP_POOL
+	} // секция EVENTS
+}
+
+// loadEventsConfig load events broker confiuration from config tree
+func loadEventsConfig(config *ConfigFile, cfg *events.Config) {
 
-	return nil
+	{ // секция EVENTS
+		cfg.JournalSize = config.Events.JournalSize
+		cfg.BufferSize = config.Events.BufferSize
+	} // секция EVENTS
 }
 
-// loadHTTPLoggerConfig load HTTP Logger confiuration from file
-func loadHTTPLoggerConfig(config *mini.Config, cfg *httplog.Config) error {
-	var myerr error
+// loadHTTPLoggerConfig load HTTP Logger confiuration from config tree
+func loadHTTPLoggerConfig(config *ConfigFile, cfg *httplog.Config) {
 
 	{ // секция LOG
-		sectionName := "LOG"
-
-		if cfg.Enable, myerr = loadBoolFromSection(sectionName, config, "HTTPLog", true, "false"); myerr != nil {
-			return myerr
-		}
+		cfg.Enable = config.Log.HTTPLog
 
 		if cfg.Enable {
-			httpLoggerType, myerr := loadStringFromSection(sectionName, config, "HTTPLogType", true, "")
-			if myerr != nil {
-				return myerr
-			}
+			httpLoggerType := config.Log.HTTPLogType
 
 			// логировать входящие запросы
 			if strings.Index(httpLoggerType, "INREQ") >= 0 {
@@ -320,114 +237,45 @@ func loadHTTPLoggerConfig(config *mini.Config, cfg *httplog.Config) error {
 				cfg.LogBody = true
 			}
 		}
-
 	} // секция LOG
-
-	return nil
 }
 
-// loadDBServiceConfig load PostgreSQL confiuration from file
-func loadDBServiceConfig(config *mini.Config, cfg *db.Config) error {
-	var myerr error
-
-	{ // секция POSTGRESQL
-		sectionName := "DB"
-
-		if cfg.SQLCfg.Host, myerr = loadStringFromSection(sectionName, config, "Host", true, ""); myerr != nil {
-			return myerr
-		}
-		if cfg.SQLCfg.Port, myerr = loadStringFromSection(sectionName, config, "Port", true, ""); myerr != nil {
-			return myerr
-		}
-
-		if cfg.SQLCfg.Dbname, myerr = loadStringFromSection(sectionName, config, "Dbname", true, ""); myerr != nil {
-			return myerr
-		}
-
-		if cfg.SQLCfg.SslMode, myerr = loadStringFromSection(sectionName, config, "SslMode", true, ""); myerr != nil {
-			return myerr
-		}
-
-		if cfg.SQLCfg.User, myerr = loadStringFromSection(sectionName, config, "User", true, ""); myerr != nil {
-			return myerr
-		}
-
-		if cfg.SQLCfg.Pass, myerr = loadStringFromSection(sectionName, config, "Pass", true, ""); myerr != nil {
-			return myerr
-		}
-
-		if cfg.SQLCfg.DriverName, myerr = loadStringFromSection(sectionName, config, "DriverName", true, "pgx"); myerr != nil {
-			return myerr
-		}
-
-		if cfg.SQLCfg.ConnMaxLifetime, myerr = loadIntFromSection(sectionName, config, "ConnMaxLifetime", true, "10000"); myerr != nil {
-			return myerr
-		}
-
-		if cfg.SQLCfg.MaxOpenConns, myerr = loadIntFromSection(sectionName, config, "MaxOpenConns", true, "16"); myerr != nil {
-			return myerr
-		}
-
-		if cfg.SQLCfg.MaxIdleConns, myerr = loadIntFromSection(sectionName, config, "MaxIdleConns", true, "4"); myerr != nil {
-			return myerr
-		}
-
-	} // секция POSTGRESQL
-
-	return nil
-}
-
-// loadIntFromSection load int paparameter and log err
-func loadIntFromSection(sectionName string, pgcfg *mini.Config, name string, manadatory bool, defval string) (int, error) {
-	strVal := pgcfg.StringFromSection(sectionName, name, defval)
-	if manadatory && defval == "" && strVal == "" {
-		return 0, myerror.New("5007", "Missing mandatory: Section, Parameter", sectionName, name).PrintfInfo(1)
-	}
-	intVal, err := strconv.Atoi(strVal)
-	if err != nil {
-		return 0, myerror.WithCause("5005", "Incorrect integer: Section, Parameter, Value", err, sectionName, name, strVal).PrintfInfo(1)
-	}
-	// только положительные параметры
-	if intVal < 0 {
-		return 0, myerror.New("5005", "Negative integer is not allowed: Section, Parameter, Value", sectionName, name, strVal).PrintfInfo(1)
-	}
-
-	mylog.PrintfInfoMsgDepth("Load config parameter: Section, Parameter, Value", 1, sectionName, name, intVal)
-
-	return intVal, nil
+// loadDBServiceConfig load PostgreSQL confiuration from config tree
+func loadDBServiceConfig(config *ConfigFile, cfg *db.Config) {
+
+	{ // секция DB
+		cfg.SQLCfg.Host = config.DB.Host
+		cfg.SQLCfg.Port = config.DB.Port
+		cfg.SQLCfg.Dbname = config.DB.Dbname
+		cfg.SQLCfg.SslMode = config.DB.SslMode
+		cfg.SQLCfg.User = config.DB.User
+		cfg.SQLCfg.Pass = config.DB.Pass
+		cfg.SQLCfg.DriverName = config.DB.DriverName
+		cfg.SQLCfg.ConnMaxLifetime = config.DB.ConnMaxLifetime
+		cfg.SQLCfg.MaxOpenConns = config.DB.MaxOpenConns
+		cfg.SQLCfg.MaxIdleConns = config.DB.MaxIdleConns
+		cfg.SQLCfg.QueryTimeout = config.DB.QueryTimeout
+		cfg.SQLCfg.TxRetryCount = config.DB.TxRetryCount
+		cfg.SQLCfg.TxRetryBackoff = config.DB.TxRetryBackoff
+		cfg.SQLCfg.TxRetryMaxBackoff = config.DB.TxRetryMaxBackoff
+		cfg.SQLCfg.Replicas = config.DB.Replicas
+		cfg.SQLCfg.ReplicaCheckInterval = config.DB.ReplicaCheckInterval
+		cfg.SQLDir = config.DB.SQLDir
+		cfg.SQLReload = config.DB.SQLReload
+	} // секция DB
+
+	{ // секция NOTIFY
+		cfg.Notify = config.Notify.UseNotify
+	} // секция NOTIFY
 }
 
-// loadStringFromSection load str paparameter and log err
-func loadStringFromSection(sectionName string, pgcfg *mini.Config, name string, manadatory bool, defval string) (string, error) {
-	strVal := pgcfg.StringFromSection(sectionName, name, defval)
-	if manadatory && defval == "" && strVal == "" {
-		return "", myerror.New("5007", "Missing mandatory: Section, Parameter", sectionName, name).PrintfInfo(1)
-	}
-	mylog.PrintfInfoMsgDepth("Load config parameter: Section, Parameter, Value", 1, sectionName, name, strVal)
-
-	return strVal, nil
-}
-
-// loadBoolFromSection load bool paparameter and log err
-func loadBoolFromSection(sectionName string, pgcfg *mini.Config, name string, manadatory bool, defval string) (bool, error) {
-	var boolVal bool
-	strVal := pgcfg.StringFromSection(sectionName, name, defval)
-	if manadatory && defval == "" && strVal == "" {
-		return false, myerror.New("5007", "Missing mandatory: Section, Parameter", sectionName, name).PrintfInfo(1)
-	}
-
-	if strVal != "" {
-		switch strVal {
-		case "true":
-			boolVal = true
-		case "false":
-			boolVal = false
-		default:
-			return false, myerror.New("5014", "Incorrect boolean, оnly avaliable: 'true', 'false': Section, Parameter, Value", sectionName, name, strVal).PrintfInfo(1)
-		}
-	}
-
-	mylog.PrintfInfoMsgDepth("Load config parameter: Section, Parameter, Value", 1, sectionName, name, boolVal)
+// loadListenerConfig load listener confiuration from config tree, connect string is taken from DB service
+func loadListenerConfig(config *ConfigFile, connectString string, cfg *listener.Config) {
 
-	return boolVal, nil
+	{ // секция NOTIFY
+		cfg.ConnectString = connectString
+		cfg.MinReconnectInterval = config.Notify.MinReconnectInterval
+		cfg.MaxReconnectInterval = config.Notify.MaxReconnectInterval
+		cfg.PingInterval = config.Notify.PingInterval
+	} // секция NOTIFY
 }
@@ -0,0 +1,654 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sasbury/mini"
+	"gopkg.in/yaml.v2"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/httpserver/httpservice"
+	"github.com/romapres2010/httpserver/json"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// ConfigFile represent typed tree of configuration file
+type ConfigFile struct {
+	HTTPServer  HTTPServerSection  `cfg:"HTTP_SERVER"`
+	TLS         TLSSection         `cfg:"TLS"`
+	JWT         JWTSection         `cfg:"JWT"`
+	Auth        AuthSection        `cfg:"AUTHENTIFICATION"`
+	Log         LogSection         `cfg:"LOG"`
+	HTTPPool    HTTPPoolSection    `cfg:"HTTP_POOL"`
+	Compress    CompressSection    `cfg:"COMPRESS"`
+	RateLimit   RateLimitSection   `cfg:"RATE_LIMIT"`
+	Concurrency ConcurrencySection `cfg:"CONCURRENCY"`
+	CORS        CORSSection        `cfg:"CORS"`
+	APIVersion  APIVersionSection  `cfg:"API_VERSION"`
+	Events      EventsSection      `cfg:"EVENTS"`
+	Notify      NotifySection      `cfg:"NOTIFY"`
+	DB          DBSection          `cfg:"DB"`
+}
+
+// HTTPServerSection represent section HTTP_SERVER
+type HTTPServerSection struct {
+	ReadTimeout        int  `cfg:"ReadTimeout" default:"60"`
+	WriteTimeout       int  `cfg:"WriteTimeout" default:"60"`
+	IdleTimeout        int  `cfg:"IdleTimeout" default:"60"`
+	MaxHeaderBytes     int  `cfg:"MaxHeaderBytes" default:"0"`
+	MaxBodyBytes       int  `cfg:"MaxBodyBytes" default:"0"`
+	MaxStreamBodyBytes int  `cfg:"MaxStreamBodyBytes" default:"0"`
+	UseProfile         bool `cfg:"UseProfile" default:"false"`
+	ShutdownTimeout    int  `cfg:"ShutdownTimeout" default:"30"`
+	IdempotencyTTL     int  `cfg:"IdempotencyTTL" default:"86400"`
+	IdempotencyMaxKeys int  `cfg:"IdempotencyMaxKeys" default:"10000"`
+	UseOpenAPI         bool `cfg:"UseOpenAPI" default:"true"`
+	UseDocsUI          bool `cfg:"UseDocsUI" default:"false"`
+}
+
+// TLSSection represent section TLS
+type TLSSection struct {
+	UseTLS        bool   `cfg:"UseTLS" default:"false"`
+	UseHSTS       bool   `cfg:"UseHSTS" default:"false"`
+	TLSCertFile   string `cfg:"TLSCertFile"`
+	TLSKeyFile    string `cfg:"TLSKeyFile"`
+	TLSMinVersion string `cfg:"TLSMinVersion" default:"VersionSSL30" enum:"VersionTLS13,VersionTLS12,VersionTLS11,VersionTLS10,VersionSSL30"`
+	TLSMaxVersion string `cfg:"TLSMaxVersion" default:"VersionTLS13" enum:"VersionTLS13,VersionTLS12,VersionTLS11,VersionTLS10,VersionSSL30"`
+}
+
+// JWTSection represent section JWT
+type JWTSection struct {
+	UseJWT       bool `cfg:"UseJWT" default:"false"`
+	JWTExpiresAt int  `cfg:"JWTExpiresAt" default:"10000"`
+}
+
+// AuthSection represent section AUTHENTIFICATION
+type AuthSection struct {
+	AuthType     string `cfg:"AuthType" default:"NONE" enum:"NONE,INTERNAL,MSAD"`
+	UsersFile    string `cfg:"UsersFile"`
+	MSADServer   string `cfg:"MSADServer"`
+	MSADPort     int    `cfg:"MSADPort"`
+	MSADBaseDN   string `cfg:"MSADBaseDN"`
+	MSADSecurity string `cfg:"MSADSecurity" default:"SecurityNone" enum:"SecurityNone,SecurityTLS,SecurityStartTLS"`
+}
+
+// LogSection represent section LOG
+type LogSection struct {
+	HTTPLog         bool   `cfg:"HTTPLog" default:"false"`
+	HTTPLogType     string `cfg:"HTTPLogType"`
+	HTTPLogFileName string `cfg:"HTTPLogFileName"`
+	HTTPErrLog      string `cfg:"HTTPErrLog"`
+}
+
+// HTTPPoolSection represent section HTTP_POOL
+type HTTPPoolSection struct {
+	UseBufPool       bool `cfg:"UseBufPool" default:"false"`
+	BufPooledSize    int  `cfg:"BufPooledSize" default:"512"`
+	BufPooledMaxSize int  `cfg:"BufPooledMaxSize" default:"32768"`
+}
+
+// CompressSection represent section COMPRESS
+type CompressSection struct {
+	UseCompress  bool     `cfg:"UseCompress" default:"false"`
+	MinSize      int      `cfg:"MinSize" default:"1024"`
+	Level        int      `cfg:"Level" default:"5"`
+	ContentTypes []string `cfg:"ContentTypes" default:"application/json,application/xml,application/msgpack,application/x-ndjson,text/*"`
+}
+
+// RateLimitSection represent section RATE_LIMIT
+type RateLimitSection struct {
+	UseRateLimit    bool     `cfg:"UseRateLimit" default:"false"`
+	KeyBy           string   `cfg:"KeyBy" default:"IP" enum:"IP,USER,APIKEY"`
+	APIKeyHeader    string   `cfg:"APIKeyHeader" default:"X-API-Key"`
+	Requests        int      `cfg:"Requests" default:"100"`
+	Period          int      `cfg:"Period" default:"1"`
+	Burst           int      `cfg:"Burst" default:"200"`
+	MaxKeys         int      `cfg:"MaxKeys" default:"100000"`
+	AuthRequests    int      `cfg:"AuthRequests" default:"10"`
+	AuthPeriod      int      `cfg:"AuthPeriod" default:"60"`
+	AuthBurst       int      `cfg:"AuthBurst" default:"5"`
+	LockoutFailures int      `cfg:"LockoutFailures" default:"5"`
+	LockoutWindow   int      `cfg:"LockoutWindow" default:"300"`
+	LockoutDuration int      `cfg:"LockoutDuration" default:"900"`
+	RouteLimits     []string `cfg:"RouteLimits"`
+}
+
+// parseRouteRateLimits parse rate limits of routes in format HandlerName=Requests/Period/Burst
+func parseRouteRateLimits(routeLimits []string) (map[string]httpservice.RouteRateLimit, error) {
+	limits := make(map[string]httpservice.RouteRateLimit, len(routeLimits))
+	for _, routeLimit := range routeLimits {
+		parts := strings.SplitN(routeLimit, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("'%s' must be in format HandlerName=Requests/Period/Burst", routeLimit)
+		}
+		values := strings.Split(parts[1], "/")
+		if len(values) != 3 {
+			return nil, fmt.Errorf("'%s' must be in format HandlerName=Requests/Period/Burst", routeLimit)
+		}
+		var limit [3]int
+		for i, value := range values {
+			v, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("'%s' Requests, Period and Burst must be integers greater than 0", routeLimit)
+			}
+			limit[i] = v
+		}
+		limits[strings.TrimSpace(parts[0])] = httpservice.RouteRateLimit{Requests: limit[0], Period: limit[1], Burst: limit[2]}
+	}
+	return limits, nil
+}
+
+// ConcurrencySection represent section CONCURRENCY
+type ConcurrencySection struct {
+	UseConcurrencyLimit bool     `cfg:"UseConcurrencyLimit" default:"false"`
+	MaxInFlight         int      `cfg:"MaxInFlight" default:"64"`
+	MaxQueue            int      `cfg:"MaxQueue" default:"256"`
+	QueueTimeout        int      `cfg:"QueueTimeout" default:"1000"`
+	RetryAfter          int      `cfg:"RetryAfter" default:"1"`
+	Adaptive            bool     `cfg:"Adaptive" default:"false"`
+	MinInFlight         int      `cfg:"MinInFlight" default:"4"`
+	TargetLatency       int      `cfg:"TargetLatency" default:"200"`
+	RouteLimits         []string `cfg:"RouteLimits"`
+}
+
+// parseRouteLimits parse concurrency limits of routes in format HandlerName=MaxInFlight
+func parseRouteLimits(routeLimits []string) (map[string]int, error) {
+	limits := make(map[string]int, len(routeLimits))
+	for _, routeLimit := range routeLimits {
+		parts := strings.SplitN(routeLimit, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("'%s' must be in format HandlerName=MaxInFlight", routeLimit)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("'%s' limit must be an integer greater than 0", routeLimit)
+		}
+		limits[strings.TrimSpace(parts[0])] = limit
+	}
+	return limits, nil
+}
+
+// CORSSection represent section CORS
+type CORSSection struct {
+	UseCORS          bool     `cfg:"UseCORS" default:"false"`
+	AllowedOrigins   []string `cfg:"AllowedOrigins" default:"*"`
+	AllowedMethods   []string `cfg:"AllowedMethods" default:"GET,POST,PUT,PATCH,DELETE"`
+	AllowedHeaders   []string `cfg:"AllowedHeaders" default:"Accept,Content-Type,Content-Encoding,Authorization,If-Match,If-None-Match,Idempotency-Key,X-API-Key"`
+	ExposedHeaders   []string `cfg:"ExposedHeaders" default:"ETag,RequestID,Errcode,Idempotent-Replayed,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,API-Version,Deprecation,Sunset,Link"`
+	AllowCredentials bool     `cfg:"AllowCredentials" default:"false"`
+	MaxAge           int      `cfg:"MaxAge" default:"600"`
+	RouteOrigins     []string `cfg:"RouteOrigins"`
+}
+
+// parseRouteOrigins parse allowed origins of routes in format HandlerName=origin|origin
+func parseRouteOrigins(routeOrigins []string) (map[string][]string, error) {
+	origins := make(map[string][]string, len(routeOrigins))
+	for _, routeOrigin := range routeOrigins {
+		parts := strings.SplitN(routeOrigin, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("'%s' must be in format HandlerName=origin|origin", routeOrigin)
+		}
+		origins[strings.TrimSpace(parts[0])] = strings.Split(strings.TrimSpace(parts[1]), "|")
+	}
+	return origins, nil
+}
+
+// APIVersionSection represent section API_VERSION
+type APIVersionSection struct {
+	UseVersioning  bool     `cfg:"UseVersioning" default:"false"`
+	Versions       []string `cfg:"Versions" default:"v1,v2"`
+	DefaultVersion string   `cfg:"DefaultVersion" default:"v1"`
+	Deprecated     []string `cfg:"Deprecated"`
+	Sunset         []string `cfg:"Sunset"`
+}
+
+// parseSunset parse sunset dates of API versions in format Version=YYYY-MM-DD
+func parseSunset(sunset []string) (map[string]time.Time, error) {
+	dates := make(map[string]time.Time, len(sunset))
+	for _, versionSunset := range sunset {
+		parts := strings.SplitN(versionSunset, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("'%s' must be in format Version=YYYY-MM-DD", versionSunset)
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("'%s' date must be in format YYYY-MM-DD", versionSunset)
+		}
+		dates[strings.TrimSpace(parts[0])] = date
+	}
+	return dates, nil
+}
+
+// EventsSection represent section EVENTS
+type EventsSection struct {
+	UseEvents   bool `cfg:"UseEvents" default:"false"`
+	JournalSize int  `cfg:"JournalSize" default:"1000"`
+	BufferSize  int  `cfg:"BufferSize" default:"64"`
+	Heartbeat   int  `cfg:"Heartbeat" default:"15"`
+	Retry       int  `cfg:"Retry" default:"3000"`
+}
+
+// NotifySection represent section NOTIFY
+type NotifySection struct {
+	UseNotify            bool `cfg:"UseNotify" default:"false"`
+	MinReconnectInterval int  `cfg:"MinReconnectInterval" default:"1000"`
+	MaxReconnectInterval int  `cfg:"MaxReconnectInterval" default:"60000"`
+	PingInterval         int  `cfg:"PingInterval" default:"90000"`
+}
+
+// DBSection represent section DB
+type DBSection struct {
+	Host                 string   `cfg:"Host" required:"true"`
+	Port                 string   `cfg:"Port" required:"true"`
+	Dbname               string   `cfg:"Dbname" required:"true"`
+	SslMode              string   `cfg:"SslMode" required:"true"`
+	User                 string   `cfg:"User" required:"true"`
+	Pass                 string   `cfg:"Pass" required:"true" secret:"true"`
+	DriverName           string   `cfg:"DriverName" default:"pgx"`
+	ConnMaxLifetime      int      `cfg:"ConnMaxLifetime" default:"10000"`
+	MaxOpenConns         int      `cfg:"MaxOpenConns" default:"16"`
+	MaxIdleConns         int      `cfg:"MaxIdleConns" default:"4"`
+	QueryTimeout         int      `cfg:"QueryTimeout" default:"0"`
+	TxRetryCount         int      `cfg:"TxRetryCount" default:"3"`
+	TxRetryBackoff       int      `cfg:"TxRetryBackoff" default:"20"`
+	TxRetryMaxBackoff    int      `cfg:"TxRetryMaxBackoff" default:"1000"`
+	Replicas             []string `cfg:"Replicas"`
+	ReplicaCheckInterval int      `cfg:"ReplicaCheckInterval" default:"5000"`
+	AutoMigrate          bool     `cfg:"AutoMigrate" default:"false"`
+	SQLDir               string   `cfg:"SQLDir"`
+	SQLReload            bool     `cfg:"SQLReload" default:"false"`
+}
+
+// Поддерживаемые форматы конфигурационного файла
+const (
+	ConfigFormatINI  = "INI"
+	ConfigFormatYAML = "YAML"
+	ConfigFormatTOML = "TOML"
+)
+
+// ConfigFormat detect config file format by file extension
+func ConfigFormat(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		return ConfigFormatYAML
+	case ".toml":
+		return ConfigFormatTOML
+	default:
+		return ConfigFormatINI
+	}
+}
+
+// ValidateConfigFile load config file and return all problems found in it
+func ValidateConfigFile(fileName string) (*ConfigFile, ConfigProblems, error) {
+	if fileName == "" {
+		return nil, nil, myerror.New("6013", "Config file is null").PrintfInfo()
+	}
+	mylog.PrintfInfoMsg("Loading HTTP Server config from file: FileName, Format", fileName, ConfigFormat(fileName))
+
+	// Считать информацию о файле
+	_, err := os.Stat(fileName)
+	if os.IsNotExist(err) {
+		return nil, nil, myerror.New("5003", "Config file does not exist: FileName", fileName).PrintfInfo()
+	}
+
+	// Считать секции конфигурации из файла без приведения типов
+	var raw map[string]interface{}
+	switch ConfigFormat(fileName) {
+	case ConfigFormatYAML:
+		raw, err = readYAMLConfig(fileName)
+	case ConfigFormatTOML:
+		raw, err = readTOMLConfig(fileName)
+	default:
+		raw, err = readINIConfig(fileName)
+	}
+	if err != nil {
+		return nil, nil, myerror.WithCause("5004", "Error load config file: FileName", err, fileName).PrintfInfo()
+	}
+
+	// Разберем конфигурацию в типизированное дерево, собирая все проблемы
+	config := &ConfigFile{}
+	problems := decodeConfig(raw, config)
+	problems = append(problems, config.validate()...)
+
+	return config, problems, nil
+}
+
+// loadConfigFile load and validate confiuration file
+func loadConfigFile(fileName string) (*ConfigFile, error) {
+	config, problems, err := ValidateConfigFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			mylog.PrintfInfoMsg("Config problem: FileName, Problem", fileName, problem.String())
+		}
+		return nil, myerror.New("5020", "Config file is invalid: FileName, Problems", fileName, problems.String()).PrintfInfo()
+	}
+
+	return config, nil
+}
+
+// validate check cross parameter rules, which can not be declared in schema
+func (c *ConfigFile) validate() (problems ConfigProblems) {
+	if c.TLS.UseTLS {
+		if c.TLS.TLSCertFile != "" {
+			if _, err := os.Stat(c.TLS.TLSCertFile); os.IsNotExist(err) {
+				problems.add("TLS", "TLSCertFile", "sertificate file '%s' does not exist", c.TLS.TLSCertFile)
+			}
+		} else {
+			problems.add("TLS", "TLSCertFile", "missing mandatory parameter for UseTLS = true")
+		}
+		if c.TLS.TLSKeyFile != "" {
+			if _, err := os.Stat(c.TLS.TLSKeyFile); os.IsNotExist(err) {
+				problems.add("TLS", "TLSKeyFile", "private key file '%s' does not exist", c.TLS.TLSKeyFile)
+			}
+		} else {
+			problems.add("TLS", "TLSKeyFile", "missing mandatory parameter for UseTLS = true")
+		}
+	}
+
+	if c.Auth.AuthType == "MSAD" {
+		if c.Auth.MSADServer == "" {
+			problems.add("AUTHENTIFICATION", "MSADServer", "missing mandatory parameter for AuthType = MSAD")
+		}
+		if c.Auth.MSADPort == 0 {
+			problems.add("AUTHENTIFICATION", "MSADPort", "missing mandatory parameter for AuthType = MSAD")
+		}
+		if c.Auth.MSADBaseDN == "" {
+			problems.add("AUTHENTIFICATION", "MSADBaseDN", "missing mandatory parameter for AuthType = MSAD")
+		}
+	}
+
+	if c.Compress.UseCompress && (c.Compress.Level < 1 || c.Compress.Level > 9) {
+		problems.add("COMPRESS", "Level", "must be in range 1 - 9")
+	}
+
+	if c.RateLimit.UseRateLimit {
+		if c.RateLimit.Requests <= 0 || c.RateLimit.Period <= 0 {
+			problems.add("RATE_LIMIT", "Requests", "Requests and Period must be greater than 0 for UseRateLimit = true")
+		}
+		if c.RateLimit.Burst <= 0 {
+			problems.add("RATE_LIMIT", "Burst", "must be greater than 0 for UseRateLimit = true")
+		}
+		if _, err := parseRouteRateLimits(c.RateLimit.RouteLimits); err != nil {
+			problems.add("RATE_LIMIT", "RouteLimits", "%v", err)
+		}
+	}
+	if c.RateLimit.AuthRequests > 0 && (c.RateLimit.AuthPeriod <= 0 || c.RateLimit.AuthBurst <= 0) {
+		problems.add("RATE_LIMIT", "AuthPeriod", "AuthPeriod and AuthBurst must be greater than 0 for AuthRequests > 0")
+	}
+	if c.RateLimit.LockoutFailures > 0 && (c.RateLimit.LockoutWindow <= 0 || c.RateLimit.LockoutDuration <= 0) {
+		problems.add("RATE_LIMIT", "LockoutDuration", "LockoutWindow and LockoutDuration must be greater than 0 for LockoutFailures > 0")
+	}
+
+	if c.Concurrency.UseConcurrencyLimit {
+		if c.Concurrency.MaxInFlight <= 0 {
+			problems.add("CONCURRENCY", "MaxInFlight", "must be greater than 0 for UseConcurrencyLimit = true")
+		}
+		if c.Concurrency.Adaptive && (c.Concurrency.MinInFlight <= 0 || c.Concurrency.MinInFlight > c.Concurrency.MaxInFlight) {
+			problems.add("CONCURRENCY", "MinInFlight", "must be in range 1 - MaxInFlight for Adaptive = true")
+		}
+		if c.Concurrency.Adaptive && c.Concurrency.TargetLatency <= 0 {
+			problems.add("CONCURRENCY", "TargetLatency", "must be greater than 0 for Adaptive = true")
+		}
+		if _, err := parseRouteLimits(c.Concurrency.RouteLimits); err != nil {
+			problems.add("CONCURRENCY", "RouteLimits", "%v", err)
+		}
+	}
+
+	if c.CORS.UseCORS {
+		if c.CORS.AllowCredentials {
+			for _, origin := range c.CORS.AllowedOrigins {
+				if strings.TrimSpace(origin) == "*" {
+					problems.add("CORS", "AllowedOrigins", "origin '*' is not allowed for AllowCredentials = true")
+				}
+			}
+		}
+		if _, err := parseRouteOrigins(c.CORS.RouteOrigins); err != nil {
+			problems.add("CORS", "RouteOrigins", "%v", err)
+		} else if c.CORS.AllowCredentials {
+			// политика маршрута наследует AllowCredentials общей политики
+			for _, routeOrigin := range c.CORS.RouteOrigins {
+				parts := strings.SplitN(routeOrigin, "=", 2)
+				for _, origin := range strings.Split(parts[1], "|") {
+					if strings.TrimSpace(origin) == "*" {
+						problems.add("CORS", "RouteOrigins", "origin '*' of route '%s' is not allowed for AllowCredentials = true", strings.TrimSpace(parts[0]))
+					}
+				}
+			}
+		}
+	}
+
+	if c.APIVersion.UseVersioning {
+		versions := make(map[string]bool, len(c.APIVersion.Versions))
+		for _, version := range c.APIVersion.Versions {
+			if _, ok := json.Versions[version]; !ok {
+				problems.add("API_VERSION", "Versions", "unknown API version '%s'", version)
+			}
+			versions[version] = true
+		}
+		if len(versions) == 0 {
+			problems.add("API_VERSION", "Versions", "is mandatory for UseVersioning = true")
+		}
+		if !versions[c.APIVersion.DefaultVersion] {
+			problems.add("API_VERSION", "DefaultVersion", "'%s' is not in Versions", c.APIVersion.DefaultVersion)
+		}
+		for _, version := range c.APIVersion.Deprecated {
+			if !versions[version] {
+				problems.add("API_VERSION", "Deprecated", "'%s' is not in Versions", version)
+			}
+		}
+		if sunset, err := parseSunset(c.APIVersion.Sunset); err != nil {
+			problems.add("API_VERSION", "Sunset", "%v", err)
+		} else {
+			for version := range sunset {
+				if !versions[version] {
+					problems.add("API_VERSION", "Sunset", "'%s' is not in Versions", version)
+				}
+			}
+		}
+	}
+
+	if c.Events.UseEvents {
+		if c.Events.JournalSize <= 0 {
+			problems.add("EVENTS", "JournalSize", "must be greater than 0 for UseEvents = true")
+		}
+		if c.Events.BufferSize <= 0 {
+			problems.add("EVENTS", "BufferSize", "must be greater than 0 for UseEvents = true")
+		}
+		if c.Events.Heartbeat <= 0 {
+			problems.add("EVENTS", "Heartbeat", "must be greater than 0 for UseEvents = true")
+		} else if c.HTTPServer.WriteTimeout > 0 && c.Events.Heartbeat >= c.HTTPServer.WriteTimeout {
+			problems.add("EVENTS", "Heartbeat", "must be less than HTTP_SERVER WriteTimeout")
+		}
+	}
+
+	if c.Notify.UseNotify {
+		if !c.Events.UseEvents {
+			problems.add("NOTIFY", "UseNotify", "EVENTS UseEvents = true is mandatory for UseNotify = true")
+		}
+		if c.DB.DriverName != "postgres" && c.DB.DriverName != "pgx" {
+			problems.add("NOTIFY", "UseNotify", "is supported only for DB DriverName 'postgres', 'pgx'")
+		}
+		if c.Notify.MinReconnectInterval <= 0 {
+			problems.add("NOTIFY", "MinReconnectInterval", "must be greater than 0 for UseNotify = true")
+		} else if c.Notify.MinReconnectInterval > c.Notify.MaxReconnectInterval {
+			problems.add("NOTIFY", "MinReconnectInterval", "must not be greater than MaxReconnectInterval")
+		}
+	}
+
+	if c.DB.SQLDir != "" {
+		if info, err := os.Stat(c.DB.SQLDir); err != nil || !info.IsDir() {
+			problems.add("DB", "SQLDir", "SQL catalog directory '%s' does not exist", c.DB.SQLDir)
+		}
+	} else if c.DB.SQLReload {
+		problems.add("DB", "SQLReload", "SQLDir is mandatory for SQLReload = true")
+	}
+
+	if c.DB.TxRetryBackoff > c.DB.TxRetryMaxBackoff {
+		problems.add("DB", "TxRetryBackoff", "must not be greater than TxRetryMaxBackoff")
+	}
+
+	for _, replica := range c.DB.Replicas {
+		if _, _, err := net.SplitHostPort(replica); err != nil {
+			problems.add("DB", "Replicas", "incorrect replica '%s', expected host:port", replica)
+		}
+	}
+
+	if c.HTTPPool.UseBufPool && c.HTTPPool.BufPooledSize > c.HTTPPool.BufPooledMaxSize {
+		problems.add("HTTP_POOL", "BufPooledSize", "must not be greater than BufPooledMaxSize")
+	}
+
+	return problems
+}
+
+// Print write effective config tree in requested format, secret values are masked
+func (c *ConfigFile) Print(w io.Writer, format string) error {
+	sections := encodeConfig(c)
+	buf := &bytes.Buffer{}
+
+	switch strings.ToUpper(format) {
+	case ConfigFormatYAML:
+		doc := make(yaml.MapSlice, 0, len(sections))
+		for _, section := range sections {
+			params := make(yaml.MapSlice, 0, len(section.Params))
+			for _, param := range section.Params {
+				params = append(params, yaml.MapItem{Key: param.Key, Value: param.Value})
+			}
+			doc = append(doc, yaml.MapItem{Key: section.Name, Value: params})
+		}
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return myerror.WithCause("5021", "Error print config: Format", err, format).PrintfInfo()
+		}
+		buf.Write(data)
+	case ConfigFormatTOML, ConfigFormatINI:
+		isTOML := strings.ToUpper(format) == ConfigFormatTOML
+		for i, section := range sections {
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(buf, "[%s]\n", section.Name)
+			for _, param := range section.Params {
+				fmt.Fprintf(buf, "%s = %s\n", param.Key, formatConfigValue(param.Value, isTOML))
+			}
+		}
+	default:
+		return myerror.New("5021", "Incorrect config print format, only avaliable 'yaml', 'toml', 'ini': Format", format).PrintfInfo()
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return myerror.WithCause("5021", "Error print config: Format", err, format).PrintfInfo()
+	}
+	return nil
+}
+
+// formatConfigValue format single parameter value for TOML or INI file
+func formatConfigValue(value interface{}, isTOML bool) string {
+	switch v := value.(type) {
+	case string:
+		if isTOML {
+			return strconv.Quote(v)
+		}
+		return v
+	case []string:
+		if isTOML {
+			strs := make([]string, 0, len(v))
+			for _, s := range v {
+				strs = append(strs, strconv.Quote(s))
+			}
+			return "[" + strings.Join(strs, ", ") + "]"
+		}
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// readINIConfig read INI config file into raw sections
+func readINIConfig(fileName string) (map[string]interface{}, error) {
+	config, err := mini.LoadConfiguration(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+
+	// параметры вне секций не поддерживаются
+	for _, key := range config.Keys() {
+		raw[key] = config.String(key, "")
+	}
+
+	for _, sectionName := range config.SectionNames() {
+		section := make(map[string]interface{})
+		for _, key := range config.KeysForSection(sectionName) {
+			values := config.StringsFromSection(sectionName, key)
+			arr := make([]interface{}, 0, len(values))
+			for _, v := range values {
+				arr = append(arr, v)
+			}
+			section[key] = arr
+		}
+		raw[sectionName] = section
+	}
+	return raw, nil
+}
+
+// readYAMLConfig read YAML config file into raw sections
+func readYAMLConfig(fileName string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[interface{}]interface{}
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	raw, _ := normalizeYAML(doc).(map[string]interface{})
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+	return raw, nil
+}
+
+// normalizeYAML convert YAML maps with interface{} keys into maps with string keys
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for key, val := range t {
+			m[fmt.Sprint(key)] = normalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = normalizeYAML(t[i])
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// readTOMLConfig read TOML config file into raw sections
+func readTOMLConfig(fileName string) (map[string]interface{}, error) {
+	raw := make(map[string]interface{})
+	if _, err := toml.DecodeFile(fileName, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
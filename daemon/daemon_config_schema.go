@@ -0,0 +1,328 @@
+package daemon
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Схема конфигурационного файла задается тегами полей типизированного дерева ConfigFile:
+//     cfg:"Name"        имя секции или параметра в файле, сравнение без учета регистра
+//     default:"value"   значение по умолчанию, если параметр не задан
+//     required:"true"   параметр обязателен и не имеет значения по умолчанию
+//     enum:"A,B,C"      список допустимых значений
+//     secret:"true"     значение параметра не выводится в лог
+// Поддерживаемые типы параметров: string, int (только неотрицательные), bool, []string
+
+// ConfigProblem represent single problem found in config file
+type ConfigProblem struct {
+	Section string // секция конфигурационного файла
+	Key     string // параметр конфигурационного файла
+	Msg     string // описание проблемы
+}
+
+// ConfigProblems represent all problems found in config file
+type ConfigProblems []ConfigProblem
+
+// String format problem
+func (p ConfigProblem) String() string {
+	switch {
+	case p.Section != "" && p.Key != "":
+		return fmt.Sprintf("[%s] %s: %s", p.Section, p.Key, p.Msg)
+	case p.Section != "":
+		return fmt.Sprintf("[%s]: %s", p.Section, p.Msg)
+	default:
+		return p.Msg
+	}
+}
+
+// String format all problems into one line
+func (p ConfigProblems) String() string {
+	strs := make([]string, 0, len(p))
+	for _, problem := range p {
+		strs = append(strs, problem.String())
+	}
+	return strings.Join(strs, "; ")
+}
+
+// add append new problem
+func (p *ConfigProblems) add(section string, key string, format string, args ...interface{}) {
+	*p = append(*p, ConfigProblem{Section: section, Key: key, Msg: fmt.Sprintf(format, args...)})
+}
+
+// decodeConfig decode raw sections into typed config tree and validate it against schema
+func decodeConfig(raw map[string]interface{}, out interface{}) (problems ConfigProblems) {
+	outVal := reflect.ValueOf(out).Elem()
+	outType := outVal.Type()
+
+	// индекс секций без учета регистра
+	sections := make(map[string]int, outType.NumField())
+	for i := 0; i < outType.NumField(); i++ {
+		sections[strings.ToLower(outType.Field(i).Tag.Get("cfg"))] = i
+	}
+
+	// проверим, что в файле нет неизвестных секций
+	rawSections := make(map[int]map[string]interface{}, len(raw))
+	for _, name := range sortedKeys(raw) {
+		idx, ok := sections[strings.ToLower(name)]
+		if !ok {
+			problems.add(name, "", "unknown section")
+			continue
+		}
+		section, ok := raw[name].(map[string]interface{})
+		if !ok {
+			problems.add(name, "", "section must be a table of parameters, got %s", typeName(raw[name]))
+			continue
+		}
+		rawSections[idx] = section
+	}
+
+	// разберем каждую секцию, в том числе отсутствующие в файле - для них применяются значения по умолчанию
+	for i := 0; i < outType.NumField(); i++ {
+		decodeSection(outType.Field(i).Tag.Get("cfg"), rawSections[i], outVal.Field(i), &problems)
+	}
+
+	return problems
+}
+
+// decodeSection decode raw section into typed section structure
+func decodeSection(sectionName string, raw map[string]interface{}, out reflect.Value, problems *ConfigProblems) {
+	outType := out.Type()
+
+	// индекс параметров без учета регистра
+	keys := make(map[string]int, outType.NumField())
+	for i := 0; i < outType.NumField(); i++ {
+		keys[strings.ToLower(outType.Field(i).Tag.Get("cfg"))] = i
+	}
+
+	// проверим, что в секции нет неизвестных параметров
+	values := make(map[int]interface{}, len(raw))
+	for _, name := range sortedKeys(raw) {
+		idx, ok := keys[strings.ToLower(name)]
+		if !ok {
+			problems.add(sectionName, name, "unknown parameter")
+			continue
+		}
+		values[idx] = raw[name]
+	}
+
+	for i := 0; i < outType.NumField(); i++ {
+		field := outType.Field(i)
+		name := field.Tag.Get("cfg")
+
+		value, ok := values[i]
+		if !ok {
+			defval, hasDefault := field.Tag.Lookup("default")
+			if !hasDefault {
+				if field.Tag.Get("required") == "true" {
+					problems.add(sectionName, name, "missing mandatory parameter")
+				}
+				continue
+			}
+			value = defval
+		}
+
+		if err := setConfigValue(out.Field(i), value); err != nil {
+			problems.add(sectionName, name, "%s", err)
+			continue
+		}
+
+		if enum := field.Tag.Get("enum"); enum != "" && out.Field(i).Kind() == reflect.String {
+			str := out.Field(i).String()
+			if !inList(str, strings.Split(enum, ",")) {
+				problems.add(sectionName, name, "incorrect value '%s', only avaliable '%s'", str, strings.Replace(enum, ",", "', '", -1))
+				continue
+			}
+		}
+
+		if field.Tag.Get("secret") == "true" {
+			mylog.PrintfDebugMsg("Load config parameter: Section, Parameter, Value", sectionName, name, "*****")
+		} else {
+			mylog.PrintfDebugMsg("Load config parameter: Section, Parameter, Value", sectionName, name, out.Field(i).Interface())
+		}
+	}
+}
+
+// setConfigValue convert raw value into typed field
+func setConfigValue(out reflect.Value, value interface{}) error {
+	// однозначный массив из INI файла трактуем как скалярное значение
+	if arr, ok := value.([]interface{}); ok && len(arr) == 1 && out.Kind() != reflect.Slice {
+		value = arr[0]
+	}
+
+	switch out.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			out.SetString(v)
+		case int, int64, uint64, float64, bool:
+			out.SetString(fmt.Sprint(v))
+		default:
+			return fmt.Errorf("expected string, got %s", typeName(value))
+		}
+	case reflect.Int:
+		var intVal int64
+		switch v := value.(type) {
+		case int:
+			intVal = int64(v)
+		case int64:
+			intVal = v
+		case uint64:
+			if v > math.MaxInt32 {
+				return fmt.Errorf("integer %v is out of range", v)
+			}
+			intVal = int64(v)
+		case float64:
+			if v != math.Trunc(v) {
+				return fmt.Errorf("expected integer, got float %v", v)
+			}
+			intVal = int64(v)
+		case string:
+			var err error
+			if intVal, err = strconv.ParseInt(strings.TrimSpace(v), 10, 0); err != nil {
+				return fmt.Errorf("expected integer, got '%s'", v)
+			}
+		default:
+			return fmt.Errorf("expected integer, got %s", typeName(value))
+		}
+		// только положительные параметры
+		if intVal < 0 {
+			return fmt.Errorf("negative integer %v is not allowed", intVal)
+		}
+		out.SetInt(intVal)
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			out.SetBool(v)
+		case string:
+			switch strings.TrimSpace(v) {
+			case "true":
+				out.SetBool(true)
+			case "false":
+				out.SetBool(false)
+			default:
+				return fmt.Errorf("incorrect boolean '%s', оnly avaliable: 'true', 'false'", v)
+			}
+		default:
+			return fmt.Errorf("expected boolean, got %s", typeName(value))
+		}
+	case reflect.Slice:
+		var strs []string
+		switch v := value.(type) {
+		case string:
+			strs = splitList(v)
+		case []interface{}:
+			for _, item := range v {
+				switch s := item.(type) {
+				case string:
+					// в INI файле список можно задать через запятую
+					strs = append(strs, splitList(s)...)
+				case int, int64, uint64, float64, bool:
+					strs = append(strs, fmt.Sprint(s))
+				default:
+					return fmt.Errorf("expected list of strings, got element %s", typeName(item))
+				}
+			}
+		default:
+			return fmt.Errorf("expected list of strings, got %s", typeName(value))
+		}
+		out.Set(reflect.ValueOf(strs))
+	default:
+		return fmt.Errorf("unsupported parameter type %s", out.Type())
+	}
+	return nil
+}
+
+// typeName return human readable type of raw value
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "float"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "table"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// sortedKeys return sorted keys of map for stable order of problems
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitList split comma separated list, empty items are skipped
+func splitList(value string) []string {
+	var strs []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// inList check if value present in list
+func inList(value string, list []string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// configParam represent single parameter of typed config tree
+type configParam struct {
+	Key   string
+	Value interface{}
+}
+
+// configSection represent section of typed config tree
+type configSection struct {
+	Name   string
+	Params []configParam
+}
+
+// encodeConfig convert typed config tree into ordered sections, secret values are masked
+func encodeConfig(in interface{}) []configSection {
+	inVal := reflect.ValueOf(in).Elem()
+	inType := inVal.Type()
+
+	sections := make([]configSection, 0, inType.NumField())
+	for i := 0; i < inType.NumField(); i++ {
+		sectionVal := inVal.Field(i)
+		sectionType := sectionVal.Type()
+
+		section := configSection{Name: inType.Field(i).Tag.Get("cfg")}
+		for j := 0; j < sectionType.NumField(); j++ {
+			field := sectionType.Field(j)
+			value := sectionVal.Field(j).Interface()
+			if field.Tag.Get("secret") == "true" {
+				value = "*****"
+			}
+			section.Params = append(section.Params, configParam{Key: field.Tag.Get("cfg"), Value: value})
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
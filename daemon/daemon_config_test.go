@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T, dir string, name string, text string) string {
+	fileName := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(fileName, []byte(text), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func testConfigDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "httpserver_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestValidateConfigFile_Formats(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"httpserver.cfg", "[HTTP_SERVER]\nReadTimeout = 10\n[DB]\nHost = h\nPort = 5432\nDbname = d\nSslMode = disable\nUser = u\nPass = p\n"},
+		{"httpserver.yaml", "HTTP_SERVER:\n  ReadTimeout: 10\nDB:\n  Host: h\n  Port: 5432\n  Dbname: d\n  SslMode: disable\n  User: u\n  Pass: p\n"},
+		{"httpserver.toml", "[HTTP_SERVER]\nReadTimeout = 10\n[DB]\nHost = \"h\"\nPort = \"5432\"\nDbname = \"d\"\nSslMode = \"disable\"\nUser = \"u\"\nPass = \"p\"\n"},
+	}
+
+	dir := testConfigDir(t)
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, problems, err := ValidateConfigFile(writeTestConfig(t, dir, tt.name, tt.text))
+			if err != nil {
+				t.Fatalf("ValidateConfigFile() error = %v", err)
+			}
+			if len(problems) != 0 {
+				t.Fatalf("ValidateConfigFile() problems = %v", problems)
+			}
+			if config.HTTPServer.ReadTimeout != 10 || config.HTTPServer.WriteTimeout != 60 {
+				t.Errorf("ValidateConfigFile() ReadTimeout, WriteTimeout = %v, %v, want 10, 60", config.HTTPServer.ReadTimeout, config.HTTPServer.WriteTimeout)
+			}
+			if config.DB.Port != "5432" || config.DB.DriverName != "pgx" {
+				t.Errorf("ValidateConfigFile() Port, DriverName = %v, %v, want 5432, pgx", config.DB.Port, config.DB.DriverName)
+			}
+		})
+	}
+}
+
+func TestValidateConfigFile_AllProblems(t *testing.T) {
+	text := `
+HTTP_SERVER:
+  ReadTimeout: abc
+  WriteTimeout: -1
+  Unknown: 1
+TLS:
+  UseHSTS: 1
+AUTHENTIFICATION:
+  AuthType: LDAP
+RATE_LIMIT:
+  UseRateLimit: true
+  RouteLimits: ExportDeptsHandler=10/60
+CORS:
+  UseCORS: true
+  AllowedOrigins: https://app.example.com
+  AllowCredentials: true
+  RouteOrigins: GetDeptHandler=https://a.example.com|*
+EVENTS:
+  UseEvents: true
+  JournalSize: 0
+NOTIFY:
+  UseNotify: true
+  MinReconnectInterval: 5000
+  MaxReconnectInterval: 1000
+UNKNOWN_SECTION:
+  Key: value
+`
+	dir := testConfigDir(t)
+	defer os.RemoveAll(dir)
+
+	_, problems, err := ValidateConfigFile(writeTestConfig(t, dir, "httpserver.yml", text))
+	if err != nil {
+		t.Fatalf("ValidateConfigFile() error = %v", err)
+	}
+
+	want := []string{
+		"[UNKNOWN_SECTION]: unknown section",
+		"[HTTP_SERVER] Unknown: unknown parameter",
+		"[HTTP_SERVER] ReadTimeout: expected integer",
+		"[HTTP_SERVER] WriteTimeout: negative integer",
+		"[TLS] UseHSTS: expected boolean",
+		"[AUTHENTIFICATION] AuthType: incorrect value 'LDAP'",
+		"[RATE_LIMIT] RouteLimits: 'ExportDeptsHandler=10/60' must be in format HandlerName=Requests/Period/Burst",
+		"[CORS] RouteOrigins: origin '*' of route 'GetDeptHandler' is not allowed for AllowCredentials = true",
+		"[EVENTS] JournalSize: must be greater than 0 for UseEvents = true",
+		"[NOTIFY] MinReconnectInterval: must not be greater than MaxReconnectInterval",
+		"[DB] Host: missing mandatory parameter",
+		"[DB] Pass: missing mandatory parameter",
+	}
+
+	got := problems.String()
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("ValidateConfigFile() problems do not contain %q, got %v", w, got)
+		}
+	}
+}
@@ -2,8 +2,13 @@ package db
 
 import (
 	"context"
+	"expvar"
+	"time"
 
+	myctx "github.com/romapres2010/httpserver/ctx"
 	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
+	"github.com/romapres2010/httpserver/listener"
 	mylog "github.com/romapres2010/httpserver/log"
 	mysql "github.com/romapres2010/httpserver/sqlxx"
 )
@@ -16,19 +21,26 @@ type Service struct {
 	errCh  chan<- error       // канал ошибок
 	stopCh chan struct{}      // канал подтверждения об успешном закрытии сервиса
 
-	db      *mysql.DB     // БД
-	SQLStms mysql.SQLStms // SQL команды
+	db        *mysql.DB        // БД
+	SQLStms   mysql.SQLStms    // SQL команды
+	publisher events.Publisher // публикация изменений объектов, nil - изменения не публикуются
 
 	// вложенные сервисы
 }
 
 // Config конфигурационные настройки
 type Config struct {
-	SQLCfg mysql.Config
+	SQLCfg    mysql.Config
+	SQLDir    string // каталог с SQL командами, пусто - встроенный каталог
+	SQLReload bool   // перечитывать каталог SQL команд при изменении (режим разработки)
+	Notify    bool   // публиковать изменения через PostgreSQL NOTIFY для всех экземпляров, иначе - в publisher
 }
 
-// New create DB service
-func New(ctx context.Context, errCh chan<- error, cfg *Config) (*Service, error) {
+// vars represent published statistics of DB connection pools, it is served on /debug/vars
+var vars = expvar.NewMap("db")
+
+// New create DB service, changes of Dept and Emp are published to publisher after commit
+func New(ctx context.Context, errCh chan<- error, cfg *Config, publisher events.Publisher) (*Service, error) {
 	var err error
 
 	mylog.PrintfInfoMsg("Creating new DB service")
@@ -41,9 +53,10 @@ func New(ctx context.Context, errCh chan<- error, cfg *Config) (*Service, error)
 
 	// Создаем новый сервис
 	service := &Service{
-		cfg:    cfg,
-		errCh:  errCh,
-		stopCh: make(chan struct{}, 1), // канал подтверждения об успешном закрытии сервиса
+		cfg:       cfg,
+		errCh:     errCh,
+		stopCh:    make(chan struct{}, 1), // канал подтверждения об успешном закрытии сервиса
+		publisher: publisher,
 	}
 
 	// создаем контекст с отменой
@@ -53,22 +66,10 @@ func New(ctx context.Context, errCh chan<- error, cfg *Config) (*Service, error)
 		service.ctx, service.cancel = context.WithCancel(ctx)
 	}
 
-	// Наполним список SQL команд
-	sqlStms := map[string]*mysql.SQLStm{
-		"GetDept":         &mysql.SQLStm{"SELECT deptno, dname, loc FROM dept WHERE deptno = $1", nil, true},
-		"GetDeptUK":       &mysql.SQLStm{"SELECT deptno, dname, loc FROM dept WHERE deptno = $1", nil, true},
-		"DeptExists":      &mysql.SQLStm{"SELECT 1 FROM dept WHERE deptno = $1", nil, true},
-		"GetDepts":        &mysql.SQLStm{"SELECT deptno, dname, loc FROM dept", nil, true},
-		"GetDeptsPK":      &mysql.SQLStm{"SELECT deptno FROM dept", nil, true},
-		"CreateDept":      &mysql.SQLStm{"INSERT INTO dept (deptno, dname, loc) VALUES (:deptno, :dname, :loc)", nil, false},
-		"UpdateDept":      &mysql.SQLStm{"UPDATE dept SET dname = :dname, loc = :loc WHERE deptno = :deptno", nil, false},
-		"EmpExists":       &mysql.SQLStm{"SELECT 1 FROM emp WHERE empno = $1", nil, true},
-		"GetEmp":          &mysql.SQLStm{"SELECT empno, ename, job, mgr, hiredate, sal, comm, deptno FROM emp WHERE empno = $1", nil, true},
-		"GetEmpUK":        &mysql.SQLStm{"SELECT empno, ename, job, mgr, hiredate, sal, comm, deptno FROM emp WHERE empno = $1", nil, true},
-		"GetEmpsByDept":   &mysql.SQLStm{"SELECT empno, ename, job, mgr, hiredate, sal, comm, deptno FROM emp WHERE deptno = $1", nil, true},
-		"GetEmpsPKByDept": &mysql.SQLStm{"SELECT empno FROM emp WHERE deptno = $1", nil, true},
-		"CreateEmp":       &mysql.SQLStm{"INSERT INTO emp (empno, ename, job, mgr, hiredate, sal, comm, deptno) VALUES (:empno, :ename, :job, :mgr, :hiredate, :sal, :comm, :deptno)", nil, false},
-		"UpdateEmp":       &mysql.SQLStm{"UPDATE emp SET empno = :empno, ename = :ename, job = :job, mgr = :mgr, hiredate = :hiredate, sal = :sal, comm = :comm, deptno = :deptno WHERE empno = :empno", nil, false},
+	// Загрузим SQL команды из каталога
+	sqlStms, err := service.loadSQLCatalog()
+	if err != nil {
+		return nil, err
 	}
 
 	// Создадим подключение к БД
@@ -76,10 +77,68 @@ func New(ctx context.Context, errCh chan<- error, cfg *Config) (*Service, error)
 		return nil, err
 	}
 
+	// Статистика пулов подключений
+	vars.Set("pools", expvar.Func(func() interface{} { return service.Stats() }))
+
+	// в режиме разработки перечитываем каталог SQL команд при изменении
+	if cfg.SQLDir != "" && cfg.SQLReload {
+		go service.watchSQLCatalog()
+	}
+
 	mylog.PrintfInfoMsg("DB service is created")
 	return service, nil
 }
 
+// InTx run fn as unit of work - all Dept and Emp calls with ctx passed to fn are done in one transaction.
+// Nested InTx calls are run inside savepoints of outer transaction.
+func (s *Service) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.db.InTx(ctx, txWriteOptions, fn)
+}
+
+// publish publish change of object after commit of transaction from ctx, without transaction - immediately.
+// With Notify change is sent by pg_notify in the same transaction, it is delivered to listeners of all instances
+func (s *Service) publish(ctx context.Context, typ string, object string, key int, version int64) error {
+	e := events.Event{Type: typ, Object: object, Key: key, Version: version}
+	if s.cfg.Notify {
+		return s.notify(ctx, &e)
+	}
+	if s.publisher == nil {
+		return nil
+	}
+	if tx := mysql.FromContextTx(ctx); tx != nil {
+		tx.AfterCommit(func() { s.publisher.Publish(e) })
+		return nil
+	}
+	s.publisher.Publish(e)
+	return nil
+}
+
+// notify send change of object to NOTIFY channel of object, without transaction in ctx - in its own transaction
+func (s *Service) notify(ctx context.Context, e *events.Event) error {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	e.Time = time.Now()
+	payload, err := listener.Payload(e)
+	if err != nil {
+		return myerror.WithCause("4005", "Error marshal event: reqID, object, key", err, reqID, e.Object, e.Key).PrintfInfo()
+	}
+	args := &notifyArgs{Channel: listener.Channel(e.Object), Payload: payload}
+
+	if mysql.FromContextTx(ctx) != nil {
+		_, err = s.db.Exec(ctx, sqlNotifyEvent, args)
+		return err
+	}
+	return s.db.InTx(ctx, txWriteOptions, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, sqlNotifyEvent, args)
+		return err
+	})
+}
+
+// Stats return statistics of primary and replica DB connection pools, it is published through expvar in variable "db"
+func (s *Service) Stats() []mysql.PoolStats {
+	return s.db.Stats()
+}
+
 // Shutdown shutting down service
 func (s *Service) Shutdown() (myerr error) {
 	mylog.PrintfInfoMsg("Shutdowning DB service")
@@ -90,6 +149,12 @@ func (s *Service) Shutdown() (myerr error) {
 		// закрываем вложенные сервисы
 	}
 
+	// Print statistics about DB connection pools
+	if s.db != nil {
+		s.db.PrintPoolStats()
+		myerr = s.db.Close()
+	}
+
 	mylog.PrintfInfoMsg("DB service shutdown successfuly")
 	return
 }
@@ -6,28 +6,29 @@ import (
 
 	myctx "github.com/romapres2010/httpserver/ctx"
 	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
 	mylog "github.com/romapres2010/httpserver/log"
 	model "github.com/romapres2010/httpserver/model"
 	mysql "github.com/romapres2010/httpserver/sqlxx"
 	"gopkg.in/guregu/null.v4"
 )
 
-// getDept return a Dept with a given id
-func (s *Service) getDept(ctx context.Context, tx *mysql.Tx, out *model.Dept) (exists bool, myerr error) {
+// getDept return a Dept with a given id, emps are requested only if withEmps
+func (s *Service) getDept(ctx context.Context, out *model.Dept, withEmps bool) (exists bool, myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 
 	if out != nil {
 		mylog.PrintfDebugMsg("START: reqID, Deptno", reqID, out.Deptno)
 
 		// Запросим основной объект
-		if exists, myerr = s.db.Get(reqID, tx, "GetDept", out, out.Deptno); myerr != nil {
+		if exists, myerr = s.db.Get(ctx, sqlGetDept, out, out.Deptno); myerr != nil {
 			return false, myerr
 		}
 
 		// Запросим вложенные объекты
-		if exists {
+		if exists && withEmps {
 			outEmps := model.GetEmpSlice() // Извлечем из pool срез для вложенных объектов
-			if myerr = s.getEmpsByDept(ctx, tx, out, &outEmps); myerr != nil {
+			if myerr = s.getEmpsByDept(ctx, out, &outEmps); myerr != nil {
 				return false, myerr
 			}
 			out.Emps = outEmps // Встроим срез в основной объект
@@ -38,23 +39,46 @@ func (s *Service) getDept(ctx context.Context, tx *mysql.Tx, out *model.Dept) (e
 	return false, myerror.New("4400", "Incorrect call 'out != nil': reqID", reqID).PrintfInfo()
 }
 
+// getDeptVersion return row version of Dept with a given id, versions of emps are requested only if withEmps
+func (s *Service) getDeptVersion(ctx context.Context, deptno int, out *model.Version, withEmps bool) (exists bool, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	if out != nil {
+		mylog.PrintfDebugMsg("START: reqID, Deptno", reqID, deptno)
+
+		if exists, myerr = s.db.Get(ctx, sqlGetDeptVer, &out.Version, deptno); myerr != nil {
+			return false, myerr
+		}
+
+		if exists && withEmps {
+			out.Embedded = []model.EmbeddedVersion{}
+			if myerr = s.db.Select(ctx, sqlGetEmpVers, &out.Embedded, deptno); myerr != nil {
+				return false, myerr
+			}
+		}
+
+		return exists, nil
+	}
+	return false, myerror.New("4400", "Incorrect call 'out != nil': reqID", reqID).PrintfInfo()
+}
+
 // getDeptsPK return a PK for all Dept
-func (s *Service) getDeptsPK(ctx context.Context, tx *mysql.Tx, out *model.DeptPKs) (myerr error) {
+func (s *Service) getDeptsPK(ctx context.Context, out *model.DeptPKs) (myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 
 	if out != nil {
 		mylog.PrintfDebugMsg("START: reqID", reqID)
 
-		return s.db.Select(reqID, tx, "GetDeptsPK", out)
+		return s.db.Select(ctx, sqlGetDeptsPK, out)
 	}
 	return myerror.New("4400", "Incorrect call 'out != nil': reqID", reqID).PrintfInfo()
 }
 
 // createDept create new Dept
-func (s *Service) createDept(ctx context.Context, tx *mysql.Tx, in *model.Dept, out *model.Dept) (myerr error) {
+func (s *Service) createDept(ctx context.Context, in *model.Dept, out *model.Dept) (myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 
-	if in != nil && out != nil && tx != nil {
+	if in != nil && out != nil && mysql.FromContextTx(ctx) != nil {
 		mylog.PrintfDebugMsg("START: reqID, Deptno", reqID, in.Deptno)
 
 		newDept := model.GetDept()         // Извлечем из pool структуру для нового экземпляра в БД
@@ -63,17 +87,17 @@ func (s *Service) createDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 		{ // Проверим, существует ли строка по натуральному уникальному ключу UK
 			mylog.PrintfDebugMsg("Check if row already exists: reqID, Deptno", reqID, in.Deptno)
 			var foo int
-			exists, myerr := s.db.Get(reqID, tx, "DeptExists", &foo, in.Deptno)
+			exists, myerr := s.db.Get(ctx, sqlDeptExists, &foo, in.Deptno)
 			if myerr != nil {
 				return myerr
 			}
 			if exists {
-				return myerror.New("4004", "Error create - row already exists: reqID, Deptno", reqID, in.Deptno).PrintfInfo()
+				return myerror.New(mysql.ErrCodeConstraintViolation, "Error create - row already exists: reqID, Deptno", reqID, in.Deptno).PrintfInfo()
 			}
 		} // Проверим, существует ли строка по натуральному уникальному ключу UK
 
 		{ // Выполняем вставку и получим значение сурогатного PK
-			rows, myerr := s.db.Exec(reqID, tx, "CreateDept", in)
+			rows, myerr := s.db.Exec(ctx, sqlCreateDept, in)
 			if myerr != nil {
 				return myerr
 			}
@@ -84,13 +108,16 @@ func (s *Service) createDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 
 			// считаем созданный объект - в БД могли быть тригера, которые меняли данные
 			// запрос делаем по UK, так как сурогатный PK мы еще не знаем
-			exists, myerr := s.db.Get(reqID, tx, "GetDeptUK", newDept, in.Deptno)
+			exists, myerr := s.db.Get(ctx, sqlGetDeptUK, newDept, in.Deptno)
 			if myerr != nil {
 				return myerr
 			}
 			if !exists {
 				return myerror.New("4004", "Row does not exists after creating: reqID, Deptno", reqID, in.Deptno).PrintfInfo()
 			}
+			if err := s.publish(ctx, events.TypeCreated, events.ObjectDept, newDept.Deptno, newDept.Version); err != nil {
+				return err
+			}
 		} // Выполняем вставку и получим значение сурогатного PK
 
 		{ // Обработаем вложенные объекты в рамках текущей транзации
@@ -100,7 +127,7 @@ func (s *Service) createDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 					newEmp.Deptno = null.Int{sql.NullInt64{int64(newDept.Deptno), true}}
 
 					// создаем вложенные объекты
-					if myerr = s.createEmp(ctx, tx, newEmp, nil); myerr != nil {
+					if myerr = s.createEmp(ctx, newEmp, nil); myerr != nil {
 						return myerr
 					}
 				}
@@ -110,7 +137,7 @@ func (s *Service) createDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 		// считаем обновленный объект из БД
 		if out != nil {
 			out.Deptno = newDept.Deptno // столбцы первичного ключа PK
-			exists, myerr := s.getDept(ctx, tx, out)
+			exists, myerr := s.getDept(ctx, out, true)
 			if myerr != nil {
 				return myerr
 			}
@@ -121,16 +148,18 @@ func (s *Service) createDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 		}
 		return nil
 	}
-	return myerror.New("4400", "Incorrect call 'in != nil && tx != nil': reqID", reqID).PrintfInfo()
+	return myerror.New("4400", "Incorrect call 'in != nil && tx in context': reqID", reqID).PrintfInfo()
 }
 
 // updateDept update the Dept
-func (s *Service) updateDept(ctx context.Context, tx *mysql.Tx, in *model.Dept, out *model.Dept) (exists bool, myerr error) {
+func (s *Service) updateDept(ctx context.Context, in *model.Dept, out *model.Dept) (exists bool, myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 
-	if in != nil && tx != nil {
+	if in != nil && mysql.FromContextTx(ctx) != nil {
 		mylog.PrintfDebugMsg("START: reqID, Deptno", reqID, in.Deptno)
 
+		version := in.Version // ожидаемая версия строки
+
 		oldDept := model.GetDept()         // Извлечем из pool структуру для старого экземпляра в БД
 		defer model.PutDept(oldDept, true) // Вернем структуру в pool
 
@@ -140,7 +169,7 @@ func (s *Service) updateDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 		{ // Считаем состояние объекта до обновления и проверим его существование
 			mylog.PrintfDebugMsg("Get row and check if it exists: reqID, PK", reqID, in.Deptno)
 			oldDept.Deptno = in.Deptno // столбцы первичного ключа PK
-			if exists, myerr = s.getDept(ctx, tx, oldDept); myerr != nil {
+			if exists, myerr = s.getDept(ctx, oldDept, true); myerr != nil {
 				return false, myerr
 			}
 			if !exists {
@@ -150,39 +179,57 @@ func (s *Service) updateDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 		} // Считаем состояние объекта до обновления и проверим его существование
 
 		{ // выполняем проверки / действия на основании старых и новых значений атрибутов
+			// Проверить версию строки, если версия не передана - обновляем текущую версию
+			// in не изменяем - функция может быть повторена в новой транзакции
+			if version == 0 {
+				version = oldDept.Version
+			}
+			if version != oldDept.Version {
+				return false, myerror.New(mysql.ErrCodeVersionConflict, "Error update - row version does not match: reqID, Deptno, version, current version", reqID, in.Deptno, version, oldDept.Version).PrintfInfo()
+			}
+
 			// Проверить изменение UK
 			// для Dept PK и UK совпадают - эта проверка только для примера
 			if oldDept.Deptno != in.Deptno {
 				mylog.PrintfDebugMsg("Check if row already exists: reqID, Deptno", reqID, in.Deptno)
 				var foo int
-				exists, myerr := s.db.Get(reqID, tx, "DeptExists", &foo, in.Deptno)
+				exists, myerr := s.db.Get(ctx, sqlDeptExists, &foo, in.Deptno)
 				if myerr != nil {
 					return false, myerr
 				}
 				if exists {
-					return false, myerror.New("4004", "Error update - row with UK already exists: reqID, Deptno", reqID, in.Deptno).PrintfInfo()
+					return false, myerror.New(mysql.ErrCodeConstraintViolation, "Error update - row with UK already exists: reqID, Deptno", reqID, in.Deptno).PrintfInfo()
 				}
 			}
 		} // выполняем проверки / действия на основании старых и новых значений атрибутов
 
 		{ // Выполняем обновление
-			rows, myerr := s.db.Exec(reqID, tx, "UpdateDept", in)
+			upd := *in // параметры обновления с ожидаемой версией строки
+			upd.Version = version
+			rows, myerr := s.db.Exec(ctx, sqlUpdateDept, &upd)
 			if myerr != nil {
 				return false, myerr
 			}
+			// строка изменена другой транзакцией после чтения
+			if rows == 0 {
+				return false, myerror.New(mysql.ErrCodeVersionConflict, "Error update - row was changed by another transaction: reqID, Deptno, version", reqID, in.Deptno, version).PrintfInfo()
+			}
 			// проверим количество обработанных строк
 			if rows != 1 {
 				return false, myerror.New("4004", "Error update: reqID, Deptno, rows", reqID, in.Deptno, rows).PrintfInfo()
 			}
 
 			// считаем объект по сурогатному PK
-			exists, myerr := s.db.Get(reqID, tx, "GetDept", newDept, in.Deptno)
+			exists, myerr := s.db.Get(ctx, sqlGetDept, newDept, in.Deptno)
 			if myerr != nil {
 				return false, myerr
 			}
 			if !exists {
 				return false, myerror.New("4004", "Row does not exists after creating: reqID, PK", reqID, in.Deptno).PrintfInfo()
 			}
+			if err := s.publish(ctx, events.TypeUpdated, events.ObjectDept, newDept.Deptno, newDept.Version); err != nil {
+				return false, err
+			}
 		} // Выполняем обновление
 
 		{ // Обработаем вложенные объекты в рамках текущей транзации
@@ -192,12 +239,12 @@ func (s *Service) updateDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 					inEmp.Deptno = null.Int{sql.NullInt64{int64(in.Deptno), true}}
 
 					// обновляем вложенные объекты
-					if exists, myerr = s.updateEmp(ctx, tx, inEmp, nil); myerr != nil {
+					if exists, myerr = s.updateEmp(ctx, inEmp, nil); myerr != nil {
 						return false, myerr
 					}
 					// Если одного из вложенных объектов не существует - то создать его
 					if !exists {
-						if myerr = s.createEmp(ctx, tx, inEmp, nil); myerr != nil {
+						if myerr = s.createEmp(ctx, inEmp, nil); myerr != nil {
 							return false, myerr
 						}
 					}
@@ -208,7 +255,7 @@ func (s *Service) updateDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 		// считаем обновленный объект из БД
 		if out != nil {
 			out.Deptno = in.Deptno // столбцы первичного ключа PK
-			if exists, myerr = s.getDept(ctx, tx, out); myerr != nil {
+			if exists, myerr = s.getDept(ctx, out, true); myerr != nil {
 				return false, myerr
 			}
 			// Проверка для отладки табличного API
@@ -218,64 +265,142 @@ func (s *Service) updateDept(ctx context.Context, tx *mysql.Tx, in *model.Dept,
 		}
 		return true, nil
 	}
-	return false, myerror.New("4400", "Incorrect call 'in != nil  && tx != nil': reqID", reqID).PrintfInfo()
+	return false, myerror.New("4400", "Incorrect call 'in != nil && tx in context': reqID", reqID).PrintfInfo()
+}
+
+// patchDept update changed columns of the Dept, cur is state of the Dept with emps read in current transaction.
+// Changed emps are patched, new emps are created. Emps can not be removed by patch - in must contain all emps of cur
+func (s *Service) patchDept(ctx context.Context, cur *model.Dept, in *model.Dept, out *model.Dept) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	if cur != nil && in != nil && cur.Deptno == in.Deptno && mysql.FromContextTx(ctx) != nil {
+		mylog.PrintfDebugMsg("START: reqID, Deptno", reqID, in.Deptno)
+
+		version := in.Version // ожидаемая версия строки
+
+		{ // Проверить версию строки, если версия не передана - обновляем текущую версию
+			// in не изменяем - функция может быть повторена в новой транзакции
+			if version == 0 {
+				version = cur.Version
+			}
+			if version != cur.Version {
+				return myerror.New(mysql.ErrCodeVersionConflict, "Error patch - row version does not match: reqID, Deptno, version, current version", reqID, in.Deptno, version, cur.Version).PrintfInfo()
+			}
+		} // Проверить версию строки, если версия не передана - обновляем текущую версию
+
+		{ // Проверить, что patch не удаляет вложенные объекты - удаление не поддерживается и не может быть молча пропущено
+			inEmps := make(map[int]bool, len(in.Emps))
+			for _, inEmp := range in.Emps {
+				inEmps[inEmp.Empno] = true
+			}
+			for _, curEmp := range cur.Emps {
+				if !inEmps[curEmp.Empno] {
+					return myerror.New("4400", "Incorrect call - emp can not be removed by patch: reqID, Deptno, Empno", reqID, in.Deptno, curEmp.Empno).PrintfInfo()
+				}
+			}
+		} // Проверить, что patch не удаляет вложенные объекты
+
+		{ // Выполняем обновление только измененных столбцов
+			if columns := changedColumns(cur, in, "deptno"); len(columns) > 0 {
+				mylog.PrintfDebugMsg("Changed columns: reqID, Deptno, columns", reqID, in.Deptno, columns)
+				upd := *in // параметры обновления с ожидаемой версией строки
+				upd.Version = version
+				rows, myerr := s.db.ExecText(ctx, updateColumnsSQL("dept", "deptno", columns), &upd)
+				if myerr != nil {
+					return myerr
+				}
+				// строка изменена другой транзакцией после чтения
+				if rows != 1 {
+					return myerror.New(mysql.ErrCodeVersionConflict, "Error patch - row was changed by another transaction: reqID, Deptno, version, rows", reqID, in.Deptno, version, rows).PrintfInfo()
+				}
+				if err := s.publish(ctx, events.TypeUpdated, events.ObjectDept, in.Deptno, version+1); err != nil {
+					return err
+				}
+			}
+		} // Выполняем обновление только измененных столбцов
+
+		{ // Обработаем вложенные объекты в рамках текущей транзации
+			curEmps := make(map[int]*model.Emp, len(cur.Emps))
+			for _, curEmp := range cur.Emps {
+				curEmps[curEmp.Empno] = curEmp
+			}
+			for _, inEmp := range in.Emps {
+				// Копируем сурогатный PK во внешний ключ вложенного объекта
+				inEmp.Deptno = null.IntFrom(int64(in.Deptno))
+
+				if curEmp, ok := curEmps[inEmp.Empno]; ok {
+					myerr = s.patchEmp(ctx, curEmp, inEmp, nil)
+				} else {
+					myerr = s.createEmp(ctx, inEmp, nil)
+				}
+				if myerr != nil {
+					return myerr
+				}
+			}
+		} // Обработаем вложенные объекты в рамках текущей транзации
+
+		// считаем обновленный объект из БД
+		if out != nil {
+			out.Deptno = in.Deptno // столбцы первичного ключа PK
+			exists, myerr := s.getDept(ctx, out, true)
+			if myerr != nil {
+				return myerr
+			}
+			if !exists {
+				return myerror.New("4004", "Row does not exists after patching: reqID, PK", reqID, in.Deptno).PrintfInfo()
+			}
+		}
+		return nil
+	}
+	return myerror.New("4400", "Incorrect call 'cur != nil && in != nil && cur.Deptno == in.Deptno && tx in context': reqID", reqID).PrintfInfo()
+}
+
+// GetDept return a Dept with a given id, emps are requested only if withEmps
+func (s *Service) GetDept(ctx context.Context, out *model.Dept, withEmps bool) (exists bool, myerr error) {
+	return s.getDept(ctx, out, withEmps)
 }
 
-// GetDept return a Dept with a given id
-func (s *Service) GetDept(ctx context.Context, out *model.Dept) (exists bool, myerr error) {
-	return s.getDept(ctx, nil, out)
+// GetDeptVersion return row version of Dept with a given id, versions of emps are requested only if withEmps
+func (s *Service) GetDeptVersion(ctx context.Context, deptno int, out *model.Version, withEmps bool) (exists bool, myerr error) {
+	return s.getDeptVersion(ctx, deptno, out, withEmps)
 }
 
 // GetDeptsPK return a PK for all Dept
 func (s *Service) GetDeptsPK(ctx context.Context, out *model.DeptPKs) (myerr error) {
-	return s.getDeptsPK(ctx, nil, out)
+	return s.getDeptsPK(ctx, out)
 }
 
 // CreateDept create new Dept
 func (s *Service) CreateDept(ctx context.Context, in *model.Dept, out *model.Dept) (myerr error) {
-	var tx *mysql.Tx
-	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
-
-	// Начинаем новую транзакцию
-	if tx, myerr = s.db.Beginx(reqID); myerr != nil {
-		return myerr
-	}
-
-	// Создаем объект в рамках транзации
-	if myerr = s.createDept(ctx, tx, in, out); myerr != nil {
-		_ = s.db.Rollback(reqID, tx)
-		return myerr
-	}
-
-	// завершаем транзакцию
-	return s.db.Commit(reqID, tx)
+	// Создаем объект в рамках транзации из контекста или новой транзакции, при сбое сериализации транзакция повторяется
+	return s.db.InTx(ctx, txWriteOptions, func(ctx context.Context) error {
+		return s.createDept(ctx, in, out)
+	})
 }
 
 // UpdateDept update Dept
 func (s *Service) UpdateDept(ctx context.Context, in *model.Dept, out *model.Dept) (exists bool, myerr error) {
-	var tx *mysql.Tx
-	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
-
-	// Начинаем новую транзакцию
-	if tx, myerr = s.db.Beginx(reqID); myerr != nil {
-		return false, myerr
-	}
-
-	// Создаем объект в рамках транзации
-	if exists, myerr = s.updateDept(ctx, tx, in, out); myerr != nil {
-		_ = s.db.Rollback(reqID, tx)
-		return false, myerr
-	}
-
-	// Если объект или один из вложенных подобъектов не был найден при обновлении, то откат
-	if !exists {
-		_ = s.db.Rollback(reqID, tx)
-		return false, nil
-	}
-
-	// завершаем транзакцию
-	if myerr = s.db.Commit(reqID, tx); myerr != nil {
+	// Обновляем объект в рамках транзации из контекста или новой транзакции, при сбое сериализации транзакция повторяется
+	myerr = s.db.InTx(ctx, txWriteOptions, func(ctx context.Context) (err error) {
+		if exists, err = s.updateDept(ctx, in, out); err != nil {
+			return err
+		}
+		// Если объект или один из вложенных подобъектов не был найден при обновлении, то откат
+		if !exists {
+			return mysql.ErrRollback
+		}
+		return nil
+	})
+	if myerr != nil {
 		return false, myerr
 	}
 	return exists, nil
 }
+
+// PatchDept update changed columns of the Dept and its emps, cur is state of the Dept with emps read in transaction from ctx
+func (s *Service) PatchDept(ctx context.Context, cur *model.Dept, in *model.Dept, out *model.Dept) (myerr error) {
+	// Обновляем объект в рамках транзации из контекста или новой транзакции
+	return s.db.InTx(ctx, txWriteOptions, func(ctx context.Context) error {
+		return s.patchDept(ctx, cur, in, out)
+	})
+}
@@ -32,7 +32,7 @@ func InitDbTest() *Service {
 	log.SetOutput(logFilter)
 
 	// конфигурационный файл для БД
-	pqServiceCfg := Config{mysql.Config{
+	pqServiceCfg := Config{SQLCfg: mysql.Config{
 		Host:            "130.61.117.149",
 		Port:            "5432",
 		Dbname:          "test_database",
@@ -50,7 +50,7 @@ func InitDbTest() *Service {
 	pqServiceErrCh := make(chan error, 1)
 
 	// создаем БД
-	if testDB, err = New(nil, pqServiceErrCh, &pqServiceCfg); err != nil {
+	if testDB, err = New(nil, pqServiceErrCh, &pqServiceCfg, nil); err != nil {
 		return nil
 	}
 
@@ -107,7 +107,7 @@ func BenchmarkPgDb_GetDept(b *testing.B) {
 			vOut.Deptno = tests.args[rand.Intn(deptsPKlen)].Deptno // случайным образом выбираем PK
 			//vOut := &model.Dept{}
 
-			_, err := tests.p.GetDept(ctx, vOut)
+			_, err := tests.p.GetDept(ctx, vOut, true)
 			if err != nil {
 				b.Errorf("\n PgDb.GetDept() - error GetDept(d.Deptno), %v", fmt.Sprintf("%+v", err))
 				return
@@ -5,20 +5,21 @@ import (
 
 	myctx "github.com/romapres2010/httpserver/ctx"
 	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
 	mylog "github.com/romapres2010/httpserver/log"
 	"github.com/romapres2010/httpserver/model"
 	mysql "github.com/romapres2010/httpserver/sqlxx"
 )
 
 // getEmp return a row for a given id
-func (s *Service) getEmp(ctx context.Context, tx *mysql.Tx, out *model.Emp) (exists bool, myerr error) {
+func (s *Service) getEmp(ctx context.Context, out *model.Emp) (exists bool, myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 
 	if out != nil {
 		mylog.PrintfDebugMsg("START: reqID, Empno", reqID, out.Empno)
 
 		// Запросим основной объект
-		if exists, myerr = s.db.Get(reqID, tx, "GetEmp", out, out.Empno); myerr != nil {
+		if exists, myerr = s.db.Get(ctx, sqlGetEmp, out, out.Empno); myerr != nil {
 			return false, myerr
 		}
 
@@ -33,22 +34,22 @@ func (s *Service) getEmp(ctx context.Context, tx *mysql.Tx, out *model.Emp) (exi
 }
 
 // getEmpsByDept return a rows for a given dept
-func (s *Service) getEmpsByDept(ctx context.Context, tx *mysql.Tx, in *model.Dept, out *model.EmpSlice) (myerr error) {
+func (s *Service) getEmpsByDept(ctx context.Context, in *model.Dept, out *model.EmpSlice) (myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 
 	if in != nil && out != nil {
 		mylog.PrintfDebugMsg("START: reqID, Deptno", reqID, in.Deptno)
 
-		return s.db.Select(reqID, tx, "GetEmpsByDept", out, in.Deptno)
+		return s.db.Select(ctx, sqlGetEmpsByDept, out, in.Deptno)
 	}
 	return myerror.New("4400", "Incorrect call 'in != nil && out != nil': reqID", reqID).PrintfInfo()
 }
 
 // createEmp create new Emp
-func (s *Service) createEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, out *model.Emp) (myerr error) {
+func (s *Service) createEmp(ctx context.Context, in *model.Emp, out *model.Emp) (myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 
-	if in != nil && tx != nil {
+	if in != nil && mysql.FromContextTx(ctx) != nil {
 		mylog.PrintfDebugMsg("START: reqID, Empno", reqID, in.Empno)
 
 		newEmp := model.GetEmp()   // Извлечем из pool структуру для нового экземпляра в БД
@@ -56,17 +57,17 @@ func (s *Service) createEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, ou
 
 		{ // Проверим, существует ли строка по натуральному уникальному ключу UK
 			mylog.PrintfDebugMsg("Check if row already exists: reqID, Empno", reqID, in.Empno)
-			exists, myerr := s.db.Get(reqID, tx, "EmpExists", new(int), in.Empno)
+			exists, myerr := s.db.Get(ctx, sqlEmpExists, new(int), in.Empno)
 			if myerr != nil {
 				return myerr
 			}
 			if exists {
-				return myerror.New("4004", "Error create - row already exists: reqID, Empno", reqID, in.Empno).PrintfInfo()
+				return myerror.New(mysql.ErrCodeConstraintViolation, "Error create - row already exists: reqID, Empno", reqID, in.Empno).PrintfInfo()
 			}
 		} // Проверим, существует ли строка по натуральному уникальному ключу UK
 
 		{ // Выполняем вставку и получим значение сурогатного PK
-			rows, myerr := s.db.Exec(reqID, tx, "CreateEmp", in)
+			rows, myerr := s.db.Exec(ctx, sqlCreateEmp, in)
 			if myerr != nil {
 				return myerr
 			}
@@ -77,13 +78,16 @@ func (s *Service) createEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, ou
 
 			// считаем созданный объект - в БД могли быть тригера, которые меняли данные
 			// запрос делаем по UK, так как сурогатный PK мы еще не знаем
-			exists, myerr := s.db.Get(reqID, tx, "GetEmpUK", newEmp, in.Empno)
+			exists, myerr := s.db.Get(ctx, sqlGetEmpUK, newEmp, in.Empno)
 			if myerr != nil {
 				return myerr
 			}
 			if !exists {
 				return myerror.New("4004", "Row does not exists after creating: reqID, Empno", reqID, in.Empno).PrintfInfo()
 			}
+			if err := s.publish(ctx, events.TypeCreated, events.ObjectEmp, newEmp.Empno, newEmp.Version); err != nil {
+				return err
+			}
 		} // Выполняем вставку и получим значение сурогатного PK
 
 		{ // Обработаем вложенные объекты в рамках текущей транзации
@@ -93,7 +97,7 @@ func (s *Service) createEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, ou
 		// считаем созданный объект из БД
 		if out != nil {
 			out.Empno = newEmp.Empno // столбцы первичного ключа PK
-			exists, myerr := s.getEmp(ctx, tx, out)
+			exists, myerr := s.getEmp(ctx, out)
 			if myerr != nil {
 				return myerr
 			}
@@ -104,16 +108,18 @@ func (s *Service) createEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, ou
 		}
 		return nil
 	}
-	return myerror.New("4400", "Incorrect call 'in != nil && tx != nil': reqID", reqID).PrintfInfo()
+	return myerror.New("4400", "Incorrect call 'in != nil && tx in context': reqID", reqID).PrintfInfo()
 }
 
 // updateEmp update the Emp
-func (s *Service) updateEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, out *model.Emp) (exists bool, myerr error) {
+func (s *Service) updateEmp(ctx context.Context, in *model.Emp, out *model.Emp) (exists bool, myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 
-	if in != nil && tx != nil {
+	if in != nil && mysql.FromContextTx(ctx) != nil {
 		mylog.PrintfDebugMsg("START: reqID, Empno", reqID, in.Empno)
 
+		version := in.Version // ожидаемая версия строки
+
 		oldEmp := model.GetEmp()   // Извлечем из pool структуру для старого экземпляра в БД
 		defer model.PutEmp(oldEmp) // Вернем структуру в pool
 
@@ -123,7 +129,7 @@ func (s *Service) updateEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, ou
 		{ // Считаем состояние объекта до обновления и проверим его существование
 			mylog.PrintfDebugMsg("Get row and check if it exists: reqID, PK", reqID, in.Empno)
 			oldEmp.Empno = in.Empno // столбцы первичного ключа PK
-			if exists, myerr = s.getEmp(ctx, tx, oldEmp); myerr != nil {
+			if exists, myerr = s.getEmp(ctx, oldEmp); myerr != nil {
 				return false, myerr
 			}
 			if !exists {
@@ -133,27 +139,43 @@ func (s *Service) updateEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, ou
 		} // Считаем состояние объекта до обновления и проверим его существование
 
 		{ // выполняем проверки / действия на основании старых и новых значений атрибутов
-			// ...
+			// Проверить версию строки, если версия не передана - обновляем текущую версию
+			// in не изменяем - функция может быть повторена в новой транзакции
+			if version == 0 {
+				version = oldEmp.Version
+			}
+			if version != oldEmp.Version {
+				return false, myerror.New(mysql.ErrCodeVersionConflict, "Error update - row version does not match: reqID, Empno, version, current version", reqID, in.Empno, version, oldEmp.Version).PrintfInfo()
+			}
 		} // выполняем проверки / действия на основании старых и новых значений атрибутов
 
 		{ // Выполняем обновление
-			rows, myerr := s.db.Exec(reqID, tx, "UpdateEmp", in)
+			upd := *in // параметры обновления с ожидаемой версией строки
+			upd.Version = version
+			rows, myerr := s.db.Exec(ctx, sqlUpdateEmp, &upd)
 			if myerr != nil {
 				return false, myerr
 			}
+			// строка изменена другой транзакцией после чтения
+			if rows == 0 {
+				return false, myerror.New(mysql.ErrCodeVersionConflict, "Error update - row was changed by another transaction: reqID, Empno, version", reqID, in.Empno, version).PrintfInfo()
+			}
 			// проверим количество обработанных строк
 			if rows != 1 {
 				return false, myerror.New("4004", "Error update: reqID, Empno, rows", reqID, in.Empno, rows).PrintfInfo()
 			}
 
 			// считаем объект по сурогатному PK
-			exists, myerr := s.db.Get(reqID, tx, "GetEmp", newEmp, in.Empno)
+			exists, myerr := s.db.Get(ctx, sqlGetEmp, newEmp, in.Empno)
 			if myerr != nil {
 				return false, myerr
 			}
 			if !exists {
 				return false, myerror.New("4004", "Row does not exists after creating: reqID, PK", reqID, in.Deptno).PrintfInfo()
 			}
+			if err := s.publish(ctx, events.TypeUpdated, events.ObjectEmp, newEmp.Empno, newEmp.Version); err != nil {
+				return false, err
+			}
 		} // Выполняем обновление
 
 		{ // Обработаем вложенные объекты в рамках текущей транзации
@@ -163,7 +185,7 @@ func (s *Service) updateEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, ou
 		// считаем обновленный объект из БД
 		if out != nil {
 			out.Empno = in.Empno // столбцы первичного ключа PK
-			if exists, myerr = s.getEmp(ctx, tx, out); myerr != nil {
+			if exists, myerr = s.getEmp(ctx, out); myerr != nil {
 				return false, myerr
 			}
 			// Проверка для отладки табличного API
@@ -173,64 +195,104 @@ func (s *Service) updateEmp(ctx context.Context, tx *mysql.Tx, in *model.Emp, ou
 		}
 		return true, nil
 	}
-	return false, myerror.New("4400", "Incorrect call 'in != nil && tx != nil': reqID", reqID).PrintfInfo()
+	return false, myerror.New("4400", "Incorrect call 'in != nil && tx in context': reqID", reqID).PrintfInfo()
+}
+
+// patchEmp update changed columns of the Emp, cur is state of the Emp read in current transaction
+func (s *Service) patchEmp(ctx context.Context, cur *model.Emp, in *model.Emp, out *model.Emp) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	if cur != nil && in != nil && cur.Empno == in.Empno && mysql.FromContextTx(ctx) != nil {
+		mylog.PrintfDebugMsg("START: reqID, Empno", reqID, in.Empno)
+
+		version := in.Version // ожидаемая версия строки
+
+		{ // Проверить версию строки, если версия не передана - обновляем текущую версию
+			// in не изменяем - функция может быть повторена в новой транзакции
+			if version == 0 {
+				version = cur.Version
+			}
+			if version != cur.Version {
+				return myerror.New(mysql.ErrCodeVersionConflict, "Error patch - row version does not match: reqID, Empno, version, current version", reqID, in.Empno, version, cur.Version).PrintfInfo()
+			}
+		} // Проверить версию строки, если версия не передана - обновляем текущую версию
+
+		{ // Выполняем обновление только измененных столбцов
+			if columns := changedColumns(cur, in, "empno"); len(columns) > 0 {
+				mylog.PrintfDebugMsg("Changed columns: reqID, Empno, columns", reqID, in.Empno, columns)
+				upd := *in // параметры обновления с ожидаемой версией строки
+				upd.Version = version
+				rows, myerr := s.db.ExecText(ctx, updateColumnsSQL("emp", "empno", columns), &upd)
+				if myerr != nil {
+					return myerr
+				}
+				// строка изменена другой транзакцией после чтения
+				if rows != 1 {
+					return myerror.New(mysql.ErrCodeVersionConflict, "Error patch - row was changed by another transaction: reqID, Empno, version, rows", reqID, in.Empno, version, rows).PrintfInfo()
+				}
+				if err := s.publish(ctx, events.TypeUpdated, events.ObjectEmp, in.Empno, version+1); err != nil {
+					return err
+				}
+			}
+		} // Выполняем обновление только измененных столбцов
+
+		// считаем обновленный объект из БД
+		if out != nil {
+			out.Empno = in.Empno // столбцы первичного ключа PK
+			exists, myerr := s.getEmp(ctx, out)
+			if myerr != nil {
+				return myerr
+			}
+			if !exists {
+				return myerror.New("4004", "Row does not exists after patching: reqID, PK", reqID, in.Empno).PrintfInfo()
+			}
+		}
+		return nil
+	}
+	return myerror.New("4400", "Incorrect call 'cur != nil && in != nil && cur.Empno == in.Empno && tx in context': reqID", reqID).PrintfInfo()
 }
 
 // GetEmp return a row for a given id
 func (s *Service) GetEmp(ctx context.Context, out *model.Emp) (exists bool, myerr error) {
-	return s.getEmp(ctx, nil, out)
+	return s.getEmp(ctx, out)
 }
 
 // GetEmpsByDept return a rows for a given dept
 func (s *Service) GetEmpsByDept(ctx context.Context, in *model.Dept, out *model.EmpSlice) (myerr error) {
-	return s.getEmpsByDept(ctx, nil, in, out)
+	return s.getEmpsByDept(ctx, in, out)
 }
 
 // CreateEmp create new Emp
 func (s *Service) CreateEmp(ctx context.Context, in *model.Emp, out *model.Emp) (myerr error) {
-	var tx *mysql.Tx
-	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
-
-	// Начинаем новую транзакцию
-	if tx, myerr = s.db.Beginx(reqID); myerr != nil {
-		return myerr
-	}
-
-	// Создаем объект в рамках транзации
-	if myerr = s.createEmp(ctx, tx, in, out); myerr != nil {
-		_ = s.db.Rollback(reqID, tx)
-		return myerr
-	}
-
-	// завершаем транзакцию
-	return s.db.Commit(reqID, tx)
+	// Создаем объект в рамках транзации из контекста или новой транзакции, при сбое сериализации транзакция повторяется
+	return s.db.InTx(ctx, txWriteOptions, func(ctx context.Context) error {
+		return s.createEmp(ctx, in, out)
+	})
 }
 
 // UpdateEmp update the Emp
 func (s *Service) UpdateEmp(ctx context.Context, in *model.Emp, out *model.Emp) (exists bool, myerr error) {
-	var tx *mysql.Tx
-	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
-
-	// Начинаем новую транзакцию
-	if tx, myerr = s.db.Beginx(reqID); myerr != nil {
-		return false, myerr
-	}
-
-	// Создаем объект в рамках транзации
-	if exists, myerr = s.updateEmp(ctx, tx, in, out); myerr != nil {
-		_ = s.db.Rollback(reqID, tx)
-		return false, myerr
-	}
-
-	// Если объект или один из вложенных подобъектов не был найден при обновлении, то откат
-	if !exists {
-		_ = s.db.Rollback(reqID, tx)
-		return false, nil
-	}
-
-	// завершаем транзакцию
-	if myerr = s.db.Commit(reqID, tx); myerr != nil {
+	// Обновляем объект в рамках транзации из контекста или новой транзакции, при сбое сериализации транзакция повторяется
+	myerr = s.db.InTx(ctx, txWriteOptions, func(ctx context.Context) (err error) {
+		if exists, err = s.updateEmp(ctx, in, out); err != nil {
+			return err
+		}
+		// Если объект или один из вложенных подобъектов не был найден при обновлении, то откат
+		if !exists {
+			return mysql.ErrRollback
+		}
+		return nil
+	})
+	if myerr != nil {
 		return false, myerr
 	}
 	return exists, nil
 }
+
+// PatchEmp update changed columns of the Emp, cur is state of the Emp read in transaction from ctx
+func (s *Service) PatchEmp(ctx context.Context, cur *model.Emp, in *model.Emp, out *model.Emp) (myerr error) {
+	// Обновляем объект в рамках транзации из контекста или новой транзакции
+	return s.db.InTx(ctx, txWriteOptions, func(ctx context.Context) error {
+		return s.patchEmp(ctx, cur, in, out)
+	})
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+	model "github.com/romapres2010/httpserver/model"
+)
+
+// ExportDepts read all Dept with DB cursor and pass each row to out, structure is reused between rows
+func (s *Service) ExportDepts(ctx context.Context, out func(*model.Dept) error) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	if out != nil {
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		v := model.GetDept()         // Извлечем из pool структуру для чтения строк
+		defer model.PutDept(v, true) // Вернем структуру в pool
+
+		return s.db.Query(ctx, sqlGetDepts, func(rows *sqlx.Rows) error {
+			for rows.Next() {
+				v.Reset()
+				if err := rows.StructScan(v); err != nil {
+					return myerror.WithCause("4003", "Error scan row: reqID", err, reqID).PrintfInfo()
+				}
+				if err := out(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return myerror.New("4400", "Incorrect call 'out != nil': reqID", reqID).PrintfInfo()
+}
+
+// ExportEmps read all Emp with DB cursor and pass each row to out, structure is reused between rows
+func (s *Service) ExportEmps(ctx context.Context, out func(*model.Emp) error) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	if out != nil {
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		v := model.GetEmp()   // Извлечем из pool структуру для чтения строк
+		defer model.PutEmp(v) // Вернем структуру в pool
+
+		return s.db.Query(ctx, sqlGetEmps, func(rows *sqlx.Rows) error {
+			for rows.Next() {
+				v.Reset()
+				if err := rows.StructScan(v); err != nil {
+					return myerror.WithCause("4003", "Error scan row: reqID", err, reqID).PrintfInfo()
+				}
+				if err := out(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return myerror.New("4400", "Incorrect call 'out != nil': reqID", reqID).PrintfInfo()
+}
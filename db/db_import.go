@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"io"
+	"time"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
+	mylog "github.com/romapres2010/httpserver/log"
+	model "github.com/romapres2010/httpserver/model"
+)
+
+// importProgressRows - через какое количество строк логировать прогресс загрузки
+const importProgressRows = 100000
+
+// Колонки загрузки COPY FROM STDIN
+var (
+	importDeptColumns = []string{"deptno", "dname", "loc"}
+	importEmpColumns  = []string{"empno", "ename", "job", "mgr", "hiredate", "sal", "comm", "deptno"}
+)
+
+// importSource represent adapter of model stream to sqlxx.CopySource.
+// Строки читаются по одной в переиспользуемую структуру - память не зависит от объема загрузки
+type importSource struct {
+	reqID  uint64
+	table  string
+	next   func() error                  // прочитать следующую строку потока
+	values func() ([]interface{}, error) // значения колонок текущей строки
+	rows   int64                         // количество прочитанных строк
+	start  time.Time                     // время начала загрузки
+	err    error                         // ошибка чтения потока
+}
+
+// Next read next row from stream
+func (s *importSource) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	if err := s.next(); err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		return false
+	}
+	s.rows++
+	if s.rows%importProgressRows == 0 {
+		mylog.PrintfInfoMsg("Import progress: reqID, table, rows, duration", s.reqID, s.table, s.rows, time.Since(s.start))
+	}
+	return true
+}
+
+// Values return column values of current row
+func (s *importSource) Values() ([]interface{}, error) {
+	return s.values()
+}
+
+// Err return error of reading stream
+func (s *importSource) Err() error {
+	return s.err
+}
+
+// importRows load rows from stream into table with COPY FROM STDIN
+func (s *Service) importRows(ctx context.Context, src *importSource, columns []string) (rows int64, myerr error) {
+	mylog.PrintfInfoMsg("Import START: reqID, table", src.reqID, src.table)
+
+	if rows, myerr = s.db.CopyFrom(ctx, src.table, columns, src); myerr != nil {
+		return 0, myerr
+	}
+
+	// отдельные события по строкам массовой загрузки не публикуем - подписчики перечитывают объекты целиком.
+	// Строки уже загружены, ошибка публикации только логируется
+	if err := s.publish(ctx, events.TypeReset, src.table, 0, 0); err != nil {
+		mylog.PrintfErrorMsg("Error publish import event: reqID, table, err", src.reqID, src.table, err)
+	}
+
+	mylog.PrintfInfoMsg("Import SUCCESS: reqID, table, rows, duration", src.reqID, src.table, rows, time.Since(src.start))
+	return rows, nil
+}
+
+// ImportDepts load stream of Dept with COPY FROM STDIN
+func (s *Service) ImportDepts(ctx context.Context, in model.DeptReader) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	if in != nil {
+		v := model.GetDept()         // Извлечем из pool структуру для чтения строк
+		defer model.PutDept(v, true) // Вернем структуру в pool
+
+		src := &importSource{
+			reqID: reqID,
+			table: "dept",
+			start: time.Now(),
+			next: func() error {
+				v.Reset()
+				return in.Next(v)
+			},
+			values: func() ([]interface{}, error) {
+				return []interface{}{v.Deptno, v.Dname, v.Loc}, nil
+			},
+		}
+		return s.importRows(ctx, src, importDeptColumns)
+	}
+	return 0, myerror.New("4400", "Incorrect call 'in != nil': reqID", reqID).PrintfInfo()
+}
+
+// ImportEmps load stream of Emp with COPY FROM STDIN
+func (s *Service) ImportEmps(ctx context.Context, in model.EmpReader) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	if in != nil {
+		v := model.GetEmp()   // Извлечем из pool структуру для чтения строк
+		defer model.PutEmp(v) // Вернем структуру в pool
+
+		src := &importSource{
+			reqID: reqID,
+			table: "emp",
+			start: time.Now(),
+			next: func() error {
+				v.Reset()
+				return in.Next(v)
+			},
+			values: func() ([]interface{}, error) {
+				// COPY передает значения в бинарном формате - дату нужно передать как time.Time
+				var hiredate interface{}
+				if v.Hiredate.Valid {
+					t, err := time.Parse(model.DateLayout, v.Hiredate.String)
+					if err != nil {
+						return nil, myerror.WithCause("4004", "Error parse hiredate: reqID, Empno, hiredate", err, reqID, v.Empno, v.Hiredate.String).PrintfInfo()
+					}
+					hiredate = t
+				}
+				return []interface{}{v.Empno, v.Ename, v.Job, v.Mgr, hiredate, v.Sal, v.Comm, v.Deptno}, nil
+			},
+		}
+		return s.importRows(ctx, src, importEmpColumns)
+	}
+	return 0, myerror.New("4400", "Incorrect call 'in != nil': reqID", reqID).PrintfInfo()
+}
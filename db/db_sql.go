@@ -0,0 +1,163 @@
+package db
+
+//go:generate go run ../cmd/embedfiles -dir sql -ext .sql -pkg db -var sqlFiles -out sql_files.go
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+
+	mylog "github.com/romapres2010/httpserver/log"
+	mysql "github.com/romapres2010/httpserver/sqlxx"
+)
+
+// Имена SQL команд, на которые ссылается код сервиса
+const (
+	sqlGetDept       = "GetDept"
+	sqlGetDeptUK     = "GetDeptUK"
+	sqlDeptExists    = "DeptExists"
+	sqlGetDepts      = "GetDepts"
+	sqlGetDeptsPK    = "GetDeptsPK"
+	sqlGetDeptVer    = "GetDeptVersion"
+	sqlCreateDept    = "CreateDept"
+	sqlUpdateDept    = "UpdateDept"
+	sqlEmpExists     = "EmpExists"
+	sqlGetEmp        = "GetEmp"
+	sqlGetEmpUK      = "GetEmpUK"
+	sqlGetEmps       = "GetEmps"
+	sqlGetEmpsByDept = "GetEmpsByDept"
+	sqlGetEmpVers    = "GetEmpVersionsByDept"
+	sqlCreateEmp     = "CreateEmp"
+	sqlUpdateEmp     = "UpdateEmp"
+	sqlNotifyEvent   = "NotifyEvent"
+)
+
+// requiredSQL represent SQL statements which must be defined in catalog
+var requiredSQL = []string{
+	sqlGetDept,
+	sqlGetDeptUK,
+	sqlDeptExists,
+	sqlGetDepts,
+	sqlGetDeptsPK,
+	sqlGetDeptVer,
+	sqlCreateDept,
+	sqlUpdateDept,
+	sqlEmpExists,
+	sqlGetEmp,
+	sqlGetEmpUK,
+	sqlGetEmps,
+	sqlGetEmpsByDept,
+	sqlGetEmpVers,
+	sqlCreateEmp,
+	sqlUpdateEmp,
+}
+
+// notifyArgs represent parameters of pg_notify
+type notifyArgs struct {
+	Channel string `db:"channel"`
+	Payload string `db:"payload"`
+}
+
+// txWriteOptions represent options of transactions which modify objects.
+// SERIALIZABLE - проверки версий и If-Match по прочитанным в транзакции строкам не теряют параллельные изменения:
+// конфликт завершается ошибкой 40001 и транзакция повторяется в RunTx
+var txWriteOptions = &mysql.TxOptions{Isolation: sql.LevelSerializable}
+
+// changedColumns return DB columns of in which values differ from cur, columns are taken from tags db.
+// Columns of key and row version are not compared
+func changedColumns(cur interface{}, in interface{}, key string) []string {
+	curValue := reflect.Indirect(reflect.ValueOf(cur))
+	inValue := reflect.Indirect(reflect.ValueOf(in))
+	var columns []string
+	for i := 0; i < curValue.NumField(); i++ {
+		column := curValue.Type().Field(i).Tag.Get("db")
+		if column == "" || column == key || column == "version" {
+			continue
+		}
+		if !reflect.DeepEqual(curValue.Field(i).Interface(), inValue.Field(i).Interface()) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// updateColumnsSQL return UPDATE of columns of table, row is updated only if its row version matches - optimistic lock.
+// Команда не входит в каталог SQL: набор столбцов свой для каждого PATCH, поэтому она не подготавливается
+// и не переопределяется файлами каталога. Имена таблицы и столбцов берутся из тегов db модели, а не из запроса,
+// значения передаются только через именованные параметры
+func updateColumnsSQL(table string, key string, columns []string) string {
+	var b strings.Builder
+	b.WriteString("UPDATE " + table + " SET ")
+	for _, column := range columns {
+		b.WriteString(column + " = :" + column + ", ")
+	}
+	b.WriteString("version = version + 1 WHERE " + key + " = :" + key + " AND version = :version")
+	return b.String()
+}
+
+// sqlReloadInterval represent SQL catalog directory check interval in development mode
+const sqlReloadInterval = 2 * time.Second
+
+// loadSQLCatalog load SQL statements from catalog directory or from embedded catalog and check them
+func (s *Service) loadSQLCatalog() (mysql.SQLStms, error) {
+	var err error
+	files := sqlFiles // встроенный каталог
+
+	if s.cfg.SQLDir != "" {
+		if files, err = mysql.ReadCatalogDir(s.cfg.SQLDir); err != nil {
+			return nil, err
+		}
+	}
+
+	sqlStms, err := mysql.LoadCatalog(files, s.cfg.SQLCfg.DriverName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = mysql.CheckCatalog(sqlStms, requiredSQL); err != nil {
+		return nil, err
+	}
+
+	// SQL команда публикации изменений нужна только при использовании NOTIFY
+	if s.cfg.Notify {
+		if err = mysql.CheckCatalog(sqlStms, []string{sqlNotifyEvent}); err != nil {
+			return nil, err
+		}
+	}
+
+	return sqlStms, nil
+}
+
+// watchSQLCatalog reload SQL catalog from directory on change, used in development mode
+func (s *Service) watchSQLCatalog() {
+	mylog.PrintfInfoMsg("SQL catalog reload is enabled: Dir", s.cfg.SQLDir)
+
+	modTime, _ := mysql.CatalogModTime(s.cfg.SQLDir)
+
+	ticker := time.NewTicker(sqlReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			newModTime, err := mysql.CatalogModTime(s.cfg.SQLDir)
+			if err != nil || !newModTime.After(modTime) {
+				continue
+			}
+			modTime = newModTime
+
+			// при ошибке продолжаем работать с текущим каталогом
+			sqlStms, err := s.loadSQLCatalog()
+			if err != nil {
+				mylog.PrintfErrorInfo(err)
+				continue
+			}
+			if err = s.db.Reload(sqlStms); err != nil {
+				mylog.PrintfErrorInfo(err)
+			}
+		}
+	}
+}
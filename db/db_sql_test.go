@@ -0,0 +1,37 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/romapres2010/httpserver/model"
+	"gopkg.in/guregu/null.v4"
+
+	mysql "github.com/romapres2010/httpserver/sqlxx"
+)
+
+func TestEmbeddedSQLCatalog(t *testing.T) {
+	for _, driver := range []string{"pgx", "postgres"} {
+		sqlStms, err := mysql.LoadCatalog(sqlFiles, driver)
+		if err != nil {
+			t.Fatalf("LoadCatalog(%v) error = %v", driver, err)
+		}
+		if err = mysql.CheckCatalog(sqlStms, requiredSQL); err != nil {
+			t.Errorf("CheckCatalog(%v) error = %v", driver, err)
+		}
+	}
+}
+
+func TestChangedColumns(t *testing.T) {
+	cur := &model.Emp{Empno: 7839, Ename: null.StringFrom("KING"), Sal: null.IntFrom(5000), Version: 1}
+	in := &model.Emp{Empno: 7839, Ename: null.StringFrom("KING"), Job: null.StringFrom("PRESIDENT"), Version: 2}
+
+	columns := changedColumns(cur, in, "empno")
+	if want := []string{"job", "sal"}; !reflect.DeepEqual(columns, want) {
+		t.Fatalf("changedColumns() = %v, want %v", columns, want)
+	}
+	want := "UPDATE emp SET job = :job, sal = :sal, version = version + 1 WHERE empno = :empno AND version = :version"
+	if got := updateColumnsSQL("emp", "empno", columns); got != want {
+		t.Errorf("updateColumnsSQL() = %v, want %v", got, want)
+	}
+}
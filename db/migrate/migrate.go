@@ -0,0 +1,301 @@
+package migrate
+
+//go:generate go run ../../cmd/embedfiles -dir sql -ext .sql -pkg migrate -var sqlFiles -out sql_files.go
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Файлы миграций лежат в каталоге sql и встраиваются в бинарник через go generate:
+//     NNNN_name.up.sql    - применение миграции
+//     NNNN_name.down.sql  - откат миграции
+// Применение и откат каждой миграции выполняются в отдельной транзакции.
+// Миграции рассчитаны на PostgreSQL - используется pg_advisory_lock.
+
+// lockID ключ advisory lock, чтобы несколько демонов не применяли миграции одновременно
+const lockID = 7312468510462139
+
+// Таблица с применеными миграциями
+const (
+	createTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    bigint      NOT NULL,
+    name       text        NOT NULL,
+    checksum   text        NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT schema_migrations_pk PRIMARY KEY (version)
+)`
+	selectAppliedSQL = "SELECT version, name, checksum FROM schema_migrations ORDER BY version"
+	insertAppliedSQL = "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)"
+	deleteAppliedSQL = "DELETE FROM schema_migrations WHERE version = $1"
+	lockSQL          = "SELECT pg_advisory_lock($1)"
+	unlockSQL        = "SELECT pg_advisory_unlock($1)"
+)
+
+// fileNameRe шаблон имени файла миграции
+var fileNameRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration represent single versioned migration
+type Migration struct {
+	Version  int64  // версия миграции
+	Name     string // наименование миграции
+	Up       string // SQL применения миграции
+	Down     string // SQL отката миграции
+	Checksum string // sha256 SQL применения миграции
+}
+
+// Applied represent migration applied to DB
+type Applied struct {
+	Version  int64  `db:"version"`
+	Name     string `db:"name"`
+	Checksum string `db:"checksum"`
+}
+
+// Migrator represent migration runner
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []*Migration // миграции, упорядоченные по версии
+}
+
+// Migrations parse embedded SQL files into ordered migrations
+func Migrations() ([]*Migration, error) {
+	return parseFiles(sqlFiles)
+}
+
+// parseFiles parse SQL files into ordered migrations
+func parseFiles(files map[string]string) ([]*Migration, error) {
+	byVersion := make(map[int64]*Migration)
+
+	for fileName, text := range files {
+		match := fileNameRe.FindStringSubmatch(fileName)
+		if match == nil {
+			return nil, myerror.New("4200", "Incorrect migration file name, expected NNNN_name.up.sql or NNNN_name.down.sql: FileName", fileName).PrintfInfo()
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, myerror.New("4200", "Incorrect migration version: FileName", fileName).PrintfInfo()
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, myerror.New("4200", "Different migration names with the same version: Version, Name1, Name2", version, migration.Name, match[2]).PrintfInfo()
+		}
+
+		if match[3] == "up" {
+			migration.Up = text
+			migration.Checksum = checksum(text)
+		} else {
+			migration.Down = text
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, myerror.New("4200", "Migration has no up SQL: Version, Name", migration.Version, migration.Name).PrintfInfo()
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// checksum calculate sha256 of SQL text
+func checksum(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// New create migration runner with embedded migrations
+func New(db *sqlx.DB) (*Migrator, error) {
+	{ // входные проверки
+		if db == nil {
+			return nil, myerror.New("4004", "DB is not defined").PrintfInfo()
+		}
+	} // входные проверки
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up apply pending migrations, steps <= 0 apply all of them
+func (m *Migrator) Up(ctx context.Context, steps int) (count int, myerr error) {
+	myerr = m.withLock(ctx, func(conn *sql.Conn, applied map[int64]*Applied) error {
+		for _, migration := range m.migrations {
+			if steps > 0 && count >= steps {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			mylog.PrintfInfoMsg("Applying migration: Version, Name", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration.Up, insertAppliedSQL, migration.Version, migration.Name, migration.Checksum); err != nil {
+				return myerror.WithCause("4202", "Error apply migration: Version, Name", err, migration.Version, migration.Name).PrintfInfo()
+			}
+			count++
+		}
+		return nil
+	})
+
+	mylog.PrintfInfoMsg("Migrations are applied: Count", count)
+	return count, myerr
+}
+
+// Down roll back applied migrations in reverse order, steps <= 0 roll back all of them
+func (m *Migrator) Down(ctx context.Context, steps int) (count int, myerr error) {
+	myerr = m.withLock(ctx, func(conn *sql.Conn, applied map[int64]*Applied) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if steps > 0 && count >= steps {
+				break
+			}
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return myerror.New("4203", "Migration has no down SQL: Version, Name", migration.Version, migration.Name).PrintfInfo()
+			}
+
+			mylog.PrintfInfoMsg("Rolling back migration: Version, Name", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration.Down, deleteAppliedSQL, migration.Version); err != nil {
+				return myerror.WithCause("4203", "Error roll back migration: Version, Name", err, migration.Version, migration.Name).PrintfInfo()
+			}
+			count++
+		}
+		return nil
+	})
+
+	mylog.PrintfInfoMsg("Migrations are rolled back: Count", count)
+	return count, myerr
+}
+
+// Pending return migrations which are not applied yet
+func (m *Migrator) Pending(ctx context.Context) (pending []*Migration, myerr error) {
+	myerr = m.withLock(ctx, func(conn *sql.Conn, applied map[int64]*Applied) error {
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok {
+				pending = append(pending, migration)
+			}
+		}
+		return nil
+	})
+	return pending, myerr
+}
+
+// withLock take advisory lock on dedicated connection, read and verify applied migrations, then call fn
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]*Applied) error) (myerr error) {
+	// advisory lock привязан к сессии, поэтому все команды выполняем в одном подключении
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return myerror.WithCause("4001", "Error get connection from pool", err).PrintfInfo()
+	}
+	defer conn.Close()
+
+	mylog.PrintfInfoMsg("Waiting for migration lock: LockID", lockID)
+	if _, err = conn.ExecContext(ctx, lockSQL, lockID); err != nil {
+		return myerror.WithCause("4201", "Error take migration lock: LockID", err, lockID).PrintfInfo()
+	}
+	defer func() {
+		// контекст мог быть отменен, снимаем блокировку в любом случае
+		if _, err := conn.ExecContext(context.Background(), unlockSQL, lockID); err != nil {
+			myerr = myerror.WithCause("4201", "Error release migration lock: LockID", err, lockID).PrintfInfo()
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, createTableSQL); err != nil {
+		return myerror.WithCause("4005", "Error create table schema_migrations", err).PrintfInfo()
+	}
+
+	applied, myerr := m.applied(ctx, conn)
+	if myerr != nil {
+		return myerr
+	}
+
+	return fn(conn, applied)
+}
+
+// applied read applied migrations and verify their checksums
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]*Applied, error) {
+	rows, err := conn.QueryContext(ctx, selectAppliedSQL)
+	if err != nil {
+		return nil, myerror.WithCause("4003", "Error select from schema_migrations", err).PrintfInfo()
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]*Applied)
+	for rows.Next() {
+		a := &Applied{}
+		if err = rows.Scan(&a.Version, &a.Name, &a.Checksum); err != nil {
+			return nil, myerror.WithCause("4003", "Error select from schema_migrations", err).PrintfInfo()
+		}
+		applied[a.Version] = a
+	}
+	if err = rows.Err(); err != nil {
+		return nil, myerror.WithCause("4003", "Error select from schema_migrations", err).PrintfInfo()
+	}
+
+	if err = m.verify(applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// verify compare checksums of applied migrations with embedded ones
+func (m *Migrator) verify(applied map[int64]*Applied) error {
+	known := make(map[int64]*Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for _, a := range applied {
+		migration, ok := known[a.Version]
+		if !ok {
+			mylog.PrintfInfoMsg("Applied migration is unknown to this build: Version, Name", a.Version, a.Name)
+			continue
+		}
+		if migration.Checksum != a.Checksum {
+			return myerror.New("4204", "Checksum of applied migration does not match: Version, Name, Applied, Embedded", a.Version, a.Name, a.Checksum, migration.Checksum).PrintfInfo()
+		}
+	}
+	return nil
+}
+
+// apply execute migration SQL and register it in schema_migrations in one transaction
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, text string, registerSQL string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, text); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, registerSQL, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
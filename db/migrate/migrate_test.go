@@ -0,0 +1,64 @@
+package migrate
+
+import (
+	"testing"
+)
+
+func TestParseFiles(t *testing.T) {
+	files := map[string]string{
+		"0002_add_column.up.sql":  "ALTER TABLE t ADD c int",
+		"0001_create.up.sql":      "CREATE TABLE t (id int)",
+		"0001_create.down.sql":    "DROP TABLE t",
+		"0010_add_index.up.sql":   "CREATE INDEX t_c ON t (c)",
+		"0010_add_index.down.sql": "DROP INDEX t_c",
+	}
+
+	migrations, err := parseFiles(files)
+	if err != nil {
+		t.Fatalf("parseFiles() error = %v", err)
+	}
+
+	wantVersions := []int64{1, 2, 10}
+	if len(migrations) != len(wantVersions) {
+		t.Fatalf("parseFiles() len = %v, want %v", len(migrations), len(wantVersions))
+	}
+	for i, want := range wantVersions {
+		if migrations[i].Version != want {
+			t.Errorf("parseFiles() [%v].Version = %v, want %v", i, migrations[i].Version, want)
+		}
+	}
+	if migrations[0].Down != "DROP TABLE t" || migrations[1].Down != "" {
+		t.Errorf("parseFiles() Down = %q, %q", migrations[0].Down, migrations[1].Down)
+	}
+	if migrations[0].Checksum != checksum("CREATE TABLE t (id int)") {
+		t.Errorf("parseFiles() Checksum = %v", migrations[0].Checksum)
+	}
+}
+
+func TestParseFiles_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"bad name", map[string]string{"create.up.sql": "CREATE TABLE t (id int)"}},
+		{"no up", map[string]string{"0001_create.down.sql": "DROP TABLE t"}},
+		{"different names", map[string]string{"0001_a.up.sql": "SELECT 1", "0001_b.down.sql": "SELECT 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseFiles(tt.files); err == nil {
+				t.Errorf("parseFiles() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestMigrations_Embedded(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatalf("Migrations() is empty, run go generate")
+	}
+}
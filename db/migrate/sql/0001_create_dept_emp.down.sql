@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS emp;
+
+DROP TABLE IF EXISTS dept;
+
+DROP SEQUENCE IF EXISTS dept_deptno_seq;
@@ -0,0 +1,23 @@
+CREATE SEQUENCE IF NOT EXISTS dept_deptno_seq;
+
+CREATE TABLE IF NOT EXISTS dept (
+    deptno integer      NOT NULL,
+    dname  varchar(100) NOT NULL,
+    loc    varchar(100),
+    CONSTRAINT dept_pk PRIMARY KEY (deptno)
+);
+
+CREATE TABLE IF NOT EXISTS emp (
+    empno    integer      NOT NULL,
+    ename    varchar(100),
+    job      varchar(100),
+    mgr      integer,
+    hiredate date,
+    sal      integer,
+    comm     integer,
+    deptno   integer,
+    CONSTRAINT emp_pk PRIMARY KEY (empno),
+    CONSTRAINT emp_dept_fk FOREIGN KEY (deptno) REFERENCES dept (deptno)
+);
+
+CREATE INDEX IF NOT EXISTS emp_deptno_idx ON emp (deptno);
@@ -0,0 +1,3 @@
+ALTER TABLE emp DROP COLUMN IF EXISTS version;
+
+ALTER TABLE dept DROP COLUMN IF EXISTS version;
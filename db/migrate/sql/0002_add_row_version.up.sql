@@ -0,0 +1,4 @@
+-- Версия строки для оптимистической блокировки, увеличивается при каждом обновлении
+ALTER TABLE dept ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
+ALTER TABLE emp ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+// Code generated by embedfiles from directory sql; DO NOT EDIT.
+
+package migrate
+
+// sqlFiles represent embedded files of directory sql
+var sqlFiles = map[string]string{
+	"0001_create_dept_emp.down.sql": "DROP TABLE IF EXISTS emp;\n\nDROP TABLE IF EXISTS dept;\n\nDROP SEQUENCE IF EXISTS dept_deptno_seq;\n",
+	"0001_create_dept_emp.up.sql":   "CREATE SEQUENCE IF NOT EXISTS dept_deptno_seq;\n\nCREATE TABLE IF NOT EXISTS dept (\n    deptno integer      NOT NULL,\n    dname  varchar(100) NOT NULL,\n    loc    varchar(100),\n    CONSTRAINT dept_pk PRIMARY KEY (deptno)\n);\n\nCREATE TABLE IF NOT EXISTS emp (\n    empno    integer      NOT NULL,\n    ename    varchar(100),\n    job      varchar(100),\n    mgr      integer,\n    hiredate date,\n    sal      integer,\n    comm     integer,\n    deptno   integer,\n    CONSTRAINT emp_pk PRIMARY KEY (empno),\n    CONSTRAINT emp_dept_fk FOREIGN KEY (deptno) REFERENCES dept (deptno)\n);\n\nCREATE INDEX IF NOT EXISTS emp_deptno_idx ON emp (deptno);\n",
+	"0002_add_row_version.down.sql": "ALTER TABLE emp DROP COLUMN IF EXISTS version;\n\nALTER TABLE dept DROP COLUMN IF EXISTS version;\n",
+	"0002_add_row_version.up.sql":   "-- Версия строки для оптимистической блокировки, увеличивается при каждом обновлении\nALTER TABLE dept ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;\n\nALTER TABLE emp ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;\n",
+}
@@ -0,0 +1,33 @@
+-- SQL команды объекта "Department"
+
+-- name: GetDept
+-- prepare: true
+SELECT deptno, dname, loc, version FROM dept WHERE deptno = $1;
+
+-- name: GetDeptUK
+-- prepare: true
+SELECT deptno, dname, loc, version FROM dept WHERE deptno = $1;
+
+-- name: DeptExists
+-- prepare: true
+SELECT 1 FROM dept WHERE deptno = $1;
+
+-- name: GetDepts
+-- prepare: true
+SELECT deptno, dname, loc, version FROM dept;
+
+-- name: GetDeptsPK
+-- prepare: true
+SELECT deptno FROM dept;
+
+-- name: CreateDept
+INSERT INTO dept (deptno, dname, loc) VALUES (:deptno, :dname, :loc);
+
+-- name: UpdateDept
+-- обновление только при совпадении версии строки - оптимистическая блокировка
+-- PATCH не использует эту команду: UPDATE только измененных столбцов формируется вне каталога в updateColumnsSQL
+UPDATE dept SET dname = :dname, loc = :loc, version = version + 1 WHERE deptno = :deptno AND version = :version;
+
+-- name: GetDeptVersion
+-- prepare: true
+SELECT version FROM dept WHERE deptno = $1;
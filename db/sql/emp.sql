@@ -0,0 +1,38 @@
+-- SQL команды объекта "Employee"
+
+-- name: EmpExists
+-- prepare: true
+SELECT 1 FROM emp WHERE empno = $1;
+
+-- name: GetEmp
+-- prepare: true
+SELECT empno, ename, job, mgr, hiredate, sal, comm, deptno, version FROM emp WHERE empno = $1;
+
+-- name: GetEmpUK
+-- prepare: true
+SELECT empno, ename, job, mgr, hiredate, sal, comm, deptno, version FROM emp WHERE empno = $1;
+
+-- name: GetEmpsByDept
+-- prepare: true
+SELECT empno, ename, job, mgr, hiredate, sal, comm, deptno, version FROM emp WHERE deptno = $1;
+
+-- name: GetEmps
+-- prepare: true
+-- дата в формате YYYY-MM-DD - формат загрузки
+SELECT empno, ename, job, mgr, to_char(hiredate, 'YYYY-MM-DD') AS hiredate, sal, comm, deptno, version FROM emp;
+
+-- name: GetEmpsPKByDept
+-- prepare: true
+SELECT empno FROM emp WHERE deptno = $1;
+
+-- name: CreateEmp
+INSERT INTO emp (empno, ename, job, mgr, hiredate, sal, comm, deptno) VALUES (:empno, :ename, :job, :mgr, :hiredate, :sal, :comm, :deptno);
+
+-- name: UpdateEmp
+-- обновление только при совпадении версии строки - оптимистическая блокировка
+-- PATCH не использует эту команду: UPDATE только измененных столбцов формируется вне каталога в updateColumnsSQL
+UPDATE emp SET empno = :empno, ename = :ename, job = :job, mgr = :mgr, hiredate = :hiredate, sal = :sal, comm = :comm, deptno = :deptno, version = version + 1 WHERE empno = :empno AND version = :version;
+
+-- name: GetEmpVersionsByDept
+-- prepare: true
+SELECT empno AS key, version FROM emp WHERE deptno = $1 ORDER BY empno;
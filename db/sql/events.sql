@@ -0,0 +1,5 @@
+-- SQL команды публикации изменений объектов
+
+-- name: NotifyEvent
+-- событие доставляется подписчикам LISTEN после фиксации транзакции
+SELECT pg_notify(:channel, :payload);
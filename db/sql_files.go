@@ -0,0 +1,10 @@
+// Code generated by embedfiles from directory sql; DO NOT EDIT.
+
+package db
+
+// sqlFiles represent embedded files of directory sql
+var sqlFiles = map[string]string{
+	"dept.sql":   "-- SQL команды объекта \"Department\"\n\n-- name: GetDept\n-- prepare: true\nSELECT deptno, dname, loc, version FROM dept WHERE deptno = $1;\n\n-- name: GetDeptUK\n-- prepare: true\nSELECT deptno, dname, loc, version FROM dept WHERE deptno = $1;\n\n-- name: DeptExists\n-- prepare: true\nSELECT 1 FROM dept WHERE deptno = $1;\n\n-- name: GetDepts\n-- prepare: true\nSELECT deptno, dname, loc, version FROM dept;\n\n-- name: GetDeptsPK\n-- prepare: true\nSELECT deptno FROM dept;\n\n-- name: CreateDept\nINSERT INTO dept (deptno, dname, loc) VALUES (:deptno, :dname, :loc);\n\n-- name: UpdateDept\n-- обновление только при совпадении версии строки - оптимистическая блокировка\n-- PATCH не использует эту команду: UPDATE только измененных столбцов формируется вне каталога в updateColumnsSQL\nUPDATE dept SET dname = :dname, loc = :loc, version = version + 1 WHERE deptno = :deptno AND version = :version;\n\n-- name: GetDeptVersion\n-- prepare: true\nSELECT version FROM dept WHERE deptno = $1;\n",
+	"emp.sql":    "-- SQL команды объекта \"Employee\"\n\n-- name: EmpExists\n-- prepare: true\nSELECT 1 FROM emp WHERE empno = $1;\n\n-- name: GetEmp\n-- prepare: true\nSELECT empno, ename, job, mgr, hiredate, sal, comm, deptno, version FROM emp WHERE empno = $1;\n\n-- name: GetEmpUK\n-- prepare: true\nSELECT empno, ename, job, mgr, hiredate, sal, comm, deptno, version FROM emp WHERE empno = $1;\n\n-- name: GetEmpsByDept\n-- prepare: true\nSELECT empno, ename, job, mgr, hiredate, sal, comm, deptno, version FROM emp WHERE deptno = $1;\n\n-- name: GetEmps\n-- prepare: true\n-- дата в формате YYYY-MM-DD - формат загрузки\nSELECT empno, ename, job, mgr, to_char(hiredate, 'YYYY-MM-DD') AS hiredate, sal, comm, deptno, version FROM emp;\n\n-- name: GetEmpsPKByDept\n-- prepare: true\nSELECT empno FROM emp WHERE deptno = $1;\n\n-- name: CreateEmp\nINSERT INTO emp (empno, ename, job, mgr, hiredate, sal, comm, deptno) VALUES (:empno, :ename, :job, :mgr, :hiredate, :sal, :comm, :deptno);\n\n-- name: UpdateEmp\n-- обновление только при совпадении версии строки - оптимистическая блокировка\n-- PATCH не использует эту команду: UPDATE только измененных столбцов формируется вне каталога в updateColumnsSQL\nUPDATE emp SET empno = :empno, ename = :ename, job = :job, mgr = :mgr, hiredate = :hiredate, sal = :sal, comm = :comm, deptno = :deptno, version = version + 1 WHERE empno = :empno AND version = :version;\n\n-- name: GetEmpVersionsByDept\n-- prepare: true\nSELECT empno AS key, version FROM emp WHERE deptno = $1 ORDER BY empno;\n",
+	"events.sql": "-- SQL команды публикации изменений объектов\n\n-- name: NotifyEvent\n-- событие доставляется подписчикам LISTEN после фиксации транзакции\nSELECT pg_notify(:channel, :payload);\n",
+}
@@ -0,0 +1,204 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Поток изменений объектов в памяти:
+//     каждое событие получает монотонно возрастающий ID
+//     последние JournalSize событий хранятся в журнале для возобновления подписки по Last-Event-ID
+//     подписчик, не успевающий читать события, отключается - он может переподключиться с Last-Event-ID
+//     если события после Last-Event-ID вытеснены из журнала, подписчик получает событие reset
+
+// Типы событий
+const (
+	TypeCreated = "created"
+	TypeUpdated = "updated"
+	TypeDeleted = "deleted"
+	TypeReset   = "reset" // состояние объектов нужно перечитать целиком: пропуск в журнале или массовая загрузка
+)
+
+// Объекты событий
+const (
+	ObjectDept = "dept"
+	ObjectEmp  = "emp"
+)
+
+// Event represent change of object
+type Event struct {
+	ID      uint64    `json:"id"`                // монотонно возрастающий номер события
+	Type    string    `json:"type"`              // TypeCreated | TypeUpdated | TypeDeleted | TypeReset
+	Object  string    `json:"object,omitempty"`  // ObjectDept | ObjectEmp, пусто - все объекты
+	Key     int       `json:"key,omitempty"`     // PK объекта
+	Version int64     `json:"version,omitempty"` // версия строки после изменения
+	Time    time.Time `json:"time"`              // время публикации
+}
+
+// Publisher represent publishing of object changes
+type Publisher interface {
+	Publish(e Event)
+}
+
+// Filter represent events selected by subscriber, nil map - all values
+type Filter struct {
+	Objects map[string]bool // объекты
+	Types   map[string]bool // типы событий
+	Keys    map[int]bool    // PK объектов
+}
+
+// Match return true if event is selected by filter. Event reset is selected by object only
+func (f *Filter) Match(e *Event) bool {
+	if f == nil {
+		return true
+	}
+	if f.Objects != nil && e.Object != "" && !f.Objects[e.Object] {
+		return false
+	}
+	if e.Type == TypeReset {
+		return true
+	}
+	if f.Types != nil && !f.Types[e.Type] {
+		return false
+	}
+	return f.Keys == nil || f.Keys[e.Key]
+}
+
+// Config represent broker configuration
+type Config struct {
+	JournalSize int // количество событий в журнале
+	BufferSize  int // размер очереди событий подписчика
+}
+
+// Subscription represent subscriber of broker
+type Subscription struct {
+	broker *Broker
+	filter *Filter
+	ch     chan Event
+}
+
+// Events return channel of events, it is closed when subscriber is dropped or broker is closed
+func (sub *Subscription) Events() <-chan Event {
+	return sub.ch
+}
+
+// Close unsubscribe from broker
+func (sub *Subscription) Close() {
+	sub.broker.mx.Lock()
+	defer sub.broker.mx.Unlock()
+	sub.broker.remove(sub)
+}
+
+// Broker represent in-memory journal of events and fan out to subscribers
+type Broker struct {
+	cfg     *Config
+	mx      sync.Mutex
+	lastID  uint64                 // ID последнего события
+	journal []Event                // кольцевой буфер событий
+	first   int                    // индекс самого старого события в журнале
+	count   int                    // количество событий в журнале
+	subs    map[*Subscription]bool // подписчики
+	closed  bool
+}
+
+// New create broker
+func New(cfg *Config) *Broker {
+	return &Broker{
+		cfg:     cfg,
+		journal: make([]Event, cfg.JournalSize),
+		subs:    make(map[*Subscription]bool),
+	}
+}
+
+// Publish assign next ID to event, store it in journal and send to subscribers
+func (b *Broker) Publish(e Event) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.lastID++
+	e.ID = b.lastID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	// вытесним самое старое событие
+	if len(b.journal) > 0 {
+		b.journal[(b.first+b.count)%len(b.journal)] = e
+		if b.count < len(b.journal) {
+			b.count++
+		} else {
+			b.first = (b.first + 1) % len(b.journal)
+		}
+	}
+
+	for sub := range b.subs {
+		if !sub.filter.Match(&e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// подписчик не успевает читать - отключаем, он продолжит с Last-Event-ID
+			mylog.PrintfInfoMsg("Events subscriber is too slow, it is dropped: eventID", e.ID)
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe create subscription with filter and return events of journal after lastEventID for resume.
+// If resume and events after lastEventID are not in journal, replay starts with event reset
+func (b *Broker) Subscribe(lastEventID uint64, resume bool, filter *Filter) (*Subscription, []Event) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	sub := &Subscription{broker: b, filter: filter, ch: make(chan Event, b.cfg.BufferSize)}
+	if b.closed {
+		close(sub.ch)
+		return sub, nil
+	}
+	b.subs[sub] = true
+
+	if !resume {
+		return sub, nil
+	}
+
+	var replay []Event
+	firstID := b.lastID - uint64(b.count) + 1 // ID самого старого события в журнале
+	if lastEventID > b.lastID || lastEventID+1 < firstID {
+		// пропущенных событий нет в журнале или ID от другого экземпляра журнала
+		replay = append(replay, Event{ID: b.lastID, Type: TypeReset, Time: time.Now()})
+		return sub, replay
+	}
+	for i := 0; i < b.count; i++ {
+		e := b.journal[(b.first+i)%len(b.journal)]
+		if e.ID > lastEventID && filter.Match(&e) {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay
+}
+
+// Close drop all subscribers, new subscriptions are closed immediately
+func (b *Broker) Close() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// remove drop subscriber, must be called under lock
+func (b *Broker) remove(sub *Subscription) {
+	if b.subs[sub] {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
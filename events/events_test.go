@@ -0,0 +1,129 @@
+package events
+
+import (
+	"testing"
+)
+
+// ids return IDs of events
+func ids(events []Event) []uint64 {
+	var result []uint64
+	for _, e := range events {
+		result = append(result, e.ID)
+	}
+	return result
+}
+
+func equalIDs(a []uint64, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSubscribeResume(t *testing.T) {
+	b := New(&Config{JournalSize: 3, BufferSize: 8})
+	for i := 1; i <= 5; i++ {
+		b.Publish(Event{Type: TypeUpdated, Object: ObjectDept, Key: i})
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		resume      bool
+		want        []uint64
+		reset       bool
+	}{
+		{"new subscriber", 0, false, nil, false},
+		{"in journal", 3, true, []uint64{4, 5}, false},
+		{"last event", 5, true, nil, false},
+		{"first event of journal", 2, true, []uint64{3, 4, 5}, false},
+		{"evicted from journal", 1, true, []uint64{5}, true},
+		{"unknown event", 10, true, []uint64{5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, replay := b.Subscribe(tt.lastEventID, tt.resume, nil)
+			defer sub.Close()
+			if got := ids(replay); !equalIDs(got, tt.want) {
+				t.Errorf("Subscribe() replay = %v, want %v", got, tt.want)
+			}
+			if reset := len(replay) > 0 && replay[0].Type == TypeReset; reset != tt.reset {
+				t.Errorf("Subscribe() reset = %v, want %v", reset, tt.reset)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	b := New(&Config{JournalSize: 10, BufferSize: 8})
+	filter := &Filter{Objects: map[string]bool{ObjectEmp: true}, Types: map[string]bool{TypeCreated: true}, Keys: map[int]bool{7839: true}}
+	sub, _ := b.Subscribe(0, false, filter)
+	defer sub.Close()
+
+	b.Publish(Event{Type: TypeCreated, Object: ObjectDept, Key: 7839})
+	b.Publish(Event{Type: TypeUpdated, Object: ObjectEmp, Key: 7839})
+	b.Publish(Event{Type: TypeCreated, Object: ObjectEmp, Key: 7369})
+	b.Publish(Event{Type: TypeCreated, Object: ObjectEmp, Key: 7839})
+	b.Publish(Event{Type: TypeReset, Object: ObjectDept})
+	b.Publish(Event{Type: TypeReset, Object: ObjectEmp})
+
+	var got []uint64
+	for len(sub.Events()) > 0 {
+		got = append(got, (<-sub.Events()).ID)
+	}
+	if want := []uint64{4, 6}; !equalIDs(got, want) {
+		t.Errorf("Events() = %v, want %v", got, want)
+	}
+
+	// при возобновлении фильтр применяется к журналу
+	sub2, replay := b.Subscribe(1, true, filter)
+	defer sub2.Close()
+	if got, want := ids(replay), []uint64{4, 6}; !equalIDs(got, want) {
+		t.Errorf("Subscribe() replay = %v, want %v", got, want)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	b := New(&Config{JournalSize: 10, BufferSize: 2})
+	slow, _ := b.Subscribe(0, false, nil)
+	for i := 1; i <= 3; i++ {
+		b.Publish(Event{Type: TypeCreated, Object: ObjectDept, Key: i})
+	}
+
+	// очередь переполнена - подписчик отключен, полученные события доступны до закрытия канала
+	var got []uint64
+	for e := range slow.Events() {
+		got = append(got, e.ID)
+	}
+	if want := []uint64{1, 2}; !equalIDs(got, want) {
+		t.Errorf("Events() = %v, want %v", got, want)
+	}
+	slow.Close() // повторное отключение допустимо
+
+	// подписчик продолжает с последнего полученного события
+	sub, replay := b.Subscribe(got[len(got)-1], true, nil)
+	defer sub.Close()
+	if got, want := ids(replay), []uint64{3}; !equalIDs(got, want) {
+		t.Errorf("Subscribe() replay = %v, want %v", got, want)
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := New(&Config{JournalSize: 10, BufferSize: 2})
+	sub, _ := b.Subscribe(0, false, nil)
+	b.Close()
+	if _, ok := <-sub.Events(); ok {
+		t.Errorf("Events() is not closed after Close()")
+	}
+
+	b.Publish(Event{Type: TypeCreated, Object: ObjectDept, Key: 1})
+	sub, _ = b.Subscribe(0, false, nil)
+	if _, ok := <-sub.Events(); ok {
+		t.Errorf("Events() of closed broker is not closed")
+	}
+}
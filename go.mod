@@ -3,6 +3,8 @@ module github.com/romapres2010/httpserver
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/andybalholm/brotli v1.0.0
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/go-kit/kit v0.9.0 // indirect
 	github.com/go-logfmt/logfmt v0.5.0 // indirect
@@ -18,9 +20,11 @@ require (
 	github.com/romapres2010/resttest v0.0.0-20200301185809-e98953e63648
 	github.com/sasbury/mini v0.0.0-20181226232755-dc74af49394b
 	github.com/urfave/cli v1.22.4
-	golang.org/x/crypto v0.0.0-20200429183012-4b2356b1ed79 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	golang.org/x/crypto v0.0.0-20200429183012-4b2356b1ed79
 	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
 	gopkg.in/guregu/null.v4 v4.0.0
 	gopkg.in/korylprince/go-ad-auth.v2 v2.2.0
 	gopkg.in/ldap.v3 v3.1.0 // indirect
+	gopkg.in/yaml.v2 v2.3.0
 )
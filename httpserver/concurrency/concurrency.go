@@ -0,0 +1,194 @@
+package concurrency
+
+import (
+	"container/list"
+	"context"
+	"expvar"
+	"math"
+	"sync"
+	"time"
+
+	myerror "github.com/romapres2010/httpserver/error"
+)
+
+// Ограничение количества одновременно обрабатываемых запросов:
+//     запрос сверх лимита ожидает в очереди не дольше QueueTimeout и deadline контекста
+//     при заполненной очереди запрос отклоняется сразу
+//     в адаптивном режиме лимит уменьшается, если задержка обработки превышает TargetLatency,
+//     но не чаще одного раза за окно, равное средней задержке,
+//     и медленно увеличивается до MaxInFlight, пока задержка в норме (AIMD)
+// Статистика лимитов публикуется через expvar в переменной "concurrency" (/debug/vars).
+
+// Коды ошибок перегрузки
+const (
+	ErrCodeQueueFull    = "8039" // очередь ожидания заполнена
+	ErrCodeQueueTimeout = "8040" // превышено время ожидания в очереди
+)
+
+// Config represent concurrency limit configuration
+type Config struct {
+	MaxInFlight   int           // максимальное количество одновременных запросов
+	MaxQueue      int           // максимальная длина очереди ожидания, 0 - без очереди
+	QueueTimeout  time.Duration // максимальное время ожидания в очереди
+	Adaptive      bool          // адаптивный лимит по задержке обработки
+	MinInFlight   int           // минимальный адаптивный лимит
+	TargetLatency time.Duration // целевая задержка обработки запроса
+}
+
+// Stats represent state of limiter
+type Stats struct {
+	Limit    int    `json:"limit"`    // текущий лимит
+	InFlight int    `json:"inFlight"` // обрабатывается запросов
+	Queued   int    `json:"queued"`   // ожидает в очереди
+	Rejected uint64 `json:"rejected"` // отклонено запросов
+	Timeouts uint64 `json:"timeouts"` // отклонено по времени ожидания
+}
+
+// vars represent published statistics of all limiters
+var vars = expvar.NewMap("concurrency")
+
+// Limiter represent limit of in-flight requests with bounded wait queue
+type Limiter struct {
+	cfg      *Config
+	mx       sync.Mutex
+	limit    float64    // текущий лимит, дробная часть накапливает аддитивное увеличение
+	latency  float64    // экспоненциальное скользящее среднее задержки в секундах
+	decrease time.Time  // время последнего уменьшения лимита
+	inFlight int        // обрабатывается запросов
+	queue    *list.List // очередь ожидания - каналы ожидающих запросов
+	rejected uint64
+	timeouts uint64
+}
+
+// New create limiter and publish its statistics with name
+func New(name string, cfg *Config) *Limiter {
+	l := &Limiter{
+		cfg:   cfg,
+		limit: float64(cfg.MaxInFlight),
+		queue: list.New(),
+	}
+	vars.Set(name, expvar.Func(func() interface{} { return l.Stats() }))
+	return l
+}
+
+// Acquire take slot for request, wait in queue if limit is reached.
+// Returned release function must be called after processing of request.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	l.mx.Lock()
+	if l.inFlight < int(l.limit) && l.queue.Len() == 0 {
+		l.inFlight++
+		l.mx.Unlock()
+		return l.releaseFunc(), nil
+	}
+	if l.queue.Len() >= l.cfg.MaxQueue {
+		l.rejected++
+		stats := l.stats()
+		l.mx.Unlock()
+		return nil, myerror.New(ErrCodeQueueFull, "Server is overloaded, queue is full: limit, inFlight, queued", stats.Limit, stats.InFlight, stats.Queued).PrintfInfo(1)
+	}
+
+	// ожидаем освобождения слота - его передает release
+	ready := make(chan struct{})
+	elem := l.queue.PushBack(ready)
+	l.mx.Unlock()
+
+	timer := time.NewTimer(l.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+		return l.releaseFunc(), nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mx.Lock()
+	select {
+	case <-ready:
+		// слот передан одновременно с отменой ожидания - используем его
+		l.mx.Unlock()
+		return l.releaseFunc(), nil
+	default:
+	}
+	l.queue.Remove(elem)
+	l.rejected++
+	l.timeouts++
+	l.mx.Unlock()
+	return nil, myerror.New(ErrCodeQueueTimeout, "Server is overloaded, queue wait timeout: QueueTimeout", l.cfg.QueueTimeout).PrintfInfo(1)
+}
+
+// releaseFunc return function which releases slot and registers latency of request
+func (l *Limiter) releaseFunc() func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			now := time.Now()
+			l.release(now, now.Sub(start))
+		})
+	}
+}
+
+// release free slot, adapt limit and pass free slots to waiting requests
+func (l *Limiter) release(now time.Time, latency time.Duration) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.inFlight--
+	if l.cfg.Adaptive {
+		l.adapt(now, latency)
+	}
+
+	for l.queue.Len() > 0 && l.inFlight < int(l.limit) {
+		ready := l.queue.Remove(l.queue.Front()).(chan struct{})
+		l.inFlight++
+		close(ready)
+	}
+}
+
+// adapt change limit by observed latency, must be called under lock
+func (l *Limiter) adapt(now time.Time, latency time.Duration) {
+	const weight = 0.1 // вес нового значения в скользящем среднем
+
+	if l.latency == 0 {
+		l.latency = latency.Seconds()
+	} else {
+		l.latency = (1-weight)*l.latency + weight*latency.Seconds()
+	}
+
+	if l.latency > l.cfg.TargetLatency.Seconds() {
+		// мультипликативное уменьшение не чаще одного раза за окно задержки:
+		// запросы, начатые до уменьшения, завершаются с прежней задержкой и не должны снижать лимит повторно
+		if now.Sub(l.decrease).Seconds() >= l.latency {
+			l.decrease = now
+			l.limit *= 0.9
+			if min := math.Max(float64(l.cfg.MinInFlight), 1); l.limit < min {
+				l.limit = min // хотя бы один запрос должен обрабатываться, иначе очередь не освободится
+			}
+		}
+	} else if l.inFlight+1 >= int(l.limit) {
+		// аддитивное увеличение примерно на 1 за каждые limit запросов при полной загрузке
+		l.limit += 1 / l.limit
+		if l.limit > float64(l.cfg.MaxInFlight) {
+			l.limit = float64(l.cfg.MaxInFlight)
+		}
+	}
+}
+
+// Stats return state of limiter
+func (l *Limiter) Stats() Stats {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	return l.stats()
+}
+
+// stats return state of limiter, must be called under lock
+func (l *Limiter) stats() Stats {
+	return Stats{
+		Limit:    int(l.limit),
+		InFlight: l.inFlight,
+		Queued:   l.queue.Len(),
+		Rejected: l.rejected,
+		Timeouts: l.timeouts,
+	}
+}
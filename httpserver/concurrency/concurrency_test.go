@@ -0,0 +1,112 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	myerror "github.com/romapres2010/httpserver/error"
+)
+
+// errCode return code of catalogued error
+func errCode(err error) string {
+	if myerr, ok := err.(*myerror.Error); ok {
+		return myerr.Code
+	}
+	return ""
+}
+
+func TestLimiterQueue(t *testing.T) {
+	l := New("TestLimiterQueue", &Config{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Minute})
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// второй запрос ждет в очереди, пока первый не освободит слот
+	acquired := make(chan error)
+	go func() {
+		release2, err := l.Acquire(context.Background())
+		if err == nil {
+			release2()
+		}
+		acquired <- err
+	}()
+	for l.Stats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// очередь заполнена - третий запрос отклоняется сразу
+	if _, err = l.Acquire(context.Background()); errCode(err) != ErrCodeQueueFull {
+		t.Errorf("Acquire() with full queue error = %v, want %v", err, ErrCodeQueueFull)
+	}
+
+	release()
+	release() // повторный вызов игнорируется
+	if err = <-acquired; err != nil {
+		t.Errorf("Acquire() from queue error = %v", err)
+	}
+
+	if stats := l.Stats(); stats.InFlight != 0 || stats.Queued != 0 || stats.Rejected != 1 {
+		t.Errorf("Stats() = %+v, want no requests and 1 rejected", stats)
+	}
+}
+
+func TestLimiterQueueTimeout(t *testing.T) {
+	l := New("TestLimiterQueueTimeout", &Config{MaxInFlight: 1, MaxQueue: 10, QueueTimeout: 10 * time.Millisecond})
+
+	release, _ := l.Acquire(context.Background())
+	defer release()
+
+	if _, err := l.Acquire(context.Background()); errCode(err) != ErrCodeQueueTimeout {
+		t.Errorf("Acquire() error = %v, want %v", err, ErrCodeQueueTimeout)
+	}
+
+	// deadline запроса меньше QueueTimeout
+	l.cfg.QueueTimeout = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); errCode(err) != ErrCodeQueueTimeout {
+		t.Errorf("Acquire() with deadline error = %v, want %v", err, ErrCodeQueueTimeout)
+	}
+
+	if stats := l.Stats(); stats.Queued != 0 || stats.Timeouts != 2 {
+		t.Errorf("Stats() = %+v, want empty queue and 2 timeouts", stats)
+	}
+}
+
+func TestLimiterAdaptive(t *testing.T) {
+	l := New("TestLimiterAdaptive", &Config{MaxInFlight: 10, Adaptive: true, MinInFlight: 2, TargetLatency: 100 * time.Millisecond})
+
+	now := time.Now()
+
+	// медленные запросы, завершившиеся в одном окне задержки, уменьшают лимит однократно
+	for i := 0; i < 50; i++ {
+		l.inFlight++
+		l.release(now, time.Second)
+	}
+	if stats := l.Stats(); stats.Limit != 9 {
+		t.Fatalf("Limit after slow requests in one window = %v, want 9", stats.Limit)
+	}
+
+	// задержка выше целевой в каждом следующем окне - лимит снижается до минимального
+	for i := 0; i < 50; i++ {
+		now = now.Add(time.Second)
+		l.inFlight++
+		l.release(now, time.Second)
+	}
+	if stats := l.Stats(); stats.Limit != 2 {
+		t.Fatalf("Limit after slow requests = %v, want 2", stats.Limit)
+	}
+
+	// задержка в норме при полной загрузке - лимит растет до максимального
+	for i := 0; i < 1000; i++ {
+		l.inFlight = int(l.limit)
+		l.release(now, time.Millisecond)
+	}
+	l.inFlight = 0
+	if stats := l.Stats(); stats.Limit != 10 {
+		t.Errorf("Limit after fast requests = %v, want 10", stats.Limit)
+	}
+}
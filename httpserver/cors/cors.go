@@ -0,0 +1,147 @@
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	myerror "github.com/romapres2010/httpserver/error"
+)
+
+// Cross-Origin Resource Sharing (CORS):
+//     для простых запросов с заголовком Origin добавляются заголовки Access-Control-Allow-*
+//     предварительный запрос OPTIONS (preflight) проверяет источник, метод и заголовки запроса
+// Источник задается точно "https://example.com", маской поддоменов "https://*.example.com" или "*" - любой.
+// Источник "*" недопустим при AllowCredentials - иначе любой сайт выполнит запрос с cookie пользователя.
+
+// Config represent CORS policy
+type Config struct {
+	AllowedOrigins   []string // разрешенные источники
+	AllowedMethods   []string // разрешенные методы
+	AllowedHeaders   []string // разрешенные заголовки запроса, "*" - любые
+	ExposedHeaders   []string // заголовки ответа, доступные скрипту
+	AllowCredentials bool     // разрешить передачу cookie и заголовка Authorization
+	MaxAge           int      // время кэширования результата preflight в секундах
+}
+
+// wildcard represent origin with mask of subdomains
+type wildcard struct {
+	prefix string // схема, например "https://"
+	suffix string // домен с портом, например ".example.com"
+}
+
+// Policy represent compiled CORS policy
+type Policy struct {
+	cfg       *Config
+	anyOrigin bool
+	origins   map[string]bool
+	wildcards []wildcard
+	methods   map[string]bool
+	anyHeader bool
+	headers   map[string]bool
+}
+
+// New compile CORS policy
+func New(cfg *Config) (*Policy, error) {
+	p := &Policy{
+		cfg:     cfg,
+		origins: make(map[string]bool),
+		methods: make(map[string]bool),
+		headers: make(map[string]bool),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			if cfg.AllowCredentials {
+				return nil, myerror.New("6031", "CORS origin '*' is not allowed for AllowCredentials = true").PrintfInfo()
+			}
+			p.anyOrigin = true
+		} else if i := strings.Index(origin, "*."); i >= 0 {
+			p.wildcards = append(p.wildcards, wildcard{prefix: origin[:i], suffix: origin[i+1:]})
+		} else {
+			p.origins[origin] = true
+		}
+	}
+	for _, method := range cfg.AllowedMethods {
+		p.methods[strings.ToUpper(strings.TrimSpace(method))] = true
+	}
+	for _, header := range cfg.AllowedHeaders {
+		header = strings.TrimSpace(header)
+		if header == "*" {
+			p.anyHeader = true
+		} else {
+			p.headers[http.CanonicalHeaderKey(header)] = true
+		}
+	}
+	return p, nil
+}
+
+// AllowOrigin check if origin is allowed
+func (p *Policy) AllowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		// маска соответствует поддомену любого уровня, но не самому домену
+		if len(origin) > len(w.prefix)+len(w.suffix) && strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) {
+			if sub := origin[len(w.prefix) : len(origin)-len(w.suffix)]; !strings.ContainsAny(sub, "/:@") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowMethod check if method is allowed
+func (p *Policy) AllowMethod(method string) bool {
+	return p.methods[method]
+}
+
+// AllowHeaders check if all headers of comma separated list are allowed
+func (p *Policy) AllowHeaders(headers string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range strings.Split(headers, ",") {
+		if header = strings.TrimSpace(header); header != "" && !p.headers[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+// SetHeaders set CORS headers of response for allowed origin
+func (p *Policy) SetHeaders(w http.ResponseWriter, origin string) {
+	if p.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(p.cfg.ExposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.cfg.ExposedHeaders, ", "))
+	}
+}
+
+// Preflight check preflight request and set CORS headers of response, false - request is not allowed
+func (p *Policy) Preflight(w http.ResponseWriter, origin string, method string, headers string) bool {
+	if !p.AllowOrigin(origin) || !p.AllowMethod(method) || !p.AllowHeaders(headers) {
+		return false
+	}
+	p.SetHeaders(w, origin)
+	w.Header().Del("Access-Control-Expose-Headers") // для preflight не используется
+	w.Header().Set("Access-Control-Allow-Methods", method)
+	if headers != "" {
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+	}
+	if p.cfg.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(p.cfg.MaxAge))
+	}
+	return true
+}
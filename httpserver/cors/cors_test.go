@@ -0,0 +1,94 @@
+package cors
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowOrigin(t *testing.T) {
+	p, err := New(&Config{AllowedOrigins: []string{"https://app.example.com", "https://*.example.org", "http://*.local:8080"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://.example.org", false},
+		{"https://evil.com/.example.org", false},
+		{"https://evilexample.org", false},
+		{"http://dev.local:8080", true},
+		{"http://dev.local:9090", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := p.AllowOrigin(tt.origin); got != tt.want {
+			t.Errorf("AllowOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	p, err := New(&Config{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "PUT"},
+		AllowedHeaders: []string{"content-type", "If-Match"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         600,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	if !p.Preflight(w, "https://app.example.com", "PUT", "Content-Type, if-match") {
+		t.Fatalf("Preflight() = false, want true")
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":   "*",
+		"Access-Control-Allow-Methods":  "PUT",
+		"Access-Control-Allow-Headers":  "Content-Type, if-match",
+		"Access-Control-Max-Age":        "600",
+		"Access-Control-Expose-Headers": "",
+	}
+	for key, value := range want {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("header %v = %q, want %q", key, got, value)
+		}
+	}
+
+	if p.Preflight(httptest.NewRecorder(), "https://app.example.com", "DELETE", "") {
+		t.Errorf("Preflight() with not allowed method = true, want false")
+	}
+	if p.Preflight(httptest.NewRecorder(), "https://app.example.com", "GET", "X-Custom") {
+		t.Errorf("Preflight() with not allowed header = true, want false")
+	}
+}
+
+func TestSetHeadersCredentials(t *testing.T) {
+	// "*" с передачей cookie разрешил бы запросы от любого сайта
+	if _, err := New(&Config{AllowedOrigins: []string{"https://app.example.com", " * "}, AllowCredentials: true}); err == nil {
+		t.Errorf("New() with origin '*' and AllowCredentials error = nil, want error")
+	}
+
+	p, err := New(&Config{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	w := httptest.NewRecorder()
+	p.SetHeaders(w, "https://app.example.com")
+
+	// при передаче cookie возвращается источник запроса
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want origin of request", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+}
@@ -122,12 +122,22 @@ func (log *Logger) LogHTTPInResponse(ctx context.Context, resp *http.Response) e
 
 // LogHTTPInRequest process HTTP logging for In request
 func (log *Logger) LogHTTPInRequest(ctx context.Context, req *http.Request) error {
+	return log.logHTTPInRequest(ctx, req, log.cfg.LogBody)
+}
+
+// LogHTTPInRequestStream process HTTP logging for In request with streaming body - body is not read and not logged
+func (log *Logger) LogHTTPInRequestStream(ctx context.Context, req *http.Request) error {
+	return log.logHTTPInRequest(ctx, req, false)
+}
+
+// logHTTPInRequest process HTTP logging for In request
+func (log *Logger) logHTTPInRequest(ctx context.Context, req *http.Request, logBody bool) error {
 	if log.cfg.Enable && log.file != nil {
 		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 		mylog.PrintfDebugMsg("Logging HTTP in request: reqID", reqID)
 
 		if req != nil && log.cfg.LogInReq {
-			dump, err := httputil.DumpRequest(req, log.cfg.LogBody)
+			dump, err := httputil.DumpRequest(req, logBody)
 			if err != nil {
 				return myerror.WithCause("8020", "Error dump HTTP Request: reqID", err, reqID).PrintfInfo()
 			}
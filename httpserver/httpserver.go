@@ -3,6 +3,7 @@ package httpserver
 import (
 	"context"
 	"crypto/tls"
+	"expvar"
 	"net"
 	"net/http"
 	"net/http/pprof"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/gorilla/mux"
 	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
 	"github.com/romapres2010/httpserver/httpserver/httplog"
 	"github.com/romapres2010/httpserver/httpserver/httpservice"
 	"github.com/romapres2010/httpserver/json"
@@ -53,8 +55,8 @@ type Config struct {
 	ServiceCfg httpservice.Config // конфигурация HTTP сервиса
 }
 
-// New create HTTP server
-func New(ctx context.Context, errCh chan<- error, cfg *Config, jsonService *json.Service) (*Server, error) {
+// New create HTTP server, changes of objects are streamed from broker, nil broker - stream is not published
+func New(ctx context.Context, errCh chan<- error, cfg *Config, jsonService *json.Service, broker *events.Broker) (*Server, error) {
 	var err error
 
 	mylog.PrintfInfoMsg("Creating new HTTP server")
@@ -86,7 +88,7 @@ func New(ctx context.Context, errCh chan<- error, cfg *Config, jsonService *json
 	}
 
 	// Новый HTTP сервис и HTTP logger
-	if server.httpService, server.logger, err = httpservice.New(server.ctx, &cfg.ServiceCfg, jsonService); err != nil {
+	if server.httpService, server.logger, err = httpservice.New(server.ctx, &cfg.ServiceCfg, jsonService, broker); err != nil {
 		return nil, err
 	}
 
@@ -97,6 +99,9 @@ func New(ctx context.Context, errCh chan<- error, cfg *Config, jsonService *json
 			IdleTimeout:  time.Duration(cfg.IdleTimeout * int(time.Second)),
 		}
 
+		// длительные потоковые ответы завершаются в начале остановки, иначе Shutdown ожидает их до ShutdownTimeout
+		server.httpServer.RegisterOnShutdown(server.httpService.CloseStreams)
+
 		// Если задано ограничение на header
 		if cfg.MaxHeaderBytes > 0 {
 			server.httpServer.MaxHeaderBytes = cfg.MaxHeaderBytes
@@ -147,11 +152,18 @@ func New(ctx context.Context, errCh chan<- error, cfg *Config, jsonService *json
 		// Зарегистрируем HTTP обработчиков
 		if server.httpService.Handlers != nil {
 			for _, h := range server.httpService.Handlers {
-				server.router.HandleFunc(h.Path, h.HundlerFunc).Methods(h.Method)
-				mylog.PrintfInfoMsg("Handler is registered: Path, Method", h.Path, h.Method)
+				// маршрут версионированного API регистрируется также с префиксом каждой версии
+				for _, path := range server.httpService.Paths(h.Path) {
+					server.router.HandleFunc(path, h.HundlerFunc).Methods(h.Method)
+					mylog.PrintfInfoMsg("Handler is registered: Path, Method", path, h.Method)
+				}
 			}
 		}
 
+		// Статистика expvar: лимиты одновременных запросов (переменная "concurrency") и пулы подключений к БД (переменная "db")
+		server.router.Handle("/debug/vars", expvar.Handler()).Methods("GET")
+		mylog.PrintfInfoMsg("'/debug/vars' is registered")
+
 		// Регистрация pprof-обработчиков
 		if server.cfg.UseProfile {
 			mylog.PrintfInfoMsg("'/debug/pprof' is registered")
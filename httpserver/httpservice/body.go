@@ -0,0 +1,86 @@
+package httpservice
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	myerror "github.com/romapres2010/httpserver/error"
+)
+
+// ErrCodeBodyTooLarge represent error of request body larger than MaxBodyBytes
+const ErrCodeBodyTooLarge = "8036"
+
+// limitBody replace body of request with reader limited by maxBytes, 0 - without restriction
+func limitBody(r *http.Request, reqID uint64, maxBytes int64) {
+	if maxBytes > 0 {
+		r.Body = &limitReader{body: r.Body, remaining: maxBytes, maxBytes: maxBytes, reqID: reqID}
+	}
+}
+
+// limitReader represent body of request limited by max size
+type limitReader struct {
+	body      io.ReadCloser
+	remaining int64  // осталось прочитать до достижения ограничения
+	maxBytes  int64  // ограничение размера тела
+	reqID     uint64 // уникальный номер HTTP запроса
+	err       error  // ошибка превышения размера, повторяется при каждом чтении
+}
+
+// Read supports io.Reader interface
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// читаем на 1 байт больше ограничения, чтобы отличить тело размером ровно maxBytes
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.body.Read(p)
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		return n, err
+	}
+
+	n = int(l.remaining)
+	l.remaining = 0
+	l.err = myerror.New(ErrCodeBodyTooLarge, "HTTP body is too large: reqID, MaxBodyBytes", l.reqID, l.maxBytes).PrintfInfo()
+	return n, l.err
+}
+
+// Close supports io.Closer interface
+func (l *limitReader) Close() error {
+	return l.body.Close()
+}
+
+// readBody read body of request. Body of pooled size is read into buffer from bytesPool, pooled buffer must be returned into pool
+func (s *Service) readBody(r *http.Request) (requestBuf []byte, pooled bool, err error) {
+	// тело заведомо больше буфера из pool
+	if !s.cfg.UseBufPool || s.bytesPool == nil || r.ContentLength > int64(s.cfg.BufPooledSize) {
+		requestBuf, err = ioutil.ReadAll(r.Body)
+		return requestBuf, false, err
+	}
+
+	buf := s.bytesPool.GetBuf()
+	n, err := io.ReadFull(r.Body, buf[:cap(buf)])
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		// тело поместилось в буфер
+		return buf[:n], true, nil
+	case nil:
+		// тело больше буфера - дочитываем в новый буфер
+		b := bytes.NewBuffer(make([]byte, 0, 2*n))
+		b.Write(buf[:n])
+		s.bytesPool.PutBuf(buf)
+		_, err = b.ReadFrom(r.Body)
+		return b.Bytes(), false, err
+	default:
+		s.bytesPool.PutBuf(buf)
+		return nil, false, err
+	}
+}
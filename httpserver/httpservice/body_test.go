@@ -0,0 +1,149 @@
+package httpservice
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/romapres2010/httpserver/bytespool"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/httpserver/httplog"
+)
+
+func TestLimitBody(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{"", false},
+		{"0123456789", false},
+		{"0123456789A", true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		limitBody(r, 1, 10)
+		data, err := ioutil.ReadAll(r.Body)
+		if tt.wantErr {
+			if myerr, ok := err.(*myerror.Error); !ok || myerr.Code != ErrCodeBodyTooLarge {
+				t.Errorf("ReadAll(%q) error = %v, want %v", tt.body, err, ErrCodeBodyTooLarge)
+			}
+			if len(data) != 10 {
+				t.Errorf("ReadAll(%q) len = %v, want 10", tt.body, len(data))
+			}
+		} else if err != nil || string(data) != tt.body {
+			t.Errorf("ReadAll(%q) = %q, %v", tt.body, data, err)
+		}
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	s := &Service{
+		cfg:       &Config{UseBufPool: true, BufPooledSize: 16},
+		bytesPool: bytespool.New(&bytespool.Config{PooledSize: 16}),
+	}
+	tests := []struct {
+		body       string
+		wantPooled bool
+	}{
+		{"", true},
+		{"small body", true},
+		{strings.Repeat("x", 16), false},
+		{strings.Repeat("x", 100), false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		r.ContentLength = -1 // размер тела неизвестен - читаем в буфер из pool
+		data, pooled, err := s.readBody(r)
+		if err != nil || string(data) != tt.body || pooled != tt.wantPooled {
+			t.Errorf("readBody(%q) = %q, %v, %v, want pooled %v", tt.body, data, pooled, err, tt.wantPooled)
+		}
+	}
+}
+
+func TestProcessBodyTooLarge(t *testing.T) {
+	s := &Service{
+		ctx: context.Background(),
+		cfg: &Config{MaxBodyBytes: 10},
+	}
+	echo := func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error) {
+		return requestBuf, Header{}, http.StatusOK, nil
+	}
+	tests := []struct {
+		body          string
+		contentLength int64
+		wantStatus    int
+	}{
+		{"0123456789", 10, http.StatusOK},
+		{"0123456789A", 11, http.StatusRequestEntityTooLarge},
+		{"0123456789A", -1, http.StatusRequestEntityTooLarge}, // chunked - размер выявляется при чтении
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/echo", bytes.NewBufferString(tt.body))
+		r.ContentLength = tt.contentLength
+		w := httptest.NewRecorder()
+		_ = s.process("POST", w, r, echo)
+		if w.Code != tt.wantStatus {
+			t.Errorf("process(%q, ContentLength %v) status = %v, want %v", tt.body, tt.contentLength, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+// countReader represent body of request of size bytes, which counts read bytes
+type countReader struct {
+	size int
+	n    int
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	if c.n >= c.size {
+		return 0, io.EOF
+	}
+	if len(p) > c.size-c.n {
+		p = p[:c.size-c.n]
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	c.n += len(p)
+	return len(p), nil
+}
+
+func TestProcessBodyTooLargeLogged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httplog")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger, err := httplog.New(context.Background(), &httplog.Config{Enable: true, LogInReq: true, LogBody: true}, filepath.Join(dir, "http.log"))
+	if err != nil {
+		t.Fatalf("httplog.New() error = %v", err)
+	}
+	defer logger.Close()
+
+	s := &Service{
+		ctx:    context.Background(),
+		cfg:    &Config{MaxBodyBytes: 10},
+		logger: logger,
+	}
+	echo := func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error) {
+		return requestBuf, Header{}, http.StatusOK, nil
+	}
+
+	// тело без Content-Length при логировании читается не больше ограничения
+	body := &countReader{size: 1 << 20}
+	r := httptest.NewRequest("POST", "/echo", body)
+	r.ContentLength = -1
+	w := httptest.NewRecorder()
+	_ = s.process("POST", w, r, echo)
+	if w.Code != http.StatusRequestEntityTooLarge || body.n > 11 {
+		t.Errorf("process() status = %v, read %v bytes, want %v and no more than 11 bytes", w.Code, body.n, http.StatusRequestEntityTooLarge)
+	}
+}
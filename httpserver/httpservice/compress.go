@@ -0,0 +1,80 @@
+package httpservice
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/romapres2010/httpserver/compress"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Коды ошибок сжатия
+const (
+	ErrCodeUnsupportedEncoding = "8034" // Content-Encoding запроса не поддерживается
+	ErrCodeInvalidEncoding     = "8035" // тело запроса не соответствует Content-Encoding
+)
+
+// decompressBody replace body of request with decompressor by header Content-Encoding
+func decompressBody(r *http.Request, reqID uint64) (int, error) {
+	encoding := r.Header.Get("Content-Encoding")
+	if encoding == "" || encoding == compress.EncodingIdentity {
+		return http.StatusOK, nil
+	}
+	if !compress.Supported(encoding) {
+		return http.StatusUnsupportedMediaType, myerror.New(ErrCodeUnsupportedEncoding, "Unsupported Content-Encoding, only avaliable: 'gzip', 'deflate', 'br': reqID, Content-Encoding", reqID, encoding).PrintfInfo(1)
+	}
+
+	mylog.PrintfDebugMsg("Decompress request body: reqID, Content-Encoding", reqID, encoding)
+	body, err := compress.NewReader(encoding, r.Body)
+	if err != nil {
+		return http.StatusBadRequest, myerror.WithCause(ErrCodeInvalidEncoding, "Invalid compressed HTTP body: reqID, Content-Encoding", err, reqID, encoding).PrintfInfo(1)
+	}
+
+	r.Body = &decompressReader{body, r.Body}
+	r.ContentLength = -1 // размер распакованного тела неизвестен
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	return http.StatusOK, nil
+}
+
+// decompressReader represent decompressed body of request
+type decompressReader struct {
+	decompressor io.ReadCloser // распаковка тела
+	body         io.Closer     // исходное тело запроса
+}
+
+// Read supports io.Reader interface
+func (d *decompressReader) Read(p []byte) (int, error) {
+	return d.decompressor.Read(p)
+}
+
+// Close supports io.Closer interface
+func (d *decompressReader) Close() error {
+	_ = d.decompressor.Close()
+	return d.body.Close()
+}
+
+// responseEncoding return encoding for compression of response and set Content-Encoding header, empty result - response is not compressed.
+// size < 0 - unknown size of streaming response
+func (s *Service) responseEncoding(r *http.Request, header Header, size int) string {
+	if s.compress == nil || header == nil || header["Content-Encoding"] != "" {
+		return ""
+	}
+	if !s.compress.Compressible(header["Content-Type"], size) {
+		return ""
+	}
+
+	// ответ может быть сжат - кэши должны учитывать Accept-Encoding
+	if header["Vary"] != "" {
+		header["Vary"] += ", Accept-Encoding"
+	} else {
+		header["Vary"] = "Accept-Encoding"
+	}
+
+	encoding := s.compress.Negotiate(r.Header.Get("Accept-Encoding"))
+	if encoding != "" {
+		header["Content-Encoding"] = encoding
+	}
+	return encoding
+}
@@ -0,0 +1,27 @@
+package httpservice
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/romapres2010/httpserver/httpserver/concurrency"
+)
+
+// concurrencyWrap cover handler function with concurrency limiters, overloaded server answers 503 Service Unavailable.
+// Слот маршрута занимается раньше общего, чтобы ожидающий в очереди маршрута запрос не удерживал общий слот
+func (s *Service) concurrencyWrap(limiters []*concurrency.Limiter, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, limiter := range limiters {
+			release, err := limiter.Acquire(r.Context())
+			if err != nil {
+				reqID := GetNextRequestID()
+				w.Header().Set("Retry-After", strconv.Itoa(s.cfg.OverloadRetryAfter))
+				s.processError(err, w, http.StatusServiceUnavailable, reqID) // расширенное логирование ошибки в контексте HTTP
+				return
+			}
+			defer release()
+		}
+
+		handlerFunc(w, r)
+	})
+}
@@ -0,0 +1,105 @@
+package httpservice
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/httpserver/cors"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// ErrCodeCORSRejected represent error of preflight request which is not allowed by CORS policy
+const ErrCodeCORSRejected = "8041"
+
+// corsWrap cover handler function with CORS headers for allowed origin
+func (s *Service) corsWrap(policy *cors.Policy, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Add("Vary", "Origin")
+			if policy.AllowOrigin(origin) {
+				policy.SetHeaders(w, origin)
+			} else {
+				mylog.PrintfDebugMsg("CORS origin is not allowed: origin, path", origin, r.URL.Path)
+			}
+		}
+
+		handlerFunc(w, r)
+	})
+}
+
+// preflightHandler handle OPTIONS request of path, routes represent CORS policy of each method of path
+func (s *Service) preflightHandler(routes map[string]*cors.Policy) http.HandlerFunc {
+	methods := make([]string, 0, len(routes)+1)
+	for method := range routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	allow := strings.Join(append(methods, http.MethodOptions), ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+
+		// обычный запрос OPTIONS - возвращаем список методов
+		origin, method := r.Header.Get("Origin"), r.Header.Get("Access-Control-Request-Method")
+		if origin == "" || method == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+		requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+		if policy, ok := routes[method]; !ok || !policy.Preflight(w, origin, method, requestHeaders) {
+			reqID := GetNextRequestID()
+			myerr := myerror.New(ErrCodeCORSRejected, "CORS preflight request is not allowed: reqID, path, origin, method, headers", reqID, r.URL.Path, origin, method, requestHeaders).PrintfInfo()
+			s.processError(myerr, w, http.StatusForbidden, reqID) // расширенное логирование ошибки в контексте HTTP
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// applyCORS cover handlers with CORS policy of route or common policy and add preflight handlers for each path
+func (s *Service) applyCORS() error {
+	common := &s.cfg.CORSCfg
+	policies := make(map[*cors.Config]*cors.Policy) // маршруты с одной политикой используют общий объект
+	paths := make(map[string]map[string]*cors.Policy)
+
+	for name, h := range s.Handlers {
+		cfg := common
+		if h.CORS != nil {
+			cfg = h.CORS
+		} else if origins, ok := s.cfg.CORSRouteOrigins[name]; ok {
+			// переопределение источников маршрута из конфигурации
+			routeCfg := *common
+			routeCfg.AllowedOrigins = origins
+			cfg = &routeCfg
+		}
+		policy, ok := policies[cfg]
+		if !ok {
+			var err error
+			if policy, err = cors.New(cfg); err != nil {
+				return myerror.WithCause("6031", "Incorrect CORS policy of route: name, origins", err, name, cfg.AllowedOrigins).PrintfInfo()
+			}
+			policies[cfg] = policy
+		}
+
+		h.HundlerFunc = s.corsWrap(policy, h.HundlerFunc)
+		s.Handlers[name] = h
+
+		if paths[h.Path] == nil {
+			paths[h.Path] = make(map[string]*cors.Policy)
+		}
+		paths[h.Path][h.Method] = policy
+	}
+
+	for path, routes := range paths {
+		s.Handlers["CORSPreflightHandler "+path] = Handler{path, s.recoverWrap(s.preflightHandler(routes)), http.MethodOptions, nil, nil}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package httpservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/romapres2010/httpserver/httpserver/cors"
+	myjson "github.com/romapres2010/httpserver/json"
+)
+
+func TestApplyCORS(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	s := &Service{
+		cfg: &Config{
+			CORSCfg: cors.Config{
+				AllowedOrigins: []string{"https://*.example.com"},
+				AllowedMethods: []string{"GET", "PUT", "POST"},
+				AllowedHeaders: []string{"Content-Type"},
+			},
+			CORSRouteOrigins: map[string][]string{"SinginHandler": {"https://login.example.org"}},
+		},
+		Handlers: map[string]Handler{
+			"GetDeptHandler":    {"/depts/{id:[0-9]+}", ok, "GET", nil, nil},
+			"UpdateDeptHandler": {"/depts/{id:[0-9]+}", ok, "PUT", nil, nil},
+			"SinginHandler":     {"/signin", ok, "POST", nil, nil},
+		},
+	}
+	if err := s.applyCORS(); err != nil {
+		t.Fatalf("applyCORS() error = %v", err)
+	}
+
+	preflight := func(path string, origin string, method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("OPTIONS", path, nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", method)
+		w := httptest.NewRecorder()
+		s.Handlers["CORSPreflightHandler "+path].HundlerFunc(w, r)
+		return w
+	}
+
+	w := preflight("/depts/{id:[0-9]+}", "https://app.example.com", "PUT")
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Allow") != "GET, PUT, OPTIONS" {
+		t.Errorf("preflight = %v, headers %v", w.Code, w.Header())
+	}
+
+	// метод не зарегистрирован для пути
+	if w = preflight("/depts/{id:[0-9]+}", "https://app.example.com", "POST"); w.Code != http.StatusForbidden {
+		t.Errorf("preflight of unknown method = %v, want 403", w.Code)
+	}
+
+	// источники маршрута переопределены
+	if w = preflight("/signin", "https://app.example.com", "POST"); w.Code != http.StatusForbidden {
+		t.Errorf("preflight of overridden route = %v, want 403", w.Code)
+	}
+	if w = preflight("/signin", "https://login.example.org", "POST"); w.Code != http.StatusNoContent {
+		t.Errorf("preflight of overridden route = %v, want 204", w.Code)
+	}
+
+	// простой запрос получает заголовки CORS
+	r := httptest.NewRequest("GET", "/depts/10", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	s.Handlers["GetDeptHandler"].HundlerFunc(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Vary") != "Origin" {
+		t.Errorf("GET headers = %v", w.Header())
+	}
+}
+
+func TestNewCORSRouteOrigins(t *testing.T) {
+	tests := []struct {
+		name         string
+		routeOrigins map[string][]string
+		wantErr      bool
+	}{
+		{"route origins", map[string][]string{"GetDeptHandler": {"https://app.example.com"}}, false},
+		{"any origin with credentials", map[string][]string{"GetDeptHandler": {"https://app.example.com", "*"}}, true},
+		{"unknown handler", map[string][]string{"GetDeptsHandler": {"https://app.example.com"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				UseCORS: true,
+				CORSCfg: cors.Config{
+					AllowedOrigins:   []string{"https://*.example.com"},
+					AllowedMethods:   []string{"GET"},
+					AllowCredentials: true,
+				},
+				CORSRouteOrigins: tt.routeOrigins,
+			}
+			s, _, err := New(context.Background(), cfg, &myjson.Service{}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				s.Shutdown()
+			}
+		})
+	}
+}
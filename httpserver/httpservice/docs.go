@@ -0,0 +1,306 @@
+package httpservice
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/romapres2010/httpserver/events"
+	"github.com/romapres2010/httpserver/httpserver/openapi"
+	"github.com/romapres2010/httpserver/json"
+	"github.com/romapres2010/httpserver/model"
+	"github.com/romapres2010/httpserver/patch"
+)
+
+// типы тела запроса и ответа
+var (
+	codecTypes  = []string{"application/json", "application/xml", "application/msgpack"}
+	importTypes = []string{"text/csv", "application/x-ndjson"}
+	exportTypes = []string{"application/json", "application/x-ndjson", "text/csv"}
+	textTypes   = []string{"text/plain"}
+	patchTypes  = []string{patch.MergePatchType, patch.JSONPatchType}
+)
+
+// batchResultSchema represent result of batch processing, it is marshaled manually
+var batchResultSchema = &openapi.Schema{
+	Type: "array",
+	Items: &openapi.Schema{
+		Type:     "object",
+		Required: []string{"index", "status"},
+		Properties: map[string]*openapi.Schema{
+			"index":     {Type: "integer"},
+			"status":    {Type: "string", Enum: []string{"created", "updated", "error"}},
+			"id":        {Type: "integer"},
+			"version":   {Type: "integer", Format: "int64"},
+			"errorCode": {Type: "string"},
+			"error":     {Type: "string"},
+		},
+	},
+}
+
+// importResultSchema represent result of import, it is marshaled manually
+var importResultSchema = &openapi.Schema{
+	Type:     "object",
+	Required: []string{"table", "rows", "durationMs"},
+	Properties: map[string]*openapi.Schema{
+		"table":      {Type: "string"},
+		"rows":       {Type: "integer", Format: "int64"},
+		"durationMs": {Type: "integer", Format: "int64"},
+	},
+}
+
+// eventSchema represent data of Server-Sent Event, events are separated by empty line: "id: ID\nevent: type\ndata: JSON\n\n"
+var eventSchema = &openapi.Schema{
+	Type:     "object",
+	Required: []string{"id", "type", "time"},
+	Properties: map[string]*openapi.Schema{
+		"id":      {Type: "integer", Format: "int64"},
+		"type":    {Type: "string", Enum: []string{events.TypeCreated, events.TypeUpdated, events.TypeDeleted, events.TypeReset}},
+		"object":  {Type: "string", Enum: []string{events.ObjectDept, events.ObjectEmp}},
+		"key":     {Type: "integer"},
+		"version": {Type: "integer", Format: "int64"},
+		"time":    {Type: "string", Format: "date-time"},
+	},
+}
+
+// patchSchema represent JSON Merge Patch document or array of JSON Patch operations
+var patchSchema = &openapi.Schema{Description: "JSON Merge Patch (RFC 7386) - document with changed fields, null removes field; JSON Patch (RFC 6902) - array of operations add, remove, replace, move, copy, test"}
+
+// anySchema represent arbitrary body
+var anySchema = &openapi.Schema{Type: "string", Format: "binary"}
+
+// общие параметры
+var (
+	batchModeParam = &openapi.Parameter{Name: "mode", In: "query", Description: "atomic - all elements in one transaction, item - each element in own transaction", Schema: &openapi.Schema{Type: "string", Enum: []string{"atomic", "item"}}}
+	formatParam    = &openapi.Parameter{Name: "format", In: "query", Description: "format of export", Schema: &openapi.Schema{Type: "string", Enum: []string{"json", "ndjson", "csv"}}}
+	delimiterParam = &openapi.Parameter{Name: "delimiter", In: "query", Description: "CSV delimiter, one character or 'tab'", Schema: &openapi.Schema{Type: "string"}}
+	fieldsParam    = &openapi.Parameter{Name: "fields", In: "query", Description: "comma separated names of returned fields, default all fields", Schema: &openapi.Schema{Type: "string"}}
+	includeParam   = &openapi.Parameter{Name: "include", In: "query", Description: "embedded objects returned together with fields", Schema: &openapi.Schema{Type: "string"}}
+	excludeParam   = &openapi.Parameter{Name: "exclude", In: "query", Description: "embedded objects which are not returned and not requested", Schema: &openapi.Schema{Type: "string"}}
+	idempotencyKey = &openapi.Parameter{Name: "Idempotency-Key", In: "header", Description: "repeated request with the same key returns stored response", Schema: &openapi.Schema{Type: "string"}}
+)
+
+// apiDocs represent documentation of handlers by name, each registered handler must be documented
+var apiDocs = map[string]*openapi.Annotation{
+	"EchoHandler": {
+		Summary: "Echo request headers and body", Tags: []string{"service"},
+		Request: anySchema, RequestTypes: textTypes, Response: anySchema, ResponseTypes: textTypes,
+		Errors: []int{http.StatusRequestEntityTooLarge}, Auth: true,
+	},
+	"SinginHandler": {
+		Summary: "Sign in with HTTP Basic Authentication, JWT is returned in cookie 'token'", Tags: []string{"auth"},
+		Errors: []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusInternalServerError},
+	},
+	"JWTRefreshHandler": {
+		Summary: "Refresh JWT in cookie 'token'", Tags: []string{"auth"},
+		Errors: []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusInternalServerError},
+	},
+	"HTTPLogHandler": {
+		Summary: "Set HTTP traffic logging", Tags: []string{"service"},
+		Params: []*openapi.Parameter{
+			{Name: "HTTP-Log", In: "header", Schema: &openapi.Schema{Type: "string", Enum: []string{"TRUE", "FALSE"}}},
+			{Name: "HTTP-Log-Type", In: "header", Description: "combination of INREQ, OUTREQ, INRESP, OUTRESP, BODY", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Errors: []int{http.StatusBadRequest}, Auth: true,
+	},
+	"HTTPErrorLogHandler": {
+		Summary: "Set logging of errors into HTTP response", Tags: []string{"service"},
+		Params: []*openapi.Parameter{
+			{Name: "HTTP-Err-Log", In: "header", Description: "combination of HEADER, BODY", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Auth: true,
+	},
+	"LogLevelHandler": {
+		Summary: "Set log level filter", Tags: []string{"service"},
+		Params: []*openapi.Parameter{
+			{Name: "Log-Level-Filter", In: "header", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []string{"DEBUG", "INFO", "ERROR"}}},
+		},
+		Errors: []int{http.StatusBadRequest}, Auth: true,
+	},
+	"CreateDeptHandler": {
+		Summary: "Create department with employees", Tags: []string{"depts"},
+		Params:  []*openapi.Parameter{idempotencyKey},
+		Request: model.Dept{}, RequestTypes: codecTypes, Response: model.Dept{}, ResponseTypes: codecTypes,
+		Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusNotAcceptable, http.StatusInternalServerError, http.StatusServiceUnavailable}, Auth: true,
+	},
+	"GetDeptHandler": {
+		Summary: "Get department with employees", Tags: []string{"depts"},
+		Params: []*openapi.Parameter{
+			fieldsParam, includeParam, excludeParam,
+			{Name: "If-None-Match", In: "header", Description: "List of ETags of cached representations, ETag depends on versions of department and employees and on representation", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Response: model.Dept{}, ResponseTypes: codecTypes,
+		Errors: []int{http.StatusNotModified, http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable, http.StatusInternalServerError}, Auth: true,
+	},
+	"UpdateDeptHandler": {
+		Summary: "Update department with employees", Tags: []string{"depts"},
+		Params: []*openapi.Parameter{
+			{Name: "If-Match", In: "header", Description: "List of ETags or *, ETag of current department with employees must be in the list - 412", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Request: model.Dept{}, RequestTypes: codecTypes, Response: model.Dept{}, ResponseTypes: codecTypes,
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusNotAcceptable, http.StatusInternalServerError}, Auth: true,
+	},
+	"PatchDeptHandler": {
+		Summary: "Partial update of department with employees, only changed columns are updated, employees can not be removed - 400", Tags: []string{"depts"},
+		Params: []*openapi.Parameter{
+			{Name: "If-Match", In: "header", Description: "List of ETags or *, ETag of current department with employees must be in the list - 412", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Request: patchSchema, RequestTypes: patchTypes, Response: model.Dept{}, ResponseTypes: codecTypes,
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusNotAcceptable, http.StatusInternalServerError}, Auth: true,
+	},
+	"PatchEmpHandler": {
+		Summary: "Partial update of employee, only changed columns are updated", Tags: []string{"emps"},
+		Params: []*openapi.Parameter{
+			{Name: "If-Match", In: "header", Description: "List of ETags or *, ETag of current employee must be in the list - 412", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Request: patchSchema, RequestTypes: patchTypes, Response: model.Emp{}, ResponseTypes: codecTypes,
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusNotAcceptable, http.StatusInternalServerError}, Auth: true,
+	},
+	"BatchDeptsHandler": {
+		Summary: "Create or update batch of departments", Tags: []string{"depts"},
+		Params:  []*openapi.Parameter{batchModeParam, idempotencyKey},
+		Request: []*model.Dept{}, RequestTypes: codecTypes, Response: batchResultSchema,
+		Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusInternalServerError, http.StatusServiceUnavailable}, Auth: true,
+	},
+	"BatchEmpsHandler": {
+		Summary: "Create or update batch of employees", Tags: []string{"emps"},
+		Params:  []*openapi.Parameter{batchModeParam, idempotencyKey},
+		Request: []*model.Emp{}, RequestTypes: codecTypes, Response: batchResultSchema,
+		Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusInternalServerError, http.StatusServiceUnavailable}, Auth: true,
+	},
+	"ImportDeptsHandler": {
+		Summary: "Bulk import of departments", Tags: []string{"depts"},
+		Request: []*model.Dept{}, RequestTypes: importTypes, Response: importResultSchema,
+		Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusInternalServerError}, Auth: true,
+	},
+	"ImportEmpsHandler": {
+		Summary: "Bulk import of employees", Tags: []string{"emps"},
+		Request: []*model.Emp{}, RequestTypes: importTypes, Response: importResultSchema,
+		Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusInternalServerError}, Auth: true,
+	},
+	"ExportDeptsHandler": {
+		Summary: "Streaming export of departments", Tags: []string{"depts"},
+		Params:   []*openapi.Parameter{formatParam, delimiterParam, fieldsParam},
+		Response: []*model.Dept{}, ResponseTypes: exportTypes,
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError}, Auth: true,
+	},
+	"ExportEmpsHandler": {
+		Summary: "Streaming export of employees", Tags: []string{"emps"},
+		Params:   []*openapi.Parameter{formatParam, delimiterParam, fieldsParam},
+		Response: []*model.Emp{}, ResponseTypes: exportTypes,
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError}, Auth: true,
+	},
+	"EventsHandler": {
+		Summary: "Stream of created, updated and deleted departments and employees as Server-Sent Events", Tags: []string{"events"},
+		Params: []*openapi.Parameter{
+			{Name: "Last-Event-ID", In: "header", Description: "resume stream after event, event reset is sent if events are not in journal", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+			{Name: "lastEventId", In: "query", Description: "resume stream after event, if header Last-Event-ID is not set", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+			{Name: "objects", In: "query", Description: "comma separated objects: dept, emp, default all objects", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "types", In: "query", Description: "comma separated types of events: created, updated, deleted, default all types", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "ids", In: "query", Description: "comma separated keys of objects, default all keys", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Response: eventSchema, ResponseTypes: []string{"text/event-stream"},
+		Errors: []int{http.StatusBadRequest}, Auth: true,
+	},
+	"OpenAPIHandler": {
+		Summary: "OpenAPI document of service", Tags: []string{"docs"},
+		Response: &openapi.Schema{Type: "object"}, Auth: true,
+	},
+	"DocsUIHandler": {
+		Summary: "Interactive documentation of service", Tags: []string{"docs"},
+		Response: &openapi.Schema{Type: "string"}, ResponseTypes: []string{"text/html"}, Auth: true,
+	},
+	"DocsUIAssetHandler": {
+		Summary: "Embedded resource of interactive documentation", Tags: []string{"docs"},
+		Response: anySchema, ResponseTypes: []string{"text/css", "application/javascript"},
+		Errors: []int{http.StatusNotFound}, Auth: true,
+	},
+}
+
+// routes return registered handlers with documentation, CORS preflight handlers are not documented.
+// Versioned handlers are documented also under prefix of each API version
+func (s *Service) routes() []openapi.Route {
+	routes := make([]openapi.Route, 0, len(s.Handlers))
+	for name, h := range s.Handlers {
+		if h.Method == http.MethodOptions {
+			continue
+		}
+		marshaled, versioned := versionedHandlers[name]
+		if !versioned || s.versions == nil {
+			routes = append(routes, openapi.Route{Name: name, Path: h.Path, Method: h.Method, Doc: apiDocs[name]})
+			continue
+		}
+
+		// маршрут без префикса обрабатывается версией по умолчанию
+		v := s.versions[s.cfg.DefaultVersion]
+		routes = append(routes, openapi.Route{Name: name, Path: h.Path, Method: h.Method, Doc: versionDoc(apiDocs[name], v, marshaled), Deprecated: v.deprecated})
+		for _, vName := range s.cfg.APIVersions {
+			v = s.versions[vName]
+			routes = append(routes, openapi.Route{Name: name + "_" + vName, Path: "/" + vName + h.Path, Method: h.Method, Doc: versionDoc(apiDocs[name], v, marshaled), Deprecated: v.deprecated})
+		}
+	}
+	return routes
+}
+
+// versionDoc return documentation of handler in API version, models of body are replaced by representation of version
+func versionDoc(a *openapi.Annotation, v *apiVersion, marshaled bool) *openapi.Annotation {
+	if a == nil || !marshaled {
+		return a
+	}
+	doc := *a
+	doc.Request = versionModel(a.Request, v.marshalers)
+	doc.Response = versionModel(a.Response, v.marshalers)
+	return &doc
+}
+
+// versionModel return representation of model in API version
+func versionModel(v interface{}, m *json.Marshalers) interface{} {
+	switch v.(type) {
+	case model.Dept:
+		if m.NewDept != nil {
+			return m.NewDept()
+		}
+	case model.Emp:
+		if m.NewEmp != nil {
+			return m.NewEmp()
+		}
+	case []*model.Dept:
+		if m.NewDept != nil {
+			return reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(m.NewDept())), 0, 0).Interface()
+		}
+	case []*model.Emp:
+		if m.NewEmp != nil {
+			return reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(m.NewEmp())), 0, 0).Interface()
+		}
+	}
+	return v
+}
+
+// openAPI build OpenAPI document of registered handlers
+func (s *Service) openAPI() *openapi.Document {
+	var schemes map[string]*openapi.SecurityScheme
+	var security []map[string][]string
+
+	// JWT в cookie имеет приоритет над HTTP Basic Authentication
+	if s.cfg.UseJWT {
+		schemes = map[string]*openapi.SecurityScheme{"cookieAuth": {Type: "apiKey", In: "cookie", Name: "token"}}
+		security = []map[string][]string{{"cookieAuth": {}}}
+	} else if s.cfg.AuthType == "INTERNAL" || s.cfg.AuthType == "MSAD" {
+		schemes = map[string]*openapi.SecurityScheme{"basicAuth": {Type: "http", Scheme: "basic"}}
+		security = []map[string][]string{{"basicAuth": {}}}
+	}
+
+	doc := openapi.Generate(openapi.Info{Title: openAPITitle, Version: openAPIVersion}, s.routes(), schemes, security)
+
+	// отказы общих ограничений добавляются ко всем операциям
+	for _, ops := range doc.Paths {
+		for _, op := range ops {
+			if s.cfg.UseRateLimit {
+				op.Responses["429"] = &openapi.Response{Description: http.StatusText(http.StatusTooManyRequests)}
+			}
+			if s.cfg.UseConcurrencyLimit {
+				op.Responses["503"] = &openapi.Response{Description: http.StatusText(http.StatusServiceUnavailable)}
+			}
+		}
+	}
+	return doc
+}
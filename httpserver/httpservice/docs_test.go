@@ -0,0 +1,104 @@
+package httpservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/romapres2010/httpserver/events"
+	"github.com/romapres2010/httpserver/httpserver/openapi"
+	myjson "github.com/romapres2010/httpserver/json"
+)
+
+// TestRoutesDocumented fail when registered handler has no documentation
+func TestRoutesDocumented(t *testing.T) {
+	s, _, err := New(context.Background(), &Config{UseOpenAPI: true, UseDocsUI: true, UseCORS: true, UseVersioning: true, APIVersions: []string{"v1", "v2"}, DefaultVersion: "v1"}, &myjson.Service{}, events.New(&events.Config{}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	routes := s.routes()
+	if names := openapi.Undocumented(routes); len(names) > 0 {
+		t.Errorf("undocumented routes %v, add them into apiDocs", names)
+	}
+	for name := range apiDocs {
+		if _, ok := s.Handlers[name]; !ok {
+			t.Errorf("documentation of unknown handler %v", name)
+		}
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(s.openAPIDoc, &doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	for _, route := range routes {
+		path := strings.Replace(route.Path, "{id:[0-9]+}", "{id}", 1)
+		if op := doc.Paths[path][strings.ToLower(route.Method)]; op == nil || op.OperationID != route.Name {
+			t.Errorf("operation %v %v of %v is missing in document", route.Method, path, route.Name)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	s, _, err := New(context.Background(), &Config{UseOpenAPI: true}, &myjson.Service{}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	if _, ok := s.Handlers["DocsUIHandler"]; ok {
+		t.Errorf("DocsUIHandler is registered without UseDocsUI")
+	}
+
+	w := httptest.NewRecorder()
+	s.Handlers["OpenAPIHandler"].HundlerFunc(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("GET /openapi.json = %v, headers %v", w.Code, w.Header())
+	}
+	if w.Body.String() != string(s.openAPIDoc) {
+		t.Errorf("GET /openapi.json body differs from generated document")
+	}
+}
+
+func TestDocsUIAssetHandler(t *testing.T) {
+	s, _, err := New(context.Background(), &Config{UseOpenAPI: true, UseDocsUI: true}, &myjson.Service{}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	// страница документации ссылается только на встроенные ресурсы
+	w := httptest.NewRecorder()
+	s.Handlers["DocsUIHandler"].HundlerFunc(w, httptest.NewRequest("GET", "/docs", nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "://") {
+		t.Errorf("GET /docs = %v, page refers to external resources", w.Code)
+	}
+
+	tests := []struct {
+		file        string
+		status      int
+		contentType string
+	}{
+		{"swagger-ui-bundle.js", http.StatusOK, "application/javascript"},
+		{"swagger-ui.css", http.StatusOK, "text/css"},
+		{"unknown.js", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			r := mux.SetURLVars(httptest.NewRequest("GET", "/docs/"+tt.file, nil), map[string]string{"file": tt.file})
+			w := httptest.NewRecorder()
+			s.Handlers["DocsUIAssetHandler"].HundlerFunc(w, r)
+			if w.Code != tt.status || !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+				t.Fatalf("GET /docs/%v = %v, Content-Type %v, want %v, %v", tt.file, w.Code, w.Header().Get("Content-Type"), tt.status, tt.contentType)
+			}
+			if tt.status == http.StatusOK && w.Body.String() != docsUIFiles[tt.file] {
+				t.Errorf("GET /docs/%v body differs from embedded file", tt.file)
+			}
+		})
+	}
+}
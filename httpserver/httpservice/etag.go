@@ -0,0 +1,106 @@
+package httpservice
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/json"
+	"github.com/romapres2010/httpserver/model"
+)
+
+// Коды ошибок проверки If-Match
+const (
+	ErrCodeInvalidETag        = "8043" // заголовок If-Match содержит некорректный ETag
+	ErrCodePreconditionFailed = "8044" // текущая версия объекта не соответствует If-Match
+)
+
+// ETag ресурса - строгий ETag: "version-digest"
+//     version - версия строки в БД
+//     digest - хэш версий вложенных объектов и параметров представления: формат ответа, выбранные поля,
+//     версия API и сжатие. Изменение любой из них меняет ETag, поэтому If-Match и If-None-Match сравнивают ETag целиком
+
+// etagOf - return strong ETag for version of object and representation of response to request
+func (s *Service) etagOf(r *http.Request, contentType string, version model.Version) string {
+	h := fnv.New64a()
+	write := func(values ...string) {
+		for _, v := range values {
+			_, _ = h.Write([]byte(v))
+			_, _ = h.Write([]byte{0})
+		}
+	}
+
+	{ // секция версий вложенных объектов
+		if version.Embedded != nil {
+			write("embedded")
+			for _, e := range version.Embedded {
+				write(strconv.Itoa(e.Key), strconv.FormatInt(e.Version, 10))
+			}
+		}
+	} // секция версий вложенных объектов
+
+	{ // секция параметров представления
+		write(contentType, myctx.FromContextAPIVersion(r.Context()))
+		query := r.URL.Query()
+		for _, name := range []string{"fields", "include", "exclude"} {
+			write(name, strings.Join(query[name], ","))
+		}
+		if s.compress != nil && s.compress.Compressible(contentType, -1) {
+			write(s.compress.Negotiate(r.Header.Get("Accept-Encoding")))
+		}
+	} // секция параметров представления
+
+	return `"` + strconv.FormatInt(version.Version, 10) + "-" + strconv.FormatUint(h.Sum64(), 16) + `"`
+}
+
+// validETag - check syntax of ETag: "opaque" or weak W/"opaque"
+func validETag(etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	return len(etag) >= 2 && etag[0] == '"' && etag[len(etag)-1] == '"' && !strings.Contains(etag[1:len(etag)-1], `"`)
+}
+
+// matchETag - check if comma separated list of ETags from If-None-Match contains ETag or "*", weak comparison is used
+func matchETag(header string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, e := range strings.Split(header, ",") {
+		e = strings.TrimSpace(e)
+		if e == "*" || strings.TrimPrefix(e, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch - return precondition of update by If-Match header, nil - header is not sent or "*".
+// Precondition is checked in transaction of update: ETag of current version of object in representation with contentType
+// must be in the list, strong comparison is used. Digest of ETag contains versions of embedded objects,
+// so change of embedded object fails precondition too
+func (s *Service) ifMatch(r *http.Request, reqID uint64, contentType string) (json.Precondition, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil, nil
+	}
+	etags := strings.Split(header, ",")
+	for i, etag := range etags {
+		etags[i] = strings.TrimSpace(etag)
+		if etags[i] == "*" {
+			return nil, nil
+		}
+		if !validETag(etags[i]) {
+			return nil, myerror.New(ErrCodeInvalidETag, "Failed to process header 'If-Match' invalid ETag: reqID, If-Match", reqID, header).PrintfInfo()
+		}
+	}
+
+	return func(version model.Version) error {
+		etag := s.etagOf(r, contentType, version)
+		for _, e := range etags {
+			if e == etag {
+				return nil
+			}
+		}
+		return myerror.New(ErrCodePreconditionFailed, "Version of object does not match If-Match: reqID, If-Match, ETag", reqID, header, etag).PrintfInfo()
+	}, nil
+}
@@ -0,0 +1,134 @@
+package httpservice
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/model"
+)
+
+// errCode return code of catalogued error
+func errCode(err error) string {
+	if myerr, ok := err.(*myerror.Error); ok {
+		return myerr.Code
+	}
+	return ""
+}
+
+func TestValidETag(t *testing.T) {
+	tests := []struct {
+		etag string
+		want bool
+	}{
+		{`"5"`, true},
+		{`W/"12"`, true},
+		{`"7-1f3a"`, true},
+		{`""`, true},
+		{`5`, false},
+		{`"5`, false},
+		{`"a"b"`, false},
+		{`*`, false},
+	}
+	for _, tt := range tests {
+		if got := validETag(tt.etag); got != tt.want {
+			t.Errorf("validETag(%q) = %v, want %v", tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"3-a1"`, true},
+		{`"1-a1", W/"3-a1"`, true},
+		{`*`, true},
+		{`"3"`, false},
+		{`"3-b2"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := matchETag(tt.header, `"3-a1"`); got != tt.want {
+			t.Errorf("matchETag(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestETagOf(t *testing.T) {
+	s := &Service{}
+	dept := model.Version{Version: 3, Embedded: []model.EmbeddedVersion{{Key: 7839, Version: 1}}}
+	base := s.etagOf(httptest.NewRequest("GET", "/depts/10", nil), "application/json", dept)
+
+	tests := []struct {
+		name        string
+		url         string
+		contentType string
+		version     model.Version
+		same        bool
+	}{
+		{"same", "/depts/10", "application/json", dept, true},
+		{"emp version", "/depts/10", "application/json", model.Version{Version: 3, Embedded: []model.EmbeddedVersion{{Key: 7839, Version: 2}}}, false},
+		{"emp removed", "/depts/10", "application/json", model.Version{Version: 3, Embedded: []model.EmbeddedVersion{}}, false},
+		{"without emps", "/depts/10", "application/json", model.Version{Version: 3}, false},
+		{"content type", "/depts/10", "application/xml", dept, false},
+		{"fields", "/depts/10?fields=deptName", "application/json", dept, false},
+		{"exclude", "/depts/10?exclude=emps", "application/json", dept, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.etagOf(httptest.NewRequest("GET", tt.url, nil), tt.contentType, tt.version)
+			if (got == base) != tt.same {
+				t.Errorf("etagOf() = %v, base %v, want same %v", got, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	s := &Service{}
+	r := httptest.NewRequest("PUT", "/depts/10", nil)
+	dept := model.Version{Version: 5, Embedded: []model.EmbeddedVersion{{Key: 7839, Version: 1}}}
+	etag := s.etagOf(r, "application/json", dept)
+	empChanged := s.etagOf(r, "application/json", model.Version{Version: 5, Embedded: []model.EmbeddedVersion{{Key: 7839, Version: 2}}})
+
+	tests := []struct {
+		name     string
+		ifMatch  string
+		wantNil  bool   // условие не проверяется
+		wantCode string // код ошибки разбора заголовка или проверки условия
+	}{
+		{"no header", "", true, ""},
+		{"any", `*`, true, ""},
+		{"current", etag, false, ""},
+		{"list with current", `"3-a1", ` + etag, false, ""},
+		{"emp changed", empChanged, false, ErrCodePreconditionFailed},
+		{"version only", `"5"`, false, ErrCodePreconditionFailed},
+		{"weak", "W/" + etag, false, ErrCodePreconditionFailed},
+		{"invalid", `"3", abc`, false, ErrCodeInvalidETag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/depts/10", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			precondition, err := s.ifMatch(r, 1, "application/json")
+			if err != nil {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("ifMatch() error = %v, want code %q", err, tt.wantCode)
+				}
+				return
+			}
+			if (precondition == nil) != tt.wantNil {
+				t.Fatalf("ifMatch() = %v, want nil %v", precondition, tt.wantNil)
+			}
+			if precondition != nil {
+				if err = precondition(dept); errCode(err) != tt.wantCode {
+					t.Errorf("precondition() error = %v, want code %q", err, tt.wantCode)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package httpservice
+
+import (
+	"net/http"
+	"strings"
+)
+
+// queryList return values of URL parameter name, values are separated by comma: ?fields=deptName,deptLocation
+func queryList(r *http.Request, name string) []string {
+	var list []string
+	for _, value := range r.URL.Query()[name] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
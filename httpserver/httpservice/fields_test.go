@@ -0,0 +1,17 @@
+package httpservice
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestQueryList(t *testing.T) {
+	r := httptest.NewRequest("GET", "/depts/10?fields=deptName,%20deptLocation,&fields=emps&include=", nil)
+	if got, want := queryList(r, "fields"), []string{"deptName", "deptLocation", "emps"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queryList() = %v, want %v", got, want)
+	}
+	if got := queryList(r, "include"); got != nil {
+		t.Errorf("queryList() = %v, want nil", got)
+	}
+}
@@ -13,9 +13,10 @@ import (
 // checkAuthentication chek HTTP Basic Authentication or MS AD Authentication
 func (s *Service) checkAuthentication(username, password string) error {
 
-	// В режиме "INTERNAL" сравнимаем пользователя пароль с тем что был передан при старте адаптера
+	// В режиме "INTERNAL" сравнимаем пользователя пароль с тем что был передан при старте адаптера или с файлом пользователей
 	if s.cfg.AuthType == "INTERNAL" {
-		if s.cfg.HTTPUserID != username || s.cfg.HTTPUserPwd != password {
+		validCmdUser := s.cfg.HTTPUserID != "" && s.cfg.HTTPUserID == username && s.cfg.HTTPUserPwd == password
+		if !validCmdUser && (s.users == nil || !s.users.Check(username, password)) {
 			return myerror.New("8010", "Internal authentication - invalid user or password: username", username).PrintfInfo()
 		}
 		mylog.PrintfInfoMsg("Success Internal Authentication: username", username)
@@ -63,9 +64,9 @@ func (s *Service) SinginHandler(w http.ResponseWriter, r *http.Request) {
 	mylog.PrintfDebugMsg("Get Authorization header: reqID, username", reqID, username)
 
 	// Выполняем аутентификацию
-	if myerr := s.checkAuthentication(username, password); myerr != nil {
+	if status, myerr := s.authenticate(w, r, username, password); myerr != nil {
 		mylog.PrintfErrorInfo(myerr)
-		s.processError(myerr, w, http.StatusUnauthorized, reqID)
+		s.processError(myerr, w, status, reqID)
 		return
 	}
 
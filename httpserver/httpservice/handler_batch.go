@@ -0,0 +1,60 @@
+package httpservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/json"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// BatchDeptsHandler handle JSON array for create or update of Depts
+func (s *Service) BatchDeptsHandler(w http.ResponseWriter, r *http.Request) {
+	s.batchHandler(w, r, s.jsonServiceOf(r).BatchDepts)
+}
+
+// BatchEmpsHandler handle JSON array for create or update of Emps
+func (s *Service) BatchEmpsHandler(w http.ResponseWriter, r *http.Request) {
+	s.batchHandler(w, r, s.jsonServiceOf(r).BatchEmps)
+}
+
+// batchHandler handle JSON array with batch function, mode is passed in URL parameter 'mode': atomic (default) | item
+func (s *Service) batchHandler(w http.ResponseWriter, r *http.Request, batchFn func(ctx context.Context, mode string, inBuf []byte, buf []byte) ([]byte, error)) {
+	mylog.PrintfDebugMsg("START   ==================================================================================")
+
+	// Запускаем типовой process, возврат ошибки игнорируем
+	_ = s.process("POST", w, r, func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error) {
+		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		// Считаем режим обработки пакета
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			mode = json.BatchModeAtomic
+		}
+		if mode != json.BatchModeAtomic && mode != json.BatchModeItem {
+			return nil, nil, http.StatusBadRequest, myerror.New("8001", "Failed to process parameter 'mode', only avaliable: 'atomic', 'item': reqID, mode", reqID, mode).PrintfInfo()
+		}
+
+		// вызываем JSON сервис
+		responseBuf, err := batchFn(ctx, mode, requestBuf, buf)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+
+		// формируем ответ
+		header := Header{}
+		header["Content-Type"] = "application/json; charset=utf-8"
+		header["Errcode"] = "0"
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+
+		mylog.PrintfDebugMsg("SUCCESS: reqID", reqID)
+		return responseBuf, header, http.StatusOK, nil
+	})
+
+	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
+}
@@ -0,0 +1,59 @@
+package httpservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	myjson "github.com/romapres2010/httpserver/json"
+	mysql "github.com/romapres2010/httpserver/sqlxx"
+)
+
+func TestBatchHandlerStatus(t *testing.T) {
+	s, _, err := New(context.Background(), &Config{}, &myjson.Service{}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	tests := []struct {
+		name   string
+		mode   string
+		err    error
+		status int
+	}{
+		{"success", "", nil, http.StatusOK},
+		{"unknown mode", "all", nil, http.StatusBadRequest},
+		{"malformed array", "atomic", myerror.New(myjson.ErrCodeInvalidBatch, "Error Unmarshal batch"), http.StatusBadRequest},
+		{"constraint violation", "atomic", myerror.WithCause("6002", "Error process batch element", myerror.New(mysql.ErrCodeConstraintViolation, "Constraint violation")), http.StatusBadRequest},
+		{"version conflict", "atomic", myerror.WithCause("6002", "Error process batch element", myerror.New(mysql.ErrCodeVersionConflict, "Version conflict")), http.StatusConflict},
+		{"DB error", "atomic", myerror.WithCause("6002", "Error process batch element", myerror.New("4005", "Error Exec SQL statement")), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMode string
+			batchFn := func(ctx context.Context, mode string, inBuf []byte, buf []byte) ([]byte, error) {
+				gotMode = mode
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return append(buf[:0], `[]`...), nil
+			}
+
+			r := httptest.NewRequest("POST", "/depts:batch?mode="+tt.mode, strings.NewReader(`[]`))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			s.batchHandler(w, r, batchFn)
+
+			if w.Code != tt.status {
+				t.Errorf("batchHandler() status = %v, want %v", w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && gotMode != myjson.BatchModeAtomic {
+				t.Errorf("batchHandler() mode = %q, want default %q", gotMode, myjson.BatchModeAtomic)
+			}
+		})
+	}
+}
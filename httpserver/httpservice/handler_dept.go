@@ -12,7 +12,7 @@ import (
 	mylog "github.com/romapres2010/httpserver/log"
 )
 
-// GetDeptHandler handle JSON for GetDept
+// GetDeptHandler handle GetDept, response format is negotiated by Accept
 func (s *Service) GetDeptHandler(w http.ResponseWriter, r *http.Request) {
 	mylog.PrintfDebugMsg("START   ==================================================================================")
 
@@ -30,8 +30,40 @@ func (s *Service) GetDeptHandler(w http.ResponseWriter, r *http.Request) {
 			return nil, nil, http.StatusBadRequest, myerror.WithCause("8001", "Failed to process parameter 'id' invalid number: reqID, id", err, reqID, idStr).PrintfInfo()
 		}
 
+		// Формат ответа по заголовку Accept
+		out, status, err := responseCodec(r, reqID)
+		if err != nil {
+			return nil, nil, status, err
+		}
+
+		// Выбранные поля и вложенные объекты: ?fields=deptName,deptLocation&exclude=emps
+		jsonService := s.jsonServiceOf(r)
+		fields, err := jsonService.DeptFieldset(reqID, queryList(r, "fields"), queryList(r, "include"), queryList(r, "exclude"))
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, err
+		}
+
+		// Если у клиента актуальная версия объекта - не считываем объект полностью и тело не передаем
+		if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+			version, exists, err := jsonService.GetDeptVersion(ctx, id, fields)
+			if err != nil {
+				return nil, nil, http.StatusInternalServerError, err
+			}
+			if !exists {
+				return nil, nil, http.StatusNotFound, nil
+			}
+			if etag := s.etagOf(r, out.ContentType(), version); matchETag(ifNoneMatch, etag) {
+				mylog.PrintfDebugMsg("Not modified: reqID, If-None-Match, ETag", reqID, ifNoneMatch, etag)
+				header := Header{}
+				header["ETag"] = etag
+				header["Vary"] = "Accept"
+				header["RequestID"] = fmt.Sprintf("%v", reqID)
+				return nil, header, http.StatusNotModified, nil
+			}
+		}
+
 		// вызываем JSON сервис, передаем ему буфер для копирования
-		responseBuf, err := s.jsonService.GetDept(ctx, id, buf)
+		responseBuf, version, err := jsonService.GetDept(ctx, out, id, fields, buf)
 		if err != nil {
 			return nil, nil, http.StatusInternalServerError, err
 		}
@@ -43,8 +75,10 @@ func (s *Service) GetDeptHandler(w http.ResponseWriter, r *http.Request) {
 
 		// формируем ответ
 		header := Header{}
-		header["Content-Type"] = "application/json; charset=utf-8"
+		header["Content-Type"] = out.ContentType()
+		header["Vary"] = "Accept"
 		header["Errcode"] = "0"
+		header["ETag"] = s.etagOf(r, out.ContentType(), version)
 		header["RequestID"] = fmt.Sprintf("%v", reqID)
 
 		mylog.PrintfDebugMsg("SUCCESS: reqID", reqID)
@@ -54,7 +88,7 @@ func (s *Service) GetDeptHandler(w http.ResponseWriter, r *http.Request) {
 	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
 }
 
-// CreateDeptHandler handle JSON for CreateDept
+// CreateDeptHandler handle CreateDept, request format is defined by Content-Type, response format is negotiated by Accept
 func (s *Service) CreateDeptHandler(w http.ResponseWriter, r *http.Request) {
 	mylog.PrintfDebugMsg("START   ==================================================================================")
 
@@ -64,15 +98,26 @@ func (s *Service) CreateDeptHandler(w http.ResponseWriter, r *http.Request) {
 
 		mylog.PrintfDebugMsg("START: reqID", reqID)
 
+		// Формат запроса по заголовку Content-Type, формат ответа по заголовку Accept
+		in, status, err := requestCodec(r, reqID)
+		if err != nil {
+			return nil, nil, status, err
+		}
+		out, status, err := responseCodec(r, reqID)
+		if err != nil {
+			return nil, nil, status, err
+		}
+
 		// вызываем JSON сервис
-		id, responseBuf, err := s.jsonService.CreateDept(ctx, requestBuf, buf)
+		id, responseBuf, err := s.jsonServiceOf(r).CreateDept(ctx, in, out, requestBuf, buf)
 		if err != nil {
 			return nil, nil, http.StatusInternalServerError, err
 		}
 
 		// формируем ответ
 		header := Header{}
-		header["Content-Type"] = "application/json; charset=utf-8"
+		header["Content-Type"] = out.ContentType()
+		header["Vary"] = "Accept"
 		header["Errcode"] = "0"
 		header["Id"] = fmt.Sprintf("%v", id)
 		header["RequestID"] = fmt.Sprintf("%v", reqID)
@@ -84,7 +129,7 @@ func (s *Service) CreateDeptHandler(w http.ResponseWriter, r *http.Request) {
 	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
 }
 
-// UpdateDeptHandler handle JSON for UpdateDept
+// UpdateDeptHandler handle UpdateDept, request format is defined by Content-Type, response format is negotiated by Accept
 func (s *Service) UpdateDeptHandler(w http.ResponseWriter, r *http.Request) {
 	mylog.PrintfDebugMsg("START   ==================================================================================")
 
@@ -102,8 +147,25 @@ func (s *Service) UpdateDeptHandler(w http.ResponseWriter, r *http.Request) {
 			return nil, nil, http.StatusBadRequest, myerror.WithCause("8001", "Failed to process parameter 'id' invalid number: reqID, id", err, reqID, idStr).PrintfInfo()
 		}
 
+		// Формат запроса по заголовку Content-Type, формат ответа по заголовку Accept
+		in, status, err := requestCodec(r, reqID)
+		if err != nil {
+			return nil, nil, status, err
+		}
+		out, status, err := responseCodec(r, reqID)
+		if err != nil {
+			return nil, nil, status, err
+		}
+
+		// Условие изменения по If-Match, "*" - любая версия
+		precondition, err := s.ifMatch(r, reqID, out.ContentType())
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, err
+		}
+
 		// вызываем JSON сервис
-		responseBuf, err := s.jsonService.UpdateDept(ctx, id, requestBuf, buf)
+		jsonService := s.jsonServiceOf(r)
+		responseBuf, outVersion, err := jsonService.UpdateDept(ctx, in, out, id, precondition, requestBuf, buf)
 		if err != nil {
 			return nil, nil, http.StatusInternalServerError, err
 		}
@@ -115,8 +177,10 @@ func (s *Service) UpdateDeptHandler(w http.ResponseWriter, r *http.Request) {
 
 		// формируем ответ
 		header := Header{}
-		header["Content-Type"] = "application/json; charset=utf-8"
+		header["Content-Type"] = out.ContentType()
+		header["Vary"] = "Accept"
 		header["Errcode"] = "0"
+		header["ETag"] = s.etagOf(r, out.ContentType(), outVersion)
 		header["RequestID"] = fmt.Sprintf("%v", reqID)
 
 		mylog.PrintfDebugMsg("SUCCESS: reqID", reqID)
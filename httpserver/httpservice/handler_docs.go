@@ -0,0 +1,136 @@
+package httpservice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/gorilla/mux"
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// метаданные документа OpenAPI
+const (
+	openAPITitle   = "httpserver REST API"
+	openAPIVersion = "1.0"
+)
+
+// Ресурсы Swagger UI встроены в сервер (docs_ui_files.go - swagger-ui-bundle.js и swagger-ui.css из swagger-ui-dist 4.15.5),
+// страница документации не загружает скрипты со сторонних серверов.
+// Swagger UI распространяется по лицензии Apache 2.0, текст лицензии и NOTICE - в каталоге docsui.
+// Исходные файлы в репозитории не хранятся, для обновления они встраиваются из каталога dist пакета swagger-ui-dist:
+//     go run ../../cmd/embedfiles -dir swagger-ui-dist -pkg httpservice -var docsUIFiles -out docs_ui_files.go
+
+// docsUIPage represent page of interactive documentation, Swagger UI is served by DocsUIAssetHandler
+const docsUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>` + openAPITitle + `</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = function() {
+      window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui", withCredentials: true});
+    };
+  </script>
+</body>
+</html>
+`
+
+// OpenAPIHandler handle OpenAPI document of service
+func (s *Service) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	mylog.PrintfDebugMsg("START   ==================================================================================")
+
+	// Запускаем обработчик, возврат ошибки игнорируем
+	_ = s.process("GET", w, r, func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error) {
+		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		// формируем ответ
+		header := Header{}
+		header["Content-Type"] = "application/json; charset=utf-8"
+		header["Errcode"] = "0"
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+
+		mylog.PrintfDebugMsg("SUCCESS", reqID)
+
+		// документ копируем - буфер ответа может быть возвращен в pool
+		return append(buf[:0], s.openAPIDoc...), header, http.StatusOK, nil
+	})
+
+	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
+}
+
+// DocsUIHandler handle page of interactive documentation
+func (s *Service) DocsUIHandler(w http.ResponseWriter, r *http.Request) {
+	mylog.PrintfDebugMsg("START   ==================================================================================")
+
+	// Запускаем обработчик, возврат ошибки игнорируем
+	_ = s.process("GET", w, r, func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error) {
+		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		// формируем ответ
+		header := Header{}
+		header["Content-Type"] = "text/html; charset=utf-8"
+		header["Errcode"] = "0"
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+
+		mylog.PrintfDebugMsg("SUCCESS", reqID)
+		return append(buf[:0], docsUIPage...), header, http.StatusOK, nil
+	})
+
+	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
+}
+
+// DocsUIAssetHandler handle embedded resources of interactive documentation, resource is written directly into response
+func (s *Service) DocsUIAssetHandler(w http.ResponseWriter, r *http.Request) {
+	mylog.PrintfDebugMsg("START   ==================================================================================")
+
+	// Запускаем типовой process с записью ответа напрямую, без копирования в буфер из pool, возврат ошибки игнорируем
+	_ = s.processResponseStream("GET", w, r, func(ctx context.Context) (Header, int, func(ctx context.Context, w io.Writer) error, error) {
+		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		name := mux.Vars(r)["file"]
+		asset, ok := docsUIFiles[name]
+		if !ok {
+			return nil, http.StatusNotFound, nil, myerror.New("8001", "Failed to process parameter 'file' unknown resource: reqID, file", reqID, name).PrintfInfo()
+		}
+
+		// формируем заголовок ответа
+		header := Header{}
+		switch path.Ext(name) {
+		case ".css":
+			header["Content-Type"] = "text/css; charset=utf-8"
+		case ".js":
+			header["Content-Type"] = "application/javascript; charset=utf-8"
+		default:
+			header["Content-Type"] = "application/octet-stream"
+		}
+		header["Cache-Control"] = "public, max-age=86400" // ресурсы меняются только с новой версией сервера
+		header["Errcode"] = "0"
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+
+		mylog.PrintfDebugMsg("SUCCESS", reqID)
+		return header, http.StatusOK, func(ctx context.Context, w io.Writer) error {
+			if _, err := io.WriteString(w, asset); err != nil {
+				return myerror.WithCause("8002", "Failed to write HTTP repsonse: reqID", err, reqID).PrintfInfo()
+			}
+			return nil
+		}, nil
+	})
+
+	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
+}
@@ -0,0 +1,214 @@
+package httpservice
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// eventsWriteMargin represent time before WriteTimeout, when stream of events is finished.
+// Клиент EventSource переподключается с Last-Event-ID и продолжает получать события из журнала
+const eventsWriteMargin = time.Second
+
+// defaultEventsHeartbeat represent interval of heartbeat comments, if it is not configured
+const defaultEventsHeartbeat = 15 * time.Second
+
+// streamingHandlers represent handlers of long-lived streams, they are limited only by concurrency limit of route
+var streamingHandlers = map[string]bool{
+	"EventsHandler": true,
+}
+
+// Допустимые значения фильтров событий
+var (
+	eventObjects = map[string]bool{events.ObjectDept: true, events.ObjectEmp: true}
+	eventTypes   = map[string]bool{events.TypeCreated: true, events.TypeUpdated: true, events.TypeDeleted: true}
+)
+
+// EventsHandler stream changes of Dept and Emp as Server-Sent Events.
+// Stream is resumed after ID from header Last-Event-ID or URL parameter 'lastEventId',
+// events are filtered with URL parameters 'objects', 'types' and 'ids' - comma separated lists
+func (s *Service) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	mylog.PrintfDebugMsg("START   ==================================================================================")
+
+	// Запускаем типовой process с потоковой записью ответа, возврат ошибки игнорируем
+	_ = s.processResponseStream("GET", w, r, func(ctx context.Context) (Header, int, func(ctx context.Context, w io.Writer) error, error) {
+		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		// Считаем ID последнего полученного события: EventSource передает его в заголовке при переподключении
+		var lastEventID uint64
+		lastEventIDStr := r.Header.Get("Last-Event-ID")
+		if lastEventIDStr == "" {
+			lastEventIDStr = r.URL.Query().Get("lastEventId")
+		}
+		resume := lastEventIDStr != ""
+		if resume {
+			var err error
+			if lastEventID, err = strconv.ParseUint(lastEventIDStr, 10, 64); err != nil {
+				return nil, http.StatusBadRequest, nil, myerror.WithCause("8001", "Failed to parse Last-Event-ID: reqID, Last-Event-ID", err, reqID, lastEventIDStr).PrintfInfo()
+			}
+		}
+
+		// Считаем фильтр событий
+		filter, err := eventsFilter(r, reqID)
+		if err != nil {
+			return nil, http.StatusBadRequest, nil, err
+		}
+
+		// формируем заголовок ответа
+		header := Header{}
+		header["Content-Type"] = "text/event-stream; charset=utf-8"
+		header["Cache-Control"] = "no-cache"
+		header["X-Accel-Buffering"] = "no" // отключаем буферизацию ответа в reverse proxy
+		header["Errcode"] = "0"
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+
+		return header, http.StatusOK, func(ctx context.Context, body io.Writer) error {
+			return s.streamEvents(ctx, w, body, lastEventID, resume, filter)
+		}, nil
+	})
+
+	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
+}
+
+// eventsFilter parse filter of events from URL parameters, empty parameter - all values
+func eventsFilter(r *http.Request, reqID uint64) (*events.Filter, error) {
+	filter := &events.Filter{}
+
+	if objects := queryList(r, "objects"); len(objects) > 0 {
+		filter.Objects = make(map[string]bool, len(objects))
+		for _, object := range objects {
+			if !eventObjects[object] {
+				return nil, myerror.New("8001", "Failed to process parameter 'objects', only avaliable: 'dept', 'emp': reqID, object", reqID, object).PrintfInfo(1)
+			}
+			filter.Objects[object] = true
+		}
+	}
+
+	if types := queryList(r, "types"); len(types) > 0 {
+		filter.Types = make(map[string]bool, len(types))
+		for _, typ := range types {
+			if !eventTypes[typ] {
+				return nil, myerror.New("8001", "Failed to process parameter 'types', only avaliable: 'created', 'updated', 'deleted': reqID, type", reqID, typ).PrintfInfo(1)
+			}
+			filter.Types[typ] = true
+		}
+	}
+
+	if ids := queryList(r, "ids"); len(ids) > 0 {
+		filter.Keys = make(map[int]bool, len(ids))
+		for _, id := range ids {
+			key, err := strconv.Atoi(id)
+			if err != nil {
+				return nil, myerror.WithCause("8001", "Failed to process parameter 'ids': reqID, id", err, reqID, id).PrintfInfo(1)
+			}
+			filter.Keys[key] = true
+		}
+	}
+
+	return filter, nil
+}
+
+// streamEvents write events of journal after lastEventID and new events until client disconnects, server is shutting down
+// or WriteTimeout is near. Heartbeat comments keep idle connection open through proxies
+func (s *Service) streamEvents(ctx context.Context, w http.ResponseWriter, body io.Writer, lastEventID uint64, resume bool, filter *events.Filter) error {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	sub, replay := s.events.Subscribe(lastEventID, resume, filter)
+	defer sub.Close()
+
+	mylog.PrintfDebugMsg("Events subscriber is connected: reqID, lastEventID, resume, replay", reqID, lastEventID, resume, len(replay))
+
+	// каждое событие сразу передается клиенту, в том числе через сжатие ответа
+	flush := func() error {
+		if f, ok := body.(interface{ Flush() error }); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	// ответ завершаем до наступления WriteTimeout, иначе соединение будет прервано на записи
+	var end <-chan time.Time
+	if deadline, ok := ctx.Deadline(); ok {
+		timer := time.NewTimer(time.Until(deadline) - eventsWriteMargin)
+		defer timer.Stop()
+		end = timer.C
+	}
+
+	interval := time.Duration(s.cfg.EventsHeartbeat) * time.Second
+	if interval <= 0 {
+		interval = defaultEventsHeartbeat
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	// интервал переподключения клиента
+	if s.cfg.EventsRetry > 0 {
+		if _, err := fmt.Fprintf(body, "retry: %d\n\n", s.cfg.EventsRetry); err != nil {
+			return myerror.WithCause("8002", "Failed to write event: reqID", err, reqID).PrintfInfo()
+		}
+	}
+	for i := range replay {
+		if err := writeEvent(body, &replay[i]); err != nil {
+			return myerror.WithCause("8002", "Failed to write event: reqID", err, reqID).PrintfInfo()
+		}
+	}
+	if err := flush(); err != nil {
+		return myerror.WithCause("8002", "Failed to write event: reqID", err, reqID).PrintfInfo()
+	}
+
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				// подписчик отключен брокером - клиент переподключится с Last-Event-ID
+				mylog.PrintfDebugMsg("Events subscription is closed: reqID", reqID)
+				return nil
+			}
+			if err := writeEvent(body, &e); err != nil {
+				return myerror.WithCause("8002", "Failed to write event: reqID", err, reqID).PrintfInfo()
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(body, ": heartbeat\n\n"); err != nil {
+				return myerror.WithCause("8002", "Failed to write heartbeat: reqID", err, reqID).PrintfInfo()
+			}
+		case <-end:
+			mylog.PrintfDebugMsg("Events stream is finished before WriteTimeout: reqID", reqID)
+			return nil
+		case <-s.streamsCtx.Done():
+			mylog.PrintfDebugMsg("Events stream is finished on server shutdown: reqID", reqID)
+			return nil
+		case <-ctx.Done():
+			mylog.PrintfDebugMsg("Events subscriber is disconnected: reqID", reqID)
+			return nil
+		}
+		if err := flush(); err != nil {
+			return myerror.WithCause("8002", "Failed to write event: reqID", err, reqID).PrintfInfo()
+		}
+	}
+}
+
+// writeEvent write event in format of Server-Sent Events
+func writeEvent(w io.Writer, e *events.Event) error {
+	data, err := stdjson.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -0,0 +1,73 @@
+package httpservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/romapres2010/httpserver/events"
+	myjson "github.com/romapres2010/httpserver/json"
+)
+
+func TestEventsHandler(t *testing.T) {
+	broker := events.New(&events.Config{JournalSize: 10, BufferSize: 8})
+	s, _, err := New(context.Background(), &Config{WriteTimeout: 2, EventsRetry: 1000}, &myjson.Service{}, broker)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	broker.Publish(events.Event{Type: events.TypeCreated, Object: events.ObjectDept, Key: 10})
+	broker.Publish(events.Event{Type: events.TypeUpdated, Object: events.ObjectEmp, Key: 7839})
+	broker.Publish(events.Event{Type: events.TypeUpdated, Object: events.ObjectDept, Key: 10})
+
+	tests := []struct {
+		name    string
+		url     string
+		lastID  string
+		status  int
+		want    []string
+		notWant []string
+	}{
+		{"resume", "/events", "1", http.StatusOK, []string{"retry: 1000\n", "id: 2\nevent: updated\n", "id: 3\nevent: updated\n"}, []string{"id: 1\n"}},
+		{"resume with filter", "/events?objects=dept", "1", http.StatusOK, []string{"id: 3\n"}, []string{"id: 2\n"}},
+		{"resume from query", "/events?lastEventId=2&types=updated&ids=10", "", http.StatusOK, []string{"id: 3\n"}, []string{"id: 2\n"}},
+		{"reset", "/events", "100", http.StatusOK, []string{"id: 3\nevent: reset\n"}, []string{"id: 2\n"}},
+		{"bad Last-Event-ID", "/events", "abc", http.StatusBadRequest, nil, nil},
+		{"bad objects", "/events?objects=bonus", "", http.StatusBadRequest, nil, nil},
+		{"bad ids", "/events?ids=ten", "", http.StatusBadRequest, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			if tt.lastID != "" {
+				r.Header.Set("Last-Event-ID", tt.lastID)
+			}
+			w := httptest.NewRecorder()
+			s.EventsHandler(w, r) // поток завершается до наступления WriteTimeout
+
+			if w.Code != tt.status {
+				t.Fatalf("EventsHandler() status = %v, want %v", w.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+				t.Errorf("EventsHandler() Content-Type = %q", got)
+			}
+			body := w.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("EventsHandler() body = %q, want %q", body, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(body, notWant) {
+					t.Errorf("EventsHandler() body = %q, not want %q", body, notWant)
+				}
+			}
+		})
+	}
+}
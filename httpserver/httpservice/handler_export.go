@@ -0,0 +1,98 @@
+package httpservice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"unicode/utf8"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/json"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// exportContentTypes represent Content-Type of export formats
+var exportContentTypes = map[string]string{
+	json.ExportFormatJSON:   "application/json; charset=utf-8",
+	json.ExportFormatNDJSON: "application/x-ndjson; charset=utf-8",
+	json.ExportFormatCSV:    "text/csv; charset=utf-8",
+}
+
+// ExportDeptsHandler stream all Depts
+func (s *Service) ExportDeptsHandler(w http.ResponseWriter, r *http.Request) {
+	s.exportHandler(w, r, s.jsonService.ExportDepts, func(reqID uint64, fields []string) (*json.Fieldset, error) {
+		return s.jsonService.DeptFieldset(reqID, fields, nil, nil)
+	})
+}
+
+// ExportEmpsHandler stream all Emps
+func (s *Service) ExportEmpsHandler(w http.ResponseWriter, r *http.Request) {
+	s.exportHandler(w, r, s.jsonService.ExportEmps, s.jsonService.EmpFieldset)
+}
+
+// exportHandler stream rows with export function, format is passed in URL parameter 'format': json (default) | ndjson | csv,
+// CSV delimiter is passed in URL parameter 'delimiter': one character or 'tab', default ',',
+// exported fields are passed in URL parameter 'fields' and parsed with fieldsetFn, default all fields
+func (s *Service) exportHandler(w http.ResponseWriter, r *http.Request,
+	exportFn func(ctx context.Context, format string, delimiter rune, fields *json.Fieldset, w io.Writer) (int64, error),
+	fieldsetFn func(reqID uint64, fields []string) (*json.Fieldset, error)) {
+	mylog.PrintfDebugMsg("START   ==================================================================================")
+
+	// Запускаем типовой process с потоковой записью ответа, возврат ошибки игнорируем
+	_ = s.processResponseStream("GET", w, r, func(ctx context.Context) (Header, int, func(ctx context.Context, w io.Writer) error, error) {
+		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		// Считаем формат выгрузки
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = json.ExportFormatJSON
+		}
+		contentType, ok := exportContentTypes[format]
+		if !ok {
+			return nil, http.StatusBadRequest, nil, myerror.New("8001", "Failed to process parameter 'format', only avaliable: 'json', 'ndjson', 'csv': reqID, format", reqID, format).PrintfInfo()
+		}
+
+		// Считаем разделитель CSV
+		var delimiter rune
+		switch d := r.URL.Query().Get("delimiter"); {
+		case d == "":
+		case d == "tab":
+			delimiter = '\t'
+		case utf8.RuneCountInString(d) == 1:
+			delimiter, _ = utf8.DecodeRuneInString(d)
+		default:
+			return nil, http.StatusBadRequest, nil, myerror.New("8001", "Failed to process parameter 'delimiter', only one character or 'tab' is avaliable: reqID, delimiter", reqID, d).PrintfInfo()
+		}
+		if delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError {
+			return nil, http.StatusBadRequest, nil, myerror.New("8001", "Failed to process parameter 'delimiter', invalid delimiter: reqID, delimiter", reqID, string(delimiter)).PrintfInfo()
+		}
+
+		// Считаем выгружаемые поля
+		fields, err := fieldsetFn(reqID, queryList(r, "fields"))
+		if err != nil {
+			return nil, http.StatusBadRequest, nil, err
+		}
+
+		// формируем заголовок ответа
+		header := Header{}
+		header["Content-Type"] = contentType
+		header["Errcode"] = "0"
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+
+		// тело ответа формируется JSON сервисом по мере чтения строк из БД
+		return header, http.StatusOK, func(ctx context.Context, w io.Writer) error {
+			rows, err := exportFn(ctx, format, delimiter, fields, w)
+			if err != nil {
+				return err
+			}
+			mylog.PrintfDebugMsg("SUCCESS: reqID, rows", reqID, rows)
+			return nil
+		}, nil
+	})
+
+	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
+}
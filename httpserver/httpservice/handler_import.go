@@ -0,0 +1,67 @@
+package httpservice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/json"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// importFormats represent media types of import body
+var importFormats = map[string]string{
+	"text/csv":             json.ImportFormatCSV,
+	"application/x-ndjson": json.ImportFormatNDJSON,
+	"application/ndjson":   json.ImportFormatNDJSON,
+}
+
+// ImportDeptsHandler handle CSV or NDJSON stream for bulk loading of Depts
+func (s *Service) ImportDeptsHandler(w http.ResponseWriter, r *http.Request) {
+	s.importHandler(w, r, s.jsonService.ImportDepts)
+}
+
+// ImportEmpsHandler handle CSV or NDJSON stream for bulk loading of Emps
+func (s *Service) ImportEmpsHandler(w http.ResponseWriter, r *http.Request) {
+	s.importHandler(w, r, s.jsonService.ImportEmps)
+}
+
+// importHandler handle streaming body with import function, format is defined by Content-Type: text/csv | application/x-ndjson
+func (s *Service) importHandler(w http.ResponseWriter, r *http.Request, importFn func(ctx context.Context, format string, in io.Reader, buf []byte) ([]byte, error)) {
+	mylog.PrintfDebugMsg("START   ==================================================================================")
+
+	// Запускаем типовой process со потоковым чтением тела запроса, возврат ошибки игнорируем
+	_ = s.processStream("POST", w, r, func(ctx context.Context, body io.Reader, buf []byte) ([]byte, Header, int, error) {
+		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		// Формат потока определяется по Content-Type
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		format, ok := importFormats[mediaType]
+		if err != nil || !ok {
+			return nil, nil, http.StatusUnsupportedMediaType, myerror.New(ErrCodeUnsupportedMediaType, "Unsupported Content-Type, only avaliable: 'text/csv', 'application/x-ndjson': reqID, Content-Type", reqID, r.Header.Get("Content-Type")).PrintfInfo()
+		}
+
+		// вызываем JSON сервис
+		responseBuf, err := importFn(ctx, format, body, buf)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+
+		// формируем ответ
+		header := Header{}
+		header["Content-Type"] = "application/json; charset=utf-8"
+		header["Errcode"] = "0"
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+
+		mylog.PrintfDebugMsg("SUCCESS: reqID", reqID)
+		return responseBuf, header, http.StatusOK, nil
+	})
+
+	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
+}
@@ -0,0 +1,90 @@
+package httpservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/romapres2010/httpserver/codec"
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/json"
+	mylog "github.com/romapres2010/httpserver/log"
+	"github.com/romapres2010/httpserver/model"
+	"github.com/romapres2010/httpserver/patch"
+)
+
+// PatchDeptHandler handle partial update of Dept with JSON Merge Patch or JSON Patch, response format is negotiated by Accept
+func (s *Service) PatchDeptHandler(w http.ResponseWriter, r *http.Request) {
+	s.patchHandler(w, r, s.jsonServiceOf(r).PatchDept)
+}
+
+// PatchEmpHandler handle partial update of Emp with JSON Merge Patch or JSON Patch, response format is negotiated by Accept
+func (s *Service) PatchEmpHandler(w http.ResponseWriter, r *http.Request) {
+	s.patchHandler(w, r, s.jsonServiceOf(r).PatchEmp)
+}
+
+// patchHandler apply patch with patch function, patch type is defined by Content-Type:
+// application/merge-patch+json - JSON Merge Patch, application/json-patch+json - JSON Patch
+func (s *Service) patchHandler(w http.ResponseWriter, r *http.Request,
+	patchFn func(ctx context.Context, mediaType string, out codec.Codec, id int, precondition json.Precondition, inBuf []byte, buf []byte) ([]byte, model.Version, error)) {
+	mylog.PrintfDebugMsg("START   ==================================================================================")
+
+	// Запускаем типовой process, возврат ошибки игнорируем
+	_ = s.process("PATCH", w, r, func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error) {
+		reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+		mylog.PrintfDebugMsg("START: reqID", reqID)
+
+		// Считаем параметры и проверим на число
+		vars := mux.Vars(r)
+		idStr := vars["id"]
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, myerror.WithCause("8001", "Failed to process parameter 'id' invalid number: reqID, id", err, reqID, idStr).PrintfInfo()
+		}
+
+		// Тип patch по заголовку Content-Type, формат ответа по заголовку Accept
+		mediaType, ok := patch.MediaType(r.Header.Get("Content-Type"))
+		if !ok {
+			w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType) // поддерживаемые типы patch, RFC 5789
+			return nil, nil, http.StatusUnsupportedMediaType, myerror.New(ErrCodeUnsupportedMediaType, "Unsupported Content-Type, only avaliable: 'application/merge-patch+json', 'application/json-patch+json': reqID, Content-Type", reqID, r.Header.Get("Content-Type")).PrintfInfo()
+		}
+		out, status, err := responseCodec(r, reqID)
+		if err != nil {
+			return nil, nil, status, err
+		}
+
+		// Условие изменения по If-Match, "*" - любая версия
+		precondition, err := s.ifMatch(r, reqID, out.ContentType())
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, err
+		}
+
+		// вызываем JSON сервис
+		responseBuf, outVersion, err := patchFn(ctx, mediaType, out, id, precondition, requestBuf, buf)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+
+		// Если данные не найдены
+		if responseBuf == nil {
+			return nil, nil, http.StatusNotFound, nil
+		}
+
+		// формируем ответ
+		header := Header{}
+		header["Content-Type"] = out.ContentType()
+		header["Vary"] = "Accept"
+		header["Errcode"] = "0"
+		header["ETag"] = s.etagOf(r, out.ContentType(), outVersion)
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+
+		mylog.PrintfDebugMsg("SUCCESS: reqID", reqID)
+		return responseBuf, header, http.StatusOK, nil
+	})
+
+	mylog.PrintfDebugMsg("SUCCESS ==================================================================================")
+}
@@ -0,0 +1,33 @@
+package httpservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	myjson "github.com/romapres2010/httpserver/json"
+)
+
+func TestPatchUnsupportedMediaType(t *testing.T) {
+	s, _, err := New(context.Background(), &Config{}, &myjson.Service{}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	r := httptest.NewRequest("PATCH", "/depts/10", strings.NewReader(`{"deptName":"SALES"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r = mux.SetURLVars(r, map[string]string{"id": "10"})
+	w := httptest.NewRecorder()
+	s.PatchDeptHandler(w, r)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("PatchDeptHandler() status = %v, want %v", w.Code, http.StatusUnsupportedMediaType)
+	}
+	if got := w.Header().Get("Accept-Patch"); got != "application/merge-patch+json, application/json-patch+json" {
+		t.Errorf("PatchDeptHandler() Accept-Patch = %q", got)
+	}
+}
@@ -3,19 +3,28 @@ package httpservice
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"reflect"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/romapres2010/httpserver/bytespool"
+	"github.com/romapres2010/httpserver/compress"
 	myctx "github.com/romapres2010/httpserver/ctx"
 	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
+	"github.com/romapres2010/httpserver/httpserver/concurrency"
+	"github.com/romapres2010/httpserver/httpserver/cors"
 	httplog "github.com/romapres2010/httpserver/httpserver/httplog"
+	"github.com/romapres2010/httpserver/httpserver/idempotency"
+	"github.com/romapres2010/httpserver/httpserver/ratelimit"
 	"github.com/romapres2010/httpserver/json"
 	myjwt "github.com/romapres2010/httpserver/jwt"
 	mylog "github.com/romapres2010/httpserver/log"
+	mysql "github.com/romapres2010/httpserver/sqlxx"
+	"github.com/romapres2010/httpserver/users"
 )
 
 // Header represent temporary HTTP header
@@ -26,6 +35,8 @@ type Handler struct {
 	Path        string
 	HundlerFunc func(http.ResponseWriter, *http.Request)
 	Method      string
+	RateLimit   *ratelimit.Config // политика ограничения частоты запросов маршрута, nil - общая политика
+	CORS        *cors.Config      // политика CORS маршрута, nil - общая политика
 }
 
 // Handlers represent HTTP handlers map
@@ -34,6 +45,24 @@ type Handlers map[string]Handler
 // уникальный номер HTTP запроса
 var requestID uint64
 
+// StatusClientClosedRequest represent non standard HTTP status - client closed request before response
+const StatusClientClosedRequest = 499
+
+// errorStatuses represent HTTP status of catalogued errors, it overrides status returned by handler
+var errorStatuses = map[string]int{
+	mysql.ErrCodeTimeout:             http.StatusGatewayTimeout,
+	mysql.ErrCodeCanceled:            StatusClientClosedRequest,
+	mysql.ErrCodeVersionConflict:     http.StatusConflict,
+	mysql.ErrCodeConstraintViolation: http.StatusBadRequest,
+	json.ErrCodeInvalidBatch:         http.StatusBadRequest,
+	json.ErrCodeInvalidRow:           http.StatusBadRequest,
+	json.ErrCodeInvalidPatch:         http.StatusBadRequest,
+	json.ErrCodePatchTestFailed:      http.StatusConflict,
+	ErrCodeBodyTooLarge:              http.StatusRequestEntityTooLarge,
+	ErrCodePreconditionFailed:        http.StatusPreconditionFailed,
+	idempotency.ErrCodeFull:          http.StatusServiceUnavailable,
+}
+
 // Service represent HTTP service
 type Service struct {
 	ctx      context.Context    // корневой контекст при инициации сервиса
@@ -42,41 +71,105 @@ type Service struct {
 	Handlers Handlers           // список обработчиков
 
 	// вложенные сервисы
-	logger      *httplog.Logger // сервис логирования HTTP
-	jsonService *json.Service   // реализация JSON сервиса
-	bytesPool   *bytespool.Pool // represent pooling of []byte
+	logger      *httplog.Logger    // сервис логирования HTTP
+	jsonService *json.Service      // реализация JSON сервиса
+	bytesPool   *bytespool.Pool    // represent pooling of []byte
+	users       *users.Store       // пользователи для INTERNAL аутентификации
+	idempotency *idempotency.Store // ключи идемпотентности POST запросов
+	compress    *compress.Pool     // represent pooling of compressor writers
+	lockout     *ratelimit.Lockout // блокировка после неудачных попыток аутентификации
+	openAPIDoc  []byte             // документ OpenAPI зарегистрированных обработчиков
+	events      *events.Broker     // поток изменений объектов, nil - поток не публикуется
+
+	streamsCtx   context.Context    // контекст длительных потоковых ответов
+	closeStreams context.CancelFunc // завершение длительных потоковых ответов при остановке HTTP сервера
+
+	versions       map[string]*apiVersion // версии API
+	latestVersion  string                 // последняя версия API
+	versionedPaths map[string]bool        // пути маршрутов, доступные с префиксом версии
 }
 
 // Config repsent HTTP Service configurations
 type Config struct {
-	MaxBodyBytes       int    // HTTP max body bytes - default 0 - unlimited
-	UseTLS             bool   // use SSL
-	UseHSTS            bool   // use HTTP Strict Transport Security
-	UseJWT             bool   // use JSON web token (JWT)
-	JWTExpiresAt       int    // JWT expiry time in seconds - 0 without restriction
-	JwtKey             []byte // JWT secret key
-	AuthType           string // тип аутентификации NONE, INTERNAL, MSAD
-	HTTPUserID         string // пользователь для HTTP Basic Authentication передается через командую строку
-	HTTPUserPwd        string // пароль для HTTP Basic Authentication передается через командую строку
-	MSADServer         string // MS Active Directory server
-	MSADPort           int    // MS Active Directory Port
-	MSADBaseDN         string // MS Active Directory BaseDN
-	MSADSecurity       int    // MS Active Directory Security: SecurityNone, SecurityTLS, SecurityStartTLS
-	HTTPErrorLogHeader bool   // логирование ошибок в заголовок HTTP ответа
-	HTTPErrorLogBody   bool   // логирование ошибок в тело HTTP ответа
-	HTTPLog            bool   // логирование HTTP трафика в файл
-	HTTPLogFileName    string // файл логирование HTTP трафика
-	UseBufPool         bool   // use []byte poolling
-	BufPooledSize      int    // recomended size of []byte for poolling
-	BufPooledMaxSize   int    // max size of []byte for poolling
+	MaxBodyBytes       int      // HTTP max body bytes - default 0 - unlimited
+	MaxStreamBodyBytes int      // HTTP max body bytes of streaming request - default 0 - unlimited
+	WriteTimeout       int      // HTTP write timeout duration in sec - 0 without restriction
+	UseTLS             bool     // use SSL
+	UseHSTS            bool     // use HTTP Strict Transport Security
+	UseJWT             bool     // use JSON web token (JWT)
+	JWTExpiresAt       int      // JWT expiry time in seconds - 0 without restriction
+	JwtKey             []byte   // JWT secret key
+	AuthType           string   // тип аутентификации NONE, INTERNAL, MSAD
+	HTTPUserID         string   // пользователь для HTTP Basic Authentication передается через командую строку
+	HTTPUserPwd        string   // пароль для HTTP Basic Authentication передается через командую строку
+	UsersFile          string   // файл пользователей для HTTP Basic Authentication
+	MSADServer         string   // MS Active Directory server
+	MSADPort           int      // MS Active Directory Port
+	MSADBaseDN         string   // MS Active Directory BaseDN
+	MSADSecurity       int      // MS Active Directory Security: SecurityNone, SecurityTLS, SecurityStartTLS
+	HTTPErrorLogHeader bool     // логирование ошибок в заголовок HTTP ответа
+	HTTPErrorLogBody   bool     // логирование ошибок в тело HTTP ответа
+	HTTPLog            bool     // логирование HTTP трафика в файл
+	HTTPLogFileName    string   // файл логирование HTTP трафика
+	UseBufPool         bool     // use []byte poolling
+	BufPooledSize      int      // recomended size of []byte for poolling
+	BufPooledMaxSize   int      // max size of []byte for poolling
+	IdempotencyTTL     int      // время хранения ключа Idempotency-Key в секундах, 0 - ключи не поддерживаются
+	IdempotencyMaxKeys int      // максимальное количество хранимых ключей Idempotency-Key
+	UseCompress        bool     // сжатие ответов gzip, deflate, br по заголовку Accept-Encoding
+	CompressMinSize    int      // минимальный размер ответа для сжатия
+	CompressLevel      int      // уровень сжатия 1 - 9
+	CompressTypes      []string // типы ответа для сжатия, допускается маска "text/*"
+
+	UseRateLimit          bool                      // ограничение частоты запросов общей политикой
+	RateLimitKeyBy        string                    // ключ клиента: IP, USER, APIKEY
+	RateLimitAPIKeyHeader string                    // заголовок с API key
+	RateLimitRequests     int                       // количество запросов за период
+	RateLimitPeriod       int                       // период в секундах
+	RateLimitBurst        int                       // максимальное количество запросов подряд
+	RateLimitMaxKeys      int                       // максимальное количество хранимых ключей клиентов
+	RouteRateLimits       map[string]RouteRateLimit // политики маршрутов по имени обработчика вместо общей политики
+	AuthRateRequests      int                       // количество запросов аутентификации за период с одного IP, 0 - без ограничения
+	AuthRatePeriod        int                       // период в секундах
+	AuthRateBurst         int                       // максимальное количество запросов аутентификации подряд
+	LockoutFailures       int                       // количество неудачных попыток аутентификации с одного IP до блокировки, 0 - без блокировки
+	LockoutWindow         int                       // период подсчета неудачных попыток в секундах
+	LockoutDuration       int                       // время блокировки в секундах
+
+	UseConcurrencyLimit bool           // ограничение количества одновременно обрабатываемых запросов
+	MaxInFlight         int            // максимальное количество одновременных запросов на сервер
+	MaxQueue            int            // максимальная длина очереди ожидания
+	QueueTimeout        int            // максимальное время ожидания в очереди в миллисекундах
+	OverloadRetryAfter  int            // значение заголовка Retry-After при перегрузке в секундах
+	AdaptiveLimit       bool           // адаптивный лимит по задержке обработки
+	MinInFlight         int            // минимальный адаптивный лимит
+	TargetLatency       int            // целевая задержка обработки запроса в миллисекундах
+	RouteMaxInFlight    map[string]int // лимиты маршрутов по имени обработчика
+
+	UseCORS          bool                // поддержка Cross-Origin Resource Sharing
+	CORSRouteOrigins map[string][]string // разрешенные источники маршрутов по имени обработчика
+
+	UseOpenAPI bool // публикация документа OpenAPI по адресу /openapi.json
+	UseDocsUI  bool // интерактивная документация по адресу /docs
+
+	EventsHeartbeat int // интервал комментариев heartbeat потока событий в секундах
+	EventsRetry     int // интервал переподключения клиента потока событий в миллисекундах, 0 - по умолчанию клиента
+
+	UseVersioning      bool                 // версии API: префикс пути /v1, /v2 или параметр version заголовка Accept
+	APIVersions        []string             // версии API, последняя версия - актуальная
+	DefaultVersion     string               // версия API для запросов без указания версии
+	DeprecatedVersions []string             // устаревшие версии API
+	VersionSunset      map[string]time.Time // даты прекращения поддержки версий API
 
 	// конфигурация вложенных сервисов
 	LogCfg       httplog.Config   // конфигурация HTTP логирования
+	CORSCfg      cors.Config      // общая политика CORS
 	bytesPoolCfg bytespool.Config // конфигурация bytesPool
+	compressCfg  compress.Config  // конфигурация сжатия
 }
 
-// New create new HTTP service
-func New(ctx context.Context, cfg *Config, jsonService *json.Service) (*Service, *httplog.Logger, error) {
+// New create new HTTP service, changes of objects are streamed from broker, nil broker - stream is not published
+func New(ctx context.Context, cfg *Config, jsonService *json.Service, broker *events.Broker) (*Service, *httplog.Logger, error) {
 	var err error
 
 	mylog.PrintfInfoMsg("Creating new HTTP service")
@@ -90,6 +183,7 @@ func New(ctx context.Context, cfg *Config, jsonService *json.Service) (*Service,
 	service := &Service{
 		cfg:         cfg,
 		jsonService: jsonService,
+		events:      broker,
 	}
 
 	// создаем контекст с отменой
@@ -98,26 +192,180 @@ func New(ctx context.Context, cfg *Config, jsonService *json.Service) (*Service,
 	} else {
 		service.ctx, service.cancel = context.WithCancel(ctx)
 	}
+	service.streamsCtx, service.closeStreams = context.WithCancel(service.ctx)
 
 	// создаем обработчик для логирования HTTP
 	if service.logger, err = httplog.New(service.ctx, &cfg.LogCfg, cfg.HTTPLogFileName); err != nil {
 		return nil, nil, err
 	}
 
+	// загружаем файл пользователей для INTERNAL аутентификации
+	if cfg.AuthType == "INTERNAL" && cfg.UsersFile != "" {
+		if service.users, err = users.Load(cfg.UsersFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// создаем хранилище ключей идемпотентности
+	if cfg.IdempotencyTTL > 0 {
+		service.idempotency = idempotency.New(&idempotency.Config{
+			TTL:     time.Duration(cfg.IdempotencyTTL) * time.Second,
+			MaxKeys: cfg.IdempotencyMaxKeys,
+		})
+	}
+
+	// Встроенная строгая политика для аутентификации - защита от подбора паролей
+	var authRateLimit *ratelimit.Config
+	if cfg.AuthRateRequests > 0 {
+		authRateLimit = &ratelimit.Config{
+			Requests: cfg.AuthRateRequests,
+			Period:   time.Duration(cfg.AuthRatePeriod) * time.Second,
+			Burst:    cfg.AuthRateBurst,
+			KeyBy:    ratelimit.KeyByIP,
+			MaxKeys:  cfg.RateLimitMaxKeys,
+		}
+	}
+	if cfg.LockoutFailures > 0 {
+		service.lockout = ratelimit.NewLockout(&ratelimit.LockoutConfig{
+			MaxFailures: cfg.LockoutFailures,
+			Window:      time.Duration(cfg.LockoutWindow) * time.Second,
+			Duration:    time.Duration(cfg.LockoutDuration) * time.Second,
+			MaxKeys:     cfg.RateLimitMaxKeys,
+		})
+	}
+
 	// Наполним список обрабочиков
 	service.Handlers = map[string]Handler{
 		// Типовые обработчики
-		"EchoHandler":         Handler{"/echo", service.recoverWrap(service.EchoHandler), "POST"},
-		"SinginHandler":       Handler{"/signin", service.recoverWrap(service.SinginHandler), "POST"},
-		"JWTRefreshHandler":   Handler{"/refresh", service.recoverWrap(service.JWTRefreshHandler), "POST"},
-		"HTTPLogHandler":      Handler{"/httplog", service.recoverWrap(service.HTTPLogHandler), "POST"},
-		"HTTPErrorLogHandler": Handler{"/httperrlog", service.recoverWrap(service.HTTPErrorLogHandler), "POST"},
-		"LogLevelHandler":     Handler{"/loglevel", service.recoverWrap(service.LogLevelHandler), "POST"},
+		"EchoHandler":         Handler{"/echo", service.recoverWrap(service.EchoHandler), "POST", nil, nil},
+		"SinginHandler":       Handler{"/signin", service.recoverWrap(service.SinginHandler), "POST", authRateLimit, nil},
+		"JWTRefreshHandler":   Handler{"/refresh", service.recoverWrap(service.JWTRefreshHandler), "POST", authRateLimit, nil},
+		"HTTPLogHandler":      Handler{"/httplog", service.recoverWrap(service.HTTPLogHandler), "POST", nil, nil},
+		"HTTPErrorLogHandler": Handler{"/httperrlog", service.recoverWrap(service.HTTPErrorLogHandler), "POST", nil, nil},
+		"LogLevelHandler":     Handler{"/loglevel", service.recoverWrap(service.LogLevelHandler), "POST", nil, nil},
 
 		// JSON обработчики
-		"CreateDeptHandler": Handler{"/depts", service.recoverWrap(service.CreateDeptHandler), "POST"},
-		"GetDeptHandler":    Handler{"/depts/{id:[0-9]+}", service.recoverWrap(service.GetDeptHandler), "GET"},
-		"UpdateDeptHandler": Handler{"/depts/{id:[0-9]+}", service.recoverWrap(service.UpdateDeptHandler), "PUT"},
+		"CreateDeptHandler":  Handler{"/depts", service.recoverWrap(service.CreateDeptHandler), "POST", nil, nil},
+		"GetDeptHandler":     Handler{"/depts/{id:[0-9]+}", service.recoverWrap(service.GetDeptHandler), "GET", nil, nil},
+		"UpdateDeptHandler":  Handler{"/depts/{id:[0-9]+}", service.recoverWrap(service.UpdateDeptHandler), "PUT", nil, nil},
+		"PatchDeptHandler":   Handler{"/depts/{id:[0-9]+}", service.recoverWrap(service.PatchDeptHandler), "PATCH", nil, nil},
+		"PatchEmpHandler":    Handler{"/emps/{id:[0-9]+}", service.recoverWrap(service.PatchEmpHandler), "PATCH", nil, nil},
+		"BatchDeptsHandler":  Handler{"/depts:batch", service.recoverWrap(service.BatchDeptsHandler), "POST", nil, nil},
+		"BatchEmpsHandler":   Handler{"/emps:batch", service.recoverWrap(service.BatchEmpsHandler), "POST", nil, nil},
+		"ImportDeptsHandler": Handler{"/depts:import", service.recoverWrap(service.ImportDeptsHandler), "POST", nil, nil},
+		"ImportEmpsHandler":  Handler{"/emps:import", service.recoverWrap(service.ImportEmpsHandler), "POST", nil, nil},
+		"ExportDeptsHandler": Handler{"/depts:export", service.recoverWrap(service.ExportDeptsHandler), "GET", nil, nil},
+		"ExportEmpsHandler":  Handler{"/emps:export", service.recoverWrap(service.ExportEmpsHandler), "GET", nil, nil},
+	}
+
+	// Документация API
+	if cfg.UseOpenAPI {
+		service.Handlers["OpenAPIHandler"] = Handler{"/openapi.json", service.recoverWrap(service.OpenAPIHandler), "GET", nil, nil}
+		if cfg.UseDocsUI {
+			service.Handlers["DocsUIHandler"] = Handler{"/docs", service.recoverWrap(service.DocsUIHandler), "GET", nil, nil}
+			service.Handlers["DocsUIAssetHandler"] = Handler{"/docs/{file}", service.recoverWrap(service.DocsUIAssetHandler), "GET", nil, nil}
+		}
+	}
+
+	// Поток изменений объектов
+	if broker != nil {
+		service.Handlers["EventsHandler"] = Handler{"/events", service.recoverWrap(service.EventsHandler), "GET", nil, nil}
+	}
+
+	// Версии API: маршруты с префиксом версии используют JSON сервис версии
+	if cfg.UseVersioning {
+		if err = service.applyVersions(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Ограничение одновременно обрабатываемых запросов: лимит маршрута и общий лимит сервера
+	if cfg.UseConcurrencyLimit {
+		queueTimeout := time.Duration(cfg.QueueTimeout) * time.Millisecond
+		serverLimiter := concurrency.New("server", &concurrency.Config{
+			MaxInFlight:   cfg.MaxInFlight,
+			MaxQueue:      cfg.MaxQueue,
+			QueueTimeout:  queueTimeout,
+			Adaptive:      cfg.AdaptiveLimit,
+			MinInFlight:   cfg.MinInFlight,
+			TargetLatency: time.Duration(cfg.TargetLatency) * time.Millisecond,
+		})
+		for name, h := range service.Handlers {
+			limiters := []*concurrency.Limiter{serverLimiter}
+			if streamingHandlers[name] {
+				limiters = nil // длительный поток не должен занимать общий слот
+			}
+			if maxInFlight, ok := cfg.RouteMaxInFlight[name]; ok {
+				routeLimiter := concurrency.New(name, &concurrency.Config{
+					MaxInFlight:  maxInFlight,
+					MaxQueue:     cfg.MaxQueue,
+					QueueTimeout: queueTimeout,
+				})
+				limiters = append([]*concurrency.Limiter{routeLimiter}, limiters...)
+			}
+			if len(limiters) == 0 {
+				continue
+			}
+			h.HundlerFunc = service.concurrencyWrap(limiters, h.HundlerFunc)
+			service.Handlers[name] = h
+		}
+		for name := range cfg.RouteMaxInFlight {
+			if _, ok := service.Handlers[name]; !ok {
+				return nil, nil, myerror.New("6031", "Unknown handler in concurrency limits of routes: name", name).PrintfInfo()
+			}
+		}
+	}
+
+	// Ограничение частоты запросов: политика маршрута или общая политика
+	var defaultLimiter *ratelimit.Limiter
+	if cfg.UseRateLimit {
+		for name, routeLimit := range cfg.RouteRateLimits {
+			h, ok := service.Handlers[name]
+			if !ok {
+				return nil, nil, myerror.New("6031", "Unknown handler in rate limits of routes: name", name).PrintfInfo()
+			}
+			h.RateLimit = &ratelimit.Config{
+				Requests: routeLimit.Requests,
+				Period:   time.Duration(routeLimit.Period) * time.Second,
+				Burst:    routeLimit.Burst,
+				KeyBy:    cfg.RateLimitKeyBy,
+				MaxKeys:  cfg.RateLimitMaxKeys,
+			}
+			service.Handlers[name] = h
+		}
+		defaultLimiter = ratelimit.New(&ratelimit.Config{
+			Requests: cfg.RateLimitRequests,
+			Period:   time.Duration(cfg.RateLimitPeriod) * time.Second,
+			Burst:    cfg.RateLimitBurst,
+			KeyBy:    cfg.RateLimitKeyBy,
+			MaxKeys:  cfg.RateLimitMaxKeys,
+		})
+	}
+	limiters := make(map[*ratelimit.Config]*ratelimit.Limiter) // маршруты с одной политикой используют общие корзины
+	for name, h := range service.Handlers {
+		limiter := defaultLimiter
+		if h.RateLimit != nil {
+			if limiter = limiters[h.RateLimit]; limiter == nil {
+				limiter = ratelimit.New(h.RateLimit)
+				limiters[h.RateLimit] = limiter
+			}
+		}
+		if limiter != nil {
+			h.HundlerFunc = service.limitWrap(limiter, h.HundlerFunc)
+			service.Handlers[name] = h
+		}
+	}
+
+	// CORS применяется последним, чтобы отказы по перегрузке тоже были доступны браузеру
+	if cfg.UseCORS {
+		for name := range cfg.CORSRouteOrigins {
+			if _, ok := service.Handlers[name]; !ok {
+				return nil, nil, myerror.New("6031", "Unknown handler in CORS origins of routes: name", name).PrintfInfo()
+			}
+		}
+		if err := service.applyCORS(); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// создаем BytesPool
@@ -126,6 +374,21 @@ func New(ctx context.Context, cfg *Config, jsonService *json.Service) (*Service,
 		service.bytesPool = bytespool.New(&service.cfg.bytesPoolCfg)
 	}
 
+	// создаем pool для сжатия ответов
+	if service.cfg.UseCompress {
+		service.cfg.compressCfg.MinSize = service.cfg.CompressMinSize
+		service.cfg.compressCfg.Level = service.cfg.CompressLevel
+		service.cfg.compressCfg.ContentTypes = service.cfg.CompressTypes
+		service.compress = compress.New(&service.cfg.compressCfg)
+	}
+
+	// формируем документ OpenAPI по окончательному списку обработчиков
+	if cfg.UseOpenAPI {
+		if service.openAPIDoc, err = service.openAPI().Marshal(); err != nil {
+			return nil, nil, myerror.WithCause("6032", "Failed to marshal OpenAPI document", err).PrintfInfo()
+		}
+	}
+
 	mylog.PrintfInfoMsg("HTTP service is created")
 	return service, service.logger, nil
 }
@@ -135,6 +398,12 @@ func GetNextRequestID() uint64 {
 	return atomic.AddUint64(&requestID, 1)
 }
 
+// CloseStreams finish long-lived streaming responses, it is called on start of HTTP server shutdown,
+// so server does not wait for them until ShutdownTimeout
+func (s *Service) CloseStreams() {
+	s.closeStreams()
+}
+
 // Shutdown shutting down service
 func (s *Service) Shutdown() (myerr error) {
 	defer s.cancel() // закрываем контекст
@@ -149,6 +418,11 @@ func (s *Service) Shutdown() (myerr error) {
 		s.bytesPool.PrintBytesPoolStats()
 	}
 
+	// Print statistics about compressor writers pool
+	if s.compress != nil {
+		s.compress.PrintCompressPoolStats()
+	}
+
 	return
 }
 
@@ -159,6 +433,9 @@ func (s *Service) recoverWrap(handlerFunc http.HandlerFunc) http.HandlerFunc {
 		defer func() {
 			var myerr error
 			r := recover()
+			if r == http.ErrAbortHandler {
+				panic(r) // прерывание соединения обрабатывается HTTP сервером
+			}
 			if r != nil {
 				msg := "HTTP Handler recover from panic"
 				switch t := r.(type) {
@@ -184,15 +461,263 @@ func (s *Service) recoverWrap(handlerFunc http.HandlerFunc) http.HandlerFunc {
 // process - represent server common task in process incoming HTTP request
 func (s *Service) process(method string, w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error)) (myerr error) {
 
+	// Начинаем обработку запроса
+	ctx, cancel, reqID, myerr := s.begin(method, w, r, false)
+	defer cancel()
+	if myerr != nil {
+		return myerr
+	}
+
+	// Считаем тело запроса, тело небольшого размера считывается в буфер из pool
+	mylog.PrintfDebugMsg("Reading request body: reqID", reqID)
+	var responseBuf []byte
+	requestBuf, pooledReq, err := s.readBody(r)
+	if err != nil {
+		myerr = myerror.WithCause("8001", "Failed to read HTTP body: reqID", err, reqID).PrintfInfo()
+		s.processError(myerr, w, errorStatus(myerr, http.StatusInternalServerError), reqID) // расширенное логирование ошибки в контексте HTTP
+		return myerr
+	}
+	if pooledReq {
+		defer func() {
+			// входной буфер может быть возвращен в качестве ответа - тогда он вернется в pool вместе с ответом
+			if reflect.ValueOf(requestBuf).Pointer() != reflect.ValueOf(responseBuf).Pointer() {
+				s.bytesPool.PutBuf(requestBuf)
+			}
+		}()
+	}
+	mylog.PrintfDebugMsg("Read request body: reqID, len(body), pooled", reqID, len(requestBuf), pooledReq)
+
+	// Выделяем новый буфер из pool, он может использоваться для копирования JSON / XML
+	// Если буфер будет недостаточного размера, то он не будет использован
+	var buf []byte
+	if s.cfg.UseBufPool && s.bytesPool != nil {
+		buf = s.bytesPool.GetBuf()
+		mylog.PrintfDebugMsg("Got []byte buffer from pool: size", cap(buf))
+	}
+
+	// Проверим ключ идемпотентности POST запроса - повторный запрос получает сохраненный ответ
+	var idempotencyKey string
+	var storedResp *idempotency.Response
+	if s.idempotency != nil && r.Method == http.MethodPost && r.Header.Get("Idempotency-Key") != "" {
+		// ключ уникален в рамках пользователя и ресурса - ключ другого пользователя не дает получить чужой ответ
+		idempotencyKey = myctx.FromContextUsername(ctx) + " " + r.URL.Path + " " + r.Header.Get("Idempotency-Key")
+		mylog.PrintfDebugMsg("Check idempotency key: reqID, key", reqID, idempotencyKey)
+		fingerprint := idempotency.Fingerprint([]byte(r.Method), []byte(r.URL.RequestURI()), []byte(r.Header.Get("Content-Type")), []byte(r.Header.Get("Accept")), requestBuf)
+		if storedResp, myerr = s.idempotency.Begin(idempotencyKey, fingerprint); myerr != nil {
+			s.processError(myerr, w, errorStatus(myerr, http.StatusConflict), reqID) // расширенное логирование ошибки в контексте HTTP
+			return myerr
+		}
+		if storedResp == nil {
+			// ошибочный ответ или panic в обработчике - резерв снимается, клиент может повторить запрос с тем же ключом
+			// после сохранения ответа Cancel ничего не делает
+			defer s.idempotency.Cancel(idempotencyKey)
+		}
+	}
+
+	var header Header
+	var status int
+	if storedResp != nil {
+		// повторный запрос - обработчик не вызываем
+		mylog.PrintfInfoMsg("Replay stored response for idempotency key: reqID, key", reqID, idempotencyKey)
+		responseBuf, status = storedResp.Body, storedResp.Status
+		header = Header{}
+		for key, h := range storedResp.Header {
+			header[key] = h
+		}
+		header["Idempotent-Replayed"] = "true"
+		header["RequestID"] = fmt.Sprintf("%v", reqID)
+	} else {
+		// вызываем обработчик
+		mylog.PrintfDebugMsg("Calling external function handler: reqID, function", reqID, fn)
+		responseBuf, header, status, myerr = fn(ctx, requestBuf, buf)
+		if myerr != nil {
+			// ошибочный ответ не сохраняем
+			mylog.PrintfErrorInfo(myerr)
+			s.processError(myerr, w, errorStatus(myerr, status), reqID) // расширенное логирование ошибки в контексте HTTP
+			return myerr
+		}
+
+		// сохраним ответ для повторных запросов
+		if idempotencyKey != "" {
+			s.idempotency.Complete(idempotencyKey, status, header, responseBuf)
+		}
+	}
+
+	// Если переданного буфера не хватило, то мог быть создан новый буфер. Вернем его в pool
+	// Сохраненный ответ на повторный запрос в pool не возвращаем
+	if responseBuf != nil && buf != nil && storedResp == nil && s.cfg.UseBufPool && s.bytesPool != nil {
+		// Если новый буфер подходит по размерам для хранения в pool
+		if cap(responseBuf) >= s.cfg.BufPooledSize && cap(responseBuf) <= s.cfg.BufPooledMaxSize {
+			defer s.bytesPool.PutBuf(responseBuf)
+		}
+
+		if reflect.ValueOf(buf).Pointer() != reflect.ValueOf(responseBuf).Pointer() {
+			mylog.PrintfInfoMsg("[]byte buffer: poolBufSize, responseBufSize", cap(buf), cap(responseBuf))
+		}
+	}
+
+	// Логируем ответ в файл
+	if s.logger != nil {
+		_ = s.logger.LogHTTPOutResponse(ctx, header, responseBuf, status) // При сбое HTTP логирования, делаем системное логирование, но работу не останавливаем
+	}
+
+	return s.writeResponse(w, r, reqID, header, status, responseBuf)
+}
+
+// processStream - represent server common task in process incoming HTTP request with streaming body.
+// Body is not read into memory and passed to handler as io.Reader
+func (s *Service) processStream(method string, w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, body io.Reader, buf []byte) ([]byte, Header, int, error)) (myerr error) {
+
+	// Начинаем обработку запроса
+	ctx, cancel, reqID, myerr := s.begin(method, w, r, true)
+	defer cancel()
+	if myerr != nil {
+		return myerr
+	}
+
+	// Выделяем новый буфер из pool для формирования ответа
+	var buf []byte
+	if s.cfg.UseBufPool && s.bytesPool != nil {
+		buf = s.bytesPool.GetBuf()
+		defer s.bytesPool.PutBuf(buf)
+		mylog.PrintfDebugMsg("Got []byte buffer from pool: size", cap(buf))
+	}
+
+	// вызываем обработчик - тело запроса читается обработчиком по мере обработки
+	mylog.PrintfDebugMsg("Calling external function handler with streaming body: reqID, function", reqID, fn)
+	responseBuf, header, status, myerr := fn(ctx, r.Body, buf)
+	if myerr != nil {
+		mylog.PrintfErrorInfo(myerr)
+		s.processError(myerr, w, errorStatus(myerr, status), reqID) // расширенное логирование ошибки в контексте HTTP
+		return myerr
+	}
+
+	// Логируем ответ в файл
+	if s.logger != nil {
+		_ = s.logger.LogHTTPOutResponse(ctx, header, responseBuf, status) // При сбое HTTP логирования, делаем системное логирование, но работу не останавливаем
+	}
+
+	return s.writeResponse(w, r, reqID, header, status, responseBuf)
+}
+
+// processResponseStream - represent server common task in process incoming HTTP request with streaming response.
+// fn check request and return header, status and function which writes response body directly into HTTP response.
+// Response is limited by WriteTimeout, error after start of writing body aborts connection - client gets incomplete response
+func (s *Service) processResponseStream(method string, w http.ResponseWriter, r *http.Request, fn func(ctx context.Context) (Header, int, func(ctx context.Context, w io.Writer) error, error)) (myerr error) {
+
+	// Начинаем обработку запроса
+	ctx, cancel, reqID, myerr := s.begin(method, w, r, false)
+	defer cancel()
+	if myerr != nil {
+		return myerr
+	}
+
+	// запись ответа позже WriteTimeout завершится ошибкой - прекращаем формирование ответа
+	if s.cfg.WriteTimeout > 0 {
+		var cancelWrite context.CancelFunc
+		ctx, cancelWrite = context.WithTimeout(ctx, time.Duration(s.cfg.WriteTimeout)*time.Second)
+		defer cancelWrite()
+	}
+
+	// вызываем обработчик
+	mylog.PrintfDebugMsg("Calling external function handler with streaming response: reqID, function", reqID, fn)
+	header, status, writeFn, myerr := fn(ctx)
+	if myerr != nil {
+		mylog.PrintfErrorInfo(myerr)
+		s.processError(myerr, w, errorStatus(myerr, status), reqID) // расширенное логирование ошибки в контексте HTTP
+		return myerr
+	}
+
+	// Логируем ответ в файл, тело ответа не логируется
+	if s.logger != nil {
+		_ = s.logger.LogHTTPOutResponse(ctx, header, nil, status) // При сбое HTTP логирования, делаем системное логирование, но работу не останавливаем
+	}
+
+	// Определим сжатие потока ответа, размер ответа заранее неизвестен
+	encoding := s.responseEncoding(r, header, -1)
+
+	// Записываем заголовок, тело ответа передается частями (chunked transfer encoding)
+	if myerr = s.writeResponse(w, r, reqID, header, status, nil); myerr != nil {
+		return myerr
+	}
+
+	mylog.PrintfDebugMsg("Writing HTTP response body stream: reqID, Content-Encoding", reqID, encoding)
+	var bodyWriter io.Writer = w
+	if encoding != "" {
+		cw := s.compress.GetWriter(encoding, w)
+		defer s.compress.PutWriter(encoding, cw)
+		bodyWriter = cw
+	}
+	myerr = writeFn(ctx, bodyWriter)
+	if myerr == nil && bodyWriter != w {
+		if err := bodyWriter.(compress.Writer).Close(); err != nil {
+			myerr = myerror.WithCause("8002", "Failed to write HTTP repsonse: reqID", err, reqID).PrintfInfo()
+		}
+	}
+	if myerr != nil {
+		// статус уже отправлен - прерываем соединение, чтобы клиент не принял неполный ответ за полный
+		mylog.PrintfErrorInfo(myerr)
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+// begin - represent server common task in start of processing incoming HTTP request: request context, logging, method and authentication checks.
+// Returned cancel function must be called after processing of request. Streaming body is not logged.
+func (s *Service) begin(method string, w http.ResponseWriter, r *http.Request, stream bool) (ctx context.Context, cancel context.CancelFunc, reqID uint64, myerr error) {
+
 	// Получить уникальный номер HTTP запроса
-	reqID := GetNextRequestID()
+	reqID = GetNextRequestID()
+
+	// для каждого запроса создаем новый контекст от контекста HTTP запроса - он отменяется при закрытии подключения клиентом
+	ctx, cancel = context.WithCancel(r.Context())
+
+	// при остановке сервиса отменяем активные запросы.
+	// Канал берем до запуска горутины - ctx далее дополняется значениями
+	done := ctx.Done()
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+
+	// сохраняем в контексте уникальный номер HTTP запроса
+	ctx = myctx.NewContextRequestID(ctx, reqID)
+
+	// Ограничение размера тела запроса, при потоковой обработке действует отдельное ограничение
+	maxBodyBytes := int64(s.cfg.MaxBodyBytes)
+	if stream {
+		maxBodyBytes = int64(s.cfg.MaxStreamBodyBytes)
+	}
+
+	// Тело с заранее известным размером проверим до чтения
+	if maxBodyBytes > 0 && r.ContentLength > maxBodyBytes {
+		myerr = myerror.New(ErrCodeBodyTooLarge, "HTTP body is too large: reqID, ContentLength, MaxBodyBytes", reqID, r.ContentLength, maxBodyBytes).PrintfInfo()
+		s.processError(myerr, w, http.StatusRequestEntityTooLarge, reqID) // расширенное логирование ошибки в контексте HTTP
+		return ctx, cancel, reqID, myerr
+	}
 
-	// для каждого запроса поздаем новый контекст, сохраняем в нем уникальный номер HTTP запроса
-	ctx := myctx.NewContextRequestID(s.ctx, reqID)
+	// Тело запроса со сжатием заменим на распаковку
+	if status, err := decompressBody(r, reqID); err != nil {
+		myerr = err
+		s.processError(myerr, w, status, reqID) // расширенное логирование ошибки в контексте HTTP
+		return ctx, cancel, reqID, myerr
+	}
+
+	// Ограничим размер тела запроса после распаковки.
+	// До логирования - тело без Content-Length или со сжатием читается при логировании не больше ограничения,
+	// при превышении ошибка сохраняется в теле и возвращается обработчику при чтении
+	limitBody(r, reqID, maxBodyBytes)
 
 	// Логируем входящий HTTP запрос
 	if s.logger != nil {
-		_ = s.logger.LogHTTPInRequest(ctx, r) // При сбое HTTP логирования, делаем системное логирование, но работу не останавливаем
+		if stream {
+			_ = s.logger.LogHTTPInRequestStream(ctx, r) // При сбое HTTP логирования, делаем системное логирование, но работу не останавливаем
+		} else {
+			_ = s.logger.LogHTTPInRequest(ctx, r) // При сбое HTTP логирования, делаем системное логирование, но работу не останавливаем
+		}
 	}
 
 	// Проверим разрешенный метод
@@ -200,7 +725,7 @@ func (s *Service) process(method string, w http.ResponseWriter, r *http.Request,
 	if r.Method != method {
 		myerr = myerror.New("8000", "HTTP method is not allowed: reqID, request.Method, method", reqID, r.Method, method).PrintfInfo()
 		s.processError(myerr, w, http.StatusMethodNotAllowed, reqID) // расширенное логирование ошибки в контексте HTTP
-		return myerr
+		return ctx, cancel, reqID, myerr
 	}
 
 	// Если включен режим аутентификации без использования JWT токена, то проверять пользователя и пароль каждый раз
@@ -211,18 +736,20 @@ func (s *Service) process(method string, w http.ResponseWriter, r *http.Request,
 		// Считаем из заголовка HTTP Basic Authentication
 		username, password, ok := r.BasicAuth()
 		if !ok {
-			myerr := myerror.New("8004", "Header 'Authorization' is not set").PrintfInfo()
+			myerr = myerror.New("8004", "Header 'Authorization' is not set").PrintfInfo()
 			s.processError(myerr, w, http.StatusUnauthorized, reqID)
-			return myerr
+			return ctx, cancel, reqID, myerr
 		}
 		mylog.PrintfDebugMsg("Get Authorization header: username", username)
 
 		// Выполняем аутентификацию
-		if myerr = s.checkAuthentication(username, password); myerr != nil {
+		var status int
+		if status, myerr = s.authenticate(w, r, username, password); myerr != nil {
 			mylog.PrintfErrorInfo(myerr)
-			s.processError(myerr, w, http.StatusUnauthorized, reqID)
-			return myerr
+			s.processError(myerr, w, status, reqID)
+			return ctx, cancel, reqID, myerr
 		}
+		ctx = myctx.NewContextUsername(ctx, username)
 	}
 
 	// Если используем JWT - проверим токен
@@ -232,68 +759,56 @@ func (s *Service) process(method string, w http.ResponseWriter, r *http.Request,
 		// Считаем token из requests cookies
 		cookie, err := r.Cookie("token")
 		if err != nil {
-			myerr := myerror.WithCause("8005", "JWT token does not present in Cookie. You have to authorize first.", err).PrintfInfo()
+			myerr = myerror.WithCause("8005", "JWT token does not present in Cookie. You have to authorize first.", err).PrintfInfo()
 			s.processError(myerr, w, http.StatusUnauthorized, reqID) // расширенное логирование ошибки в контексте HTTP
-			return myerr
+			return ctx, cancel, reqID, myerr
 		}
 
 		// Проверим JWT в token
-		if myerr = myjwt.CheckJWTFromCookie(cookie, s.cfg.JwtKey); myerr != nil {
+		claims, err := myjwt.CheckJWT(cookie.Value, s.cfg.JwtKey)
+		if err != nil {
+			myerr = err
 			mylog.PrintfErrorInfo(myerr)
 			s.processError(myerr, w, http.StatusUnauthorized, reqID) // расширенное логирование ошибки в контексте HTTP
-			return myerr
+			return ctx, cancel, reqID, myerr
 		}
+		ctx = myctx.NewContextUsername(ctx, claims.Username)
 	}
 
-	// Считаем тело запроса
-	mylog.PrintfDebugMsg("Reading request body: reqID", reqID)
-	requestBuf, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		myerr = myerror.WithCause("8001", "Failed to read HTTP body: reqID", err, reqID).PrintfInfo()
-		s.processError(myerr, w, http.StatusInternalServerError, reqID) // расширенное логирование ошибки в контексте HTTP
-		return myerr
-	}
-	mylog.PrintfDebugMsg("Read request body: reqID, len(body)", reqID, len(requestBuf))
-
-	// Выделяем новый буфер из pool, он может использоваться для копирования JSON / XML
-	// Если буфер будет недостаточного размера, то он не будет использован
-	var buf []byte
-	if s.cfg.UseBufPool && s.bytesPool != nil {
-		buf = s.bytesPool.GetBuf()
-		mylog.PrintfDebugMsg("Got []byte buffer from pool: size", cap(buf))
-	}
-
-	// вызываем обработчик
-	mylog.PrintfDebugMsg("Calling external function handler: reqID, function", reqID, fn)
-	responseBuf, header, status, myerr := fn(ctx, requestBuf, buf)
-	if myerr != nil {
-		mylog.PrintfErrorInfo(myerr)
-		s.processError(myerr, w, status, reqID) // расширенное логирование ошибки в контексте HTTP
-		return myerr
-	}
-
-	// Если переданного буфера не хватило, то мог быть создан новый буфер. Вернем его в pool
-	if responseBuf != nil && buf != nil && s.cfg.UseBufPool && s.bytesPool != nil {
-		// Если новый буфер подходит по размерам для хранения в pool
-		if cap(responseBuf) >= s.cfg.BufPooledSize && cap(responseBuf) <= s.cfg.BufPooledMaxSize {
-			defer s.bytesPool.PutBuf(responseBuf)
-		}
+	return ctx, cancel, reqID, nil
+}
 
-		if reflect.ValueOf(buf).Pointer() != reflect.ValueOf(responseBuf).Pointer() {
-			mylog.PrintfInfoMsg("[]byte buffer: poolBufSize, responseBufSize", cap(buf), cap(responseBuf))
+// writeResponse - represent server common task in writing HTTP response
+func (s *Service) writeResponse(w http.ResponseWriter, r *http.Request, reqID uint64, header Header, status int, responseBuf []byte) (myerr error) {
+	// Сжимаем тело ответа, если клиент поддерживает сжатие
+	if len(responseBuf) > 0 {
+		if encoding := s.responseEncoding(r, header, len(responseBuf)); encoding != "" {
+			// Выделяем новый буфер из pool для сжатого ответа
+			var buf []byte
+			if s.cfg.UseBufPool && s.bytesPool != nil {
+				buf = s.bytesPool.GetBuf()
+				defer s.bytesPool.PutBuf(buf)
+			}
+			compressedBuf, err := s.compress.Compress(encoding, responseBuf, buf)
+			if err != nil {
+				myerr = myerror.WithCause("8002", "Failed to compress HTTP repsonse: reqID, Content-Encoding", err, reqID, encoding).PrintfInfo()
+				s.processError(myerr, w, http.StatusInternalServerError, reqID) // расширенное логирование ошибки в контексте HTTP
+				return myerr
+			}
+			mylog.PrintfDebugMsg("Compressed HTTP response body: reqID, Content-Encoding, len(body), len(compressed)", reqID, encoding, len(responseBuf), len(compressedBuf))
+			responseBuf = compressedBuf
 		}
 	}
 
-	// Логируем ответ в файл
-	if s.logger != nil {
-		_ = s.logger.LogHTTPOutResponse(ctx, header, responseBuf, status) // При сбое HTTP логирования, делаем системное логирование, но работу не останавливаем
-	}
-
 	// Записываем заголовок ответа
 	mylog.PrintfDebugMsg("Set HTTP response headers: reqID", reqID)
 	if header != nil {
 		for key, h := range header {
-			w.Header().Set(key, h)
+			if key == "Vary" {
+				w.Header().Add(key, h) // Vary мог быть установлен ранее, например CORS
+			} else {
+				w.Header().Set(key, h)
+			}
 		}
 	}
 
@@ -324,6 +839,21 @@ func (s *Service) process(method string, w http.ResponseWriter, r *http.Request,
 	return nil
 }
 
+// errorStatus - return HTTP status for catalogued error or its causes, otherwise default status
+func errorStatus(err error, status int) int {
+	for err != nil {
+		myerr, ok := err.(*myerror.Error)
+		if !ok {
+			break
+		}
+		if errStatus, ok := errorStatuses[myerr.Code]; ok {
+			return errStatus
+		}
+		err = myerr.CauseErr
+	}
+	return status
+}
+
 // processError - log error into header and body
 func (s *Service) processError(err error, w http.ResponseWriter, status int, reqID uint64) {
 
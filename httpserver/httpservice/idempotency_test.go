@@ -0,0 +1,76 @@
+package httpservice
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/romapres2010/httpserver/httpserver/idempotency"
+	myjwt "github.com/romapres2010/httpserver/jwt"
+)
+
+func TestProcessIdempotency(t *testing.T) {
+	jwtKey := []byte("secret")
+	s := &Service{
+		ctx:         context.Background(),
+		cfg:         &Config{UseJWT: true, JwtKey: jwtKey},
+		idempotency: idempotency.New(&idempotency.Config{TTL: time.Hour}),
+	}
+
+	calls := 0
+	create := func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error) {
+		calls++
+		return []byte(`{"id":1}`), Header{}, http.StatusOK, nil
+	}
+	panics := func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error) {
+		calls++
+		panic("handler failed")
+	}
+
+	post := func(username string, key string, contentType string, fn func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/depts", bytes.NewBufferString(`{"deptNumber":1}`))
+		r.Header.Set("Idempotency-Key", key)
+		r.Header.Set("Content-Type", contentType)
+		cookie, err := myjwt.CreateJWTCookie(&myjwt.Claims{Username: username}, 0, jwtKey)
+		if err != nil {
+			t.Fatalf("CreateJWTCookie() error = %v", err)
+		}
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		func() {
+			defer func() { _ = recover() }()
+			_ = s.process("POST", w, r, fn)
+		}()
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		username    string
+		key         string
+		contentType string
+		fn          func(ctx context.Context, requestBuf []byte, buf []byte) ([]byte, Header, int, error)
+		status      int
+		replayed    bool
+		calls       int
+	}{
+		{"first", "alice", "k1", "application/json", create, http.StatusOK, false, 1},
+		{"replay", "alice", "k1", "application/json", create, http.StatusOK, true, 1},
+		{"other user", "bob", "k1", "application/json", create, http.StatusOK, false, 2},
+		{"other Content-Type", "alice", "k1", "application/xml", create, http.StatusConflict, false, 2},
+		{"panic", "alice", "k2", "application/json", panics, http.StatusOK, false, 3},
+		{"retry after panic", "alice", "k2", "application/json", create, http.StatusOK, false, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(tt.username, tt.key, tt.contentType, tt.fn)
+			if w.Code != tt.status || (w.Header().Get("Idempotent-Replayed") == "true") != tt.replayed || calls != tt.calls {
+				t.Errorf("process() status = %v, replayed = %v, calls = %v, want %v, %v, %v",
+					w.Code, w.Header().Get("Idempotent-Replayed"), calls, tt.status, tt.replayed, tt.calls)
+			}
+		})
+	}
+}
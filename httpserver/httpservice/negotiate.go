@@ -0,0 +1,32 @@
+package httpservice
+
+import (
+	"net/http"
+
+	"github.com/romapres2010/httpserver/codec"
+	myerror "github.com/romapres2010/httpserver/error"
+)
+
+// Коды ошибок согласования формата
+const (
+	ErrCodeUnsupportedMediaType = "8032" // Content-Type запроса не поддерживается
+	ErrCodeNotAcceptable        = "8033" // ни один из форматов Accept не поддерживается
+)
+
+// requestCodec return codec for body of request by header Content-Type
+func requestCodec(r *http.Request, reqID uint64) (codec.Codec, int, error) {
+	c, ok := codec.ByContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		return nil, http.StatusUnsupportedMediaType, myerror.New(ErrCodeUnsupportedMediaType, "Unsupported Content-Type, only avaliable: 'application/json', 'application/xml', 'application/msgpack': reqID, Content-Type", reqID, r.Header.Get("Content-Type")).PrintfInfo(1)
+	}
+	return c, http.StatusOK, nil
+}
+
+// responseCodec return codec for body of response by header Accept
+func responseCodec(r *http.Request, reqID uint64) (codec.Codec, int, error) {
+	c, ok := codec.ByAccept(r.Header.Get("Accept"))
+	if !ok {
+		return nil, http.StatusNotAcceptable, myerror.New(ErrCodeNotAcceptable, "Not acceptable, only avaliable: 'application/json', 'application/xml', 'application/msgpack': reqID, Accept", reqID, r.Header.Get("Accept")).PrintfInfo(1)
+	}
+	return c, http.StatusOK, nil
+}
@@ -0,0 +1,116 @@
+package httpservice
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/httpserver/ratelimit"
+	myjwt "github.com/romapres2010/httpserver/jwt"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Коды ошибок ограничения частоты запросов
+const (
+	ErrCodeTooManyRequests = "8037" // превышена частота запросов
+	ErrCodeAuthLocked      = "8038" // пользователь временно заблокирован после неудачных попыток аутентификации
+)
+
+// limitWrap cover handler function with rate limiter, rejected request gets 429 Too Many Requests
+func (s *Service) limitWrap(limiter *ratelimit.Limiter, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := s.clientKey(r, limiter.KeyBy())
+		res := limiter.Allow(key)
+
+		// заголовки RateLimit-* передаются в каждом ответе
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", seconds(res.Reset))
+
+		if !res.Allowed {
+			reqID := GetNextRequestID()
+			w.Header().Set("Retry-After", seconds(res.RetryAfter))
+			myerr := myerror.New(ErrCodeTooManyRequests, "Too many requests: reqID, key, path, RetryAfter", reqID, key, r.URL.Path, res.RetryAfter).PrintfInfo()
+			s.processError(myerr, w, http.StatusTooManyRequests, reqID) // расширенное логирование ошибки в контексте HTTP
+			return
+		}
+
+		handlerFunc(w, r)
+	})
+}
+
+// clientKey return key of client for rate limiter.
+// Пользователь определяется только по проверенному JWT, иначе подбором имен можно получить неограниченное число корзин
+func (s *Service) clientKey(r *http.Request, keyBy string) string {
+	switch keyBy {
+	case ratelimit.KeyByUser:
+		if s.cfg.UseJWT {
+			if cookie, err := r.Cookie("token"); err == nil {
+				if claims, err := myjwt.CheckJWT(cookie.Value, s.cfg.JwtKey); err == nil && claims.Username != "" {
+					return "user:" + claims.Username
+				}
+			}
+		}
+	case ratelimit.KeyByAPIKey:
+		if apiKey := r.Header.Get(s.cfg.RateLimitAPIKeyHeader); apiKey != "" {
+			return "key:" + apiKey
+		}
+	}
+	return "ip:" + remoteIP(r)
+}
+
+// remoteIP return IP address of client without port
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds format duration as integer seconds rounded up for Retry-After and RateLimit-Reset headers
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// authenticate check user and password with temporary lockout after repeated failures.
+// Блокируется пара пользователь и IP адрес клиента - подбор пароля с одного адреса не блокирует пользователя на других адресах
+func (s *Service) authenticate(w http.ResponseWriter, r *http.Request, username, password string) (int, error) {
+	key := lockoutKey(username, remoteIP(r))
+
+	if s.lockout != nil {
+		if remaining, locked := s.lockout.Locked(key); locked {
+			w.Header().Set("Retry-After", seconds(remaining))
+			return http.StatusTooManyRequests, myerror.New(ErrCodeAuthLocked, "User is temporarily locked after failed authentication attempts: username, ip, remaining", username, remoteIP(r), remaining).PrintfInfo()
+		}
+	}
+
+	if err := s.checkAuthentication(username, password); err != nil {
+		// блокировку вызывают только неверные пароли, но не ошибки подключения к MS AD
+		if myerr, ok := err.(*myerror.Error); ok && myerr.Code == "8010" && s.lockout != nil {
+			if s.lockout.Failure(key) {
+				mylog.PrintfInfoMsg("User is locked after failed authentication attempts: username, ip", username, remoteIP(r))
+			}
+		}
+		return http.StatusUnauthorized, err
+	}
+
+	if s.lockout != nil {
+		s.lockout.Success(key)
+	}
+	return http.StatusOK, nil
+}
+
+// lockoutKey return key of lockout for user and IP address of client
+func lockoutKey(username string, ip string) string {
+	return username + "@" + ip
+}
+
+// RouteRateLimit represent rate limit policy of route
+type RouteRateLimit struct {
+	Requests int // количество запросов за период
+	Period   int // период в секундах
+	Burst    int // максимальное количество запросов подряд
+}
@@ -0,0 +1,119 @@
+package httpservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/romapres2010/httpserver/httpserver/ratelimit"
+	myjson "github.com/romapres2010/httpserver/json"
+)
+
+func TestLimitWrap(t *testing.T) {
+	s := &Service{cfg: &Config{RateLimitAPIKeyHeader: "X-API-Key"}}
+	limiter := ratelimit.New(&ratelimit.Config{Requests: 1, Period: time.Minute, Burst: 2, KeyBy: ratelimit.KeyByAPIKey})
+	handler := s.limitWrap(limiter, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/depts/10", nil)
+		if apiKey != "" {
+			r.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := request("client-1")
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != wantRemaining || w.Header().Get("RateLimit-Limit") != "2" {
+			t.Fatalf("request #%v = %v, headers %v", i, w.Code, w.Header())
+		}
+	}
+
+	w := request("client-1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("request over limit = %v, Retry-After %q, want 429, 60", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// другой API key и запрос без ключа (по IP) ограничиваются отдельно
+	if w = request("client-2"); w.Code != http.StatusOK {
+		t.Errorf("request with other API key = %v, want 200", w.Code)
+	}
+	if w = request(""); w.Code != http.StatusOK {
+		t.Errorf("request without API key = %v, want 200", w.Code)
+	}
+}
+
+func TestAuthenticateLockout(t *testing.T) {
+	s := &Service{
+		cfg:     &Config{AuthType: "INTERNAL", HTTPUserID: "user", HTTPUserPwd: "pwd"},
+		lockout: ratelimit.NewLockout(&ratelimit.LockoutConfig{MaxFailures: 2, Window: time.Minute, Duration: time.Minute}),
+	}
+	request := func(ip string) *http.Request {
+		r := httptest.NewRequest("POST", "/signin", nil)
+		r.RemoteAddr = ip + ":1234"
+		return r
+	}
+
+	for i := 0; i < 2; i++ {
+		if status, err := s.authenticate(httptest.NewRecorder(), request("10.0.0.1"), "user", "wrong"); status != http.StatusUnauthorized || err == nil {
+			t.Fatalf("authenticate() #%v = %v, %v, want 401", i, status, err)
+		}
+	}
+
+	// верный пароль во время блокировки не принимается
+	w := httptest.NewRecorder()
+	if status, err := s.authenticate(w, request("10.0.0.1"), "user", "pwd"); status != http.StatusTooManyRequests || err == nil || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("authenticate() of locked user = %v, %v, Retry-After %q, want 429", status, err, w.Header().Get("Retry-After"))
+	}
+
+	// с другого адреса пользователь не заблокирован
+	if status, err := s.authenticate(httptest.NewRecorder(), request("10.0.0.2"), "user", "pwd"); status != http.StatusOK || err != nil {
+		t.Errorf("authenticate() from other IP = %v, %v, want 200", status, err)
+	}
+}
+
+func TestNewRouteRateLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		routeLimit map[string]RouteRateLimit
+		wantErr    bool
+		wantBurst  string
+	}{
+		{"default policy", nil, false, "100"},
+		{"route policy", map[string]RouteRateLimit{"ExportDeptsHandler": {Requests: 1, Period: 60, Burst: 2}}, false, "2"},
+		{"unknown handler", map[string]RouteRateLimit{"ExportHandler": {Requests: 1, Period: 60, Burst: 2}}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				UseRateLimit:      true,
+				RateLimitKeyBy:    ratelimit.KeyByIP,
+				RateLimitRequests: 100,
+				RateLimitPeriod:   1,
+				RateLimitBurst:    100,
+				RouteRateLimits:   tt.routeLimit,
+			}
+			s, _, err := New(context.Background(), cfg, &myjson.Service{}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer s.Shutdown()
+
+			// заголовки RateLimit-* ответа соответствуют политике маршрута
+			w := httptest.NewRecorder()
+			s.Handlers["ExportDeptsHandler"].HundlerFunc(w, httptest.NewRequest("GET", "/depts:export", nil))
+			if got := w.Header().Get("RateLimit-Limit"); got != tt.wantBurst {
+				t.Errorf("RateLimit-Limit = %q, want %q", got, tt.wantBurst)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package httpservice
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/json"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// ErrCodeUnknownVersion represent error of API version in Accept which is not supported
+const ErrCodeUnknownVersion = "8042"
+
+// apiVersion represent version of API
+type apiVersion struct {
+	name        string        // имя версии, она же префикс пути: v1, v2
+	jsonService *json.Service // JSON сервис с кодированием объектов версии
+	marshalers  *json.Marshalers
+	deprecated  bool      // версия устарела - в ответ добавляются заголовки Deprecation, Sunset, Link
+	sunset      time.Time // дата прекращения поддержки версии
+}
+
+// versionedHandlers represent handlers of versioned route sets, true - body is encoded by marshalers of version
+var versionedHandlers = map[string]bool{
+	"CreateDeptHandler":  true,
+	"GetDeptHandler":     true,
+	"UpdateDeptHandler":  true,
+	"PatchDeptHandler":   true,
+	"PatchEmpHandler":    true,
+	"BatchDeptsHandler":  true,
+	"BatchEmpsHandler":   true,
+	"ImportDeptsHandler": false,
+	"ImportEmpsHandler":  false,
+	"ExportDeptsHandler": false,
+	"ExportEmpsHandler":  false,
+}
+
+// applyVersions create API versions and cover versioned handlers with selection of version
+func (s *Service) applyVersions() error {
+	s.versions = make(map[string]*apiVersion, len(s.cfg.APIVersions))
+	for _, name := range s.cfg.APIVersions {
+		m, ok := json.Versions[name]
+		if !ok {
+			return myerror.New("6031", "Unknown API version: name", name).PrintfInfo()
+		}
+		s.versions[name] = &apiVersion{
+			name:        name,
+			jsonService: s.jsonService.WithMarshalers(m),
+			marshalers:  m,
+			sunset:      s.cfg.VersionSunset[name],
+		}
+	}
+	if _, ok := s.versions[s.cfg.DefaultVersion]; !ok {
+		return myerror.New("6031", "Default API version is not in list of versions: DefaultVersion", s.cfg.DefaultVersion).PrintfInfo()
+	}
+	for _, name := range s.cfg.DeprecatedVersions {
+		v, ok := s.versions[name]
+		if !ok {
+			return myerror.New("6031", "Unknown deprecated API version: name", name).PrintfInfo()
+		}
+		v.deprecated = true
+	}
+	s.latestVersion = s.cfg.APIVersions[len(s.cfg.APIVersions)-1]
+
+	s.versionedPaths = make(map[string]bool)
+	for name, h := range s.Handlers {
+		if _, ok := versionedHandlers[name]; ok {
+			h.HundlerFunc = s.versionWrap(h.HundlerFunc)
+			s.Handlers[name] = h
+			s.versionedPaths[h.Path] = true
+		}
+	}
+	return nil
+}
+
+// versionWrap cover handler function with selection of API version
+func (s *Service) versionWrap(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, prefixed, ok := s.requestVersion(r)
+		if !ok {
+			reqID := GetNextRequestID()
+			myerr := myerror.New(ErrCodeUnknownVersion, "Unknown API version in Accept: reqID, Accept, versions", reqID, r.Header.Get("Accept"), s.cfg.APIVersions).PrintfInfo()
+			s.processError(myerr, w, http.StatusNotAcceptable, reqID) // расширенное логирование ошибки в контексте HTTP
+			return
+		}
+		v := s.versions[name]
+		mylog.PrintfDebugMsg("API version of request: path, version", r.URL.Path, name)
+
+		w.Header().Set("API-Version", name)
+		if !prefixed {
+			w.Header().Add("Vary", "Accept") // версия выбрана по заголовку Accept
+		}
+		if v.deprecated {
+			w.Header().Set("Deprecation", "true")
+			if !v.sunset.IsZero() {
+				w.Header().Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+			}
+			if s.latestVersion != name {
+				path := r.URL.Path
+				if prefixed {
+					path = strings.TrimPrefix(path, "/"+name)
+				}
+				w.Header().Set("Link", "</"+s.latestVersion+path+`>; rel="successor-version"`)
+			}
+		}
+
+		handlerFunc(w, r.WithContext(myctx.NewContextAPIVersion(r.Context(), name)))
+	})
+}
+
+// requestVersion return version of request by path prefix, parameter version of Accept or default version.
+// ok is false, if version in Accept is not supported
+func (s *Service) requestVersion(r *http.Request) (name string, prefixed bool, ok bool) {
+	// версия в префиксе пути: /v2/depts/10
+	for name := range s.versions {
+		if strings.HasPrefix(r.URL.Path, "/"+name+"/") {
+			return name, true, true
+		}
+	}
+
+	// версия в параметре Accept: application/json; version=2
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if _, params, err := mime.ParseMediaType(part); err == nil {
+			if version, exists := params["version"]; exists {
+				name = "v" + strings.TrimPrefix(strings.ToLower(version), "v")
+				_, ok = s.versions[name]
+				return name, false, ok
+			}
+		}
+	}
+
+	return s.cfg.DefaultVersion, false, true
+}
+
+// jsonServiceOf return JSON service of API version of request
+func (s *Service) jsonServiceOf(r *http.Request) *json.Service {
+	if v, ok := s.versions[myctx.FromContextAPIVersion(r.Context())]; ok {
+		return v.jsonService
+	}
+	return s.jsonService
+}
+
+// Paths return paths of route: path itself and path under prefix of each API version for versioned routes
+func (s *Service) Paths(path string) []string {
+	paths := []string{path}
+	if s.versionedPaths[path] {
+		for _, name := range s.cfg.APIVersions {
+			paths = append(paths, "/"+name+path)
+		}
+	}
+	return paths
+}
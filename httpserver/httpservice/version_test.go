@@ -0,0 +1,86 @@
+package httpservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myjson "github.com/romapres2010/httpserver/json"
+)
+
+func TestVersionWrap(t *testing.T) {
+	s, _, err := New(context.Background(), &Config{
+		UseVersioning:      true,
+		APIVersions:        []string{"v1", "v2"},
+		DefaultVersion:     "v1",
+		DeprecatedVersions: []string{"v1"},
+		VersionSunset:      map[string]time.Time{"v1": time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}, &myjson.Service{}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer s.Shutdown()
+
+	var version string
+	handler := s.versionWrap(func(w http.ResponseWriter, r *http.Request) {
+		version = myctx.FromContextAPIVersion(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		path       string
+		accept     string
+		status     int
+		version    string
+		deprecated bool
+		link       string
+	}{
+		{"/depts/10", "", http.StatusOK, "v1", true, `</v2/depts/10>; rel="successor-version"`},
+		{"/v1/depts/10", "", http.StatusOK, "v1", true, `</v2/depts/10>; rel="successor-version"`},
+		{"/v2/depts/10", "application/json; version=1", http.StatusOK, "v2", false, ""},
+		{"/depts/10", "application/json; version=2", http.StatusOK, "v2", false, ""},
+		{"/depts/10", "application/xml;version=v2, application/json", http.StatusOK, "v2", false, ""},
+		{"/depts/10", "application/json; version=3", http.StatusNotAcceptable, "", false, ""},
+	}
+	for _, tt := range tests {
+		version = ""
+		r := httptest.NewRequest("GET", tt.path, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		if w.Code != tt.status || version != tt.version {
+			t.Errorf("%v %q: status %v, version %q, want %v, %q", tt.path, tt.accept, w.Code, version, tt.status, tt.version)
+			continue
+		}
+		if got := w.Header().Get("Deprecation") == "true"; got != tt.deprecated {
+			t.Errorf("%v %q: Deprecation = %v, want %v", tt.path, tt.accept, got, tt.deprecated)
+		}
+		if tt.deprecated && w.Header().Get("Sunset") != "Fri, 31 Dec 2027 00:00:00 GMT" {
+			t.Errorf("%v %q: Sunset = %q", tt.path, tt.accept, w.Header().Get("Sunset"))
+		}
+		if got := w.Header().Get("Link"); got != tt.link {
+			t.Errorf("%v %q: Link = %q, want %q", tt.path, tt.accept, got, tt.link)
+		}
+	}
+
+	if got := s.Paths("/depts/{id:[0-9]+}"); !reflect.DeepEqual(got, []string{"/depts/{id:[0-9]+}", "/v1/depts/{id:[0-9]+}", "/v2/depts/{id:[0-9]+}"}) {
+		t.Errorf("Paths() of versioned route = %v", got)
+	}
+	if got := s.Paths("/echo"); !reflect.DeepEqual(got, []string{"/echo"}) {
+		t.Errorf("Paths() of not versioned route = %v", got)
+	}
+}
+
+func TestUnknownVersion(t *testing.T) {
+	_, _, err := New(context.Background(), &Config{UseVersioning: true, APIVersions: []string{"v1", "v9"}, DefaultVersion: "v1"}, &myjson.Service{}, nil)
+	if err == nil {
+		t.Errorf("New() with unknown version error = nil")
+	}
+}
@@ -0,0 +1,173 @@
+package idempotency
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Хранилище ключей идемпотентности в памяти:
+//     первый запрос с ключом резервирует его, после обработки сохраняется ответ
+//     повторный запрос с тем же ключом и телом получает сохраненный ответ
+//     повторный запрос с тем же ключом и другим телом или во время обработки первого - ошибка
+// Ключ хранится TTL, при превышении MaxKeys вытесняются самые старые ключи завершенных запросов.
+
+// Коды ошибок повторного использования ключа
+const (
+	ErrCodeMismatch   = "8030" // ключ использован с другим запросом
+	ErrCodeInProgress = "8031" // запрос с ключом еще обрабатывается
+	ErrCodeFull       = "8045" // хранилище заполнено ключами обрабатываемых запросов
+)
+
+// Config represent idempotency store configuration
+type Config struct {
+	TTL     time.Duration // время хранения ключа
+	MaxKeys int           // максимальное количество хранимых ключей, 0 - без ограничения
+}
+
+// Response represent stored HTTP response
+type Response struct {
+	Status int               // HTTP статус ответа
+	Header map[string]string // заголовки ответа
+	Body   []byte            // тело ответа
+}
+
+// entry represent stored key
+type entry struct {
+	key         string
+	fingerprint string        // отпечаток запроса
+	expires     time.Time     // время окончания хранения
+	resp        *Response     // сохраненный ответ, nil - запрос обрабатывается
+	elem        *list.Element // элемент очереди вытеснения
+}
+
+// Store represent bounded in-memory idempotency store
+type Store struct {
+	cfg     *Config
+	mx      sync.Mutex
+	entries map[string]*entry
+	order   *list.List // ключи в порядке резервирования - для удаления устаревших и вытеснения
+}
+
+// New create idempotency store
+func New(cfg *Config) *Store {
+	return &Store{
+		cfg:     cfg,
+		entries: make(map[string]*entry),
+		order:   list.New(),
+	}
+}
+
+// Fingerprint calculate sha256 of request parts
+func Fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		_, _ = h.Write(part)
+		_, _ = h.Write([]byte{0}) // разделитель частей
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin reserve key for request. It returns stored response for duplicate request,
+// nil if key is reserved and request should be processed, error if key is used by other or not finished request.
+func (s *Store) Begin(key string, fingerprint string) (*Response, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	s.purge(now)
+
+	if e, ok := s.entries[key]; ok {
+		if e.fingerprint != fingerprint {
+			return nil, myerror.New(ErrCodeMismatch, "Idempotency key is already used with different request: key", key).PrintfInfo()
+		}
+		if e.resp == nil {
+			return nil, myerror.New(ErrCodeInProgress, "Request with idempotency key is in progress: key", key).PrintfInfo()
+		}
+		mylog.PrintfDebugMsg("Replay stored response: key", key)
+		return e.resp, nil
+	}
+
+	// вытесним самые старые ключи, ключи обрабатываемых запросов не вытесняются - иначе повтор будет обработан дважды
+	for s.cfg.MaxKeys > 0 && len(s.entries) >= s.cfg.MaxKeys {
+		e := s.oldestCompleted()
+		if e == nil {
+			return nil, myerror.New(ErrCodeFull, "Idempotency store is full of requests in progress: key, MaxKeys", key, s.cfg.MaxKeys).PrintfInfo()
+		}
+		s.remove(e)
+	}
+
+	e := &entry{key: key, fingerprint: fingerprint, expires: now.Add(s.cfg.TTL)}
+	e.elem = s.order.PushBack(e)
+	s.entries[key] = e
+	return nil, nil
+}
+
+// Complete store response for reserved key, body and header are copied
+func (s *Store) Complete(key string, status int, header map[string]string, body []byte) {
+	resp := &Response{
+		Status: status,
+		Header: make(map[string]string, len(header)),
+		Body:   append([]byte(nil), body...),
+	}
+	for k, v := range header {
+		resp.Header[k] = v
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	// ключ мог быть вытеснен во время обработки
+	if e, ok := s.entries[key]; ok {
+		e.resp = resp
+	}
+}
+
+// Cancel release reserved key, so request can be retried
+func (s *Store) Cancel(key string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.resp == nil {
+		s.remove(e)
+	}
+}
+
+// Len return count of stored keys
+func (s *Store) Len() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return len(s.entries)
+}
+
+// purge remove expired keys, TTL is the same for all keys, so they expire in order of reservation
+func (s *Store) purge(now time.Time) {
+	for elem := s.order.Front(); elem != nil; elem = s.order.Front() {
+		e := elem.Value.(*entry)
+		if e.expires.After(now) {
+			return
+		}
+		s.remove(e)
+	}
+}
+
+// oldestCompleted return the oldest key with stored response, nil - all keys are in progress
+func (s *Store) oldestCompleted() *entry {
+	for elem := s.order.Front(); elem != nil; elem = elem.Next() {
+		if e := elem.Value.(*entry); e.resp != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// remove delete key from store
+func (s *Store) remove(e *entry) {
+	s.order.Remove(e.elem)
+	delete(s.entries, e.key)
+}
@@ -0,0 +1,85 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	myerror "github.com/romapres2010/httpserver/error"
+)
+
+func errCode(err error) string {
+	if myerr, ok := err.(*myerror.Error); ok {
+		return myerr.Code
+	}
+	return ""
+}
+
+func TestStore(t *testing.T) {
+	s := New(&Config{TTL: time.Hour, MaxKeys: 2})
+	fp := Fingerprint([]byte("POST"), []byte("/depts"), []byte(`{"deptNumber":1}`))
+
+	// первый запрос резервирует ключ
+	if resp, err := s.Begin("k1", fp); resp != nil || err != nil {
+		t.Fatalf("Begin() = %v, %v, want reservation", resp, err)
+	}
+
+	// повтор во время обработки
+	if _, err := s.Begin("k1", fp); errCode(err) != ErrCodeInProgress {
+		t.Errorf("Begin() in progress error = %v, want %s", err, ErrCodeInProgress)
+	}
+
+	// повтор после обработки получает сохраненный ответ
+	body := []byte(`{"deptNumber":1}`)
+	s.Complete("k1", 200, map[string]string{"Id": "1"}, body)
+	body[0] = 'x' // ответ скопирован
+	resp, err := s.Begin("k1", fp)
+	if err != nil || resp == nil || resp.Status != 200 || resp.Header["Id"] != "1" || string(resp.Body) != `{"deptNumber":1}` {
+		t.Errorf("Begin() replay = %+v, %v", resp, err)
+	}
+
+	// тот же ключ с другим запросом
+	if _, err := s.Begin("k1", Fingerprint([]byte("other"))); errCode(err) != ErrCodeMismatch {
+		t.Errorf("Begin() mismatch error = %v, want %s", err, ErrCodeMismatch)
+	}
+
+	// отмена освобождает ключ
+	_, _ = s.Begin("k2", fp)
+	s.Cancel("k2")
+	if resp, err := s.Begin("k2", fp); resp != nil || err != nil {
+		t.Errorf("Begin() after Cancel = %v, %v, want reservation", resp, err)
+	}
+
+	// вытеснение самого старого ключа
+	_, _ = s.Begin("k3", fp)
+	if s.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", s.Len())
+	}
+	s.Complete("k2", 200, nil, nil)
+	if resp, err := s.Begin("k1", fp); resp != nil || err != nil {
+		t.Errorf("Begin() for evicted key = %v, %v, want reservation", resp, err)
+	}
+
+	// ключи обрабатываемых запросов не вытесняются
+	if _, err := s.Begin("k4", fp); errCode(err) != ErrCodeFull {
+		t.Errorf("Begin() with keys in progress error = %v, want %s", err, ErrCodeFull)
+	}
+	if _, err := s.Begin("k3", fp); errCode(err) != ErrCodeInProgress {
+		t.Errorf("Begin() for key in progress error = %v, want %s", err, ErrCodeInProgress)
+	}
+}
+
+func TestStoreTTL(t *testing.T) {
+	s := New(&Config{TTL: time.Millisecond})
+	fp := Fingerprint([]byte("body"))
+
+	_, _ = s.Begin("k1", fp)
+	s.Complete("k1", 200, nil, nil)
+	time.Sleep(5 * time.Millisecond)
+
+	if resp, err := s.Begin("k1", fp); resp != nil || err != nil {
+		t.Errorf("Begin() for expired key = %v, %v, want reservation", resp, err)
+	}
+	if s.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", s.Len())
+	}
+}
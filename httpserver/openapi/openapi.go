@@ -0,0 +1,224 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Генерация документа OpenAPI 3 по зарегистрированным маршрутам и аннотациям обработчиков.
+// Схемы тел запросов и ответов строятся по структурам моделей: имена полей из тегов json, обязательность и ограничения из тегов validate.
+
+// Version represent version of OpenAPI specification
+const Version = "3.0.3"
+
+// Document represent OpenAPI document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"` // путь - метод - операция
+	Components Components                       `json:"components"`
+}
+
+// Info represent metadata of API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Components represent reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme represent authentication method
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Operation represent one method of path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter represent path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query, header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody represent body of request
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response represent response with status
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType represent schema of body in media type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Annotation represent documentation of handler
+type Annotation struct {
+	Summary       string       // краткое описание операции
+	Tags          []string     // группы операций
+	Params        []*Parameter // query и header параметры, параметры пути определяются автоматически
+	Request       interface{}  // модель тела запроса, nil - запрос без тела
+	RequestTypes  []string     // типы тела запроса
+	Status        int          // статус успешного ответа, по умолчанию 200
+	Response      interface{}  // модель тела ответа, nil - ответ без тела
+	ResponseTypes []string     // типы тела ответа
+	Errors        []int        // статусы ошибок
+	Auth          bool         // требуется аутентификация
+}
+
+// Route represent registered handler
+type Route struct {
+	Name   string      // имя обработчика - operationId
+	Path   string      // путь в формате gorilla/mux
+	Method string      // HTTP метод
+	Doc    *Annotation // документация обработчика
+
+	Deprecated bool // маршрут устаревшей версии API
+}
+
+// pathParam represent path parameter of gorilla/mux: {name} or {name:pattern}
+var pathParam = regexp.MustCompile(`\{([^}:]+)(?::([^}]+))?\}`)
+
+// Generate build OpenAPI document, security is used for operations with authentication
+func Generate(info Info, routes []Route, securitySchemes map[string]*SecurityScheme, security []map[string][]string) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: securitySchemes,
+		},
+	}
+
+	for _, route := range routes {
+		path, params := convertPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = doc.operation(route, params, security)
+	}
+	return doc
+}
+
+// Marshal return JSON representation of document
+func (doc *Document) Marshal() ([]byte, error) {
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// operation build operation of route
+func (doc *Document) operation(route Route, params []*Parameter, security []map[string][]string) *Operation {
+	op := &Operation{
+		OperationID: route.Name,
+		Parameters:  params,
+		Responses:   make(map[string]*Response),
+		Deprecated:  route.Deprecated,
+	}
+
+	a := route.Doc
+	if a == nil {
+		a = &Annotation{}
+	}
+	op.Summary = a.Summary
+	op.Tags = a.Tags
+	op.Parameters = append(op.Parameters, a.Params...)
+
+	if a.Request != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: doc.content(a.Request, a.RequestTypes)}
+	}
+
+	status := a.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := &Response{Description: http.StatusText(status)}
+	if a.Response != nil {
+		resp.Content = doc.content(a.Response, a.ResponseTypes)
+	}
+	op.Responses[strconv.Itoa(status)] = resp
+
+	errors := a.Errors
+	if a.Auth && len(security) > 0 {
+		op.Security = security
+		errors = append(errors, http.StatusUnauthorized)
+	}
+	for _, code := range errors {
+		op.Responses[strconv.Itoa(code)] = &Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*MediaType{"text/plain": {Schema: &Schema{Type: "string"}}},
+		}
+	}
+	return op
+}
+
+// content build schema of body for each media type
+func (doc *Document) content(model interface{}, mediaTypes []string) map[string]*MediaType {
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{"application/json"}
+	}
+	schema := doc.SchemaOf(model)
+	content := make(map[string]*MediaType, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		content[mediaType] = &MediaType{Schema: schema}
+	}
+	return content
+}
+
+// convertPath convert gorilla/mux path into OpenAPI path and path parameters
+func convertPath(muxPath string) (string, []*Parameter) {
+	var params []*Parameter
+	path := pathParam.ReplaceAllStringFunc(muxPath, func(m string) string {
+		sub := pathParam.FindStringSubmatch(m)
+		schema := &Schema{Type: "string"}
+		if sub[2] == "[0-9]+" {
+			schema = &Schema{Type: "integer"}
+		} else if sub[2] != "" {
+			schema.Pattern = "^" + sub[2] + "$"
+		}
+		params = append(params, &Parameter{Name: sub[1], In: "path", Required: true, Schema: schema})
+		return "{" + sub[1] + "}"
+	})
+	return path, params
+}
+
+// Undocumented return sorted names of routes without documentation
+func Undocumented(routes []Route) []string {
+	var names []string
+	for _, route := range routes {
+		if route.Doc == nil {
+			names = append(names, route.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,68 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/romapres2010/httpserver/model"
+)
+
+func TestSchemaOf(t *testing.T) {
+	doc := Generate(Info{}, nil, nil, nil)
+
+	schema := doc.SchemaOf([]*model.Dept{})
+	if schema.Type != "array" || schema.Items.Ref != "#/components/schemas/Dept" {
+		t.Fatalf("SchemaOf([]*Dept) = %+v", schema)
+	}
+
+	dept := doc.Components.Schemas["Dept"]
+	if dept == nil {
+		t.Fatalf("Dept is not registered in components")
+	}
+	if !reflect.DeepEqual(dept.Required, []string{"deptNumber", "deptName"}) {
+		t.Errorf("Dept required = %v", dept.Required)
+	}
+	if loc := dept.Properties["deptLocation"]; loc == nil || loc.Type != "string" || !loc.Nullable {
+		t.Errorf("Dept deptLocation = %+v, want nullable string", loc)
+	}
+	if emps := dept.Properties["emps"]; emps == nil || emps.Items == nil || emps.Items.Ref != "#/components/schemas/Emp" {
+		t.Errorf("Dept emps = %+v, want array of Emp", emps)
+	}
+
+	emp := doc.Components.Schemas["Emp"]
+	if emp == nil {
+		t.Fatalf("Emp is not registered in components")
+	}
+	if sal := emp.Properties["sal"]; sal == nil || sal.Type != "integer" || !sal.Nullable || sal.Minimum == nil || *sal.Minimum != 0 {
+		t.Errorf("Emp sal = %+v, want nullable integer with minimum 0", sal)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	routes := []Route{
+		{Name: "GetDeptHandler", Path: "/depts/{id:[0-9]+}", Method: "GET", Doc: &Annotation{Response: model.Dept{}, Errors: []int{404}, Auth: true}},
+		{Name: "EchoHandler", Path: "/echo", Method: "POST"},
+	}
+	security := []map[string][]string{{"basicAuth": {}}}
+	doc := Generate(Info{Title: "test", Version: "1"}, routes, map[string]*SecurityScheme{"basicAuth": {Type: "http", Scheme: "basic"}}, security)
+
+	op := doc.Paths["/depts/{id}"]["get"]
+	if op == nil {
+		t.Fatalf("paths = %v, want /depts/{id}", doc.Paths)
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "id" || op.Parameters[0].In != "path" || op.Parameters[0].Schema.Type != "integer" {
+		t.Errorf("parameters = %+v, want integer path parameter id", op.Parameters)
+	}
+	for _, code := range []string{"200", "404", "401"} {
+		if op.Responses[code] == nil {
+			t.Errorf("response %v is missing", code)
+		}
+	}
+	if !reflect.DeepEqual(op.Security, security) {
+		t.Errorf("security = %v", op.Security)
+	}
+
+	if names := Undocumented(routes); !reflect.DeepEqual(names, []string{"EchoHandler"}) {
+		t.Errorf("Undocumented() = %v", names)
+	}
+}
@@ -0,0 +1,124 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// Schema represent JSON schema of OpenAPI
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// схемы типов, которые не выводятся из структуры
+var (
+	nullStringType = reflect.TypeOf(null.String{})
+	nullIntType    = reflect.TypeOf(null.Int{})
+)
+
+// SchemaOf return schema of model, Schema is returned as is, named structs are registered in components
+func (doc *Document) SchemaOf(model interface{}) *Schema {
+	if schema, ok := model.(*Schema); ok {
+		return schema
+	}
+	return doc.schemaOfType(reflect.TypeOf(model))
+}
+
+// schemaOfType return schema of type
+func (doc *Document) schemaOfType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case nullStringType:
+		return &Schema{Type: "string", Nullable: true}
+	case nullIntType:
+		return &Schema{Type: "integer", Format: "int64", Nullable: true}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: doc.schemaOfType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: doc.schemaOfType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return doc.structSchema(t)
+		}
+		// именованная структура описывается в components один раз
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			doc.Components.Schemas[t.Name()] = &Schema{} // защита от рекурсии
+			doc.Components.Schemas[t.Name()] = doc.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema return schema of struct fields by json and validate tags
+func (doc *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" { // неэкспортируемое поле
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := doc.schemaOfType(field.Type)
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			parts := strings.SplitN(rule, "=", 2)
+			switch parts[0] {
+			case "required":
+				schema.Required = append(schema.Required, name)
+			case "gte", "min":
+				if len(parts) == 2 {
+					if value, err := strconv.ParseFloat(parts[1], 64); err == nil {
+						fieldSchema.Minimum = &value
+					}
+				}
+			case "lte", "max":
+				if len(parts) == 2 {
+					if value, err := strconv.ParseFloat(parts[1], 64); err == nil {
+						fieldSchema.Maximum = &value
+					}
+				}
+			}
+		}
+		schema.Properties[name] = fieldSchema
+	}
+	return schema
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Временная блокировка после MaxFailures неудачных попыток аутентификации в течение Window.
+// Блокировка действует Duration, успешная аутентификация сбрасывает счетчик.
+// При превышении MaxKeys удаляются истекшие ключи, затем ключи с самой давней неудачной попыткой.
+
+// LockoutConfig represent lockout policy
+type LockoutConfig struct {
+	MaxFailures int           // количество неудачных попыток до блокировки
+	Window      time.Duration // период подсчета неудачных попыток
+	Duration    time.Duration // время блокировки
+	MaxKeys     int           // максимальное количество хранимых ключей, 0 - без ограничения
+}
+
+// failures represent failed attempts of key
+type failures struct {
+	key         string
+	count       int           // количество неудачных попыток в текущем периоде
+	windowStart time.Time     // начало периода подсчета
+	lockedUntil time.Time     // окончание блокировки
+	elem        *list.Element // элемент очереди вытеснения
+}
+
+// Lockout represent bounded in-memory registry of failed attempts
+type Lockout struct {
+	cfg   *LockoutConfig
+	mx    sync.Mutex
+	keys  map[string]*failures
+	order *list.List // ключи в порядке последней неудачной попытки - для вытеснения
+	now   func() time.Time
+}
+
+// NewLockout create lockout registry
+func NewLockout(cfg *LockoutConfig) *Lockout {
+	return &Lockout{
+		cfg:   cfg,
+		keys:  make(map[string]*failures),
+		order: list.New(),
+		now:   time.Now,
+	}
+}
+
+// Locked return remaining time of lockout of key
+func (l *Lockout) Locked(key string) (time.Duration, bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if f, ok := l.keys[key]; ok {
+		if remaining := f.lockedUntil.Sub(l.now()); remaining > 0 {
+			return remaining, true
+		}
+	}
+	return 0, false
+}
+
+// Failure register failed attempt of key, it returns true if key is locked
+func (l *Lockout) Failure(key string) bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := l.now()
+	f, ok := l.keys[key]
+	if !ok {
+		if l.cfg.MaxKeys > 0 && len(l.keys) >= l.cfg.MaxKeys {
+			l.purge(now)
+			// истекших ключей недостаточно - вытесним ключи с самой давней неудачной попыткой
+			for len(l.keys) >= l.cfg.MaxKeys {
+				l.remove(l.order.Front().Value.(*failures))
+			}
+		}
+		f = &failures{key: key, windowStart: now}
+		f.elem = l.order.PushBack(f)
+		l.keys[key] = f
+	} else {
+		l.order.MoveToBack(f.elem)
+		if now.Sub(f.windowStart) > l.cfg.Window {
+			// начинаем новый период подсчета
+			f.count, f.windowStart = 0, now
+		}
+	}
+
+	f.count++
+	if f.count >= l.cfg.MaxFailures {
+		f.count, f.windowStart = 0, now
+		f.lockedUntil = now.Add(l.cfg.Duration)
+		return true
+	}
+	return false
+}
+
+// Success reset failed attempts of key
+func (l *Lockout) Success(key string) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if f, ok := l.keys[key]; ok {
+		l.remove(f)
+	}
+}
+
+// Len return count of stored keys
+func (l *Lockout) Len() int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	return len(l.keys)
+}
+
+// purge delete keys with expired window and lockout, must be called under lock
+func (l *Lockout) purge(now time.Time) {
+	for _, f := range l.keys {
+		if now.Sub(f.windowStart) > l.cfg.Window && !now.Before(f.lockedUntil) {
+			l.remove(f)
+		}
+	}
+}
+
+// remove delete key, must be called under lock
+func (l *Lockout) remove(f *failures) {
+	l.order.Remove(f.elem)
+	delete(l.keys, f.key)
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// Ограничение частоты запросов алгоритмом token bucket:
+//     каждый ключ (IP, пользователь, API key) имеет корзину емкостью Burst
+//     корзина пополняется на Requests токенов за Period, каждый запрос расходует один токен
+//     запрос при пустой корзине отклоняется до пополнения
+// При превышении MaxKeys вытесняются корзины, которые дольше всех не использовались.
+
+// Способы определения ключа клиента
+const (
+	KeyByIP     = "IP"     // IP адрес клиента
+	KeyByUser   = "USER"   // имя аутентифицированного пользователя, без аутентификации - IP адрес
+	KeyByAPIKey = "APIKEY" // заголовок с API key, без заголовка - IP адрес
+)
+
+// Config represent rate limit policy
+type Config struct {
+	Requests int           // количество запросов за период
+	Period   time.Duration // период пополнения корзины
+	Burst    int           // емкость корзины - максимальное количество запросов подряд
+	KeyBy    string        // способ определения ключа клиента IP, USER, APIKEY
+	MaxKeys  int           // максимальное количество хранимых ключей, 0 - без ограничения
+}
+
+// Result represent result of rate limit check
+type Result struct {
+	Allowed    bool          // запрос разрешен
+	Limit      int           // емкость корзины
+	Remaining  int           // осталось токенов после запроса
+	Reset      time.Duration // время до полного пополнения корзины
+	RetryAfter time.Duration // время до появления токена, если запрос отклонен
+}
+
+// bucket represent token bucket of key
+type bucket struct {
+	key    string
+	tokens float64       // текущее количество токенов
+	last   time.Time     // время последнего пополнения
+	elem   *list.Element // элемент очереди вытеснения
+}
+
+// Limiter represent bounded in-memory set of token buckets
+type Limiter struct {
+	cfg     *Config
+	rate    float64 // токенов в секунду
+	mx      sync.Mutex
+	buckets map[string]*bucket
+	order   *list.List // корзины в порядке использования - для вытеснения
+	now     func() time.Time
+}
+
+// New create rate limiter
+func New(cfg *Config) *Limiter {
+	return &Limiter{
+		cfg:     cfg,
+		rate:    float64(cfg.Requests) / cfg.Period.Seconds(),
+		buckets: make(map[string]*bucket),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Allow take token from bucket of key
+func (l *Limiter) Allow(key string) Result {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := l.now()
+	burst := float64(l.cfg.Burst)
+
+	b, ok := l.buckets[key]
+	if ok {
+		// пополняем корзину за прошедшее время
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+		l.order.MoveToBack(b.elem)
+	} else {
+		// вытесним давно неиспользуемые корзины
+		for l.cfg.MaxKeys > 0 && len(l.buckets) >= l.cfg.MaxKeys {
+			l.remove(l.order.Front().Value.(*bucket))
+		}
+		b = &bucket{key: key, tokens: burst, last: now}
+		b.elem = l.order.PushBack(b)
+		l.buckets[key] = b
+	}
+
+	res := Result{Limit: l.cfg.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.duration(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = l.duration(burst - b.tokens)
+	return res
+}
+
+// duration return time to refill tokens
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.rate * float64(time.Second)))
+}
+
+// remove delete bucket, must be called under lock
+func (l *Limiter) remove(b *bucket) {
+	l.order.Remove(b.elem)
+	delete(l.buckets, b.key)
+}
+
+// KeyBy return method of definition of client key
+func (l *Limiter) KeyBy() string {
+	return l.cfg.KeyBy
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// clock represent manual time source for tests
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLimiterAllow(t *testing.T) {
+	c := &clock{t: time.Now()}
+	l := New(&Config{Requests: 2, Period: time.Second, Burst: 3})
+	l.now = c.now
+
+	// корзина полная - проходят Burst запросов подряд
+	for i := 0; i < 3; i++ {
+		if res := l.Allow("a"); !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("Allow() #%v = %+v, want allowed with remaining %v", i, res, 2-i)
+		}
+	}
+	res := l.Allow("a")
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond || res.Limit != 3 {
+		t.Fatalf("Allow() = %+v, want rejected with RetryAfter 500ms", res)
+	}
+
+	// другой ключ имеет свою корзину
+	if res = l.Allow("b"); !res.Allowed {
+		t.Errorf("Allow(b) = %+v, want allowed", res)
+	}
+
+	// через 500ms появляется один токен
+	c.advance(500 * time.Millisecond)
+	if res = l.Allow("a"); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Allow() after refill = %+v, want allowed", res)
+	}
+	if res = l.Allow("a"); res.Allowed {
+		t.Errorf("Allow() = %+v, want rejected", res)
+	}
+
+	// корзина не пополняется больше Burst
+	c.advance(time.Hour)
+	if res = l.Allow("a"); !res.Allowed || res.Remaining != 2 || res.Reset != 500*time.Millisecond {
+		t.Errorf("Allow() after long pause = %+v, want remaining 2 and reset 500ms", res)
+	}
+}
+
+func TestLimiterMaxKeys(t *testing.T) {
+	l := New(&Config{Requests: 1, Period: time.Hour, Burst: 1, MaxKeys: 2})
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("a") // "b" дольше не использовался
+	l.Allow("c")
+
+	if len(l.buckets) != 2 {
+		t.Fatalf("len(buckets) = %v, want 2", len(l.buckets))
+	}
+	if _, ok := l.buckets["b"]; ok {
+		t.Errorf("least recently used key was not evicted")
+	}
+}
+
+func TestLockout(t *testing.T) {
+	c := &clock{t: time.Now()}
+	l := NewLockout(&LockoutConfig{MaxFailures: 3, Window: time.Minute, Duration: 10 * time.Minute})
+	l.now = c.now
+
+	// неудачные попытки за пределами периода не накапливаются
+	l.Failure("user")
+	l.Failure("user")
+	c.advance(2 * time.Minute)
+	if l.Failure("user") {
+		t.Fatalf("Failure() locked after expired window")
+	}
+
+	l.Failure("user")
+	if !l.Failure("user") {
+		t.Fatalf("Failure() not locked after MaxFailures")
+	}
+	if remaining, locked := l.Locked("user"); !locked || remaining != 10*time.Minute {
+		t.Errorf("Locked() = %v, %v, want 10m, true", remaining, locked)
+	}
+	if _, locked := l.Locked("other"); locked {
+		t.Errorf("Locked(other) = true, want false")
+	}
+
+	c.advance(10 * time.Minute)
+	if _, locked := l.Locked("user"); locked {
+		t.Errorf("Locked() after lockout duration = true, want false")
+	}
+
+	// успешная аутентификация сбрасывает счетчик
+	l.Failure("user")
+	l.Failure("user")
+	l.Success("user")
+	if l.Failure("user") {
+		t.Errorf("Failure() locked after Success")
+	}
+}
+
+func TestLockoutMaxKeys(t *testing.T) {
+	c := &clock{t: time.Now()}
+	l := NewLockout(&LockoutConfig{MaxFailures: 3, Window: time.Minute, Duration: 10 * time.Minute, MaxKeys: 2})
+	l.now = c.now
+
+	// истекших ключей нет - вытесняется ключ с самой давней неудачной попыткой
+	l.Failure("a")
+	c.advance(time.Second)
+	l.Failure("b")
+	c.advance(time.Second)
+	l.Failure("a")
+	l.Failure("c")
+	if l.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", l.Len())
+	}
+	if _, ok := l.keys["b"]; ok {
+		t.Errorf("key with oldest failure was not evicted")
+	}
+
+	// истекший ключ удаляется раньше недавнего
+	c.advance(2 * time.Minute)
+	l.Failure("c")
+	l.Failure("d")
+	if _, ok := l.keys["c"]; !ok || l.Len() != 2 {
+		t.Errorf("recent key was evicted: Len() = %d", l.Len())
+	}
+}
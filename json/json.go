@@ -20,13 +20,22 @@ type Service struct {
 	errCh  chan<- error       // канал ошибок
 	stopCh chan struct{}      // канал подтверждения об успешном закрытии сервиса
 
+	marshalers *Marshalers // кодирование объектов в версии API, nil - объекты кодируются как есть
+
 	// вложенные сервисы
-	empService  model.EmpService
-	deptService model.DeptService
+	empService    model.EmpService
+	deptService   model.DeptService
+	txService     model.TxService
+	importService model.ImportService
+	exportService model.ExportService
 }
 
+// Precondition check current version of object, read in transaction of update, before update - e.g. by If-Match.
+// Error of precondition cancels update
+type Precondition func(version model.Version) error
+
 // New returns a new Service
-func New(ctx context.Context, errCh chan<- error, cfg *Config, empService model.EmpService, deptService model.DeptService) (*Service, error) {
+func New(ctx context.Context, errCh chan<- error, cfg *Config, empService model.EmpService, deptService model.DeptService, txService model.TxService, importService model.ImportService, exportService model.ExportService) (*Service, error) {
 	//var err error
 
 	mylog.PrintfInfoMsg("Creating new JSON service")
@@ -41,15 +50,27 @@ func New(ctx context.Context, errCh chan<- error, cfg *Config, empService model.
 		if deptService == nil {
 			return nil, myerror.New("6030", "Empty DeptService service").PrintfInfo()
 		}
+		if txService == nil {
+			return nil, myerror.New("6030", "Empty TxService service").PrintfInfo()
+		}
+		if importService == nil {
+			return nil, myerror.New("6030", "Empty ImportService service").PrintfInfo()
+		}
+		if exportService == nil {
+			return nil, myerror.New("6030", "Empty ExportService service").PrintfInfo()
+		}
 	} // входные проверки
 
 	// Создаем новый сервис
 	service := &Service{
-		cfg:         cfg,
-		errCh:       errCh,
-		stopCh:      make(chan struct{}, 1), // канал подтверждения об успешном закрытии сервиса
-		empService:  empService,
-		deptService: deptService,
+		cfg:           cfg,
+		errCh:         errCh,
+		stopCh:        make(chan struct{}, 1), // канал подтверждения об успешном закрытии сервиса
+		empService:    empService,
+		deptService:   deptService,
+		txService:     txService,
+		importService: importService,
+		exportService: exportService,
 	}
 
 	// создаем контекст с отменой
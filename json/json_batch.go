@@ -0,0 +1,247 @@
+package json
+
+import (
+	"context"
+	"fmt"
+
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+	"github.com/romapres2010/httpserver/codec"
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+	model "github.com/romapres2010/httpserver/model"
+)
+
+// Режимы пакетной обработки
+const (
+	BatchModeAtomic = "atomic" // все элементы в одной транзакции, при ошибке откатываются все
+	BatchModeItem   = "item"   // каждый элемент в отдельной транзакции, результат по каждому элементу
+)
+
+// ErrCodeInvalidBatch - тело запроса не является JSON массивом элементов пакета
+const ErrCodeInvalidBatch = "6008"
+
+// Статусы обработки элемента пакета
+const (
+	BatchStatusCreated = "created"
+	BatchStatusUpdated = "updated"
+	BatchStatusError   = "error"
+)
+
+// BatchResult represent result of processing one element of batch
+type BatchResult struct {
+	Index     int    // номер элемента в пакете
+	Status    string // BatchStatusCreated | BatchStatusUpdated | BatchStatusError
+	ID        int    // PK элемента
+	Version   int64  // версия строки после обработки
+	ErrorCode string // код ошибки
+	Error     string // текст ошибки
+}
+
+// batchResultsMarshal - marshal results of batch into JSON array
+func batchResultsMarshal(reqID uint64, results []*BatchResult, buf []byte) (outBuf []byte, myerr error) {
+	mylog.PrintfDebugMsgDepth("Marshal with EasyJSON: reqID", 1, reqID)
+
+	w := jwriter.Writer{} // подготовим EasyJSON Writer
+	w.RawByte('[')
+	for i, r := range results {
+		if i > 0 {
+			w.RawByte(',')
+		}
+		w.RawString(`{"index":`)
+		w.Int(r.Index)
+		w.RawString(`,"status":`)
+		w.String(r.Status)
+		if r.Status != BatchStatusError {
+			w.RawString(`,"id":`)
+			w.Int(r.ID)
+			w.RawString(`,"version":`)
+			w.Int64(r.Version)
+		} else {
+			w.RawString(`,"errorCode":`)
+			w.String(r.ErrorCode)
+			w.RawString(`,"error":`)
+			w.String(r.Error)
+		}
+		w.RawByte('}')
+	}
+	w.RawByte(']')
+
+	if w.Error != nil {
+		return nil, myerror.WithCause("6001", "Error Marshal: reqID", w.Error, reqID).PrintfInfo(1)
+	}
+
+	mylog.PrintfDebugMsgDepth("SUCCESS: reqID", 1, reqID)
+	return w.Buffer.BuildBytes(buf), nil
+}
+
+// batch - process count elements with fn in mode BatchModeAtomic or BatchModeItem
+func (s *Service) batch(ctx context.Context, mode string, count int, fn func(ctx context.Context, i int, result *BatchResult) error) (results []*BatchResult, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	results = make([]*BatchResult, count)
+	for i := range results {
+		results[i] = &BatchResult{Index: i}
+	}
+
+	switch mode {
+	case BatchModeAtomic:
+		// все элементы в одной транзакции - вложенные вызовы сервисов выполняются в точках сохранения
+		myerr = s.txService.InTx(ctx, func(ctx context.Context) error {
+			for i := 0; i < count; i++ {
+				if err := fn(ctx, i, results[i]); err != nil {
+					return myerror.WithCause("6002", "Error process batch element, batch is rolled back: reqID, index", err, reqID, i).PrintfInfo()
+				}
+			}
+			return nil
+		})
+		if myerr != nil {
+			return nil, myerr
+		}
+	case BatchModeItem:
+		// каждый элемент в отдельной транзакции, ошибка элемента не прерывает обработку пакета
+		for i := 0; i < count; i++ {
+			err := s.txService.InTx(ctx, func(ctx context.Context) error {
+				return fn(ctx, i, results[i])
+			})
+			if err != nil {
+				// запрос отменен - остальные элементы обрабатывать бессмысленно
+				if ctx.Err() != nil {
+					return nil, err
+				}
+				results[i].Status = BatchStatusError
+				results[i].Error = fmt.Sprintf("%v", err)
+				if e, ok := err.(*myerror.Error); ok {
+					results[i].ErrorCode = e.Code
+				}
+			}
+		}
+	default:
+		return nil, myerror.New("6002", "Incorrect batch mode, only avaliable: 'atomic', 'item': reqID, mode", reqID, mode).PrintfInfo()
+	}
+
+	return results, nil
+}
+
+// BatchDepts create or update depts from JSON array and return a JSON array of results
+func (s *Service) BatchDepts(ctx context.Context, mode string, inBuf []byte, buf []byte) (outBuf []byte, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	mylog.PrintfDebugMsg("START: reqID, mode", reqID, mode)
+
+	// Парсим JSON массив в срез структур из pool
+	mylog.PrintfDebugMsg("Unmarshal with EasyJSON: reqID", reqID)
+	var vIns []*model.Dept
+	defer func() {
+		for _, v := range vIns {
+			model.PutDept(v, true) // возвращаем в pool струкуру со всеми вложенными объектами
+		}
+	}()
+	in := jlexer.Lexer{Data: inBuf}
+	in.Delim('[')
+	for !in.IsDelim(']') && in.Ok() {
+		v := model.GetDept() // Извлечем из pool новую структуру
+		vIns = append(vIns, v)
+		if s.marshalers != nil && s.marshalers.NewDept != nil {
+			// элемент в представлении версии API
+			r := s.marshalers.NewDept()
+			in.AddError(codec.JSON.Unmarshal(in.Raw(), r))
+			r.ToDept(v)
+		} else {
+			v.UnmarshalEasyJSON(&in)
+		}
+		in.WantComma()
+	}
+	in.Delim(']')
+	in.Consumed()
+	if err := in.Error(); err != nil {
+		return nil, myerror.WithCause(ErrCodeInvalidBatch, "Error Unmarshal batch: reqID, buf", err, reqID, string(inBuf)).PrintfInfo()
+	}
+
+	// вызываем сервис обработки для каждого элемента: обновим существующий объект или создадим новый
+	results, myerr := s.batch(ctx, mode, len(vIns), func(ctx context.Context, i int, result *BatchResult) error {
+		vOut := model.GetDept()         // Извлечем из pool новую структуру
+		defer model.PutDept(vOut, true) // возвращаем в pool струкуру со всеми вложенными объектами
+
+		exists, err := s.deptService.UpdateDept(ctx, vIns[i], vOut)
+		if err != nil {
+			return err
+		}
+		result.Status = BatchStatusUpdated
+		if !exists {
+			if err = s.deptService.CreateDept(ctx, vIns[i], vOut); err != nil {
+				return err
+			}
+			result.Status = BatchStatusCreated
+		}
+		result.ID, result.Version = vOut.Deptno, vOut.Version
+		return nil
+	})
+	if myerr != nil {
+		return nil, myerr
+	}
+
+	// сформируем json
+	return batchResultsMarshal(reqID, results, buf)
+}
+
+// BatchEmps create or update emps from JSON array and return a JSON array of results
+func (s *Service) BatchEmps(ctx context.Context, mode string, inBuf []byte, buf []byte) (outBuf []byte, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	mylog.PrintfDebugMsg("START: reqID, mode", reqID, mode)
+
+	// Парсим JSON массив в срез структур из pool
+	mylog.PrintfDebugMsg("Unmarshal with EasyJSON: reqID", reqID)
+	var vIns []*model.Emp
+	defer func() {
+		for _, v := range vIns {
+			model.PutEmp(v) // возвращаем в pool струкуру
+		}
+	}()
+	in := jlexer.Lexer{Data: inBuf}
+	in.Delim('[')
+	for !in.IsDelim(']') && in.Ok() {
+		v := model.GetEmp() // Извлечем из pool новую структуру
+		vIns = append(vIns, v)
+		if s.marshalers != nil && s.marshalers.NewEmp != nil {
+			// элемент в представлении версии API
+			r := s.marshalers.NewEmp()
+			in.AddError(codec.JSON.Unmarshal(in.Raw(), r))
+			r.ToEmp(v)
+		} else {
+			v.UnmarshalEasyJSON(&in)
+		}
+		in.WantComma()
+	}
+	in.Delim(']')
+	in.Consumed()
+	if err := in.Error(); err != nil {
+		return nil, myerror.WithCause(ErrCodeInvalidBatch, "Error Unmarshal batch: reqID, buf", err, reqID, string(inBuf)).PrintfInfo()
+	}
+
+	// вызываем сервис обработки для каждого элемента: обновим существующий объект или создадим новый
+	results, myerr := s.batch(ctx, mode, len(vIns), func(ctx context.Context, i int, result *BatchResult) error {
+		vOut := model.GetEmp()   // Извлечем из pool новую структуру
+		defer model.PutEmp(vOut) // возвращаем в pool струкуру
+
+		exists, err := s.empService.UpdateEmp(ctx, vIns[i], vOut)
+		if err != nil {
+			return err
+		}
+		result.Status = BatchStatusUpdated
+		if !exists {
+			if err = s.empService.CreateEmp(ctx, vIns[i], vOut); err != nil {
+				return err
+			}
+			result.Status = BatchStatusCreated
+		}
+		result.ID, result.Version = vOut.Empno, vOut.Version
+		return nil
+	})
+	if myerr != nil {
+		return nil, myerr
+	}
+
+	// сформируем json
+	return batchResultsMarshal(reqID, results, buf)
+}
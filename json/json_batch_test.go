@@ -0,0 +1,173 @@
+package json
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	model "github.com/romapres2010/httpserver/model"
+	mysql "github.com/romapres2010/httpserver/sqlxx"
+)
+
+// batchStoreMock represent model.DeptService and model.EmpService over versions of rows,
+// as model.TxService it restores rows if fn returns error, nested calls are savepoints
+type batchStoreMock struct {
+	rows map[int]int64 // версии строк по PK
+	errs map[int]error // ошибки создания строк по PK
+}
+
+func (m *batchStoreMock) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	saved := make(map[int]int64, len(m.rows))
+	for k, v := range m.rows {
+		saved[k] = v
+	}
+	if err := fn(ctx); err != nil {
+		m.rows = saved
+		if err == mysql.ErrRollback {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *batchStoreMock) create(pk int) (int64, error) {
+	if err := m.errs[pk]; err != nil {
+		return 0, err
+	}
+	return 1, m.InTx(context.Background(), func(ctx context.Context) error {
+		if _, ok := m.rows[pk]; ok {
+			return myerror.New(mysql.ErrCodeConstraintViolation, "Error create - row already exists: PK", pk)
+		}
+		m.rows[pk] = 1
+		return nil
+	})
+}
+
+func (m *batchStoreMock) update(pk int) (int64, bool) {
+	version, ok := m.rows[pk]
+	if !ok {
+		return 0, false
+	}
+	m.rows[pk] = version + 1
+	return version + 1, true
+}
+
+func (m *batchStoreMock) GetDept(ctx context.Context, out *model.Dept, withEmps bool) (bool, error) {
+	return false, nil
+}
+
+func (m *batchStoreMock) GetDeptVersion(ctx context.Context, deptno int, out *model.Version, withEmps bool) (bool, error) {
+	return false, nil
+}
+
+func (m *batchStoreMock) GetDeptsPK(ctx context.Context, out *model.DeptPKs) error {
+	return nil
+}
+
+func (m *batchStoreMock) CreateDept(ctx context.Context, in *model.Dept, out *model.Dept) (err error) {
+	out.Deptno = in.Deptno
+	out.Version, err = m.create(in.Deptno)
+	return err
+}
+
+func (m *batchStoreMock) UpdateDept(ctx context.Context, in *model.Dept, out *model.Dept) (exists bool, err error) {
+	out.Deptno = in.Deptno
+	out.Version, exists = m.update(in.Deptno)
+	return exists, nil
+}
+
+func (m *batchStoreMock) PatchDept(ctx context.Context, cur *model.Dept, in *model.Dept, out *model.Dept) error {
+	return nil
+}
+
+func (m *batchStoreMock) GetEmp(ctx context.Context, out *model.Emp) (bool, error) {
+	return false, nil
+}
+
+func (m *batchStoreMock) GetEmpsByDept(ctx context.Context, in *model.Dept, out *model.EmpSlice) error {
+	return nil
+}
+
+func (m *batchStoreMock) CreateEmp(ctx context.Context, in *model.Emp, out *model.Emp) (err error) {
+	out.Empno = in.Empno
+	out.Version, err = m.create(in.Empno)
+	return err
+}
+
+func (m *batchStoreMock) UpdateEmp(ctx context.Context, in *model.Emp, out *model.Emp) (exists bool, err error) {
+	out.Empno = in.Empno
+	out.Version, exists = m.update(in.Empno)
+	return exists, nil
+}
+
+func (m *batchStoreMock) PatchEmp(ctx context.Context, cur *model.Emp, in *model.Emp, out *model.Emp) error {
+	return nil
+}
+
+// errorText match text of error in results, it contains ID of error
+var errorText = regexp.MustCompile(`"error":"[^"]*"`)
+
+func TestBatch(t *testing.T) {
+	violation := myerror.New(mysql.ErrCodeConstraintViolation, "Constraint violation")
+
+	tests := []struct {
+		name     string
+		object   string
+		mode     string
+		body     string
+		errs     map[int]error
+		want     string
+		wantCode string
+		wantRows map[int]int64
+	}{
+		{"update and create", "dept", BatchModeAtomic, `[{"deptNumber":10,"deptName":"A"},{"deptNumber":50,"deptName":"B"}]`, nil,
+			`[{"index":0,"status":"updated","id":10,"version":4},{"index":1,"status":"created","id":50,"version":1}]`, "",
+			map[int]int64{10: 4, 50: 1}},
+		{"atomic rollback", "dept", BatchModeAtomic, `[{"deptNumber":10,"deptName":"A"},{"deptNumber":50,"deptName":"B"},{"deptNumber":60,"deptName":"C"}]`, map[int]error{60: violation},
+			``, "6002", map[int]int64{10: 3}},
+		{"per item", "dept", BatchModeItem, `[{"deptNumber":10,"deptName":"A"},{"deptNumber":60,"deptName":"C"},{"deptNumber":50,"deptName":"B"}]`, map[int]error{60: violation},
+			`[{"index":0,"status":"updated","id":10,"version":4},{"index":1,"status":"error","errorCode":"4013","error":""},{"index":2,"status":"created","id":50,"version":1}]`, "",
+			map[int]int64{10: 4, 50: 1}},
+		{"emps", "emp", BatchModeItem, `[{"empNo":10,"empName":"KING"},{"empNo":7900,"empName":"JAMES"}]`, nil,
+			`[{"index":0,"status":"updated","id":10,"version":4},{"index":1,"status":"created","id":7900,"version":1}]`, "",
+			map[int]int64{10: 4, 7900: 1}},
+		{"emps atomic rollback", "emp", BatchModeAtomic, `[{"empNo":7900,"empName":"JAMES"},{"empNo":60,"empName":"C"}]`, map[int]error{60: violation},
+			``, "6002", map[int]int64{10: 3}},
+		{"empty", "dept", BatchModeAtomic, `[]`, nil, `[]`, "", map[int]int64{10: 3}},
+		{"not array", "dept", BatchModeAtomic, `{"deptNumber":10}`, nil, ``, ErrCodeInvalidBatch, map[int]int64{10: 3}},
+		{"malformed element", "dept", BatchModeItem, `[{"deptNumber":10},{"deptNumber":"X"}]`, nil, ``, ErrCodeInvalidBatch, map[int]int64{10: 3}},
+		{"unterminated", "emp", BatchModeAtomic, `[{"empNo":10}`, nil, ``, ErrCodeInvalidBatch, map[int]int64{10: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &batchStoreMock{rows: map[int]int64{10: 3}, errs: tt.errs}
+			s := &Service{deptService: mock, empService: mock, txService: mock}
+
+			batchFn := s.BatchDepts
+			if tt.object == "emp" {
+				batchFn = s.BatchEmps
+			}
+			data, err := batchFn(context.Background(), tt.mode, []byte(tt.body), nil)
+			if tt.wantCode != "" {
+				if myerr, ok := err.(*myerror.Error); !ok || myerr.Code != tt.wantCode {
+					t.Fatalf("Batch() error = %v, want code %v", err, tt.wantCode)
+				}
+			} else if err != nil {
+				t.Fatalf("Batch() error = %v", err)
+			} else if string(errorText.ReplaceAll(data, []byte(`"error":""`))) != tt.want {
+				t.Errorf("Batch() = %s, want %s", data, tt.want)
+			}
+
+			if len(mock.rows) != len(tt.wantRows) {
+				t.Fatalf("Batch() rows = %v, want %v", mock.rows, tt.wantRows)
+			}
+			for pk, version := range tt.wantRows {
+				if mock.rows[pk] != version {
+					t.Errorf("Batch() rows = %v, want %v", mock.rows, tt.wantRows)
+				}
+			}
+		})
+	}
+}
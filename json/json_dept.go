@@ -3,31 +3,59 @@ package json
 import (
 	"context"
 
-	jwriter "github.com/mailru/easyjson/jwriter"
+	"github.com/romapres2010/httpserver/codec"
 	myctx "github.com/romapres2010/httpserver/ctx"
 	myerror "github.com/romapres2010/httpserver/error"
 	mylog "github.com/romapres2010/httpserver/log"
 	model "github.com/romapres2010/httpserver/model"
 )
 
-func deptMarshal(reqID uint64, v *model.Dept, buf []byte) (outBuf []byte, myerr error) {
-	mylog.PrintfDebugMsgDepth("Marshal with EasyJSON: reqID", 1, reqID)
+// deptMarshal encode Dept or its representation in version of API with codec into buf, fields select encoded fields, nil - all fields
+func (s *Service) deptMarshal(reqID uint64, c codec.Codec, v *model.Dept, fields *Fieldset, buf []byte) (outBuf []byte, myerr error) {
+	mylog.PrintfDebugMsgDepth("Marshal: reqID, Content-Type", 1, reqID, c.ContentType())
 
-	w := jwriter.Writer{} // подготовим EasyJSON Writer
-	v.MarshalEasyJSON(&w) // сформируем JSON во внутренний буфер EasyJSON Writer
-
-	if w.Error != nil {
-		return nil, myerror.WithCause("6001", "Error Marshal: reqID", w.Error, reqID).PrintfInfo(1)
+	var obj interface{} = v
+	if s.marshalers != nil && s.marshalers.NewDept != nil {
+		r := s.marshalers.NewDept()
+		r.FromDept(v)
+		obj = r
+	}
+	if fields.sparse() {
+		obj = newSparseObject(obj, fields)
 	}
 
-	// Скопируем из внутреннего буфера EasyJSON Writer во внешний буфер
 	// Если размер внешнего буфера будет мал - то он использован не будет
+	outBuf, err := c.Marshal(obj, buf)
+	if err != nil {
+		return nil, myerror.WithCause("6001", "Error Marshal: reqID, Content-Type", err, reqID, c.ContentType()).PrintfInfo(1)
+	}
+
 	mylog.PrintfDebugMsgDepth("SUCCESS: reqID", 1, reqID)
-	return w.Buffer.BuildBytes(buf), nil
+	return outBuf, nil
 }
 
-// GetDept return a JSON for a given PK
-func (s *Service) GetDept(ctx context.Context, id int, buf []byte) (outBuf []byte, myerr error) {
+// deptUnmarshal decode Dept or its representation in version of API with codec
+func (s *Service) deptUnmarshal(reqID uint64, c codec.Codec, inBuf []byte, v *model.Dept) (myerr error) {
+	mylog.PrintfDebugMsgDepth("Unmarshal: reqID, Content-Type", 1, reqID, c.ContentType())
+
+	if s.marshalers != nil && s.marshalers.NewDept != nil {
+		r := s.marshalers.NewDept()
+		if err := c.Unmarshal(inBuf, r); err != nil {
+			return myerror.WithCause("6001", "Error Unmarshal: reqID, Content-Type, buf", err, reqID, c.ContentType(), string(inBuf)).PrintfInfo(1)
+		}
+		r.ToDept(v)
+		return nil
+	}
+
+	if err := c.Unmarshal(inBuf, v); err != nil {
+		return myerror.WithCause("6001", "Error Unmarshal: reqID, Content-Type, buf", err, reqID, c.ContentType(), string(inBuf)).PrintfInfo(1)
+	}
+	return nil
+}
+
+// GetDept return a Dept encoded with codec out and row version for a given PK,
+// fields select encoded fields, nil - all fields, emps are not requested if they are not selected
+func (s *Service) GetDept(ctx context.Context, out codec.Codec, id int, fields *Fieldset, buf []byte) (outBuf []byte, version model.Version, myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 	mylog.PrintfDebugMsg("START: reqID", reqID)
 
@@ -36,21 +64,29 @@ func (s *Service) GetDept(ctx context.Context, id int, buf []byte) (outBuf []byt
 	vOut.Deptno = id                // параметры для запроса передаются в структуре
 
 	// вызываем сервис обработки
-	exists, myerr := s.deptService.GetDept(ctx, vOut)
+	exists, myerr := s.deptService.GetDept(ctx, vOut, fields == nil || fields.Embedded)
 	if myerr != nil {
-		return nil, myerr
+		return nil, model.Version{}, myerr
 	}
 
-	// сформируем json
+	// сформируем ответ
 	if exists {
-		return deptMarshal(reqID, vOut, buf)
+		outBuf, myerr = s.deptMarshal(reqID, out, vOut, fields, buf)
+		return outBuf, vOut.VersionOf(fields == nil || fields.Embedded), myerr
 	}
 
-	return nil, nil // возврат пустого буфера - признак, что объекта не найдено
+	return nil, model.Version{}, nil // возврат пустого буфера - признак, что объекта не найдено
+}
+
+// GetDeptVersion return version of dept with emps, which are included in representation with fields.
+// It is used to check If-None-Match without reading and encoding of dept
+func (s *Service) GetDeptVersion(ctx context.Context, id int, fields *Fieldset) (version model.Version, exists bool, myerr error) {
+	exists, myerr = s.deptService.GetDeptVersion(ctx, id, &version, fields == nil || fields.Embedded)
+	return version, exists, myerr
 }
 
-// CreateDept create dept and return a JSON
-func (s *Service) CreateDept(ctx context.Context, inBuf []byte, buf []byte) (id int, outBuf []byte, myerr error) {
+// CreateDept create dept from body encoded with codec in and return a Dept encoded with codec out
+func (s *Service) CreateDept(ctx context.Context, in codec.Codec, out codec.Codec, inBuf []byte, buf []byte) (id int, outBuf []byte, myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 	mylog.PrintfDebugMsg("START: reqID", reqID)
 
@@ -60,10 +96,9 @@ func (s *Service) CreateDept(ctx context.Context, inBuf []byte, buf []byte) (id
 	vOut := model.GetDept()         // Извлечем из pool новую структуру
 	defer model.PutDept(vOut, true) // возвращаем в pool струкуру
 
-	// Парсим JSON в структуру
-	mylog.PrintfDebugMsg("Unmarshal with EasyJSON: reqID", reqID)
-	if err := vIn.UnmarshalJSON(inBuf); err != nil {
-		return 0, nil, myerror.WithCause("6001", "Error Unmarshal: reqID, buf", err, reqID, string(inBuf)).PrintfInfo()
+	// Парсим тело запроса в структуру
+	if myerr = s.deptUnmarshal(reqID, in, inBuf, vIn); myerr != nil {
+		return 0, nil, myerr
 	}
 
 	// вызываем сервис обработки
@@ -71,16 +106,17 @@ func (s *Service) CreateDept(ctx context.Context, inBuf []byte, buf []byte) (id
 		return 0, nil, myerr
 	}
 
-	// сформируем json
-	if outBuf, myerr = deptMarshal(reqID, vOut, buf); myerr != nil {
+	// сформируем ответ
+	if outBuf, myerr = s.deptMarshal(reqID, out, vOut, nil, buf); myerr != nil {
 		return 0, nil, myerr
 	}
 
 	return vOut.Deptno, outBuf, myerr
 }
 
-// UpdateDept update dept and return a JSON
-func (s *Service) UpdateDept(ctx context.Context, id int, inBuf []byte, buf []byte) (outBuf []byte, myerr error) {
+// UpdateDept update dept from body encoded with codec in and return a Dept encoded with codec out and new row version.
+// If precondition is not nil, it checks version of dept with emps in transaction of update, current row version overrides version from body.
+func (s *Service) UpdateDept(ctx context.Context, in codec.Codec, out codec.Codec, id int, precondition Precondition, inBuf []byte, buf []byte) (outBuf []byte, outVersion model.Version, myerr error) {
 	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
 	mylog.PrintfDebugMsg("START: reqID", reqID)
 
@@ -90,27 +126,106 @@ func (s *Service) UpdateDept(ctx context.Context, id int, inBuf []byte, buf []by
 	vOut := model.GetDept()         // Извлечем из pool новую структуру
 	defer model.PutDept(vOut, true) // возвращаем в pool струкуру
 
-	// Парсим JSON в структуру
-	mylog.PrintfDebugMsg("Unmarshal with EasyJSON: reqID", reqID)
-	if err := vIn.UnmarshalJSON(inBuf); err != nil {
-		return nil, myerror.WithCause("6001", "Error Unmarshal: reqID, buf", err, reqID, string(inBuf)).PrintfInfo()
+	// Парсим тело запроса в структуру
+	if myerr = s.deptUnmarshal(reqID, in, inBuf, vIn); myerr != nil {
+		return nil, model.Version{}, myerr
 	}
 
 	// проверим ID объекта
 	if id != vIn.Deptno {
-		return nil, myerror.New("6001", "Resource ID does not corespond to JSON: resource.id, json.Deptno", id, vIn.Deptno).PrintfInfo()
+		return nil, model.Version{}, myerror.New("6001", "Resource ID does not corespond to body: resource.id, body.Deptno", id, vIn.Deptno).PrintfInfo()
 	}
 
 	// вызываем сервис обработки
-	exists, myerr := s.deptService.UpdateDept(ctx, vIn, vOut)
+	var exists bool
+	myerr = s.txService.InTx(ctx, func(ctx context.Context) (err error) {
+		// условие проверяется по версии dept и emps в транзакции изменения - изменение emp тоже меняет ETag dept
+		if precondition != nil {
+			var cur model.Version
+			if exists, err = s.deptService.GetDeptVersion(ctx, id, &cur, true); err != nil || !exists {
+				return err
+			}
+			if err = precondition(cur); err != nil {
+				return err
+			}
+			vIn.Version = cur.Version
+		}
+		exists, err = s.deptService.UpdateDept(ctx, vIn, vOut)
+		return err
+	})
+	if myerr != nil {
+		return nil, model.Version{}, myerr
+	}
+
+	// сформируем ответ
+	if exists {
+		outBuf, myerr = s.deptMarshal(reqID, out, vOut, nil, buf)
+		return outBuf, vOut.VersionOf(true), myerr
+	}
+
+	return nil, model.Version{}, nil // возврат пустого буфера - признак, что объекта не найдено
+}
+
+// PatchDept apply patch of mediaType to JSON representation of Dept with emps in transaction and return patched Dept encoded with codec out and new row version.
+// If precondition is not nil, it checks version of dept with emps, read in transaction, before patch is applied
+func (s *Service) PatchDept(ctx context.Context, mediaType string, out codec.Codec, id int, precondition Precondition, inBuf []byte, buf []byte) (outBuf []byte, outVersion model.Version, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	mylog.PrintfDebugMsg("START: reqID, mediaType", reqID, mediaType)
+
+	vOut := model.GetDept()         // Извлечем из pool новую структуру
+	defer model.PutDept(vOut, true) // возвращаем в pool струкуру со всеми вложенными объектами
+
+	var exists bool
+	myerr = s.txService.InTx(ctx, func(ctx context.Context) (err error) {
+		vCur := model.GetDept()         // текущее состояние объекта
+		defer model.PutDept(vCur, true) // возвращаем в pool струкуру со всеми вложенными объектами
+		vIn := model.GetDept()          // состояние объекта после применения patch
+		defer model.PutDept(vIn, true)  // возвращаем в pool струкуру со всеми вложенными объектами
+
+		// Считаем текущее состояние объекта в транзакции
+		vCur.Deptno = id
+		if exists, err = s.deptService.GetDept(ctx, vCur, true); err != nil || !exists {
+			return err
+		}
+		if precondition != nil {
+			if err = precondition(vCur.VersionOf(true)); err != nil {
+				return err
+			}
+		}
+
+		// Применим patch к JSON представлению объекта
+		doc, err := s.deptMarshal(reqID, codec.JSON, vCur, nil, nil)
+		if err != nil {
+			return err
+		}
+		patched, err := applyPatch(reqID, mediaType, doc, inBuf)
+		if err != nil {
+			return err
+		}
+		if err = s.deptUnmarshal(reqID, codec.JSON, patched, vIn); err != nil {
+			return myerror.WithCause(ErrCodeInvalidPatch, "Result of patch is not valid Dept: reqID", err, reqID).PrintfInfo()
+		}
+
+		// проверим результат
+		if vIn.Deptno != id {
+			return myerror.New(ErrCodeInvalidPatch, "Resource ID can not be changed by patch: reqID, resource.id, body.Deptno", reqID, id, vIn.Deptno).PrintfInfo()
+		}
+		if err = validatePatchedDept(vCur, vIn); err != nil {
+			return myerror.WithCause(ErrCodeInvalidPatch, "Result of patch is not valid Dept: reqID", err, reqID).PrintfInfo()
+		}
+
+		// обновим только измененные столбцы
+		return s.deptService.PatchDept(ctx, vCur, vIn, vOut)
+	})
 	if myerr != nil {
-		return nil, myerr
+		return nil, model.Version{}, myerr
 	}
 
-	// сформируем json
+	// сформируем ответ
 	if exists {
-		return deptMarshal(reqID, vOut, buf)
+		outBuf, myerr = s.deptMarshal(reqID, out, vOut, nil, buf)
+		return outBuf, vOut.VersionOf(true), myerr
 	}
 
-	return nil, nil // возврат пустого буфера - признак, что объекта не найдено
+	return nil, model.Version{}, nil // возврат пустого буфера - признак, что объекта не найдено
 }
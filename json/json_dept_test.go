@@ -0,0 +1,48 @@
+package json
+
+import (
+	"context"
+	"testing"
+
+	"github.com/romapres2010/httpserver/codec"
+	myerror "github.com/romapres2010/httpserver/error"
+	mysql "github.com/romapres2010/httpserver/sqlxx"
+)
+
+func TestUpdateDept(t *testing.T) {
+	body := `{"deptNumber":10,"deptName":"ACCOUNTING","deptLocation":"BOSTON","version":5}`
+
+	tests := []struct {
+		name        string
+		precond     Precondition
+		wantVersion int64 // версия строки, с которой выполнено обновление
+		wantCode    string
+	}{
+		{"without precondition", nil, 5, ""},
+		{"precondition", versionIs(3), 3, ""},
+		{"precondition failed", versionIs(2), 0, mysql.ErrCodeVersionConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &deptServiceMock{}
+			s := &Service{deptService: mock, txService: txServiceMock{}}
+
+			data, version, err := s.UpdateDept(context.Background(), codec.JSON, codec.JSON, 10, tt.precond, []byte(body), nil)
+			if tt.wantCode != "" {
+				if myerr, ok := err.(*myerror.Error); !ok || myerr.Code != tt.wantCode {
+					t.Fatalf("UpdateDept() error = %v, want code %v", err, tt.wantCode)
+				}
+				if mock.updated != nil {
+					t.Errorf("UpdateDept() is saved after error")
+				}
+				return
+			}
+			if err != nil || data == nil {
+				t.Fatalf("UpdateDept() = %s, error = %v", data, err)
+			}
+			if mock.updated.Version != tt.wantVersion || version.Version != tt.wantVersion+1 {
+				t.Errorf("UpdateDept() updated version %v, new version %v, want %v", mock.updated.Version, version.Version, tt.wantVersion)
+			}
+		})
+	}
+}
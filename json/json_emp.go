@@ -0,0 +1,115 @@
+package json
+
+import (
+	"context"
+
+	"github.com/romapres2010/httpserver/codec"
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+	model "github.com/romapres2010/httpserver/model"
+)
+
+// empMarshal encode Emp or its representation in version of API with codec into buf
+func (s *Service) empMarshal(reqID uint64, c codec.Codec, v *model.Emp, buf []byte) (outBuf []byte, myerr error) {
+	mylog.PrintfDebugMsgDepth("Marshal: reqID, Content-Type", 1, reqID, c.ContentType())
+
+	var obj interface{} = v
+	if s.marshalers != nil && s.marshalers.NewEmp != nil {
+		r := s.marshalers.NewEmp()
+		r.FromEmp(v)
+		obj = r
+	}
+
+	// Если размер внешнего буфера будет мал - то он использован не будет
+	outBuf, err := c.Marshal(obj, buf)
+	if err != nil {
+		return nil, myerror.WithCause("6001", "Error Marshal: reqID, Content-Type", err, reqID, c.ContentType()).PrintfInfo(1)
+	}
+
+	mylog.PrintfDebugMsgDepth("SUCCESS: reqID", 1, reqID)
+	return outBuf, nil
+}
+
+// empUnmarshal decode Emp or its representation in version of API with codec
+func (s *Service) empUnmarshal(reqID uint64, c codec.Codec, inBuf []byte, v *model.Emp) (myerr error) {
+	mylog.PrintfDebugMsgDepth("Unmarshal: reqID, Content-Type", 1, reqID, c.ContentType())
+
+	if s.marshalers != nil && s.marshalers.NewEmp != nil {
+		r := s.marshalers.NewEmp()
+		if err := c.Unmarshal(inBuf, r); err != nil {
+			return myerror.WithCause("6001", "Error Unmarshal: reqID, Content-Type, buf", err, reqID, c.ContentType(), string(inBuf)).PrintfInfo(1)
+		}
+		r.ToEmp(v)
+		return nil
+	}
+
+	if err := c.Unmarshal(inBuf, v); err != nil {
+		return myerror.WithCause("6001", "Error Unmarshal: reqID, Content-Type, buf", err, reqID, c.ContentType(), string(inBuf)).PrintfInfo(1)
+	}
+	return nil
+}
+
+// PatchEmp apply patch of mediaType to JSON representation of Emp in transaction and return patched Emp encoded with codec out and new row version.
+// If precondition is not nil, it checks version of emp, read in transaction, before patch is applied
+func (s *Service) PatchEmp(ctx context.Context, mediaType string, out codec.Codec, id int, precondition Precondition, inBuf []byte, buf []byte) (outBuf []byte, outVersion model.Version, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	mylog.PrintfDebugMsg("START: reqID, mediaType", reqID, mediaType)
+
+	vOut := model.GetEmp()   // Извлечем из pool новую структуру
+	defer model.PutEmp(vOut) // возвращаем в pool струкуру
+
+	var exists bool
+	myerr = s.txService.InTx(ctx, func(ctx context.Context) (err error) {
+		vCur := model.GetEmp()   // текущее состояние объекта
+		defer model.PutEmp(vCur) // возвращаем в pool струкуру
+		vIn := model.GetEmp()    // состояние объекта после применения patch
+		defer model.PutEmp(vIn)  // возвращаем в pool струкуру
+
+		// Считаем текущее состояние объекта в транзакции
+		vCur.Empno = id
+		if exists, err = s.empService.GetEmp(ctx, vCur); err != nil || !exists {
+			return err
+		}
+		if precondition != nil {
+			if err = precondition(vCur.VersionOf()); err != nil {
+				return err
+			}
+		}
+
+		// Применим patch к JSON представлению объекта
+		doc, err := s.empMarshal(reqID, codec.JSON, vCur, nil)
+		if err != nil {
+			return err
+		}
+		patched, err := applyPatch(reqID, mediaType, doc, inBuf)
+		if err != nil {
+			return err
+		}
+		if err = s.empUnmarshal(reqID, codec.JSON, patched, vIn); err != nil {
+			return myerror.WithCause(ErrCodeInvalidPatch, "Result of patch is not valid Emp: reqID", err, reqID).PrintfInfo()
+		}
+
+		// проверим результат
+		if vIn.Empno != id {
+			return myerror.New(ErrCodeInvalidPatch, "Resource ID can not be changed by patch: reqID, resource.id, body.Empno", reqID, id, vIn.Empno).PrintfInfo()
+		}
+		if err = validatePatchedEmp(vCur, vIn); err != nil {
+			return myerror.WithCause(ErrCodeInvalidPatch, "Result of patch is not valid Emp: reqID", err, reqID).PrintfInfo()
+		}
+
+		// обновим только измененные столбцы
+		return s.empService.PatchEmp(ctx, vCur, vIn, vOut)
+	})
+	if myerr != nil {
+		return nil, model.Version{}, myerr
+	}
+
+	// сформируем ответ
+	if exists {
+		outBuf, myerr = s.empMarshal(reqID, out, vOut, buf)
+		return outBuf, vOut.VersionOf(), myerr
+	}
+
+	return nil, model.Version{}, nil // возврат пустого буфера - признак, что объекта не найдено
+}
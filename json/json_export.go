@@ -0,0 +1,227 @@
+package json
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strconv"
+
+	jwriter "github.com/mailru/easyjson/jwriter"
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+	model "github.com/romapres2010/httpserver/model"
+	"gopkg.in/guregu/null.v4"
+)
+
+// Форматы выгрузки
+const (
+	ExportFormatJSON   = "json"   // JSON массив
+	ExportFormatNDJSON = "ndjson" // JSON объект на каждой строке
+	ExportFormatCSV    = "csv"    // CSV, первая строка - имена полей JSON, формат совпадает с форматом загрузки
+)
+
+// exportBufSize - размер буфера выгрузки, при заполнении буфер отправляется клиенту
+const exportBufSize = 32 * 1024
+
+// CSV колонки выгрузки
+var (
+	deptCSVHeader = []string{"deptNumber", "deptName", "deptLocation"}
+	empCSVHeader  = []string{"empNo", "empName", "job", "mgr", "hiredate", "sal", "comm", "deptNumber"}
+)
+
+// formatNullInt format integer, null is empty value
+func formatNullInt(v null.Int) string {
+	if !v.Valid {
+		return ""
+	}
+	return strconv.FormatInt(v.Int64, 10)
+}
+
+// exportWriter represent streaming writer of rows in format ExportFormatJSON, ExportFormatNDJSON or ExportFormatCSV
+type exportWriter struct {
+	reqID  uint64
+	format string
+	rows   int64         // количество записанных строк
+	w      *bufio.Writer // буфер выгрузки
+	csv    *csv.Writer   // CSV поток
+	record []string      // переиспользуемая строка CSV
+	jw     jwriter.Writer
+
+	fields  *Fieldset     // выбранные поля, nil - все поля
+	sparse  *sparseObject // переиспользуемое представление строки с выбранными полями
+	columns []bool        // выбранные колонки CSV
+}
+
+// newExportWriter create streaming writer of format, delimiter is used for CSV only, row is pointer to exported structure
+func newExportWriter(reqID uint64, format string, delimiter rune, fields *Fieldset, row interface{}, w io.Writer) (*exportWriter, error) {
+	ew := &exportWriter{reqID: reqID, format: format, w: bufio.NewWriterSize(w, exportBufSize), fields: fields}
+	if fields.sparse() {
+		ew.sparse = newSparseObject(row, fields)
+	}
+
+	switch format {
+	case ExportFormatJSON, ExportFormatNDJSON:
+	case ExportFormatCSV:
+		ew.csv = csv.NewWriter(ew.w)
+		if delimiter != 0 {
+			ew.csv.Comma = delimiter
+		}
+	default:
+		return nil, myerror.New("6004", "Incorrect export format, only avaliable: 'json', 'ndjson', 'csv': reqID, format", reqID, format).PrintfInfo()
+	}
+	return ew, nil
+}
+
+// begin write start of stream
+func (ew *exportWriter) begin(header []string) error {
+	switch ew.format {
+	case ExportFormatJSON:
+		return ew.writeErr(ew.w.WriteByte('['))
+	case ExportFormatCSV:
+		if ew.fields.sparse() {
+			ew.columns = make([]bool, len(header))
+			for i, name := range header {
+				ew.columns[i] = ew.fields.Has(name)
+			}
+			header = ew.selectColumns(append([]string(nil), header...))
+		}
+		return ew.writeErr(ew.csv.Write(header))
+	}
+	return nil
+}
+
+// selectColumns remove not selected columns from CSV record
+func (ew *exportWriter) selectColumns(record []string) []string {
+	if ew.columns == nil {
+		return record
+	}
+	selected := record[:0]
+	for i, value := range record {
+		if ew.columns[i] {
+			selected = append(selected, value)
+		}
+	}
+	return selected
+}
+
+// writeJSON write row marshaled into jwriter, row is pointer to exported structure
+func (ew *exportWriter) writeJSON(row interface{}, marshal func(w *jwriter.Writer)) error {
+	if ew.format == ExportFormatJSON && ew.rows > 0 {
+		ew.jw.RawByte(',')
+	}
+	if ew.sparse != nil {
+		ew.sparse.value = reflect.ValueOf(row)
+		marshal = ew.sparse.MarshalEasyJSON
+	}
+	marshal(&ew.jw)
+	if ew.format == ExportFormatNDJSON {
+		ew.jw.RawByte('\n')
+	}
+	if ew.jw.Error != nil {
+		return myerror.WithCause("6001", "Error Marshal: reqID, row", ew.jw.Error, ew.reqID, ew.rows+1).PrintfInfo()
+	}
+	ew.rows++
+	_, err := ew.jw.DumpTo(ew.w) // буфер jwriter освобождается
+	return ew.writeErr(err)
+}
+
+// writeCSV write row as CSV record
+func (ew *exportWriter) writeCSV(fill func(record []string) []string) error {
+	ew.record = ew.selectColumns(fill(ew.record[:0]))
+	ew.rows++
+	return ew.writeErr(ew.csv.Write(ew.record))
+}
+
+// end write end of stream and flush buffer
+func (ew *exportWriter) end() error {
+	switch ew.format {
+	case ExportFormatJSON:
+		if err := ew.w.WriteByte(']'); err != nil {
+			return ew.writeErr(err)
+		}
+	case ExportFormatCSV:
+		ew.csv.Flush()
+		if err := ew.csv.Error(); err != nil {
+			return ew.writeErr(err)
+		}
+	}
+	return ew.writeErr(ew.w.Flush())
+}
+
+// writeErr wrap error of writing into stream
+func (ew *exportWriter) writeErr(err error) error {
+	if err != nil {
+		return myerror.WithCause("6004", "Error write export stream: reqID, row", err, ew.reqID, ew.rows).PrintfInfo(1)
+	}
+	return nil
+}
+
+// ExportDepts write all depts into w in format ExportFormatJSON, ExportFormatNDJSON or ExportFormatCSV with delimiter,
+// fields select exported fields, nil - all fields
+func (s *Service) ExportDepts(ctx context.Context, format string, delimiter rune, fields *Fieldset, w io.Writer) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	mylog.PrintfDebugMsg("START: reqID, format", reqID, format)
+
+	ew, myerr := newExportWriter(reqID, format, delimiter, fields, (*model.Dept)(nil), w)
+	if myerr != nil {
+		return 0, myerr
+	}
+
+	if myerr = ew.begin(deptCSVHeader); myerr != nil {
+		return 0, myerr
+	}
+	myerr = s.exportService.ExportDepts(ctx, func(v *model.Dept) error {
+		if ew.csv != nil {
+			return ew.writeCSV(func(record []string) []string {
+				return append(record, strconv.Itoa(v.Deptno), v.Dname, v.Loc.String)
+			})
+		}
+		return ew.writeJSON(v, v.MarshalEasyJSON)
+	})
+	if myerr != nil {
+		return ew.rows, myerr
+	}
+	if myerr = ew.end(); myerr != nil {
+		return ew.rows, myerr
+	}
+
+	mylog.PrintfDebugMsg("SUCCESS: reqID, rows", reqID, ew.rows)
+	return ew.rows, nil
+}
+
+// ExportEmps write all emps into w in format ExportFormatJSON, ExportFormatNDJSON or ExportFormatCSV with delimiter,
+// fields select exported fields, nil - all fields
+func (s *Service) ExportEmps(ctx context.Context, format string, delimiter rune, fields *Fieldset, w io.Writer) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	mylog.PrintfDebugMsg("START: reqID, format", reqID, format)
+
+	ew, myerr := newExportWriter(reqID, format, delimiter, fields, (*model.Emp)(nil), w)
+	if myerr != nil {
+		return 0, myerr
+	}
+
+	if myerr = ew.begin(empCSVHeader); myerr != nil {
+		return 0, myerr
+	}
+	myerr = s.exportService.ExportEmps(ctx, func(v *model.Emp) error {
+		if ew.csv != nil {
+			return ew.writeCSV(func(record []string) []string {
+				return append(record, strconv.Itoa(v.Empno), v.Ename.String, v.Job.String, formatNullInt(v.Mgr),
+					v.Hiredate.String, formatNullInt(v.Sal), formatNullInt(v.Comm), formatNullInt(v.Deptno))
+			})
+		}
+		return ew.writeJSON(v, v.MarshalEasyJSON)
+	})
+	if myerr != nil {
+		return ew.rows, myerr
+	}
+	if myerr = ew.end(); myerr != nil {
+		return ew.rows, myerr
+	}
+
+	mylog.PrintfDebugMsg("SUCCESS: reqID, rows", reqID, ew.rows)
+	return ew.rows, nil
+}
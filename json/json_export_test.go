@@ -0,0 +1,61 @@
+package json
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	model "github.com/romapres2010/httpserver/model"
+	"gopkg.in/guregu/null.v4"
+)
+
+// exportServiceMock represent model.ExportService over slice of Emp
+type exportServiceMock struct {
+	emps []*model.Emp
+}
+
+func (m *exportServiceMock) ExportDepts(ctx context.Context, out func(*model.Dept) error) error {
+	return nil
+}
+
+func (m *exportServiceMock) ExportEmps(ctx context.Context, out func(*model.Emp) error) error {
+	for _, v := range m.emps {
+		if err := out(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportEmps(t *testing.T) {
+	s := &Service{exportService: &exportServiceMock{emps: []*model.Emp{
+		{Empno: 1, Ename: null.StringFrom("KING"), Hiredate: null.StringFrom("1981-11-17"), Sal: null.IntFrom(5000)},
+		{Empno: 2, Job: null.StringFrom("CLERK; TEMP")},
+	}}}
+
+	tests := []struct {
+		format    string
+		delimiter rune
+		want      string
+	}{
+		{ExportFormatJSON, 0, `[{"empNo":1,"empName":"KING","job":null,"mgr":null,"hiredate":"1981-11-17","sal":5000,"comm":null,"deptNumber":null},{"empNo":2,"empName":null,"job":"CLERK; TEMP","mgr":null,"hiredate":null,"sal":null,"comm":null,"deptNumber":null}]`},
+		{ExportFormatNDJSON, 0, "{\"empNo\":1,\"empName\":\"KING\",\"job\":null,\"mgr\":null,\"hiredate\":\"1981-11-17\",\"sal\":5000,\"comm\":null,\"deptNumber\":null}\n{\"empNo\":2,\"empName\":null,\"job\":\"CLERK; TEMP\",\"mgr\":null,\"hiredate\":null,\"sal\":null,\"comm\":null,\"deptNumber\":null}\n"},
+		{ExportFormatCSV, ';', "empNo;empName;job;mgr;hiredate;sal;comm;deptNumber\n1;KING;;;1981-11-17;5000;;\n2;;\"CLERK; TEMP\";;;;;\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			rows, err := s.ExportEmps(context.Background(), tt.format, tt.delimiter, nil, &buf)
+			if err != nil || rows != 2 {
+				t.Fatalf("ExportEmps() = %v, %v, want 2 rows", rows, err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("ExportEmps() output\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := s.ExportEmps(context.Background(), "xml", 0, nil, &bytes.Buffer{}); err == nil {
+		t.Errorf("ExportEmps() for unknown format error = nil")
+	}
+}
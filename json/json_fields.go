@@ -0,0 +1,286 @@
+package json
+
+import (
+	stdjson "encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strconv"
+	"strings"
+
+	jwriter "github.com/mailru/easyjson/jwriter"
+	myerror "github.com/romapres2010/httpserver/error"
+	model "github.com/romapres2010/httpserver/model"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/guregu/null.v4"
+)
+
+// ErrCodeInvalidFields represent error of unknown field in parameters fields, include, exclude
+const ErrCodeInvalidFields = "6005"
+
+// Fieldset represent sparse fieldset of object and control of embedded objects.
+// Names of fields are names of JSON representation, embedded objects are included by default
+type Fieldset struct {
+	fields   map[string]bool // выбранные поля, nil - все поля
+	excluded map[string]bool // исключенные вложенные объекты
+	Embedded bool            // вложенные объекты нужно запрашивать
+}
+
+// Has return true if field is selected
+func (fs *Fieldset) Has(name string) bool {
+	if fs == nil {
+		return true
+	}
+	if fs.excluded[name] {
+		return false
+	}
+	return fs.fields == nil || fs.fields[name]
+}
+
+// sparse return true if not all fields are selected
+func (fs *Fieldset) sparse() bool {
+	return fs != nil && (fs.fields != nil || len(fs.excluded) > 0)
+}
+
+// objectField represent field of object representation
+type objectField struct {
+	index     int    // номер поля в структуре
+	name      string // имя поля JSON
+	xmlName   string // имя элемента XML
+	omitempty bool   // пустое значение не выводится
+	embedded  bool   // вложенные объекты
+}
+
+// objectFields return fields of struct type t by json tags, embedded objects are slices of structs
+func objectFields(t reflect.Type) []objectField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fields := make([]objectField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if field.PkgPath != "" || tag[0] == "-" || tag[0] == "" {
+			continue
+		}
+		f := objectField{index: i, name: tag[0], xmlName: tag[0]}
+		for _, opt := range tag[1:] {
+			if opt == "omitempty" {
+				f.omitempty = true
+			}
+		}
+		if xmlTag := strings.Split(field.Tag.Get("xml"), ",")[0]; xmlTag != "" {
+			f.xmlName = xmlTag
+		}
+		if field.Type.Kind() == reflect.Slice {
+			elem := field.Type.Elem()
+			for elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			f.embedded = elem.Kind() == reflect.Struct
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// NewFieldset parse lists of fields, include and exclude for representation v of object
+func NewFieldset(reqID uint64, v interface{}, fields []string, include []string, exclude []string) (*Fieldset, error) {
+	known := make(map[string]objectField)
+	for _, f := range objectFields(reflect.TypeOf(v)) {
+		known[f.name] = f
+	}
+	check := func(param string, names []string, embedded bool) error {
+		for _, name := range names {
+			if f, ok := known[name]; !ok || (embedded && !f.embedded) {
+				return myerror.New(ErrCodeInvalidFields, "Unknown field in parameter: reqID, parameter, field", reqID, param, name).PrintfInfo(2)
+			}
+		}
+		return nil
+	}
+	if err := check("fields", fields, false); err != nil {
+		return nil, err
+	}
+	if err := check("include", include, true); err != nil {
+		return nil, err
+	}
+	if err := check("exclude", exclude, true); err != nil {
+		return nil, err
+	}
+
+	fs := &Fieldset{}
+	if len(fields) > 0 {
+		fs.fields = make(map[string]bool, len(fields)+len(include))
+		for _, name := range append(fields, include...) {
+			fs.fields[name] = true
+		}
+	}
+	for _, name := range exclude {
+		if fs.fields[name] {
+			return nil, myerror.New(ErrCodeInvalidFields, "Field is included and excluded at the same time: reqID, field", reqID, name).PrintfInfo(1)
+		}
+		for _, inc := range include {
+			if inc == name {
+				return nil, myerror.New(ErrCodeInvalidFields, "Field is included and excluded at the same time: reqID, field", reqID, name).PrintfInfo(1)
+			}
+		}
+		if fs.excluded == nil {
+			fs.excluded = make(map[string]bool, len(exclude))
+		}
+		fs.excluded[name] = true
+	}
+	for _, f := range known {
+		if f.embedded && fs.Has(f.name) {
+			fs.Embedded = true
+		}
+	}
+	return fs, nil
+}
+
+// DeptFieldset return fieldset of Dept in version of API
+func (s *Service) DeptFieldset(reqID uint64, fields []string, include []string, exclude []string) (*Fieldset, error) {
+	var v interface{} = model.Dept{}
+	if s.marshalers != nil && s.marshalers.NewDept != nil {
+		v = s.marshalers.NewDept()
+	}
+	return NewFieldset(reqID, v, fields, include, exclude)
+}
+
+// EmpFieldset return fieldset of Emp
+func (s *Service) EmpFieldset(reqID uint64, fields []string) (*Fieldset, error) {
+	return NewFieldset(reqID, model.Emp{}, fields, nil, nil)
+}
+
+// sparseObject represent object with selected fields only, fields are encoded in order of struct.
+// It supports JSON, XML and MessagePack codecs
+type sparseObject struct {
+	value  reflect.Value // структура объекта
+	fields []objectField // выбранные поля
+}
+
+// newSparseObject create sparse representation of object v, v must be pointer to struct
+func newSparseObject(v interface{}, fs *Fieldset) *sparseObject {
+	value := reflect.ValueOf(v)
+	o := &sparseObject{value: value}
+	for _, f := range objectFields(value.Type()) {
+		if fs.Has(f.name) {
+			o.fields = append(o.fields, f)
+		}
+	}
+	return o
+}
+
+// skip return true if value of field is not encoded
+func (o *sparseObject) skip(f objectField, v reflect.Value) bool {
+	if !f.omitempty {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (o *sparseObject) MarshalEasyJSON(w *jwriter.Writer) {
+	value := reflect.Indirect(o.value)
+	w.RawByte('{')
+	first := true
+	for _, f := range o.fields {
+		v := value.Field(f.index)
+		if o.skip(f, v) {
+			continue
+		}
+		if !first {
+			w.RawByte(',')
+		}
+		first = false
+		w.String(f.name)
+		w.RawByte(':')
+		w.Raw(stdjson.Marshal(v.Interface()))
+	}
+	w.RawByte('}')
+}
+
+// MarshalXML supports xml.Marshaler interface
+func (o *sparseObject) MarshalXML(e *xml.Encoder, start xml.StartElement) (err error) {
+	value := reflect.Indirect(o.value)
+	start = xml.StartElement{Name: xml.Name{Local: xmlElementName(value.Type())}}
+	if err = e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, f := range o.fields {
+		v := value.Field(f.index)
+		if o.skip(f, v) {
+			continue
+		}
+		name := xml.StartElement{Name: xml.Name{Local: strings.Split(f.xmlName, ">")[0]}}
+		switch fv := v.Interface().(type) {
+		case null.String:
+			if fv.Valid {
+				err = e.EncodeElement(fv.String, name)
+			}
+		case null.Int:
+			if fv.Valid {
+				err = e.EncodeElement(strconv.FormatInt(fv.Int64, 10), name)
+			}
+		default:
+			if f.embedded {
+				// вложенные объекты в элементе-обертке
+				if err = e.EncodeToken(name); err != nil {
+					return err
+				}
+				for i := 0; i < v.Len(); i++ {
+					if err = e.Encode(v.Index(i).Interface()); err != nil {
+						return err
+					}
+				}
+				err = e.EncodeToken(name.End())
+			} else if v.Kind() != reflect.Ptr || !v.IsNil() {
+				err = e.EncodeElement(v.Interface(), name)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// EncodeMsgpack supports msgpack.CustomEncoder interface
+func (o *sparseObject) EncodeMsgpack(enc *msgpack.Encoder) error {
+	value := reflect.Indirect(o.value)
+	count := 0
+	for _, f := range o.fields {
+		if !o.skip(f, value.Field(f.index)) {
+			count++
+		}
+	}
+	if err := enc.EncodeMapLen(count); err != nil {
+		return err
+	}
+	for _, f := range o.fields {
+		v := value.Field(f.index)
+		if o.skip(f, v) {
+			continue
+		}
+		if err := enc.EncodeString(f.name); err != nil {
+			return err
+		}
+		if err := enc.Encode(v.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xmlElementName return name of XML element of struct type: tag of field XMLName or type name in lower case
+func xmlElementName(t reflect.Type) string {
+	if field, ok := t.FieldByName("XMLName"); ok {
+		if name := strings.Split(field.Tag.Get("xml"), ",")[0]; name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(t.Name())
+}
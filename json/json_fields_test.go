@@ -0,0 +1,191 @@
+package json
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/romapres2010/httpserver/codec"
+	model "github.com/romapres2010/httpserver/model"
+	"gopkg.in/guregu/null.v4"
+)
+
+// deptServiceMock represent model.DeptService with one Dept
+type deptServiceMock struct {
+	withEmps bool        // признак последнего запроса вложенных объектов
+	patched  *model.Dept // последний результат patch
+	updated  *model.Dept // последний результат update
+}
+
+func (m *deptServiceMock) GetDept(ctx context.Context, out *model.Dept, withEmps bool) (bool, error) {
+	m.withEmps = withEmps
+	out.Dname = "ACCOUNTING"
+	out.Loc = null.StringFrom("NEW YORK")
+	out.Version = 3
+	if withEmps {
+		out.Emps = append(out.Emps, &model.Emp{Empno: 7839, Ename: null.StringFrom("KING"), Deptno: null.IntFrom(10), Version: 1})
+	}
+	return true, nil
+}
+
+func (m *deptServiceMock) GetDeptVersion(ctx context.Context, deptno int, out *model.Version, withEmps bool) (bool, error) {
+	m.withEmps = withEmps
+	out.Version = 3
+	if withEmps {
+		out.Embedded = []model.EmbeddedVersion{{Key: 7839, Version: 1}}
+	}
+	return true, nil
+}
+
+func (m *deptServiceMock) GetDeptsPK(ctx context.Context, out *model.DeptPKs) error {
+	return nil
+}
+
+func (m *deptServiceMock) CreateDept(ctx context.Context, in *model.Dept, out *model.Dept) error {
+	return nil
+}
+
+func (m *deptServiceMock) UpdateDept(ctx context.Context, in *model.Dept, out *model.Dept) (bool, error) {
+	// копия результата - структуры in возвращаются в pool
+	m.updated = &model.Dept{Deptno: in.Deptno, Dname: in.Dname, Loc: in.Loc, Version: in.Version}
+	*out = *m.updated
+	out.Version = in.Version + 1
+	return true, nil
+}
+
+func (m *deptServiceMock) PatchDept(ctx context.Context, cur *model.Dept, in *model.Dept, out *model.Dept) error {
+	// копия результата - структуры in возвращаются в pool
+	m.patched = &model.Dept{Deptno: in.Deptno, Dname: in.Dname, Loc: in.Loc, Version: in.Version}
+	for _, emp := range in.Emps {
+		e := *emp
+		m.patched.Emps = append(m.patched.Emps, &e)
+	}
+	*out = *m.patched
+	out.Version = cur.Version + 1
+	return nil
+}
+
+func TestNewFieldset(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []string
+		include  []string
+		exclude  []string
+		wantErr  bool
+		embedded bool
+	}{
+		{"all", nil, nil, nil, false, true},
+		{"fields", []string{"deptName", "deptLocation"}, nil, nil, false, false},
+		{"fields with emps", []string{"deptName", "emps"}, nil, nil, false, true},
+		{"include", []string{"deptName"}, []string{"emps"}, nil, false, true},
+		{"exclude", nil, nil, []string{"emps"}, false, false},
+		{"unknown field", []string{"dname"}, nil, nil, true, false},
+		{"include not embedded", nil, []string{"deptName"}, nil, true, false},
+		{"include and exclude", nil, []string{"emps"}, []string{"emps"}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, err := (&Service{}).DeptFieldset(0, tt.fields, tt.include, tt.exclude)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeptFieldset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && fs.Embedded != tt.embedded {
+				t.Errorf("DeptFieldset().Embedded = %v, want %v", fs.Embedded, tt.embedded)
+			}
+		})
+	}
+
+	// в версии 2 вложенные объекты называются employees
+	if _, err := (&Service{}).WithMarshalers(Versions["v2"]).DeptFieldset(0, []string{"name"}, []string{"employees"}, nil); err != nil {
+		t.Errorf("DeptFieldset() of version 2 error = %v", err)
+	}
+}
+
+func TestGetDeptFields(t *testing.T) {
+	mock := &deptServiceMock{}
+	tests := []struct {
+		name     string
+		s        *Service
+		c        codec.Codec
+		fields   []string
+		exclude  []string
+		want     string
+		withEmps bool
+	}{
+		{"json", &Service{}, codec.JSON, []string{"deptName", "deptLocation"}, nil,
+			`{"deptName":"ACCOUNTING","deptLocation":"NEW YORK"}`, false},
+		{"json exclude", &Service{}, codec.JSON, nil, []string{"emps"},
+			`{"deptNumber":10,"deptName":"ACCOUNTING","deptLocation":"NEW YORK","version":3}`, false},
+		{"json emps", &Service{}, codec.JSON, []string{"deptName", "emps"}, nil,
+			`{"deptName":"ACCOUNTING","emps":[{"empNo":7839,"empName":"KING","job":null,"mgr":null,"hiredate":null,"sal":null,"comm":null,"deptNumber":10,"version":1}]}`, true},
+		{"xml", &Service{}, codec.XML, []string{"deptName", "emps"}, nil,
+			`<dept><deptName>ACCOUNTING</deptName><emps><emp><empNo>7839</empNo><empName>KING</empName><deptNumber>10</deptNumber><version>1</version></emp></emps></dept>`, true},
+		{"json v2", (&Service{}).WithMarshalers(Versions["v2"]), codec.JSON, []string{"name", "location"}, nil,
+			`{"name":"ACCOUNTING","location":"NEW YORK"}`, false},
+		{"xml v2", (&Service{}).WithMarshalers(Versions["v2"]), codec.XML, []string{"name", "employees"}, nil,
+			`<dept><name>ACCOUNTING</name><employees><emp><id>7839</id><name>KING</name><deptId>10</deptId><version>1</version></emp></employees></dept>`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.s.deptService = mock
+			fs, err := tt.s.DeptFieldset(0, tt.fields, nil, tt.exclude)
+			if err != nil {
+				t.Fatalf("DeptFieldset() error = %v", err)
+			}
+			data, _, err := tt.s.GetDept(context.Background(), tt.c, 10, fs, nil)
+			if err != nil {
+				t.Fatalf("GetDept() error = %v", err)
+			}
+			if got := strings.TrimPrefix(string(data), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"); got != tt.want {
+				t.Errorf("GetDept() = %s, want %s", got, tt.want)
+			}
+			if mock.withEmps != tt.withEmps {
+				t.Errorf("GetDept() requested emps = %v, want %v", mock.withEmps, tt.withEmps)
+			}
+		})
+	}
+
+	// MessagePack декодируется в полную структуру
+	s := &Service{deptService: mock}
+	fs, _ := s.DeptFieldset(0, []string{"deptName"}, nil, nil)
+	data, _, err := s.GetDept(context.Background(), codec.MsgPack, 10, fs, nil)
+	if err != nil {
+		t.Fatalf("GetDept() error = %v", err)
+	}
+	got := &model.Dept{}
+	if err = codec.MsgPack.Unmarshal(data, got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Dname != "ACCOUNTING" || got.Deptno != 0 || got.Loc.Valid || got.Emps != nil {
+		t.Errorf("GetDept() MessagePack = %+v, want deptName only", got)
+	}
+}
+
+func TestExportEmpsFields(t *testing.T) {
+	s := &Service{exportService: &exportServiceMock{emps: []*model.Emp{
+		{Empno: 1, Ename: null.StringFrom("KING"), Sal: null.IntFrom(5000)},
+	}}}
+	fs, err := s.EmpFieldset(0, []string{"empName", "sal"})
+	if err != nil {
+		t.Fatalf("EmpFieldset() error = %v", err)
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{ExportFormatJSON, `[{"empName":"KING","sal":5000}]`},
+		{ExportFormatCSV, "empName,sal\nKING,5000\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf strings.Builder
+			if _, err := s.ExportEmps(context.Background(), tt.format, 0, fs, &buf); err != nil {
+				t.Fatalf("ExportEmps() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("ExportEmps() = %s, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
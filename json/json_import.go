@@ -0,0 +1,334 @@
+package json
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+	model "github.com/romapres2010/httpserver/model"
+	"gopkg.in/guregu/null.v4"
+)
+
+// Форматы потока загрузки
+const (
+	ImportFormatCSV    = "csv"    // CSV, первая строка - имена полей JSON
+	ImportFormatNDJSON = "ndjson" // JSON объект на каждой строке
+)
+
+// ErrCodeInvalidRow - строка потока загрузки не прошла проверку
+const ErrCodeInvalidRow = "6003"
+
+// ndjsonMaxLineSize - максимальная длина строки NDJSON
+const ndjsonMaxLineSize = 1024 * 1024
+
+// fieldSetter represent setter of field from CSV value, empty value is null
+type fieldSetter func(v interface{}, value string) error
+
+// deptFields represent CSV columns of Dept
+var deptFields = map[string]fieldSetter{
+	"deptNumber": func(v interface{}, value string) (err error) {
+		v.(*model.Dept).Deptno, err = parseInt(value)
+		return err
+	},
+	"deptName": func(v interface{}, value string) error {
+		v.(*model.Dept).Dname = value
+		return nil
+	},
+	"deptLocation": func(v interface{}, value string) error {
+		v.(*model.Dept).Loc = null.NewString(value, value != "")
+		return nil
+	},
+}
+
+// empFields represent CSV columns of Emp
+var empFields = map[string]fieldSetter{
+	"empNo": func(v interface{}, value string) (err error) {
+		v.(*model.Emp).Empno, err = parseInt(value)
+		return err
+	},
+	"empName": func(v interface{}, value string) error {
+		v.(*model.Emp).Ename = null.NewString(value, value != "")
+		return nil
+	},
+	"job": func(v interface{}, value string) error {
+		v.(*model.Emp).Job = null.NewString(value, value != "")
+		return nil
+	},
+	"mgr": func(v interface{}, value string) (err error) {
+		v.(*model.Emp).Mgr, err = parseNullInt(value)
+		return err
+	},
+	"hiredate": func(v interface{}, value string) error {
+		v.(*model.Emp).Hiredate = null.NewString(value, value != "")
+		return nil
+	},
+	"sal": func(v interface{}, value string) (err error) {
+		v.(*model.Emp).Sal, err = parseNullInt(value)
+		return err
+	},
+	"comm": func(v interface{}, value string) (err error) {
+		v.(*model.Emp).Comm, err = parseNullInt(value)
+		return err
+	},
+	"deptNumber": func(v interface{}, value string) (err error) {
+		v.(*model.Emp).Deptno, err = parseNullInt(value)
+		return err
+	},
+}
+
+// parseInt parse required integer
+func parseInt(value string) (int, error) {
+	return strconv.Atoi(value)
+}
+
+// parseNullInt parse integer, empty value is null
+func parseNullInt(value string) (null.Int, error) {
+	if value == "" {
+		return null.Int{}, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	return null.NewInt(i, err == nil), err
+}
+
+// validateDept check Dept before loading
+func validateDept(v *model.Dept) error {
+	if v.Deptno <= 0 {
+		return fmt.Errorf("deptNumber must be positive, got %v", v.Deptno)
+	}
+	if v.Dname == "" {
+		return fmt.Errorf("deptName is required")
+	}
+	return nil
+}
+
+// validateEmp check Emp before loading
+func validateEmp(v *model.Emp) error {
+	if v.Empno <= 0 {
+		return fmt.Errorf("empNo must be positive, got %v", v.Empno)
+	}
+	if v.Sal.Valid && v.Sal.Int64 < 0 {
+		return fmt.Errorf("sal must not be negative, got %v", v.Sal.Int64)
+	}
+	if v.Comm.Valid && v.Comm.Int64 < 0 {
+		return fmt.Errorf("comm must not be negative, got %v", v.Comm.Int64)
+	}
+	if v.Hiredate.Valid {
+		if _, err := time.Parse(model.DateLayout, v.Hiredate.String); err != nil {
+			return fmt.Errorf("hiredate must be in format %s, got %q", model.DateLayout, v.Hiredate.String)
+		}
+	}
+	return nil
+}
+
+// rowReader represent streaming reader of CSV or NDJSON rows with validation.
+// Строки читаются по одной - память не зависит от объема потока
+type rowReader struct {
+	reqID     uint64
+	format    string
+	row       int                                   // номер текущей строки данных
+	csv       *csv.Reader                           // CSV поток
+	setters   []fieldSetter                         // setter для каждой колонки CSV
+	fields    map[string]fieldSetter                // допустимые колонки CSV
+	scanner   *bufio.Scanner                        // NDJSON поток
+	unmarshal func(v interface{}, in *jlexer.Lexer) // разбор строки NDJSON
+	validate  func(v interface{}) error             // проверка прочитанной строки
+}
+
+// newRowReader create streaming reader of format
+func newRowReader(reqID uint64, format string, in io.Reader, fields map[string]fieldSetter) (*rowReader, error) {
+	r := &rowReader{reqID: reqID, format: format, fields: fields}
+
+	switch format {
+	case ImportFormatCSV:
+		r.csv = csv.NewReader(in)
+		r.csv.ReuseRecord = true // не выделяем память на каждую строку
+	case ImportFormatNDJSON:
+		r.scanner = bufio.NewScanner(in)
+		r.scanner.Buffer(make([]byte, 0, 64*1024), ndjsonMaxLineSize)
+	default:
+		return nil, myerror.New(ErrCodeInvalidRow, "Incorrect import format, only avaliable: 'csv', 'ndjson': reqID, format", reqID, format).PrintfInfo()
+	}
+	return r, nil
+}
+
+// isBodyError check if err is catalogued error of reading stream (e.g. size limit exceeded), it is returned as is
+func isBodyError(err error) bool {
+	_, ok := err.(*myerror.Error)
+	return ok
+}
+
+// readHeader read CSV header with JSON field names
+func (r *rowReader) readHeader() error {
+	header, err := r.csv.Read()
+	if err == io.EOF || isBodyError(err) {
+		return err
+	}
+	if err != nil {
+		return myerror.WithCause(ErrCodeInvalidRow, "Error read CSV header: reqID", err, r.reqID).PrintfInfo()
+	}
+	r.setters = make([]fieldSetter, len(header))
+	for i, name := range header {
+		setter, ok := r.fields[name]
+		if !ok {
+			return myerror.New(ErrCodeInvalidRow, "Unknown CSV column: reqID, column", r.reqID, name).PrintfInfo()
+		}
+		r.setters[i] = setter
+	}
+	return nil
+}
+
+// next read next row into v, return io.EOF at the end of stream
+func (r *rowReader) next(v interface{}) error {
+	if r.csv != nil {
+		if r.setters == nil {
+			if err := r.readHeader(); err != nil {
+				return err
+			}
+		}
+		record, err := r.csv.Read()
+		if err == io.EOF || isBodyError(err) {
+			return err
+		}
+		r.row++
+		if err != nil {
+			return myerror.WithCause(ErrCodeInvalidRow, "Error read CSV row: reqID, row", err, r.reqID, r.row).PrintfInfo()
+		}
+		for i, value := range record {
+			if err = r.setters[i](v, value); err != nil {
+				return myerror.WithCause(ErrCodeInvalidRow, "Error parse CSV value: reqID, row, column", err, r.reqID, r.row, i+1).PrintfInfo()
+			}
+		}
+	} else {
+		// пропускаем пустые строки
+		var line []byte
+		for len(line) == 0 {
+			if !r.scanner.Scan() {
+				if err := r.scanner.Err(); isBodyError(err) {
+					return err
+				} else if err != nil {
+					return myerror.WithCause(ErrCodeInvalidRow, "Error read NDJSON line: reqID, row", err, r.reqID, r.row+1).PrintfInfo()
+				}
+				return io.EOF
+			}
+			r.row++
+			line = r.scanner.Bytes()
+		}
+		in := jlexer.Lexer{Data: line}
+		r.unmarshal(v, &in)
+		in.Consumed()
+		if err := in.Error(); err != nil {
+			return myerror.WithCause(ErrCodeInvalidRow, "Error Unmarshal NDJSON line: reqID, row", err, r.reqID, r.row).PrintfInfo()
+		}
+	}
+
+	if err := r.validate(v); err != nil {
+		return myerror.WithCause(ErrCodeInvalidRow, "Invalid row: reqID, row", err, r.reqID, r.row).PrintfInfo()
+	}
+	return nil
+}
+
+// deptReader represent model.DeptReader over CSV or NDJSON stream
+type deptReader struct {
+	*rowReader
+}
+
+// Next read next Dept
+func (r *deptReader) Next(out *model.Dept) error {
+	return r.next(out)
+}
+
+// newDeptReader create model.DeptReader over CSV or NDJSON stream
+func newDeptReader(reqID uint64, format string, in io.Reader) (*deptReader, error) {
+	rr, err := newRowReader(reqID, format, in, deptFields)
+	if err != nil {
+		return nil, err
+	}
+	rr.unmarshal = func(v interface{}, in *jlexer.Lexer) { v.(*model.Dept).UnmarshalEasyJSON(in) }
+	rr.validate = func(v interface{}) error { return validateDept(v.(*model.Dept)) }
+	return &deptReader{rr}, nil
+}
+
+// empReader represent model.EmpReader over CSV or NDJSON stream
+type empReader struct {
+	*rowReader
+}
+
+// Next read next Emp
+func (r *empReader) Next(out *model.Emp) error {
+	return r.next(out)
+}
+
+// newEmpReader create model.EmpReader over CSV or NDJSON stream
+func newEmpReader(reqID uint64, format string, in io.Reader) (*empReader, error) {
+	rr, err := newRowReader(reqID, format, in, empFields)
+	if err != nil {
+		return nil, err
+	}
+	rr.unmarshal = func(v interface{}, in *jlexer.Lexer) { v.(*model.Emp).UnmarshalEasyJSON(in) }
+	rr.validate = func(v interface{}) error { return validateEmp(v.(*model.Emp)) }
+	return &empReader{rr}, nil
+}
+
+// importResultMarshal - marshal result of import into JSON
+func importResultMarshal(reqID uint64, table string, rows int64, duration time.Duration, buf []byte) (outBuf []byte, myerr error) {
+	w := jwriter.Writer{} // подготовим EasyJSON Writer
+	w.RawString(`{"table":`)
+	w.String(table)
+	w.RawString(`,"rows":`)
+	w.Int64(rows)
+	w.RawString(`,"durationMs":`)
+	w.Int64(int64(duration / time.Millisecond))
+	w.RawByte('}')
+
+	if w.Error != nil {
+		return nil, myerror.WithCause("6001", "Error Marshal: reqID", w.Error, reqID).PrintfInfo(1)
+	}
+	return w.Buffer.BuildBytes(buf), nil
+}
+
+// ImportDepts load stream of depts in format ImportFormatCSV or ImportFormatNDJSON
+func (s *Service) ImportDepts(ctx context.Context, format string, in io.Reader, buf []byte) (outBuf []byte, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	mylog.PrintfDebugMsg("START: reqID, format", reqID, format)
+
+	r, myerr := newDeptReader(reqID, format, in)
+	if myerr != nil {
+		return nil, myerr
+	}
+
+	start := time.Now()
+	rows, myerr := s.importService.ImportDepts(ctx, r)
+	if myerr != nil {
+		return nil, myerr
+	}
+
+	return importResultMarshal(reqID, "dept", rows, time.Since(start), buf)
+}
+
+// ImportEmps load stream of emps in format ImportFormatCSV or ImportFormatNDJSON
+func (s *Service) ImportEmps(ctx context.Context, format string, in io.Reader, buf []byte) (outBuf []byte, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	mylog.PrintfDebugMsg("START: reqID, format", reqID, format)
+
+	r, myerr := newEmpReader(reqID, format, in)
+	if myerr != nil {
+		return nil, myerr
+	}
+
+	start := time.Now()
+	rows, myerr := s.importService.ImportEmps(ctx, r)
+	if myerr != nil {
+		return nil, myerr
+	}
+
+	return importResultMarshal(reqID, "emp", rows, time.Since(start), buf)
+}
@@ -0,0 +1,81 @@
+package json
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	model "github.com/romapres2010/httpserver/model"
+)
+
+func TestEmpReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		rows    int
+		errCode string
+	}{
+		{"csv", ImportFormatCSV, "empNo,empName,hiredate,sal\n1,KING,1981-11-17,5000\n2,,,\n", 2, ""},
+		{"ndjson", ImportFormatNDJSON, "{\"empNo\":1,\"empName\":\"KING\",\"sal\":5000}\n\n{\"empNo\":2}\n", 2, ""},
+		{"csv empty", ImportFormatCSV, "", 0, ""},
+		{"csv unknown column", ImportFormatCSV, "empNo,salary\n1,100\n", 0, ErrCodeInvalidRow},
+		{"csv bad number", ImportFormatCSV, "empNo,sal\n1,100\n2,abc\n", 1, ErrCodeInvalidRow},
+		{"csv negative sal", ImportFormatCSV, "empNo,sal\n1,-1\n", 0, ErrCodeInvalidRow},
+		{"ndjson bad date", ImportFormatNDJSON, "{\"empNo\":1,\"hiredate\":\"17.11.1981\"}\n", 0, ErrCodeInvalidRow},
+		{"ndjson bad json", ImportFormatNDJSON, "{\"empNo\":1}\n{\"empNo\":\n", 1, ErrCodeInvalidRow},
+		{"ndjson no empNo", ImportFormatNDJSON, "{\"empName\":\"KING\"}\n", 0, ErrCodeInvalidRow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newEmpReader(0, tt.format, strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("newEmpReader() error = %v", err)
+			}
+
+			rows := 0
+			v := &model.Emp{}
+			for {
+				v.Reset()
+				if err = r.Next(v); err != nil {
+					break
+				}
+				rows++
+			}
+
+			if tt.errCode == "" && err != io.EOF {
+				t.Errorf("Next() error = %v, want io.EOF", err)
+			}
+			if tt.errCode != "" {
+				if myerr, ok := err.(*myerror.Error); !ok || myerr.Code != tt.errCode {
+					t.Errorf("Next() error = %v, want code %s", err, tt.errCode)
+				}
+			}
+			if rows != tt.rows {
+				t.Errorf("Next() rows = %d, want %d", rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestDeptReaderCSV(t *testing.T) {
+	r, err := newDeptReader(0, ImportFormatCSV, strings.NewReader("deptName,deptNumber,deptLocation\nSALES,30,\n"))
+	if err != nil {
+		t.Fatalf("newDeptReader() error = %v", err)
+	}
+	v := &model.Dept{}
+	if err = r.Next(v); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if v.Deptno != 30 || v.Dname != "SALES" || v.Loc.Valid {
+		t.Errorf("Next() = %+v, want deptno 30, dname SALES, null loc", v)
+	}
+	if err = r.Next(v); err != io.EOF {
+		t.Errorf("Next() error = %v, want io.EOF", err)
+	}
+
+	if _, err = newDeptReader(0, "xml", strings.NewReader("")); err == nil {
+		t.Errorf("newDeptReader() for unknown format error = nil")
+	}
+}
@@ -0,0 +1,65 @@
+package json
+
+import (
+	"fmt"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	model "github.com/romapres2010/httpserver/model"
+	"github.com/romapres2010/httpserver/patch"
+	"gopkg.in/guregu/null.v4"
+)
+
+// Коды ошибок частичного обновления
+const (
+	ErrCodeInvalidPatch    = "6006" // patch не может быть применен или результат patch некорректен
+	ErrCodePatchTestFailed = "6007" // не выполнена операция test JSON Patch
+)
+
+// applyPatch apply patch of mediaType patch.MergePatchType or patch.JSONPatchType to JSON document doc
+func applyPatch(reqID uint64, mediaType string, doc []byte, inBuf []byte) ([]byte, error) {
+	patched, err := patch.Apply(mediaType, doc, inBuf)
+	if err == patch.ErrTestFailed {
+		return nil, myerror.WithCause(ErrCodePatchTestFailed, "Patch is not applied: reqID, Content-Type", err, reqID, mediaType).PrintfInfo(1)
+	}
+	if err != nil {
+		return nil, myerror.WithCause(ErrCodeInvalidPatch, "Patch can not be applied: reqID, Content-Type, patch", err, reqID, mediaType, string(inBuf)).PrintfInfo(1)
+	}
+	return patched, nil
+}
+
+// validatePatchedEmp check Emp after patch, cur is state before patch, nil - new Emp.
+// Unchanged hiredate is not checked - it is returned from DB in format of DB driver
+func validatePatchedEmp(cur *model.Emp, in *model.Emp) error {
+	v := *in
+	if cur != nil && v.Hiredate == cur.Hiredate {
+		v.Hiredate = null.String{}
+	}
+	return validateEmp(&v)
+}
+
+// validatePatchedDept check Dept with emps after patch, cur is state before patch. Emps can not be removed by patch
+func validatePatchedDept(cur *model.Dept, in *model.Dept) error {
+	if err := validateDept(in); err != nil {
+		return err
+	}
+	curEmps := make(map[int]*model.Emp, len(cur.Emps))
+	for _, curEmp := range cur.Emps {
+		curEmps[curEmp.Empno] = curEmp
+	}
+	inEmps := make(map[int]bool, len(in.Emps))
+	for _, inEmp := range in.Emps {
+		if inEmps[inEmp.Empno] {
+			return fmt.Errorf("emp %v is duplicated", inEmp.Empno)
+		}
+		inEmps[inEmp.Empno] = true
+		if err := validatePatchedEmp(curEmps[inEmp.Empno], inEmp); err != nil {
+			return fmt.Errorf("emp %v: %v", inEmp.Empno, err)
+		}
+	}
+	for _, curEmp := range cur.Emps {
+		if !inEmps[curEmp.Empno] {
+			return fmt.Errorf("emp %v can not be removed by patch", curEmp.Empno)
+		}
+	}
+	return nil
+}
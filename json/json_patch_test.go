@@ -0,0 +1,80 @@
+package json
+
+import (
+	"context"
+	"testing"
+
+	"github.com/romapres2010/httpserver/codec"
+	myerror "github.com/romapres2010/httpserver/error"
+	model "github.com/romapres2010/httpserver/model"
+	"github.com/romapres2010/httpserver/patch"
+	mysql "github.com/romapres2010/httpserver/sqlxx"
+)
+
+// txServiceMock represent model.TxService without DB
+type txServiceMock struct{}
+
+func (txServiceMock) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// versionIs return precondition, which is satisfied by current row version equal to version with versions of emps
+func versionIs(version int64) Precondition {
+	return func(v model.Version) error {
+		if v.Version != version || v.Embedded == nil {
+			return myerror.New(mysql.ErrCodeVersionConflict, "Version does not match: version, current version", version, v.Version)
+		}
+		return nil
+	}
+}
+
+func TestPatchDept(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         *Service
+		mediaType string
+		precond   Precondition
+		patch     string
+		want      string
+		wantCode  string
+	}{
+		{"merge", &Service{}, patch.MergePatchType, nil, `{"deptLocation":"BOSTON"}`,
+			`{"deptNumber":10,"deptName":"ACCOUNTING","deptLocation":"BOSTON","version":4,"emps":[{"empNo":7839,"empName":"KING","job":null,"mgr":null,"hiredate":null,"sal":null,"comm":null,"deptNumber":10,"version":1}]}`, ""},
+		{"json patch", &Service{}, patch.JSONPatchType, versionIs(3), `[{"op":"test","path":"/emps/0/empNo","value":7839},{"op":"replace","path":"/emps/0/sal","value":5000}]`,
+			`{"deptNumber":10,"deptName":"ACCOUNTING","deptLocation":"NEW YORK","version":4,"emps":[{"empNo":7839,"empName":"KING","job":null,"mgr":null,"hiredate":null,"sal":5000,"comm":null,"deptNumber":10,"version":1}]}`, ""},
+		{"merge v2", (&Service{}).WithMarshalers(Versions["v2"]), patch.MergePatchType, nil, `{"location":null}`,
+			`{"id":10,"name":"ACCOUNTING","version":4,"employees":[{"id":7839,"name":"KING","deptId":10,"version":1}]}`, ""},
+		{"change id", &Service{}, patch.MergePatchType, nil, `{"deptNumber":20}`, ``, ErrCodeInvalidPatch},
+		{"remove emps", &Service{}, patch.MergePatchType, nil, `{"emps":null}`, ``, ErrCodeInvalidPatch},
+		{"replace emps", &Service{}, patch.MergePatchType, nil, `{"emps":[]}`, ``, ErrCodeInvalidPatch},
+		{"remove emp", &Service{}, patch.JSONPatchType, nil, `[{"op":"remove","path":"/emps/0"}]`, ``, ErrCodeInvalidPatch},
+		{"invalid result", &Service{}, patch.JSONPatchType, nil, `[{"op":"replace","path":"/deptName","value":""}]`, ``, ErrCodeInvalidPatch},
+		{"invalid patch", &Service{}, patch.JSONPatchType, nil, `[{"op":"remove","path":"/unknown"}]`, ``, ErrCodeInvalidPatch},
+		{"test failed", &Service{}, patch.JSONPatchType, nil, `[{"op":"test","path":"/deptName","value":"SALES"}]`, ``, ErrCodePatchTestFailed},
+		{"version", &Service{}, patch.MergePatchType, versionIs(2), `{"deptLocation":"BOSTON"}`, ``, mysql.ErrCodeVersionConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &deptServiceMock{}
+			tt.s.deptService = mock
+			tt.s.txService = txServiceMock{}
+
+			data, version, err := tt.s.PatchDept(context.Background(), tt.mediaType, codec.JSON, 10, tt.precond, []byte(tt.patch), nil)
+			if tt.wantCode != "" {
+				if myerr, ok := err.(*myerror.Error); !ok || myerr.Code != tt.wantCode {
+					t.Fatalf("PatchDept() error = %v, want code %v", err, tt.wantCode)
+				}
+				if mock.patched != nil {
+					t.Errorf("PatchDept() is saved after error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchDept() error = %v", err)
+			}
+			if string(data) != tt.want || version.Version != 4 {
+				t.Errorf("PatchDept() = %s, %v, want %s, 4", data, version, tt.want)
+			}
+		})
+	}
+}
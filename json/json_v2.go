@@ -0,0 +1,99 @@
+package json
+
+import (
+	stdjson "encoding/json"
+	"encoding/xml"
+
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+	model "github.com/romapres2010/httpserver/model"
+	"gopkg.in/guregu/null.v4"
+)
+
+// Представление объектов в версии 2 API: единообразные имена полей, сотрудники в поле employees.
+// Представление кодируется по тегам: JSON через encoding/json, XML через encoding/xml, MessagePack по тегам json
+
+// DeptV2 represent Dept in version 2 of API
+type DeptV2 struct {
+	XMLName   xml.Name `json:"-" xml:"dept"`
+	ID        int      `json:"id" xml:"id" validate:"required"`
+	Name      string   `json:"name" xml:"name" validate:"required"`
+	Location  *string  `json:"location,omitempty" xml:"location,omitempty"`
+	Version   int64    `json:"version,omitempty" xml:"version,omitempty"`
+	Employees []*EmpV2 `json:"employees,omitempty" xml:"employees>emp,omitempty"`
+}
+
+// EmpV2 represent Emp in version 2 of API
+type EmpV2 struct {
+	XMLName    xml.Name `json:"-" xml:"emp"`
+	ID         int      `json:"id" xml:"id" validate:"required"`
+	Name       *string  `json:"name,omitempty" xml:"name,omitempty"`
+	Job        *string  `json:"job,omitempty" xml:"job,omitempty"`
+	ManagerID  *int64   `json:"managerId,omitempty" xml:"managerId,omitempty"`
+	HireDate   *string  `json:"hireDate,omitempty" xml:"hireDate,omitempty"`
+	Salary     *int64   `json:"salary,omitempty" xml:"salary,omitempty" validate:"gte=0"`
+	Commission *int64   `json:"commission,omitempty" xml:"commission,omitempty" validate:"gte=0"`
+	DeptID     *int64   `json:"deptId,omitempty" xml:"deptId,omitempty"`
+	Version    int64    `json:"version,omitempty" xml:"version,omitempty"`
+}
+
+// типы без методов easyjson - для кодирования через encoding/json
+type (
+	deptV2 DeptV2
+	empV2  EmpV2
+)
+
+// FromDept supports DeptRepresentation interface
+func (r *DeptV2) FromDept(v *model.Dept) {
+	r.ID, r.Name, r.Location, r.Version = v.Deptno, v.Dname, v.Loc.Ptr(), v.Version
+	r.Employees = make([]*EmpV2, len(v.Emps))
+	for i, emp := range v.Emps {
+		r.Employees[i] = &EmpV2{}
+		r.Employees[i].FromEmp(emp)
+	}
+}
+
+// ToDept supports DeptRepresentation interface
+func (r *DeptV2) ToDept(v *model.Dept) {
+	v.Deptno, v.Dname, v.Loc, v.Version = r.ID, r.Name, null.StringFromPtr(r.Location), r.Version
+	if len(r.Employees) > 0 && v.Emps == nil {
+		v.Emps = []*model.Emp(model.GetEmpSlice()) // Извлечем из pool срез для вложенных объектов
+	}
+	for _, e := range r.Employees {
+		emp := model.GetEmp() // Извлечем из pool структуру
+		e.ToEmp(emp)
+		v.Emps = append(v.Emps, emp)
+	}
+}
+
+// FromEmp supports EmpRepresentation interface
+func (r *EmpV2) FromEmp(v *model.Emp) {
+	r.ID, r.Name, r.Job, r.ManagerID, r.HireDate = v.Empno, v.Ename.Ptr(), v.Job.Ptr(), v.Mgr.Ptr(), v.Hiredate.Ptr()
+	r.Salary, r.Commission, r.DeptID, r.Version = v.Sal.Ptr(), v.Comm.Ptr(), v.Deptno.Ptr(), v.Version
+}
+
+// ToEmp supports EmpRepresentation interface
+func (r *EmpV2) ToEmp(v *model.Emp) {
+	v.Empno, v.Ename, v.Job, v.Mgr, v.Hiredate = r.ID, null.StringFromPtr(r.Name), null.StringFromPtr(r.Job), null.IntFromPtr(r.ManagerID), null.StringFromPtr(r.HireDate)
+	v.Sal, v.Comm, v.Deptno, v.Version = null.IntFromPtr(r.Salary), null.IntFromPtr(r.Commission), null.IntFromPtr(r.DeptID), r.Version
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (r DeptV2) MarshalEasyJSON(w *jwriter.Writer) {
+	w.Raw(stdjson.Marshal(deptV2(r)))
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (r *DeptV2) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	l.AddError(stdjson.Unmarshal(l.Raw(), (*deptV2)(r)))
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (r EmpV2) MarshalEasyJSON(w *jwriter.Writer) {
+	w.Raw(stdjson.Marshal(empV2(r)))
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (r *EmpV2) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	l.AddError(stdjson.Unmarshal(l.Raw(), (*empV2)(r)))
+}
@@ -0,0 +1,40 @@
+package json
+
+import (
+	model "github.com/romapres2010/httpserver/model"
+)
+
+// DeptRepresentation represent Dept in version of API
+type DeptRepresentation interface {
+	FromDept(v *model.Dept) // заполнить представление из Dept
+	ToDept(v *model.Dept)   // перенести представление в Dept
+}
+
+// EmpRepresentation represent Emp in version of API
+type EmpRepresentation interface {
+	FromEmp(v *model.Emp) // заполнить представление из Emp
+	ToEmp(v *model.Emp)   // перенести представление в Emp
+}
+
+// Marshalers represent encoding of model objects in version of API.
+// Representation is encoded with codec instead of model object, nil constructor - model object is encoded as is
+type Marshalers struct {
+	NewDept func() DeptRepresentation // новое представление Dept
+	NewEmp  func() EmpRepresentation  // новое представление Emp
+}
+
+// Versions represent marshalers of API versions
+var Versions = map[string]*Marshalers{
+	"v1": {},
+	"v2": {
+		NewDept: func() DeptRepresentation { return &DeptV2{} },
+		NewEmp:  func() EmpRepresentation { return &EmpV2{} },
+	},
+}
+
+// WithMarshalers return copy of service, which encode model objects with marshalers m
+func (s *Service) WithMarshalers(m *Marshalers) *Service {
+	service := *s
+	service.marshalers = m
+	return &service
+}
@@ -0,0 +1,63 @@
+package json
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/romapres2010/httpserver/codec"
+	model "github.com/romapres2010/httpserver/model"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestDeptV2(t *testing.T) {
+	dept := &model.Dept{
+		Deptno:  10,
+		Dname:   "ACCOUNTING",
+		Loc:     null.StringFrom("NEW YORK"),
+		Version: 3,
+		Emps: []*model.Emp{
+			{Empno: 7839, Ename: null.StringFrom("KING"), Sal: null.IntFrom(5000), Deptno: null.IntFrom(10), Version: 1},
+		},
+	}
+	s := (&Service{}).WithMarshalers(Versions["v2"])
+
+	for _, c := range []codec.Codec{codec.JSON, codec.XML, codec.MsgPack} {
+		t.Run(c.ContentType(), func(t *testing.T) {
+			data, err := s.deptMarshal(0, c, dept, nil, nil)
+			if err != nil {
+				t.Fatalf("deptMarshal() error = %v", err)
+			}
+			if c == codec.JSON {
+				want := `{"id":10,"name":"ACCOUNTING","location":"NEW YORK","version":3,"employees":[{"id":7839,"name":"KING","salary":5000,"deptId":10,"version":1}]}`
+				if string(data) != want {
+					t.Errorf("deptMarshal() = %s, want %s", data, want)
+				}
+			}
+			if strings.Contains(string(data), "deptNumber") {
+				t.Errorf("deptMarshal() = %s, contains field of version 1", data)
+			}
+
+			got := &model.Dept{}
+			if err = s.deptUnmarshal(0, c, data, got); err != nil {
+				t.Fatalf("deptUnmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, dept) {
+				t.Errorf("deptUnmarshal() = %+v, want %+v", got, dept)
+			}
+		})
+	}
+}
+
+func TestDeptV1(t *testing.T) {
+	dept := &model.Dept{Deptno: 10, Dname: "ACCOUNTING"}
+	s := (&Service{}).WithMarshalers(Versions["v1"])
+
+	data, err := s.deptMarshal(0, codec.JSON, dept, nil, nil)
+	if err != nil {
+		t.Fatalf("deptMarshal() error = %v", err)
+	}
+	if want := `{"deptNumber":10,"deptName":"ACCOUNTING","deptLocation":null}`; string(data) != want {
+		t.Errorf("deptMarshal() = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,193 @@
+package listener
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	"github.com/romapres2010/httpserver/events"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Доставка изменений объектов между экземплярами httpserver:
+//     при изменении Dept и Emp сервис DB в той же транзакции выполняет pg_notify в канал объекта
+//     PostgreSQL доставляет уведомление после фиксации транзакции всем подключениям, выполнившим LISTEN
+//     listener каждого экземпляра, в том числе изменившего объект, публикует событие своим подписчикам
+//     подключение listener выделенное, вне пула sqlxx: LISTEN привязан к сессии
+//     после потери подключения listener переподключается и повторяет LISTEN всех каналов,
+//     уведомления за время разрыва потеряны - подписчики получают событие reset
+
+// channelPrefix represent prefix of NOTIFY channels of objects
+const channelPrefix = "httpserver_"
+
+// defaultPingInterval represent interval of connection check, if it is not configured
+const defaultPingInterval = 90 * time.Second
+
+// Channel return NOTIFY channel of object
+func Channel(object string) string {
+	return channelPrefix + object
+}
+
+// Channels represent NOTIFY channels of Dept and Emp
+var Channels = []string{Channel(events.ObjectDept), Channel(events.ObjectEmp)}
+
+// Payload return payload of NOTIFY for event
+func Payload(e *events.Event) (string, error) {
+	data, err := stdjson.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Config represent listener configuration
+type Config struct {
+	ConnectString        string // строка подключения к БД в формате lib/pq
+	MinReconnectInterval int    // начальная задержка перед переподключением в милисекундах
+	MaxReconnectInterval int    // максимальная задержка перед переподключением в милисекундах
+	PingInterval         int    // интервал проверки подключения при отсутствии уведомлений в милисекундах
+}
+
+// Service represent listener of PostgreSQL notifications
+type Service struct {
+	ctx    context.Context    // корневой контекст при инициации сервиса
+	cancel context.CancelFunc // функция закрытия глобального контекста
+	cfg    *Config            // конфигурационные параметры
+	errCh  chan<- error       // канал ошибок
+	stopCh chan struct{}      // канал подтверждения об успешном закрытии сервиса
+
+	listener  *pq.Listener     // выделенное подключение для LISTEN
+	publisher events.Publisher // подписчики в текущем экземпляре
+}
+
+// New create listener service, subscribe to channels of Dept and Emp and start fan out of notifications to publisher
+func New(ctx context.Context, errCh chan<- error, cfg *Config, publisher events.Publisher) (*Service, error) {
+	mylog.PrintfInfoMsg("Creating new listener service")
+
+	{ // входные проверки
+		if cfg == nil {
+			return nil, myerror.New("6030", "Empty listener service config").PrintfInfo()
+		}
+		if publisher == nil {
+			return nil, myerror.New("6030", "Empty listener publisher").PrintfInfo()
+		}
+	} // входные проверки
+
+	// Создаем новый сервис
+	service := &Service{
+		cfg:       cfg,
+		errCh:     errCh,
+		stopCh:    make(chan struct{}, 1), // канал подтверждения об успешном закрытии сервиса
+		publisher: publisher,
+	}
+
+	// создаем контекст с отменой
+	if ctx == nil {
+		service.ctx, service.cancel = context.WithCancel(context.Background())
+	} else {
+		service.ctx, service.cancel = context.WithCancel(ctx)
+	}
+
+	// pq.Listener сам переподключается с увеличением задержки и повторяет LISTEN всех каналов
+	service.listener = pq.NewListener(cfg.ConnectString,
+		time.Duration(cfg.MinReconnectInterval)*time.Millisecond,
+		time.Duration(cfg.MaxReconnectInterval)*time.Millisecond,
+		service.logEvent)
+
+	for _, channel := range Channels {
+		if err := service.listener.Listen(channel); err != nil {
+			_ = service.listener.Close()
+			service.cancel()
+			return nil, myerror.WithCause("4001", "Error LISTEN PostgreSQL channel: channel", err, channel).PrintfInfo()
+		}
+	}
+
+	go service.run()
+
+	mylog.PrintfInfoMsg("Listener service is created: channels", Channels)
+	return service, nil
+}
+
+// run fan out notifications to publisher until service is shutting down
+func (s *Service) run() {
+	defer func() { s.stopCh <- struct{}{} }()
+
+	interval := defaultPingInterval
+	if s.cfg.PingInterval > 0 {
+		interval = time.Duration(s.cfg.PingInterval) * time.Millisecond
+	}
+	ping := time.NewTimer(interval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case n := <-s.listener.Notify:
+			s.notify(n)
+		case <-ping.C:
+			// без уведомлений разрыв подключения может быть не обнаружен
+			go func() {
+				if err := s.listener.Ping(); err != nil {
+					mylog.PrintfInfoMsg("PostgreSQL listener ping failed", err)
+				}
+			}()
+		case <-s.ctx.Done():
+			return
+		}
+		if !ping.Stop() {
+			select {
+			case <-ping.C:
+			default:
+			}
+		}
+		ping.Reset(interval)
+	}
+}
+
+// notify publish event from notification, nil notification - connection was restored and notifications could be lost
+func (s *Service) notify(n *pq.Notification) {
+	if n == nil {
+		mylog.PrintfInfoMsg("PostgreSQL listener is reconnected, subscribers are reset")
+		s.publisher.Publish(events.Event{Type: events.TypeReset})
+		return
+	}
+
+	e := events.Event{}
+	if err := stdjson.Unmarshal([]byte(n.Extra), &e); err != nil {
+		_ = myerror.WithCause("4007", "Error parse PostgreSQL notification: channel, payload", err, n.Channel, n.Extra).PrintfInfo()
+		return
+	}
+	e.ID = 0 // ID назначается потоком изменений текущего экземпляра
+	s.publisher.Publish(e)
+}
+
+// logEvent log state of listener connection
+func (s *Service) logEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventConnected:
+		mylog.PrintfInfoMsg("PostgreSQL listener is connected")
+	case pq.ListenerEventDisconnected:
+		mylog.PrintfInfoMsg("PostgreSQL listener is disconnected", err)
+	case pq.ListenerEventReconnected:
+		mylog.PrintfInfoMsg("PostgreSQL listener is reconnected")
+	case pq.ListenerEventConnectionAttemptFailed:
+		mylog.PrintfInfoMsg("PostgreSQL listener connection attempt failed", err)
+	}
+}
+
+// Shutdown shutting down service
+func (s *Service) Shutdown() (myerr error) {
+	mylog.PrintfInfoMsg("Shutdowning listener service")
+
+	s.cancel() // закрываем контекст
+	<-s.stopCh // ожидаем завершения рассылки уведомлений
+
+	if err := s.listener.Close(); err != nil {
+		myerr = myerror.WithCause("4004", "Error close PostgreSQL listener", err).PrintfInfo()
+	}
+
+	mylog.PrintfInfoMsg("Listener service shutdown successfuly")
+	return
+}
@@ -0,0 +1,54 @@
+package listener
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/romapres2010/httpserver/events"
+)
+
+// publisherMock represent events.Publisher which records events
+type publisherMock struct {
+	events []events.Event
+}
+
+func (m *publisherMock) Publish(e events.Event) {
+	m.events = append(m.events, e)
+}
+
+func TestNotify(t *testing.T) {
+	e := &events.Event{ID: 42, Type: events.TypeUpdated, Object: events.ObjectEmp, Key: 7839, Version: 3, Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	payload, err := Payload(e)
+	if err != nil {
+		t.Fatalf("Payload() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		n    *pq.Notification
+		want []events.Event
+	}{
+		{"event", &pq.Notification{Channel: Channel(events.ObjectEmp), Extra: payload},
+			[]events.Event{{Type: events.TypeUpdated, Object: events.ObjectEmp, Key: 7839, Version: 3, Time: e.Time}}},
+		{"reconnect", nil, []events.Event{{Type: events.TypeReset}}},
+		{"bad payload", &pq.Notification{Channel: Channel(events.ObjectDept), Extra: "{"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &publisherMock{}
+			s := &Service{publisher: mock}
+			s.notify(tt.n)
+			if len(mock.events) != len(tt.want) {
+				t.Fatalf("notify() events = %+v, want %+v", mock.events, tt.want)
+			}
+			for i := range tt.want {
+				if got := mock.events[i]; got.ID != tt.want[i].ID || got.Type != tt.want[i].Type || got.Object != tt.want[i].Object ||
+					got.Key != tt.want[i].Key || got.Version != tt.want[i].Version || !got.Time.Equal(tt.want[i].Time) {
+					t.Errorf("notify() event = %+v, want %+v", got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"gopkg.in/guregu/null.v4"
 )
 
+// DateLayout represent format of date fields
+const DateLayout = "2006-01-02"
+
 // Dept represent object "Department"
 //easyjson:json
 type Dept struct {
-	Deptno int         `db:"deptno" json:"deptNumber" validate:"required"`
-	Dname  string      `db:"dname" json:"deptName" validate:"required"`
-	Loc    null.String `db:"loc" json:"deptLocation,nullempty"`
-	Emps   []*Emp      `json:"emps,omitempty"` // срез указателей на дочерние emp
+	Deptno  int         `db:"deptno" json:"deptNumber" validate:"required"`
+	Dname   string      `db:"dname" json:"deptName" validate:"required"`
+	Loc     null.String `db:"loc" json:"deptLocation,nullempty"`
+	Version int64       `db:"version" json:"version,omitempty"` // версия строки для оптимистической блокировки
+	Emps    []*Emp      `json:"emps,omitempty"`                 // срез указателей на дочерние emp
 }
 
 // DeptPK represent Primary Key of the object "Department"
@@ -32,6 +36,7 @@ type Emp struct {
 	Sal      null.Int    `db:"sal" json:"sal,nullempty" validate:"gte=0"`
 	Comm     null.Int    `db:"comm" json:"comm,nullempty" validate:"gte=0"`
 	Deptno   null.Int    `db:"deptno" json:"deptNumber,nullempty"`
+	Version  int64       `db:"version" json:"version,omitempty"` // версия строки для оптимистической блокировки
 }
 
 // EmpPK represent Primary Key of the object "Employee"
@@ -67,6 +67,8 @@ func easyjsonC80ae7adDecodeGithubComRomapres2010HttpserverModel(in *jlexer.Lexer
 			if data := in.Raw(); in.Ok() {
 				in.AddError((out.Deptno).UnmarshalJSON(data))
 			}
+		case "version":
+			out.Version = int64(in.Int64())
 		default:
 			in.SkipRecursive()
 		}
@@ -121,6 +123,11 @@ func easyjsonC80ae7adEncodeGithubComRomapres2010HttpserverModel(out *jwriter.Wri
 		out.RawString(prefix)
 		out.Raw((in.Deptno).MarshalJSON())
 	}
+	if in.Version != 0 {
+		const prefix string = ",\"version\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.Version))
+	}
 	out.RawByte('}')
 }
 
@@ -174,6 +181,8 @@ func easyjsonC80ae7adDecodeGithubComRomapres2010HttpserverModel1(in *jlexer.Lexe
 			if data := in.Raw(); in.Ok() {
 				in.AddError((out.Loc).UnmarshalJSON(data))
 			}
+		case "version":
+			out.Version = int64(in.Int64())
 		case "emps":
 			if in.IsNull() {
 				in.Skip()
@@ -236,6 +245,11 @@ func easyjsonC80ae7adEncodeGithubComRomapres2010HttpserverModel1(out *jwriter.Wr
 		out.RawString(prefix)
 		out.Raw((in.Loc).MarshalJSON())
 	}
+	if in.Version != 0 {
+		const prefix string = ",\"version\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.Version))
+	}
 	if len(in.Emps) != 0 {
 		const prefix string = ",\"emps\":"
 		out.RawString(prefix)
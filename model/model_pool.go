@@ -58,6 +58,7 @@ func (p *Dept) Reset() {
 	p.Dname = ""
 	p.Loc.String = ""
 	p.Loc.Valid = false
+	p.Version = 0
 	if p.Emps != nil {
 		EmpSlice(p.Emps).Reset()
 	}
@@ -81,6 +82,7 @@ func (p *Emp) Reset() {
 	p.Comm.Valid = false
 	p.Deptno.Int64 = 0
 	p.Deptno.Valid = false
+	p.Version = 0
 }
 
 // GetDept allocates a new struct or grabs a cached one
@@ -6,10 +6,12 @@ import (
 
 // DeptService represent basic interface for Dept
 type DeptService interface {
-	GetDept(ctx context.Context, out *Dept) (bool, error)
+	GetDept(ctx context.Context, out *Dept, withEmps bool) (bool, error)
+	GetDeptVersion(ctx context.Context, deptno int, out *Version, withEmps bool) (bool, error)
 	GetDeptsPK(ctx context.Context, out *DeptPKs) error
 	CreateDept(ctx context.Context, in *Dept, out *Dept) error
 	UpdateDept(ctx context.Context, in *Dept, out *Dept) (bool, error)
+	PatchDept(ctx context.Context, cur *Dept, in *Dept, out *Dept) error
 
 	//RandomGetDept(ctx context.Context, v *Dept) error    // Для целей нагрузочного тестирования
 	//RandomUpdateDept(ctx context.Context, v *Dept) error // Для целей нагрузочного тестирования
@@ -21,4 +23,32 @@ type EmpService interface {
 	GetEmpsByDept(ctx context.Context, in *Dept, out *EmpSlice) error
 	CreateEmp(ctx context.Context, in *Emp, out *Emp) error
 	UpdateEmp(ctx context.Context, in *Emp, out *Emp) (bool, error)
+	PatchEmp(ctx context.Context, cur *Emp, in *Emp, out *Emp) error
+}
+
+// TxService represent unit of work - all service calls with ctx passed to fn are done in one transaction
+type TxService interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// DeptReader represent stream of Dept, Next fill out with next Dept and return io.EOF at the end of stream
+type DeptReader interface {
+	Next(out *Dept) error
+}
+
+// EmpReader represent stream of Emp, Next fill out with next Emp and return io.EOF at the end of stream
+type EmpReader interface {
+	Next(out *Emp) error
+}
+
+// ImportService represent bulk loading of Dept and Emp streams, it returns count of loaded rows
+type ImportService interface {
+	ImportDepts(ctx context.Context, in DeptReader) (int64, error)
+	ImportEmps(ctx context.Context, in EmpReader) (int64, error)
+}
+
+// ExportService represent streaming read of all Dept and Emp, out is called for each row, structure is reused between calls
+type ExportService interface {
+	ExportDepts(ctx context.Context, out func(*Dept) error) error
+	ExportEmps(ctx context.Context, out func(*Emp) error) error
 }
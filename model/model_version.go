@@ -0,0 +1,35 @@
+package model
+
+import (
+	"sort"
+)
+
+// Version represent row version of object and versions of embedded objects - state of object, which is identified by ETag
+type Version struct {
+	Version  int64             // версия строки объекта
+	Embedded []EmbeddedVersion // версии вложенных объектов в порядке PK, nil - вложенные объекты не входят в представление
+}
+
+// EmbeddedVersion represent row version of embedded object
+type EmbeddedVersion struct {
+	Key     int   `db:"key"`     // PK вложенного объекта
+	Version int64 `db:"version"` // версия строки вложенного объекта
+}
+
+// VersionOf return version of Dept, withEmps - embedded Emps are included in version
+func (d *Dept) VersionOf(withEmps bool) Version {
+	v := Version{Version: d.Version}
+	if withEmps {
+		v.Embedded = make([]EmbeddedVersion, 0, len(d.Emps))
+		for _, emp := range d.Emps {
+			v.Embedded = append(v.Embedded, EmbeddedVersion{Key: emp.Empno, Version: emp.Version})
+		}
+		sort.Slice(v.Embedded, func(i, j int) bool { return v.Embedded[i].Key < v.Embedded[j].Key })
+	}
+	return v
+}
+
+// VersionOf return version of Emp
+func (e *Emp) VersionOf() Version {
+	return Version{Version: e.Version}
+}
@@ -0,0 +1,221 @@
+package model
+
+import (
+	"encoding/xml"
+	"strconv"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// XML представление объектов: имена элементов совпадают с именами полей JSON, null поля не выводятся
+
+// xmlEncodeString encode element with string value
+func xmlEncodeString(e *xml.Encoder, name string, value string) error {
+	return e.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+// xmlEncodeNullString encode element with string value, null value is skipped
+func xmlEncodeNullString(e *xml.Encoder, name string, value null.String) error {
+	if !value.Valid {
+		return nil
+	}
+	return xmlEncodeString(e, name, value.String)
+}
+
+// xmlEncodeNullInt encode element with integer value, null value is skipped
+func xmlEncodeNullInt(e *xml.Encoder, name string, value null.Int) error {
+	if !value.Valid {
+		return nil
+	}
+	return xmlEncodeString(e, name, strconv.FormatInt(value.Int64, 10))
+}
+
+// xmlDecodeString decode string value of element
+func xmlDecodeString(d *xml.Decoder, start *xml.StartElement) (value string, err error) {
+	err = d.DecodeElement(&value, start)
+	return value, err
+}
+
+// xmlDecodeInt decode integer value of element
+func xmlDecodeInt(d *xml.Decoder, start *xml.StartElement) (int, error) {
+	value, err := xmlDecodeString(d, start)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// xmlDecodeNullInt decode integer value of element, empty element is null
+func xmlDecodeNullInt(d *xml.Decoder, start *xml.StartElement) (null.Int, error) {
+	value, err := xmlDecodeString(d, start)
+	if err != nil || value == "" {
+		return null.Int{}, err
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	return null.NewInt(i, err == nil), err
+}
+
+// xmlDecodeChildren call fn for each child element of start
+func xmlDecodeChildren(d *xml.Decoder, fn func(child *xml.StartElement) error) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err = fn(&t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// MarshalXML supports xml.Marshaler interface
+func (v *Dept) MarshalXML(e *xml.Encoder, start xml.StartElement) (err error) {
+	start = xml.StartElement{Name: xml.Name{Local: "dept"}}
+	if err = e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err = xmlEncodeString(e, "deptNumber", strconv.Itoa(v.Deptno)); err != nil {
+		return err
+	}
+	if err = xmlEncodeString(e, "deptName", v.Dname); err != nil {
+		return err
+	}
+	if err = xmlEncodeNullString(e, "deptLocation", v.Loc); err != nil {
+		return err
+	}
+	if v.Version != 0 {
+		if err = xmlEncodeString(e, "version", strconv.FormatInt(v.Version, 10)); err != nil {
+			return err
+		}
+	}
+	if len(v.Emps) > 0 {
+		emps := xml.StartElement{Name: xml.Name{Local: "emps"}}
+		if err = e.EncodeToken(emps); err != nil {
+			return err
+		}
+		for _, emp := range v.Emps {
+			if emp != nil {
+				if err = emp.MarshalXML(e, xml.StartElement{}); err != nil {
+					return err
+				}
+			}
+		}
+		if err = e.EncodeToken(emps.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML supports xml.Unmarshaler interface
+func (v *Dept) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return xmlDecodeChildren(d, func(child *xml.StartElement) (err error) {
+		switch child.Name.Local {
+		case "deptNumber":
+			v.Deptno, err = xmlDecodeInt(d, child)
+		case "deptName":
+			v.Dname, err = xmlDecodeString(d, child)
+		case "deptLocation":
+			var value string
+			value, err = xmlDecodeString(d, child)
+			v.Loc = null.StringFrom(value)
+		case "version":
+			var value int
+			value, err = xmlDecodeInt(d, child)
+			v.Version = int64(value)
+		case "emps":
+			if v.Emps == nil {
+				v.Emps = []*Emp(GetEmpSlice()) // Извлечем из pool срез для вложенных объектов
+			}
+			err = xmlDecodeChildren(d, func(child *xml.StartElement) error {
+				if child.Name.Local != "emp" {
+					return d.Skip()
+				}
+				emp := GetEmp() // Извлечем из pool структуру
+				v.Emps = append(v.Emps, emp)
+				return d.DecodeElement(emp, child)
+			})
+		default:
+			err = d.Skip()
+		}
+		return err
+	})
+}
+
+// MarshalXML supports xml.Marshaler interface
+func (v *Emp) MarshalXML(e *xml.Encoder, start xml.StartElement) (err error) {
+	start = xml.StartElement{Name: xml.Name{Local: "emp"}}
+	if err = e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err = xmlEncodeString(e, "empNo", strconv.Itoa(v.Empno)); err != nil {
+		return err
+	}
+	if err = xmlEncodeNullString(e, "empName", v.Ename); err != nil {
+		return err
+	}
+	if err = xmlEncodeNullString(e, "job", v.Job); err != nil {
+		return err
+	}
+	if err = xmlEncodeNullInt(e, "mgr", v.Mgr); err != nil {
+		return err
+	}
+	if err = xmlEncodeNullString(e, "hiredate", v.Hiredate); err != nil {
+		return err
+	}
+	if err = xmlEncodeNullInt(e, "sal", v.Sal); err != nil {
+		return err
+	}
+	if err = xmlEncodeNullInt(e, "comm", v.Comm); err != nil {
+		return err
+	}
+	if err = xmlEncodeNullInt(e, "deptNumber", v.Deptno); err != nil {
+		return err
+	}
+	if v.Version != 0 {
+		if err = xmlEncodeString(e, "version", strconv.FormatInt(v.Version, 10)); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML supports xml.Unmarshaler interface
+func (v *Emp) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return xmlDecodeChildren(d, func(child *xml.StartElement) (err error) {
+		var value string
+		switch child.Name.Local {
+		case "empNo":
+			v.Empno, err = xmlDecodeInt(d, child)
+		case "empName":
+			value, err = xmlDecodeString(d, child)
+			v.Ename = null.StringFrom(value)
+		case "job":
+			value, err = xmlDecodeString(d, child)
+			v.Job = null.StringFrom(value)
+		case "mgr":
+			v.Mgr, err = xmlDecodeNullInt(d, child)
+		case "hiredate":
+			value, err = xmlDecodeString(d, child)
+			v.Hiredate = null.StringFrom(value)
+		case "sal":
+			v.Sal, err = xmlDecodeNullInt(d, child)
+		case "comm":
+			v.Comm, err = xmlDecodeNullInt(d, child)
+		case "deptNumber":
+			v.Deptno, err = xmlDecodeNullInt(d, child)
+		case "version":
+			var version int
+			version, err = xmlDecodeInt(d, child)
+			v.Version = int64(version)
+		default:
+			err = d.Skip()
+		}
+		return err
+	})
+}
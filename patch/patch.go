@@ -0,0 +1,419 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Частичное обновление JSON документа:
+//   JSON Merge Patch (RFC 7386) - документ с измененными полями, null удаляет поле
+//   JSON Patch (RFC 6902) - список операций add, remove, replace, move, copy, test над путями JSON Pointer (RFC 6901)
+
+// Типы тела запроса PATCH
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// ErrTestFailed represent failure of operation test of JSON Patch, patch is not applied
+var ErrTestFailed = errors.New("JSON Patch test operation failed")
+
+// MediaType return type of patch by Content-Type, ok is false for unsupported Content-Type
+func MediaType(contentType string) (mediaType string, ok bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	return mediaType, mediaType == MergePatchType || mediaType == JSONPatchType
+}
+
+// Apply apply patch of mediaType MergePatchType or JSONPatchType to JSON document doc
+func Apply(mediaType string, doc []byte, patch []byte) ([]byte, error) {
+	switch mediaType {
+	case MergePatchType:
+		return MergePatch(doc, patch)
+	case JSONPatchType:
+		return JSONPatch(doc, patch)
+	}
+	return nil, fmt.Errorf("unsupported patch type %q", mediaType)
+}
+
+// MergePatch apply JSON Merge Patch to JSON document doc
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %v", err)
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+// mergePatch merge patch into target: objects are merged recursively, null removes member, other values replace target
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{}, len(p))
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}
+
+// operation represent operation of JSON Patch, nil members are not passed
+type operation struct {
+	Op    string
+	Path  *string
+	From  *string
+	Value json.RawMessage // null передается как "null"
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface, value null is distinguished from missing value
+func (op *operation) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(members["op"], &op.Op); err != nil {
+		return fmt.Errorf("member 'op' is required: %v", err)
+	}
+	for name, field := range map[string]**string{"path": &op.Path, "from": &op.From} {
+		if raw, ok := members[name]; ok {
+			if err := json.Unmarshal(raw, field); err != nil {
+				return fmt.Errorf("member '%s' must be string: %v", name, err)
+			}
+		}
+	}
+	op.Value = members["value"]
+	return nil
+}
+
+// JSONPatch apply JSON Patch to JSON document doc, operations are applied in order, on any error document is not changed
+func JSONPatch(doc []byte, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+	var ops []operation
+	if err = json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch, array of operations is expected: %v", err)
+	}
+
+	for i, op := range ops {
+		if target, err = applyOperation(target, op); err != nil {
+			if err == ErrTestFailed {
+				return nil, err
+			}
+			return nil, fmt.Errorf("operation %v '%s': %v", i, op.Op, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// applyOperation apply one operation to document and return new document
+func applyOperation(doc interface{}, op operation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, errors.New("member 'path' is required")
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("member 'value' is required")
+		}
+		if value, err = decode(op.Value); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, errors.New("member 'from' is required")
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = get(doc, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if *op.Path == *op.From {
+				return doc, nil
+			}
+			if strings.HasPrefix(*op.Path+"/", *op.From+"/") {
+				return nil, errors.New("location can not be moved into one of its children")
+			}
+			if doc, err = update(doc, from, remove); err != nil {
+				return nil, err
+			}
+		} else {
+			value = clone(value)
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("unknown operation '%s'", op.Op)
+	}
+
+	// операция над всем документом
+	if len(path) == 0 {
+		switch op.Op {
+		case "remove":
+			return nil, errors.New("root can not be removed")
+		case "test":
+			if !equal(doc, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+		return value, nil
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return update(doc, path, func(container interface{}, key string) (interface{}, error) {
+			return add(container, key, value)
+		})
+	case "remove":
+		return update(doc, path, remove)
+	case "replace":
+		return update(doc, path, func(container interface{}, key string) (interface{}, error) {
+			return replace(container, key, value)
+		})
+	default: // test
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+}
+
+// parsePointer split JSON Pointer into reference tokens, "" - whole document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer '%s'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// get return value of document by path
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, key := range path {
+		var err error
+		if doc, err = child(doc, key); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// child return member of object or element of array by key
+func child(container interface{}, key string) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		value, ok := c[key]
+		if !ok {
+			return nil, fmt.Errorf("member '%s' does not exist", key)
+		}
+		return value, nil
+	case []interface{}:
+		i, err := index(c, key, false)
+		if err != nil {
+			return nil, err
+		}
+		return c[i], nil
+	}
+	return nil, fmt.Errorf("value with member '%s' is not object or array", key)
+}
+
+// update apply fn to container of last token of not empty path and return new document.
+// Arrays are changed by fn, so changed container is set back into its parent
+func update(doc interface{}, path []string, fn func(container interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	value, err := child(doc, path[0])
+	if err != nil {
+		return nil, err
+	}
+	if value, err = update(value, path[1:], fn); err != nil {
+		return nil, err
+	}
+	return replace(doc, path[0], value)
+}
+
+// add add value into container, element of array is inserted, "-" - end of array
+func add(container interface{}, key string, value interface{}) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		c[key] = value
+		return c, nil
+	case []interface{}:
+		if key == "-" {
+			return append(c, value), nil
+		}
+		i, err := index(c, key, true)
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, nil)
+		copy(c[i+1:], c[i:])
+		c[i] = value
+		return c, nil
+	}
+	return nil, fmt.Errorf("value with member '%s' is not object or array", key)
+}
+
+// replace replace existing value in container
+func replace(container interface{}, key string, value interface{}) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if _, ok := c[key]; !ok {
+			return nil, fmt.Errorf("member '%s' does not exist", key)
+		}
+		c[key] = value
+		return c, nil
+	case []interface{}:
+		i, err := index(c, key, false)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = value
+		return c, nil
+	}
+	return nil, fmt.Errorf("value with member '%s' is not object or array", key)
+}
+
+// remove remove existing value from container
+func remove(container interface{}, key string) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if _, ok := c[key]; !ok {
+			return nil, fmt.Errorf("member '%s' does not exist", key)
+		}
+		delete(c, key)
+		return c, nil
+	case []interface{}:
+		i, err := index(c, key, false)
+		if err != nil {
+			return nil, err
+		}
+		return append(c[:i], c[i+1:]...), nil
+	}
+	return nil, fmt.Errorf("value with member '%s' is not object or array", key)
+}
+
+// index parse index of array element, end is true if index equal to length of array is allowed
+func index(array []interface{}, key string, end bool) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || (key != "0" && key[0] == '0') {
+		return 0, fmt.Errorf("invalid array index '%s'", key)
+	}
+	if i > len(array) || (i == len(array) && !end) {
+		return 0, fmt.Errorf("array index '%s' is out of bounds", key)
+	}
+	return i, nil
+}
+
+// equal compare JSON values, numbers are compared by value
+func equal(a interface{}, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			if other, ok := bv[key]; !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if av == bv {
+			return true
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	}
+	return a == b
+}
+
+// clone return deep copy of JSON value
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, member := range v {
+			c[key] = clone(member)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i := range v {
+			c[i] = clone(v[i])
+		}
+		return c
+	}
+	return value
+}
+
+// decode parse JSON value, numbers are kept as json.Number without loss of precision
+func decode(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
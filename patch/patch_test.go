@@ -0,0 +1,112 @@
+package patch
+
+import (
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	// примеры RFC 7386, Appendix A
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"sal":12345678901234567890}`, `{"comm":1}`, `{"comm":1,"sal":12345678901234567890}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s) error = %v", tt.doc, tt.patch, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a":`)); err == nil {
+		t.Errorf("MergePatch() for invalid patch error = nil")
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	// примеры RFC 6902, Appendix A
+	tests := []struct {
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, false},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, false},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, false},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, false},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, false},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, false},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, false},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`, false},
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ``, true},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`, false},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ``, true},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`, false},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, ``, true},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`, false},
+		{`{"foo":"bar"}`, `[{"op":"copy","from":"/foo","path":"/baz"}]`, `{"baz":"bar","foo":"bar"}`, false},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`, false},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar"}]`, ``, true},
+		{`{"foo":[1]}`, `[{"op":"add","path":"/foo/2","value":1}]`, ``, true},
+		{`{"foo":[1]}`, `[{"op":"remove","path":"/foo/01"}]`, ``, true},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, ``, true},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, ``, true},
+		{`{"foo":"bar"}`, `[{"op":"unknown","path":"/baz"}]`, ``, true},
+		{`{"foo":"bar"}`, `{"op":"add","path":"/baz","value":1}`, ``, true},
+		{`{"sal":1.0}`, `[{"op":"test","path":"/sal","value":1},{"op":"replace","path":"/sal","value":null}]`, `{"sal":null}`, false},
+	}
+	for _, tt := range tests {
+		got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("JSONPatch(%s, %s) error = %v, wantErr %v", tt.doc, tt.patch, err, tt.wantErr)
+			continue
+		}
+		if err == nil && string(got) != tt.want {
+			t.Errorf("JSONPatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+
+	if _, err := JSONPatch([]byte(`{"a":1}`), []byte(`[{"op":"test","path":"/a","value":2}]`)); err != ErrTestFailed {
+		t.Errorf("JSONPatch() for failed test error = %v, want ErrTestFailed", err)
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+		ok          bool
+	}{
+		{"application/merge-patch+json", MergePatchType, true},
+		{"application/json-patch+json; charset=utf-8", JSONPatchType, true},
+		{"application/json", "application/json", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := MediaType(tt.contentType); got != tt.want || ok != tt.ok {
+			t.Errorf("MediaType(%q) = %q, %v, want %q, %v", tt.contentType, got, ok, tt.want, tt.ok)
+		}
+	}
+}
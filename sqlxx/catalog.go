@@ -0,0 +1,213 @@
+package sqlxx
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Каталог SQL команд - набор *.sql файлов:
+//     Name.sql           - одна SQL команда с именем Name
+//     Name.<driver>.sql  - вариант SQL команды для драйвера <driver>, например GetDept.godror.sql
+//     catalog.sql        - несколько SQL команд, каждая начинается со строки "-- name: Name"
+// Файл с суффиксом драйвера применяется только для этого драйвера и перекрывает общий вариант.
+// В заголовке SQL команды можно указать признак предварительной подготовки и время выполнения:
+//     -- prepare: true
+//     -- timeout: 5s
+
+// Директивы в заголовке SQL команды
+const (
+	catalogNameDirective    = "-- name:"
+	catalogPrepareDirective = "-- prepare:"
+	catalogTimeoutDirective = "-- timeout:"
+)
+
+// catalogItem represent SQL statement parsed from file
+type catalogItem struct {
+	stm      *SQLStm
+	fileName string // файл, из которого загружена SQL команда
+	driver   bool   // SQL команда загружена из варианта для драйвера
+}
+
+// LoadCatalog parse SQL files into statements for driver, files is a map of file name to its content
+func LoadCatalog(files map[string]string, driverName string) (SQLStms, error) {
+	items := make(map[string]*catalogItem)
+
+	// стабильный порядок файлов для воспроизводимых ошибок
+	fileNames := make([]string, 0, len(files))
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		if !strings.HasSuffix(fileName, ".sql") {
+			continue
+		}
+
+		// Name.sql или Name.<driver>.sql
+		parts := strings.Split(strings.TrimSuffix(filepath.Base(fileName), ".sql"), ".")
+		if len(parts) > 2 {
+			return nil, myerror.New("4101", "Incorrect SQL file name, expected Name.sql or Name.<driver>.sql: FileName", fileName).PrintfInfo()
+		}
+		isDriver := len(parts) == 2
+		if isDriver && parts[1] != driverName {
+			continue // вариант для другого драйвера
+		}
+
+		stms, err := parseCatalogFile(parts[0], files[fileName])
+		if err != nil {
+			return nil, myerror.WithCause("4101", "Error parse SQL file: FileName", err, fileName).PrintfInfo()
+		}
+
+		for name, stm := range stms {
+			if prev, ok := items[name]; ok {
+				if prev.driver == isDriver {
+					return nil, myerror.New("4101", "SQL statement is defined twice: Name, FileName1, FileName2", name, prev.fileName, fileName).PrintfInfo()
+				}
+				if prev.driver {
+					continue // вариант для драйвера уже загружен
+				}
+			}
+			items[name] = &catalogItem{stm: stm, fileName: fileName, driver: isDriver}
+		}
+	}
+
+	sqlStms := make(SQLStms, len(items))
+	for name, item := range items {
+		sqlStms[name] = item.stm
+	}
+	return sqlStms, nil
+}
+
+// parseCatalogFile parse file with one unnamed or several named SQL statements
+func parseCatalogFile(defaultName string, text string) (map[string]*SQLStm, error) {
+	stms := make(map[string]*SQLStm)
+
+	var name string
+	var prepare bool
+	var timeout time.Duration
+	var lines []string
+
+	// завершить текущую SQL команду
+	flush := func() error {
+		stmText := strings.TrimSpace(strings.Join(lines, "\n"))
+		stmText = strings.TrimSpace(strings.TrimSuffix(stmText, ";"))
+		if stmText == "" {
+			if name != "" {
+				return myerror.New("4101", "SQL statement is empty: Name", name)
+			}
+			return nil
+		}
+		if name == "" {
+			name = defaultName
+		}
+		if _, ok := stms[name]; ok {
+			return myerror.New("4101", "SQL statement is defined twice: Name", name)
+		}
+		stms[name] = &SQLStm{Text: stmText, IsPrepare: prepare, Timeout: timeout}
+		name, prepare, timeout, lines = "", false, 0, nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, catalogNameDirective):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if name = strings.TrimSpace(strings.TrimPrefix(trimmed, catalogNameDirective)); name == "" {
+				return nil, myerror.New("4101", "SQL statement name is empty")
+			}
+		case strings.HasPrefix(trimmed, catalogPrepareDirective):
+			prepare = strings.TrimSpace(strings.TrimPrefix(trimmed, catalogPrepareDirective)) == "true"
+		case strings.HasPrefix(trimmed, catalogTimeoutDirective):
+			var err error
+			if timeout, err = time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(trimmed, catalogTimeoutDirective))); err != nil || timeout < 0 {
+				return nil, myerror.New("4101", "Incorrect SQL statement timeout, expected duration like 500ms or 5s: Line", trimmed)
+			}
+		case strings.HasPrefix(trimmed, "--") && len(lines) == 0:
+			// комментарии перед SQL командой пропускаем
+		default:
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return stms, nil
+}
+
+// ReadCatalogDir read all *.sql files from directory
+func ReadCatalogDir(dir string) (map[string]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, myerror.WithCause("4101", "Error read SQL catalog directory: Dir", err, dir).PrintfInfo()
+	}
+
+	files := make(map[string]string)
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".sql") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, myerror.WithCause("4101", "Error read SQL file: FileName", err, info.Name()).PrintfInfo()
+		}
+		files[info.Name()] = string(data)
+	}
+
+	mylog.PrintfInfoMsg("SQL catalog is read: Dir, Files", dir, len(files))
+	return files, nil
+}
+
+// CatalogModTime return last modification time of directory and its *.sql files
+func CatalogModTime(dir string) (modTime time.Time, err error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return modTime, err
+	}
+	// добавление и удаление файлов меняет время модификации каталога
+	modTime = info.ModTime()
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return modTime, err
+	}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".sql") && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+// CheckCatalog check that all required statements are defined
+func CheckCatalog(sqlStms SQLStms, required []string) error {
+	var missing []string
+	for _, name := range required {
+		if _, ok := sqlStms[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return myerror.New("4100", "SQL statements are not defined in catalog: Names", strings.Join(missing, ", ")).PrintfInfo()
+	}
+	return nil
+}
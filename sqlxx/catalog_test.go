@@ -0,0 +1,79 @@
+package sqlxx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	files := map[string]string{
+		"catalog.sql": `-- общий каталог
+-- name: GetDept
+-- prepare: true
+SELECT deptno, dname
+  FROM dept
+ WHERE deptno = $1;
+
+-- name: CreateDept
+-- timeout: 5s
+INSERT INTO dept (deptno) VALUES (:deptno);
+`,
+		"GetDept.godror.sql":  "-- prepare: true\nSELECT deptno, dname FROM dept WHERE deptno = :1",
+		"GetEmp.sql":          "SELECT * FROM emp WHERE empno = $1;\n",
+		"GetEmp.postgres.sql": "SELECT * FROM emp WHERE empno = $1 /* pq */",
+		"readme.txt":          "not a SQL file",
+	}
+
+	tests := []struct {
+		driver  string
+		getDept string
+		getEmp  string
+	}{
+		{"pgx", "SELECT deptno, dname\n  FROM dept\n WHERE deptno = $1", "SELECT * FROM emp WHERE empno = $1"},
+		{"postgres", "SELECT deptno, dname\n  FROM dept\n WHERE deptno = $1", "SELECT * FROM emp WHERE empno = $1 /* pq */"},
+		{"godror", "SELECT deptno, dname FROM dept WHERE deptno = :1", "SELECT * FROM emp WHERE empno = $1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			sqlStms, err := LoadCatalog(files, tt.driver)
+			if err != nil {
+				t.Fatalf("LoadCatalog() error = %v", err)
+			}
+			if len(sqlStms) != 3 {
+				t.Errorf("LoadCatalog() len = %v, want 3", len(sqlStms))
+			}
+			if got := sqlStms["GetDept"]; got == nil || got.Text != tt.getDept || !got.IsPrepare {
+				t.Errorf("LoadCatalog() GetDept = %+v, want %q prepared", got, tt.getDept)
+			}
+			if got := sqlStms["GetEmp"]; got == nil || got.Text != tt.getEmp {
+				t.Errorf("LoadCatalog() GetEmp = %+v, want %q", got, tt.getEmp)
+			}
+			if got := sqlStms["CreateDept"]; got == nil || got.IsPrepare || got.Timeout != 5*time.Second {
+				t.Errorf("LoadCatalog() CreateDept = %+v, want not prepared with timeout 5s", got)
+			}
+			if err = CheckCatalog(sqlStms, []string{"GetDept", "GetEmp", "UpdateDept"}); err == nil {
+				t.Errorf("CheckCatalog() error = nil, want missing UpdateDept")
+			}
+		})
+	}
+}
+
+func TestLoadCatalog_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"defined twice", map[string]string{"a.sql": "-- name: GetDept\nSELECT 1", "GetDept.sql": "SELECT 2"}},
+		{"empty statement", map[string]string{"a.sql": "-- name: GetDept\n-- name: GetEmp\nSELECT 1"}},
+		{"bad timeout", map[string]string{"GetDept.sql": "-- timeout: 5\nSELECT 1"}},
+		{"bad file name", map[string]string{"GetDept.pgx.old.sql": "SELECT 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadCatalog(tt.files, "pgx"); err == nil {
+				t.Errorf("LoadCatalog() error = nil, want error")
+			}
+		})
+	}
+}
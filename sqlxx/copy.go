@@ -0,0 +1,158 @@
+package sqlxx
+
+import (
+	"context"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/stdlib"
+	"github.com/lib/pq"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// CopySource represent source of rows for COPY FROM STDIN, it is compatible with pgx.CopyFromSource
+type CopySource interface {
+	// Next returns true if there is another row, false at the end of rows or on error
+	Next() bool
+
+	// Values returns the values for the current row
+	Values() ([]interface{}, error)
+
+	// Err returns any error that has been encountered by the CopySource
+	Err() error
+}
+
+// ctxCopySource - stop reading rows if context is done
+type ctxCopySource struct {
+	ctx context.Context
+	src CopySource
+	err error
+}
+
+// Next returns true if there is another row and context is not done
+func (s *ctxCopySource) Next() bool {
+	if s.err = s.ctx.Err(); s.err != nil {
+		return false
+	}
+	return s.src.Next()
+}
+
+// Values returns the values for the current row
+func (s *ctxCopySource) Values() ([]interface{}, error) {
+	return s.src.Values()
+}
+
+// Err returns error of context or source
+func (s *ctxCopySource) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.src.Err()
+}
+
+// CopyFrom load rows from src into table with COPY FROM STDIN. Rows are streamed to DB as they are read from src.
+// COPY is run in its own transaction, ctx must not carry transaction
+func (db *DB) CopyFrom(ctx context.Context, table string, columns []string, src CopySource) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	sqlID := GetNextSQLID()
+
+	{ // входные проверки
+		if src == nil {
+			return 0, myerror.New("4400", "Incorrect call - nil CopySource: reqID, table", reqID, table).PrintfInfo()
+		}
+		if FromContextTx(ctx) != nil {
+			return 0, myerror.New("4400", "Incorrect call - COPY in transaction from context: reqID, table", reqID, table).PrintfInfo()
+		}
+	}
+
+	mylog.PrintfDebugMsg("COPY FROM STDIN: reqID, sqlID, table, columns", reqID, sqlID, table, columns)
+
+	src = &ctxCopySource{ctx: ctx, src: src}
+
+	switch db.cfg.DriverName {
+	case "pgx":
+		rows, myerr = db.copyFromPgx(ctx, sqlID, table, columns, src)
+	case "postgres":
+		rows, myerr = db.copyFromPq(ctx, sqlID, table, columns, src)
+	default:
+		return 0, myerror.New("4400", "COPY is not supported by driver: reqID, driver", reqID, db.cfg.DriverName).PrintfInfo()
+	}
+	if myerr != nil {
+		return 0, myerr
+	}
+
+	mylog.PrintfDebugMsg("COPY FROM STDIN - SUCCESS: reqID, sqlID, table, rows", reqID, sqlID, table, rows)
+	return rows, nil
+}
+
+// copyFromPgx - COPY with native pgx protocol on connection acquired from pool
+func (db *DB) copyFromPgx(ctx context.Context, sqlID uint64, table string, columns []string, src CopySource) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	conn, err := stdlib.AcquireConn(db.DB.DB)
+	if err != nil {
+		return 0, myerror.WithCause("4001", "Error acquire pgx connection: reqID, sqlID", err, reqID, sqlID).PrintfInfo()
+	}
+	defer func() {
+		if err := stdlib.ReleaseConn(db.DB.DB, conn); err != nil {
+			mylog.PrintfErrorInfo(myerror.WithCause("4001", "Error release pgx connection: reqID, sqlID", err, reqID, sqlID))
+		}
+	}()
+
+	// pgx выполняет COPY в неявной транзакции - при ошибке строки не загружаются
+	count, err := conn.CopyFrom(pgx.Identifier{table}, columns, src)
+	if err != nil {
+		return 0, contextError(ctx, err, "4005", "Error COPY FROM STDIN: reqID, sqlID, table", reqID, sqlID, table)
+	}
+	return int64(count), nil
+}
+
+// copyFromPq - COPY with lib/pq prepared COPY statement in transaction
+func (db *DB) copyFromPq(ctx context.Context, sqlID uint64, table string, columns []string, src CopySource) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	tx, myerr := db.Beginx(ctx)
+	if myerr != nil {
+		return 0, myerr
+	}
+
+	// при ошибке откатываем транзакцию
+	defer func() {
+		if myerr != nil {
+			_ = db.Rollback(ctx, tx)
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return 0, contextError(ctx, err, "4002", "Error prepare COPY FROM STDIN: reqID, sqlID, table", reqID, sqlID, table)
+	}
+	defer stmt.Close()
+
+	// строки буферизуются драйвером и отправляются на сервер по мере заполнения буфера
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return 0, myerror.WithCause("4005", "Error get COPY row values: reqID, sqlID, table, row", err, reqID, sqlID, table, rows+1).PrintfInfo()
+		}
+		if _, err = stmt.ExecContext(ctx, values...); err != nil {
+			return 0, contextError(ctx, err, "4005", "Error COPY FROM STDIN: reqID, sqlID, table, row", reqID, sqlID, table, rows+1)
+		}
+		rows++
+	}
+	if err = src.Err(); err != nil {
+		return 0, contextError(ctx, err, "4005", "Error read COPY rows: reqID, sqlID, table, row", reqID, sqlID, table, rows+1)
+	}
+
+	// завершаем COPY
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return 0, contextError(ctx, err, "4005", "Error finish COPY FROM STDIN: reqID, sqlID, table", reqID, sqlID, table)
+	}
+
+	if myerr = db.Commit(ctx, tx); myerr != nil {
+		return 0, myerr
+	}
+	return rows, nil
+}
@@ -0,0 +1,295 @@
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Чтение с реплик:
+//     подготовленные SELECT команды вне транзакции выполняются на репликах по кругу (round-robin)
+//     реплика, не ответившая на ping или вернувшая ошибку, исключается до следующей успешной проверки
+//     если исправных реплик нет или реплика вернула ошибку, SELECT выполняется на основном сервере
+//     SELECT в транзакции или в контексте с признаком read-your-writes выполняется на основном сервере
+
+// Роль пула подключений
+const (
+	PoolRolePrimary = "primary"
+	PoolRoleReplica = "replica"
+)
+
+// Интервал проверки реплик по умолчанию
+const defaultReplicaCheckInterval = 5 * time.Second
+
+// readYourWritesKey is the context key for read-your-writes flag
+type readYourWritesKey struct{}
+
+// NewContextReadYourWrites returns a new Context, SELECT statements with this Context are run on primary
+func NewContextReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// FromContextReadYourWrites extracts the read-your-writes flag from ctx, if present.
+func FromContextReadYourWrites(ctx context.Context) bool {
+	readYourWrites, ok := ctx.Value(readYourWritesKey{}).(bool)
+	if !ok {
+		return false
+	}
+	return readYourWrites
+}
+
+// replica represent replica connection pool
+type replica struct {
+	queries uint64   // количество выполненных SELECT команд
+	errors  uint64   // количество ошибок выполнения SELECT команд
+	name    string   // host:port реплики
+	db      *sqlx.DB // пул подключений
+	healthy int32    // 1 - реплика исправна
+}
+
+// PoolStats represent statistics of connection pool
+type PoolStats struct {
+	Name    string // host:port сервера БД
+	Role    string // PoolRolePrimary | PoolRoleReplica
+	Healthy bool   // признак исправности пула
+	Queries uint64 // количество выполненных на пуле SELECT команд
+	Errors  uint64 // количество ошибок выполнения SELECT команд
+	sql.DBStats
+}
+
+// connectString - build connect string for host and port
+func connectString(cfg *Config, host string, port string) string {
+	return fmt.Sprintf("host=%s port=%s dbname=%s sslmode=%s user=%s password=%s ", host, port, cfg.Dbname, cfg.SslMode, cfg.User, cfg.Pass)
+}
+
+// openReplicas - open connection pools to replicas, unavailable replica is marked unhealthy
+func (db *DB) openReplicas() (myerr error) {
+	for _, name := range db.cfg.Replicas {
+		host, port, err := net.SplitHostPort(name)
+		if err != nil {
+			return myerror.WithCause("4001", "Incorrect replica address, expected host:port: replica", err, name).PrintfInfo()
+		}
+
+		// sqlx.Open не проверяет подключение - недоступная реплика не мешает старту сервиса
+		sqlxDb, err := sqlx.Open(db.cfg.DriverName, connectString(db.cfg, host, port))
+		if err != nil {
+			return myerror.WithCause("4001", "Error open replica: replica", err, name).PrintfInfo()
+		}
+		sqlxDb.SetMaxOpenConns(db.cfg.MaxOpenConns)
+		sqlxDb.SetMaxIdleConns(db.cfg.MaxIdleConns)
+		sqlxDb.SetConnMaxLifetime(time.Duration(db.cfg.ConnMaxLifetime * int(time.Millisecond)))
+
+		db.replicas = append(db.replicas, &replica{name: name, db: sqlxDb})
+	}
+
+	if len(db.replicas) > 0 {
+		db.checkReplicas()
+		go db.watchReplicas()
+	}
+	return nil
+}
+
+// watchReplicas - periodically check replicas health
+func (db *DB) watchReplicas() {
+	interval := defaultReplicaCheckInterval
+	if db.cfg.ReplicaCheckInterval > 0 {
+		interval = time.Duration(db.cfg.ReplicaCheckInterval) * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stopCh:
+			return
+		case <-ticker.C:
+			db.checkReplicas()
+		}
+	}
+}
+
+// checkReplicas - ping replicas and mark them healthy or unhealthy
+func (db *DB) checkReplicas() {
+	for _, r := range db.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultReplicaCheckInterval)
+		err := r.db.PingContext(ctx)
+		cancel()
+
+		if err != nil {
+			if atomic.SwapInt32(&r.healthy, 0) == 1 {
+				mylog.PrintfInfoMsg("Replica is unhealthy: replica, err", r.name, err)
+			} else {
+				mylog.PrintfDebugMsg("Replica is unavailable: replica, err", r.name, err)
+			}
+			continue
+		}
+
+		// реплика стала доступна - подготовим для нее SQL команды
+		if atomic.LoadInt32(&r.healthy) == 0 {
+			if err = db.prepareReplica(r); err != nil {
+				mylog.PrintfInfoMsg("Error prepare SQL statements on replica: replica, err", r.name, err)
+				continue
+			}
+			atomic.StoreInt32(&r.healthy, 1)
+			mylog.PrintfInfoMsg("Replica is healthy: replica", r.name)
+		}
+	}
+}
+
+// prepareReplica - prepare SELECT statements of current catalog on replica
+func (db *DB) prepareReplica(r *replica) error {
+	db.mx.Lock()
+	defer db.mx.Unlock()
+
+	for i, rr := range db.replicas {
+		if rr == r {
+			return db.prepareReplicaStms(db.sqlStms, i)
+		}
+	}
+	return nil
+}
+
+// prepareReplicaStms - prepare SELECT statements on replica with index i, db.mx must be locked
+func (db *DB) prepareReplicaStms(sqlStms SQLStms, i int) error {
+	r := db.replicas[i]
+	for _, h := range sqlStms {
+		if !h.IsPrepare || !isSelect(h.Text) {
+			continue
+		}
+		if len(h.replicaStmts) != len(db.replicas) {
+			h.replicaStmts = make([]*sqlx.Stmt, len(db.replicas))
+		}
+		if h.replicaStmts[i] != nil {
+			continue
+		}
+		stmt, err := r.db.Preparex(h.Text)
+		if err != nil {
+			return myerror.WithCause("4002", "Error prepare SQL stament on replica: replica, SQL", err, r.name, h.Text)
+		}
+		h.replicaStmts[i] = stmt
+	}
+	return nil
+}
+
+// prepareReplicas - prepare SELECT statements on all healthy replicas, unprepared replica is marked unhealthy, db.mx must be locked
+func (db *DB) prepareReplicas(sqlStms SQLStms) {
+	for i, r := range db.replicas {
+		if atomic.LoadInt32(&r.healthy) == 0 {
+			continue
+		}
+		if err := db.prepareReplicaStms(sqlStms, i); err != nil {
+			atomic.StoreInt32(&r.healthy, 0)
+			mylog.PrintfInfoMsg("Replica is unhealthy: replica, err", r.name, err)
+		}
+	}
+}
+
+// isSelect - check that SQL statement is SELECT
+func isSelect(text string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(text)), "SELECT")
+}
+
+// replicaStmt - choose next healthy replica by round-robin, return nil if SELECT should be run on primary
+func (db *DB) replicaStmt(ctx context.Context, sqlStm *SQLStm) (*replica, *sqlx.Stmt) {
+	if len(db.replicas) == 0 {
+		return nil, nil
+	}
+	if FromContextTx(ctx) != nil || FromContextReadYourWrites(ctx) {
+		return nil, nil
+	}
+
+	db.mx.RLock()
+	defer db.mx.RUnlock()
+
+	next := atomic.AddUint32(&db.replicaNext, 1)
+	for j := 0; j < len(db.replicas); j++ {
+		i := int((next + uint32(j)) % uint32(len(db.replicas)))
+		r := db.replicas[i]
+		if atomic.LoadInt32(&r.healthy) == 1 && len(sqlStm.replicaStmts) == len(db.replicas) && sqlStm.replicaStmts[i] != nil {
+			return r, sqlStm.replicaStmts[i]
+		}
+	}
+	return nil, nil
+}
+
+// failed - mark replica unhealthy after SQL error, SELECT will be repeated on primary
+func (r *replica) failed(err error) {
+	atomic.AddUint64(&r.errors, 1)
+	if atomic.SwapInt32(&r.healthy, 0) == 1 {
+		mylog.PrintfInfoMsg("Replica is unhealthy, fallback to primary: replica, err", r.name, err)
+	}
+}
+
+// runSelect - run SELECT on replica, fallback to primary statement stm if there is no healthy replica or replica fails
+func (db *DB) runSelect(ctx context.Context, sqlStm *SQLStm, stm *sqlx.Stmt, run func(stm *sqlx.Stmt) error) error {
+	if r, replicaStm := db.replicaStmt(ctx, sqlStm); replicaStm != nil {
+		err := run(replicaStm)
+		if err == nil || err == sql.ErrNoRows {
+			atomic.AddUint64(&r.queries, 1)
+			return err
+		}
+		// превышение времени или отмена запроса - не сбой реплики, повторять на основном сервере нет смысла
+		if ctx.Err() != nil {
+			return err
+		}
+		r.failed(err)
+	}
+
+	atomic.AddUint64(&db.queries, 1)
+	err := run(stm)
+	if err != nil && err != sql.ErrNoRows {
+		atomic.AddUint64(&db.errors, 1)
+	}
+	return err
+}
+
+// closeReplicas - stop health check and close replica connection pools
+func (db *DB) closeReplicas() {
+	close(db.stopCh)
+	for _, r := range db.replicas {
+		if err := r.db.Close(); err != nil {
+			mylog.PrintfInfoMsg("Error close replica: replica, err", r.name, err)
+		}
+	}
+}
+
+// Stats return statistics of primary and replica connection pools
+func (db *DB) Stats() []PoolStats {
+	stats := make([]PoolStats, 0, len(db.replicas)+1)
+	stats = append(stats, PoolStats{
+		Name:    net.JoinHostPort(db.cfg.Host, db.cfg.Port),
+		Role:    PoolRolePrimary,
+		Healthy: true,
+		Queries: atomic.LoadUint64(&db.queries),
+		Errors:  atomic.LoadUint64(&db.errors),
+		DBStats: db.DB.Stats(),
+	})
+	for _, r := range db.replicas {
+		stats = append(stats, PoolStats{
+			Name:    r.name,
+			Role:    PoolRoleReplica,
+			Healthy: atomic.LoadInt32(&r.healthy) == 1,
+			Queries: atomic.LoadUint64(&r.queries),
+			Errors:  atomic.LoadUint64(&r.errors),
+			DBStats: r.db.Stats(),
+		})
+	}
+	return stats
+}
+
+// PrintPoolStats print statistics about connection pools
+func (db *DB) PrintPoolStats() {
+	for _, s := range db.Stats() {
+		mylog.PrintfInfoMsg("Usage DB pool: name, role, healthy, queries, errors, open, inUse, idle, waitCount", s.Name, s.Role, s.Healthy, s.Queries, s.Errors, s.OpenConnections, s.InUse, s.Idle, s.WaitCount)
+	}
+}
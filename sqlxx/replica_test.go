@@ -0,0 +1,66 @@
+package sqlxx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestReplicaStmt(t *testing.T) {
+	db := &DB{replicas: []*replica{{name: "r0", healthy: 1}, {name: "r1", healthy: 1}}}
+	sqlStm := &SQLStm{replicaStmts: []*sqlx.Stmt{{}, {}}}
+	ctx := context.Background()
+
+	// round-robin по исправным репликам
+	first, _ := db.replicaStmt(ctx, sqlStm)
+	second, _ := db.replicaStmt(ctx, sqlStm)
+	if first == nil || second == nil || first == second {
+		t.Fatalf("replicaStmt() = %v, %v, want different replicas", first, second)
+	}
+
+	// неисправная реплика пропускается
+	db.replicas[0].healthy = 0
+	for i := 0; i < 3; i++ {
+		if r, _ := db.replicaStmt(ctx, sqlStm); r != db.replicas[1] {
+			t.Errorf("replicaStmt() = %v, want r1", r)
+		}
+	}
+
+	// нет исправных реплик - основной сервер
+	db.replicas[1].failed(nil)
+	if r, stmt := db.replicaStmt(ctx, sqlStm); r != nil || stmt != nil {
+		t.Errorf("replicaStmt() = %v, want primary", r)
+	}
+	db.replicas[0].healthy, db.replicas[1].healthy = 1, 1
+
+	// транзакция и read-your-writes - основной сервер
+	if r, _ := db.replicaStmt(NewContextTx(ctx, &Tx{}), sqlStm); r != nil {
+		t.Errorf("replicaStmt() in transaction = %v, want primary", r)
+	}
+	if r, _ := db.replicaStmt(NewContextReadYourWrites(ctx), sqlStm); r != nil {
+		t.Errorf("replicaStmt() with read-your-writes = %v, want primary", r)
+	}
+
+	// SQL команда не подготовлена на репликах - основной сервер
+	if r, _ := db.replicaStmt(ctx, &SQLStm{}); r != nil {
+		t.Errorf("replicaStmt() for not prepared statement = %v, want primary", r)
+	}
+}
+
+func TestIsSelect(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"SELECT * FROM dept", true},
+		{"  select 1", true},
+		{"UPDATE dept SET dname = :dname", false},
+		{"INSERT INTO dept (deptno) VALUES (:deptno)", false},
+	}
+	for _, tt := range tests {
+		if got := isSelect(tt.text); got != tt.want {
+			t.Errorf("isSelect(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
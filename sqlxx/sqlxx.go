@@ -1,9 +1,11 @@
 package sqlxx
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -11,43 +13,84 @@ import (
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 
+	myctx "github.com/romapres2010/httpserver/ctx"
 	myerror "github.com/romapres2010/httpserver/error"
 	mylog "github.com/romapres2010/httpserver/log"
 )
 
 // Config конфигурационные настройки БД
 type Config struct {
-	ConnectString   string // строка подключения к БД
-	Host            string // host БД
-	Port            string // порт листенера БД
-	Dbname          string // имя БД
-	SslMode         string // режим SSL
-	User            string // пользователь для подключения к БД
-	Pass            string // пароль пользователя
-	ConnMaxLifetime int    // время жизни подключения в милисекундах
-	MaxOpenConns    int    // максимальное количество открытых подключений
-	MaxIdleConns    int    // максимальное количество простаивающих подключений
-	DriverName      string // имя драйвера "postgres" | "pgx" | "godror"
+	ConnectString     string // строка подключения к БД
+	Host              string // host БД
+	Port              string // порт листенера БД
+	Dbname            string // имя БД
+	SslMode           string // режим SSL
+	User              string // пользователь для подключения к БД
+	Pass              string // пароль пользователя
+	ConnMaxLifetime   int    // время жизни подключения в милисекундах
+	MaxOpenConns      int    // максимальное количество открытых подключений
+	MaxIdleConns      int    // максимальное количество простаивающих подключений
+	DriverName        string // имя драйвера "postgres" | "pgx" | "godror"
+	QueryTimeout      int    // время выполнения SQL команды по умолчанию в милисекундах, 0 - без ограничения
+	TxRetryCount      int    // количество повторов транзакции при сбое сериализации или взаимной блокировке
+	TxRetryBackoff    int    // начальная задержка перед повтором транзакции в милисекундах
+	TxRetryMaxBackoff int    // максимальная задержка перед повтором транзакции в милисекундах
+
+	Replicas             []string // реплики для чтения в формате host:port, остальные параметры подключения как у основного сервера
+	ReplicaCheckInterval int      // интервал проверки реплик в милисекундах
 }
 
+// Коды ошибок прерывания SQL команды
+const (
+	ErrCodeTimeout  = "4010" // превышено время выполнения SQL команды
+	ErrCodeCanceled = "4011" // SQL команда отменена - клиент закрыл запрос или сервер останавливается
+)
+
+// ErrCodeVersionConflict represent error code of optimistic lock failure - row version was changed by another transaction
+const ErrCodeVersionConflict = "4012"
+
+// ErrCodeConstraintViolation represent error code of integrity constraint violation: unique, foreign key, not null or check
+const ErrCodeConstraintViolation = "4013"
+
 // DB is a wrapper around sqlx.DB
 type DB struct {
+	queries uint64 // количество выполненных на основном сервере SELECT команд
+	errors  uint64 // количество ошибок выполнения SELECT команд на основном сервере
+
 	*sqlx.DB
 
-	cfg     *Config
-	sqlStms SQLStms // SQL команды
+	cfg      *Config
+	mx       sync.RWMutex
+	sqlStms  SQLStms         // SQL команды
+	stmsUsed *sync.WaitGroup // выполняемые с текущими SQL командами запросы, ранее подготовленные команды закрываются при Reload после их завершения
+
+	replicas    []*replica    // реплики для чтения
+	replicaNext uint32        // счетчик для выбора реплики по кругу
+	stopCh      chan struct{} // канал остановки проверки реплик
 }
 
 // Tx is an sqlx wrapper around sqlx.Tx
 type Tx struct {
 	*sqlx.Tx
+
+	savepointID uint32   // номер последней точки сохранения во вложенных InTx
+	afterCommit []func() // функции, выполняемые после фиксации транзакции
 }
 
-// SQLStm represent SQL text and sqlStm
+// AfterCommit register fn to be run after successful commit of transaction.
+// Functions registered inside rolled back savepoint are discarded
+func (tx *Tx) AfterCommit(fn func()) {
+	tx.afterCommit = append(tx.afterCommit, fn)
+}
+
+// SQLStm represent SQL text and sqlStm, statements are loaded from SQL catalog by LoadCatalog
 type SQLStm struct {
-	Text      string     // текст SQL команды
-	Stmt      *sqlx.Stmt // подготовленная SQL команда
-	IsPrepare bool       // признак, нужно ли предварительно готовить SQL команду
+	Text      string        // текст SQL команды
+	Stmt      *sqlx.Stmt    // подготовленная SQL команда
+	IsPrepare bool          // признак, нужно ли предварительно готовить SQL команду
+	Timeout   time.Duration // время выполнения SQL команды, 0 - значение по умолчанию QueryTimeout
+
+	replicaStmts []*sqlx.Stmt // подготовленная на репликах SELECT команда, индекс соответствует реплике
 }
 
 // SQLStms represent SQLStm map
@@ -70,12 +113,14 @@ func New(cfg *Config, sqlStms SQLStms) (db *DB, myerr error) {
 	} // входные проверки
 
 	// Сформировать строку подключения
-	cfg.ConnectString = fmt.Sprintf("host=%s port=%s dbname=%s sslmode=%s user=%s password=%s ", cfg.Host, cfg.Port, cfg.Dbname, cfg.SslMode, cfg.User, cfg.Pass)
+	cfg.ConnectString = connectString(cfg, cfg.Host, cfg.Port)
 
 	// Создаем новый сервис
 	db = &DB{
-		cfg:     cfg,
-		sqlStms: sqlStms,
+		cfg:      cfg,
+		sqlStms:  sqlStms,
+		stmsUsed: &sync.WaitGroup{},
+		stopCh:   make(chan struct{}),
 	}
 
 	// открываем соединение с БД
@@ -98,11 +143,16 @@ func New(cfg *Config, sqlStms SQLStms) (db *DB, myerr error) {
 		return nil, err
 	}
 
+	// Подключимся к репликам для чтения
+	if err = db.openReplicas(); err != nil {
+		return nil, err
+	}
+
 	mylog.PrintfInfoMsg("Success connect to PostgreSQL server")
 	return db, nil
 }
 
-// Preparex - prepare SQL statements
+// Preparex - prepare SQL statements, on error already prepared statements are closed
 func (db *DB) Preparex(SQLStms SQLStms) (myerr error) {
 	var err error
 
@@ -114,6 +164,7 @@ func (db *DB) Preparex(SQLStms SQLStms) (myerr error) {
 	for _, h := range SQLStms {
 		if h.IsPrepare {
 			if h.Stmt, err = db.DB.Preparex(h.Text); err != nil {
+				closeSQLStms(SQLStms) // закроем команды, подготовленные до ошибки
 				return myerror.WithCause("4002", "Error prepare SQL stament: SQL", err, h.Text).PrintfInfo()
 			}
 			mylog.PrintfInfoMsg("SQL stament is prepared: SQL", h.Text)
@@ -122,8 +173,76 @@ func (db *DB) Preparex(SQLStms SQLStms) (myerr error) {
 	return nil
 }
 
-// Beginx - begin a new transaction
-func (db *DB) Beginx(reqID uint64) (tx *Tx, myerr error) {
+// Reload - prepare new SQL statements and replace current ones.
+// Replaced statements are closed after completion of requests, which are using them
+func (db *DB) Reload(sqlStms SQLStms) (myerr error) {
+	// Подготовим новые SQL команды, при ошибке остаются текущие
+	if myerr = db.Preparex(sqlStms); myerr != nil {
+		return myerr
+	}
+
+	db.mx.Lock()
+	db.prepareReplicas(sqlStms)
+	oldStms, oldUsed := db.sqlStms, db.stmsUsed
+	db.sqlStms, db.stmsUsed = sqlStms, &sync.WaitGroup{}
+	db.mx.Unlock()
+
+	// Закроем ранее подготовленные SQL команды после завершения выполняемых с ними запросов.
+	// Новые запросы получают команды уже из нового каталога, поэтому счетчик старого каталога только уменьшается
+	go func() {
+		if oldUsed != nil {
+			oldUsed.Wait()
+		}
+		closeSQLStms(oldStms)
+	}()
+
+	mylog.PrintfInfoMsg("SQL statements are reloaded: Count", len(sqlStms))
+	return nil
+}
+
+// closeSQLStms - close prepared SQL statements on master and replicas
+func closeSQLStms(sqlStms SQLStms) {
+	for _, h := range sqlStms {
+		if h.Stmt != nil {
+			if err := h.Stmt.Close(); err != nil {
+				mylog.PrintfInfoMsg("Error close SQL stament: SQL, err", h.Text, err)
+			}
+			h.Stmt = nil
+		}
+		for i, stmt := range h.replicaStmts {
+			if stmt != nil {
+				if err := stmt.Close(); err != nil {
+					mylog.PrintfInfoMsg("Error close SQL stament on replica: SQL, err", h.Text, err)
+				}
+				h.replicaStmts[i] = nil
+			}
+		}
+	}
+}
+
+// getSQLStm - return SQL statement by name. Statement is not closed by Reload until release is called
+func (db *DB) getSQLStm(sqlT string) (sqlStm *SQLStm, release func(), ok bool) {
+	db.mx.RLock()
+	defer db.mx.RUnlock()
+	if sqlStm, ok = db.sqlStms[sqlT]; !ok {
+		return nil, nil, false
+	}
+	if db.stmsUsed == nil {
+		return sqlStm, func() {}, true
+	}
+	db.stmsUsed.Add(1)
+	return sqlStm, db.stmsUsed.Done, true
+}
+
+// Beginx - begin a new transaction with default options, transaction is rolled back if ctx is canceled
+func (db *DB) Beginx(ctx context.Context) (tx *Tx, myerr error) {
+	return db.BeginTxx(ctx, nil)
+}
+
+// BeginTxx - begin a new transaction with options, transaction is rolled back if ctx is canceled
+func (db *DB) BeginTxx(ctx context.Context, opts *TxOptions) (tx *Tx, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
 	// функция восстановления после паники
 	defer func() {
 		r := recover()
@@ -144,15 +263,22 @@ func (db *DB) Beginx(reqID uint64) (tx *Tx, myerr error) {
 		return nil, myerror.New("4004", "DB is not defined").PrintfInfo()
 	}
 
-	sqlxTx, err := db.DB.Beginx()
+	var txOpts *sql.TxOptions
+	if opts != nil {
+		txOpts = &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	}
+
+	sqlxTx, err := db.DB.BeginTxx(ctx, txOpts)
 	if err != nil {
-		return nil, myerror.WithCause("4006", "Error begin a new transaction", err).PrintfInfo()
+		return nil, contextError(ctx, err, "4006", "Error begin a new transaction: reqID", reqID)
 	}
-	return &Tx{sqlxTx}, nil
+	return &Tx{Tx: sqlxTx}, nil
 }
 
 // Rollback - rollback the transaction
-func (db *DB) Rollback(reqID uint64, tx *Tx) (myerr error) {
+func (db *DB) Rollback(ctx context.Context, tx *Tx) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
 	// функция восстановления после паники
 	defer func() {
 		r := recover()
@@ -175,6 +301,11 @@ func (db *DB) Rollback(reqID uint64, tx *Tx) (myerr error) {
 	}
 
 	if err := tx.Rollback(); err != nil {
+		// транзакция уже откачена при отмене контекста
+		if err == sql.ErrTxDone && ctx.Err() != nil {
+			mylog.PrintfDebugMsgDepth("Transaction rollbacked by context", 1, reqID)
+			return nil
+		}
 		return myerror.WithCause("4008", "Error rollback the transaction: reqID", err, reqID).PrintfInfo()
 	}
 	mylog.PrintfDebugMsgDepth("Transaction rollbacked", 1, reqID)
@@ -182,7 +313,9 @@ func (db *DB) Rollback(reqID uint64, tx *Tx) (myerr error) {
 }
 
 // Commit - commit the transaction
-func (db *DB) Commit(reqID uint64, tx *Tx) (myerr error) {
+func (db *DB) Commit(ctx context.Context, tx *Tx) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
 	// функция восстановления после паники
 	defer func() {
 		r := recover()
@@ -205,18 +338,21 @@ func (db *DB) Commit(reqID uint64, tx *Tx) (myerr error) {
 	}
 
 	if err := tx.Commit(); err != nil {
-		return myerror.WithCause("4008", "Error commit the transaction: reqID", err, reqID).PrintfInfo()
+		return contextError(ctx, err, "4008", "Error commit the transaction: reqID", reqID)
 	}
 	mylog.PrintfDebugMsgDepth("Transaction commited", 1, reqID)
 	return nil
 }
 
-// Select - represent common task in process SQL Select statement
-func (db *DB) Select(reqID uint64, tx *Tx, sqlT string, dest interface{}, args ...interface{}) (myerr error) {
-	sqlStm, ok := db.sqlStms[sqlT]
+// Select - represent common task in process SQL Select statement, transaction is taken from ctx if present
+func (db *DB) Select(ctx context.Context, sqlT string, dest interface{}, args ...interface{}) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	sqlStm, release, ok := db.getSQLStm(sqlT)
 	if !ok {
 		return myerror.New("4100", "SQL statement is not defined: reqID, sql", reqID, sqlT).PrintfInfo()
 	}
+	defer release()
 
 	// функция восстановления после паники
 	defer func() {
@@ -240,27 +376,42 @@ func (db *DB) Select(reqID uint64, tx *Tx, sqlT string, dest interface{}, args .
 
 		mylog.PrintfDebugMsg("reqID, sqlID, SQL", reqID, sqlID, sqlStm.Text)
 
+		// Ограничим время выполнения SQL команды
+		stmCtx, cancel := db.withTimeout(ctx, sqlStm)
+		defer cancel()
+
 		stm := sqlStm.Stmt
-		// Помещаем запрос в рамки транзакции
-		if tx != nil {
-			stm = tx.Stmtx(sqlStm.Stmt)
+		// Помещаем запрос в рамки транзакции из контекста
+		if tx := FromContextTx(ctx); tx != nil && stm != nil {
+			stm = tx.StmtxContext(stmCtx, stm)
 		}
 
-		//Выполняем запрос
-		if err := stm.Select(dest, args...); err != nil {
-			return myerror.WithCause("4003", "Error Select SQL statement: reqID, sqlID, SQL", err, reqID, sqlID, sqlStm.Text).PrintfInfo()
+		//Выполняем запрос, вне транзакции - на реплике
+		err := db.runSelect(stmCtx, sqlStm, stm, func(stm *sqlx.Stmt) error {
+			// после сбоя реплики в dest могут остаться прочитанные строки
+			reflect.ValueOf(dest).Elem().Set(reflect.Zero(reflect.TypeOf(dest).Elem()))
+			if stm == nil {
+				return sqlx.SelectContext(stmCtx, db.queryer(ctx), dest, sqlStm.Text, args...)
+			}
+			return stm.SelectContext(stmCtx, dest, args...)
+		})
+		if err != nil {
+			return contextError(stmCtx, err, "4003", "Error Select SQL statement: reqID, sqlID, SQL", reqID, sqlID, sqlStm.Text)
 		}
 		return nil
 	}
 	return myerror.New("4400", "Incorrect call - nil dest interface{} pointer: reqID, sql", reqID, sqlT).PrintfInfo()
 }
 
-// Get - represent common task in process SQL Select statement with only one rows
-func (db *DB) Get(reqID uint64, tx *Tx, sqlT string, dest interface{}, args ...interface{}) (exists bool, myerr error) {
-	sqlStm, ok := db.sqlStms[sqlT]
+// Get - represent common task in process SQL Select statement with only one rows, transaction is taken from ctx if present
+func (db *DB) Get(ctx context.Context, sqlT string, dest interface{}, args ...interface{}) (exists bool, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	sqlStm, release, ok := db.getSQLStm(sqlT)
 	if !ok {
 		return false, myerror.New("4100", "SQL statement is not defined: reqID, sql", reqID, sqlT).PrintfInfo()
 	}
+	defer release()
 
 	// функция восстановления после паники
 	defer func() {
@@ -284,32 +435,127 @@ func (db *DB) Get(reqID uint64, tx *Tx, sqlT string, dest interface{}, args ...i
 
 		mylog.PrintfDebugMsg("reqID, sqlID, SQL", reqID, sqlID, sqlStm.Text)
 
+		// Ограничим время выполнения SQL команды
+		stmCtx, cancel := db.withTimeout(ctx, sqlStm)
+		defer cancel()
+
 		stm := sqlStm.Stmt
-		// Помещаем запрос в рамки транзакции
-		if tx != nil {
-			stm = tx.Stmtx(sqlStm.Stmt)
+		// Помещаем запрос в рамки транзакции из контекста
+		if tx := FromContextTx(ctx); tx != nil && stm != nil {
+			stm = tx.StmtxContext(stmCtx, stm)
 		}
 
-		//Выполняем запрос
-		if err := stm.Get(dest, args...); err != nil {
+		//Выполняем запрос, вне транзакции - на реплике
+		err := db.runSelect(stmCtx, sqlStm, stm, func(stm *sqlx.Stmt) error {
+			if stm == nil {
+				return sqlx.GetContext(stmCtx, db.queryer(ctx), dest, sqlStm.Text, args...)
+			}
+			return stm.GetContext(stmCtx, dest, args...)
+		})
+		if err != nil {
 			// NO_DATA_FOUND - ошибкой не считаем
 			if err == sql.ErrNoRows {
 				return false, nil
 			}
-			return false, myerror.WithCause("4003", "Error Get SQL statement: reqID, sqlID, SQL", err, reqID, sqlID, sqlStm.Text).PrintfInfo()
+			return false, contextError(stmCtx, err, "4003", "Error Get SQL statement: reqID, sqlID, SQL", reqID, sqlID, sqlStm.Text)
 		}
 		return true, nil
 	}
 	return false, myerror.New("4400", "Incorrect call - nil dest interface{} pointer: reqID, sql", reqID, sqlT).PrintfInfo()
 }
 
-// Exec - represent common task in process DML statement
-func (db *DB) Exec(reqID uint64, tx *Tx, sqlT string, args interface{}) (rows int64, myerr error) {
-	sqlStm, ok := db.sqlStms[sqlT]
+// Query - run SELECT statement and pass cursor to fn, rows are fetched from DB while fn iterates them.
+// Default QueryTimeout is not applied - cursor is limited by ctx and "-- timeout" directive of SQL statement
+func (db *DB) Query(ctx context.Context, sqlT string, fn func(rows *sqlx.Rows) error, args ...interface{}) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	sqlStm, release, ok := db.getSQLStm(sqlT)
+	if !ok {
+		return myerror.New("4100", "SQL statement is not defined: reqID, sql", reqID, sqlT).PrintfInfo()
+	}
+	defer release()
+
+	if fn != nil {
+		// Получить уникальный номер SQL
+		sqlID := GetNextSQLID()
+
+		mylog.PrintfDebugMsg("reqID, sqlID, SQL", reqID, sqlID, sqlStm.Text)
+
+		// Ограничим время выполнения SQL команды только явно заданным значением
+		stmCtx, cancel := ctx, context.CancelFunc(func() {})
+		if sqlStm.Timeout > 0 {
+			stmCtx, cancel = context.WithTimeout(ctx, sqlStm.Timeout)
+		}
+		defer cancel()
+
+		stm := sqlStm.Stmt
+		// Помещаем запрос в рамки транзакции из контекста
+		if tx := FromContextTx(ctx); tx != nil && stm != nil {
+			stm = tx.StmtxContext(stmCtx, stm)
+		}
+
+		// Вне транзакции - на реплике. Строки передаются в fn по мере чтения, поэтому повтор на основном сервере невозможен
+		r, replicaStm := db.replicaStmt(ctx, sqlStm)
+		if replicaStm != nil {
+			stm = replicaStm
+			atomic.AddUint64(&r.queries, 1)
+		} else {
+			atomic.AddUint64(&db.queries, 1)
+		}
+
+		var rows *sqlx.Rows
+		var err error
+		if stm != nil {
+			rows, err = stm.QueryxContext(stmCtx, args...)
+		} else {
+			rows, err = db.queryer(ctx).QueryxContext(stmCtx, sqlStm.Text, args...)
+		}
+		if err == nil {
+			err = fn(rows)
+			if closeErr := rows.Close(); err == nil {
+				err = closeErr
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+		}
+		if err != nil {
+			if _, ok := err.(*myerror.Error); ok {
+				return err // ошибка обработки строк в fn
+			}
+			if r != nil && stmCtx.Err() == nil {
+				r.failed(err)
+			} else if r == nil {
+				atomic.AddUint64(&db.errors, 1)
+			}
+			return contextError(stmCtx, err, "4003", "Error Query SQL statement: reqID, sqlID, SQL", reqID, sqlID, sqlStm.Text)
+		}
+		return nil
+	}
+	return myerror.New("4400", "Incorrect call - nil fn: reqID, sql", reqID, sqlT).PrintfInfo()
+}
+
+// Exec - represent common task in process DML statement, transaction must be in ctx
+func (db *DB) Exec(ctx context.Context, sqlT string, args interface{}) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	sqlStm, release, ok := db.getSQLStm(sqlT)
 	if !ok {
 		return 0, myerror.New("4100", "SQL statement is not defined: reqID, sql", reqID, sqlT).PrintfInfo()
 	}
+	defer release()
+	return db.exec(ctx, reqID, sqlT, sqlStm, args)
+}
+
+// ExecText - process DML statement generated by caller, for example UPDATE of changed columns only.
+// Statement is not prepared, named parameters are bound from args, transaction must be in ctx
+func (db *DB) ExecText(ctx context.Context, text string, args interface{}) (rows int64, myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+	return db.exec(ctx, reqID, text, &SQLStm{Text: text}, args)
+}
 
+// exec - process DML statement sqlStm with named parameters args
+func (db *DB) exec(ctx context.Context, reqID uint64, sqlT string, sqlStm *SQLStm, args interface{}) (rows int64, myerr error) {
 	// функция восстановления после паники
 	defer func() {
 		r := recover()
@@ -332,15 +578,20 @@ func (db *DB) Exec(reqID uint64, tx *Tx, sqlT string, args interface{}) (rows in
 
 		mylog.PrintfDebugMsg("reqID, sqlID, SQL", reqID, sqlID, sqlStm.Text)
 
-		// Проверяем определен ли контекст транзакции
+		// Проверяем определена ли транзакция в контексте
+		tx := FromContextTx(ctx)
 		if tx == nil {
 			return 0, myerror.New("4004", "Transaction is not defined: reqID, sqlID, SQL", reqID, sqlID, sqlStm.Text).PrintfInfo()
 		}
 
+		// Ограничим время выполнения SQL команды
+		stmCtx, cancel := db.withTimeout(ctx, sqlStm)
+		defer cancel()
+
 		// Выполняем DML
-		res, err := tx.NamedExec(sqlStm.Text, args)
+		res, err := tx.NamedExecContext(stmCtx, sqlStm.Text, args)
 		if err != nil {
-			return 0, myerror.WithCause("4005", "Error Exec SQL statement: reqID, sqlID, SQL, args", err, reqID, sqlID, sqlStm.Text, args).PrintfInfo()
+			return 0, contextError(stmCtx, err, "4005", "Error Exec SQL statement: reqID, sqlID, SQL, args", reqID, sqlID, sqlStm.Text, args)
 		}
 
 		// Количество обработанных строк
@@ -352,3 +603,50 @@ func (db *DB) Exec(reqID uint64, tx *Tx, sqlT string, args interface{}) (rows in
 	}
 	return 0, myerror.New("4400", "Incorrect call - nil args interface{} pointer: reqID, sql", reqID, sqlT).PrintfInfo()
 }
+
+// Close - close replicas and primary connection pools
+func (db *DB) Close() (myerr error) {
+	db.closeReplicas()
+
+	if err := db.DB.Close(); err != nil {
+		return myerror.WithCause("4004", "Error close DB", err).PrintfInfo()
+	}
+	return nil
+}
+
+// queryer - return executor of SQL statement, which is not prepared: transaction from ctx or primary DB.
+// Такие команды выполняются по тексту и не направляются на реплики
+func (db *DB) queryer(ctx context.Context) sqlx.QueryerContext {
+	if tx := FromContextTx(ctx); tx != nil {
+		return tx
+	}
+	return db.DB
+}
+
+// withTimeout - limit SQL statement execution time with statement timeout or global default
+func (db *DB) withTimeout(ctx context.Context, sqlStm *SQLStm) (context.Context, context.CancelFunc) {
+	timeout := sqlStm.Timeout
+	if timeout == 0 && db.cfg != nil {
+		timeout = time.Duration(db.cfg.QueryTimeout) * time.Millisecond
+	}
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// contextError - classify SQL error: timeout, cancellation or common error with code
+func contextError(ctx context.Context, err error, code string, msg string, args ...interface{}) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return myerror.WithCause(ErrCodeTimeout, "SQL timeout exceeded. "+msg, err, args...).PrintfInfo(1)
+	case context.Canceled:
+		return myerror.WithCause(ErrCodeCanceled, "SQL canceled. "+msg, err, args...).PrintfInfo(1)
+	default:
+		// SQLSTATE класса 23 - нарушение ограничения целостности, вызвано данными запроса
+		if strings.HasPrefix(SQLState(err), "23") {
+			return myerror.WithCause(ErrCodeConstraintViolation, "Constraint violation. "+msg, err, args...).PrintfInfo(1)
+		}
+		return myerror.WithCause(code, msg, err, args...).PrintfInfo(1)
+	}
+}
@@ -0,0 +1,110 @@
+package sqlxx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestPreparexError(t *testing.T) {
+	db, conn := newFakeDB(&Config{})
+	conn.execErrs = map[string]error{"SELECT bad": errors.New("syntax error")}
+
+	sqlStms := SQLStms{
+		"GetDept": {Text: "SELECT dept", IsPrepare: true},
+		"GetEmp":  {Text: "SELECT emp", IsPrepare: true},
+		"GetBad":  {Text: "SELECT bad", IsPrepare: true},
+		"Create":  {Text: "INSERT dept"},
+	}
+	if err := db.Preparex(sqlStms); errCode(err) != "4002" {
+		t.Fatalf("Preparex() error = %v, want 4002", err)
+	}
+
+	// все подготовленные до ошибки команды должны быть закрыты
+	commands := conn.commands()
+	for _, text := range []string{"SELECT dept", "SELECT emp"} {
+		if prepared, closed := count(commands, "PREPARE "+text), count(commands, "CLOSE "+text); prepared != closed {
+			t.Errorf("Preparex() %q prepared %v times, closed %v times", text, prepared, closed)
+		}
+	}
+	for name, h := range sqlStms {
+		if h.Stmt != nil {
+			t.Errorf("Preparex() %q Stmt is not nil after error", name)
+		}
+	}
+}
+
+func TestReloadInUse(t *testing.T) {
+	db, conn := newFakeDB(&Config{})
+
+	oldStms := SQLStms{"GetDept": {Text: "SELECT old", IsPrepare: true}}
+	if err := db.Preparex(oldStms); err != nil {
+		t.Fatalf("Preparex() error = %v", err)
+	}
+	db.sqlStms = oldStms
+
+	// запрос, выполняемый со старой командой
+	sqlStm, release, ok := db.getSQLStm("GetDept")
+	if !ok || sqlStm.Text != "SELECT old" {
+		t.Fatalf("getSQLStm() = %v, %v", sqlStm, ok)
+	}
+
+	if err := db.Reload(SQLStms{"GetDept": {Text: "SELECT new", IsPrepare: true}}); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if newStm, newRelease, _ := db.getSQLStm("GetDept"); newStm.Text != "SELECT new" {
+		t.Errorf("getSQLStm() after Reload = %q, want %q", newStm.Text, "SELECT new")
+	} else {
+		newRelease()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if count(conn.commands(), "CLOSE SELECT old") != 0 || sqlStm.Stmt == nil {
+		t.Fatalf("Reload() closed statement in use")
+	}
+
+	release()
+	deadline := time.Now().Add(time.Second)
+	for count(conn.commands(), "CLOSE SELECT old") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Reload() did not close statement after release")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if count(conn.commands(), "CLOSE SELECT new") != 0 {
+		t.Errorf("Reload() closed new statement")
+	}
+}
+
+func TestNotPrepared(t *testing.T) {
+	db, conn := newFakeDB(&Config{})
+	db.sqlStms = SQLStms{"GetVersion": {Text: "SELECT version"}}
+
+	// команда без "-- prepare: true" выполняется по тексту вне транзакции и в транзакции
+	run := func(ctx context.Context) error {
+		var version int64
+		if exists, err := db.Get(ctx, "GetVersion", &version); err != nil || exists {
+			t.Errorf("Get() = %v, %v, want not exists", exists, err)
+		}
+		var versions []int64
+		if err := db.Select(ctx, "GetVersion", &versions); err != nil {
+			t.Errorf("Select() error = %v", err)
+		}
+		if err := db.Query(ctx, "GetVersion", func(rows *sqlx.Rows) error { return nil }); err != nil {
+			t.Errorf("Query() error = %v", err)
+		}
+		return nil
+	}
+	_ = run(context.Background())
+	if err := db.InTx(context.Background(), nil, run); err != nil {
+		t.Fatalf("InTx() error = %v", err)
+	}
+
+	commands := conn.commands()
+	if count(commands, "SELECT version") != 6 || count(commands, "COMMIT") != 1 {
+		t.Errorf("commands = %q, want 6 queries by text and 1 commit", commands)
+	}
+}
@@ -0,0 +1,204 @@
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/lib/pq"
+
+	myctx "github.com/romapres2010/httpserver/ctx"
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Параметры повтора транзакции по умолчанию
+const (
+	defaultTxRetryCount      = 3
+	defaultTxRetryBackoff    = 20 * time.Millisecond
+	defaultTxRetryMaxBackoff = 1 * time.Second
+)
+
+// retryableSQLStates represent SQLSTATE, при которых транзакция может быть повторена
+var retryableSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// ErrRollback - returned from transaction function to roll back transaction without error
+var ErrRollback = errors.New("rollback transaction")
+
+// TxOptions represent options of transaction started by RunTx
+type TxOptions struct {
+	Isolation sql.IsolationLevel // уровень изоляции транзакции, sql.LevelDefault - уровень БД по умолчанию
+	ReadOnly  bool               // транзакция только на чтение
+}
+
+// txKey is the context key for the transaction
+type txKey struct{}
+
+// NewContextTx returns a new Context carrying Tx.
+func NewContextTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// FromContextTx extracts the Tx from ctx, if present.
+func FromContextTx(ctx context.Context) *Tx {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	if !ok {
+		return nil
+	}
+	return tx
+}
+
+// InTx - run fn as unit of work. Transaction is passed to fn in ctx and is used by Select, Get and Exec.
+// If ctx already carries transaction, fn is run inside savepoint: error rolls back only the savepoint.
+// Otherwise new transaction is started by RunTx with retry on serialization failure or deadlock.
+// If fn returns ErrRollback, its changes are rolled back and InTx returns nil.
+func (db *DB) InTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) (myerr error) {
+	if tx := FromContextTx(ctx); tx != nil {
+		return db.runSavepoint(ctx, tx, fn)
+	}
+
+	return db.RunTx(ctx, opts, func(tx *Tx) error {
+		return fn(NewContextTx(ctx, tx))
+	})
+}
+
+// runSavepoint - run fn inside savepoint of transaction
+func (db *DB) runSavepoint(ctx context.Context, tx *Tx, fn func(ctx context.Context) error) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	savepoint := fmt.Sprintf("sp_%d", atomic.AddUint32(&tx.savepointID, 1))
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return contextError(ctx, err, "4005", "Error create savepoint: reqID, savepoint", reqID, savepoint)
+	}
+	mylog.PrintfDebugMsg("Savepoint created: reqID, savepoint", reqID, savepoint)
+
+	afterCommit := len(tx.afterCommit) // функции, зарегистрированные до точки сохранения
+
+	if err := fn(ctx); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			// транзакция непригодна, возвращаем ошибку отката - внешняя транзакция будет откачена целиком
+			return contextError(ctx, rbErr, "4008", "Error rollback to savepoint: reqID, savepoint", reqID, savepoint)
+		}
+		mylog.PrintfDebugMsg("Rollbacked to savepoint: reqID, savepoint", reqID, savepoint)
+		tx.afterCommit = tx.afterCommit[:afterCommit]
+		if err == ErrRollback {
+			return nil
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return contextError(ctx, err, "4008", "Error release savepoint: reqID, savepoint", reqID, savepoint)
+	}
+	return nil
+}
+
+// RunTx - run fn in transaction: commit on success, rollback on error.
+// Transaction is retried with jittered exponential backoff on serialization failure or deadlock.
+// If fn returns ErrRollback, transaction is rolled back and RunTx returns nil.
+func (db *DB) RunTx(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) (myerr error) {
+	reqID := myctx.FromContextRequestID(ctx) // RequestID передается через context
+
+	retryCount, baseBackoff, maxBackoff := db.retryConfig()
+
+	for attempt := 0; ; attempt++ {
+		if myerr = db.runTxOnce(ctx, opts, fn); myerr == nil {
+			return nil
+		}
+
+		// повторяем только сбои сериализации и взаимные блокировки
+		sqlState := SQLState(myerr)
+		if !retryableSQLStates[sqlState] || attempt >= retryCount {
+			return myerr
+		}
+
+		// экспоненциальная задержка с полным jitter
+		backoff := baseBackoff << uint(attempt)
+		if backoff > maxBackoff || backoff <= 0 {
+			backoff = maxBackoff
+		}
+		delay := time.Duration(rand.Int63n(int64(backoff)) + 1)
+
+		mylog.PrintfInfoMsg("Retrying transaction: reqID, SQLSTATE, attempt, delay", reqID, sqlState, attempt+1, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return contextError(ctx, ctx.Err(), "4006", "Transaction retry is interrupted: reqID", reqID)
+		case <-timer.C:
+		}
+	}
+}
+
+// runTxOnce - run fn in one transaction
+func (db *DB) runTxOnce(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) (myerr error) {
+	tx, myerr := db.BeginTxx(ctx, opts)
+	if myerr != nil {
+		return myerr
+	}
+
+	if err := fn(tx); err != nil {
+		_ = db.Rollback(ctx, tx) // ошибку отката не возвращаем, важнее исходная ошибка
+		if err == ErrRollback {
+			return nil
+		}
+		return err
+	}
+
+	if myerr = db.Commit(ctx, tx); myerr != nil {
+		return myerr
+	}
+
+	for _, fn := range tx.afterCommit {
+		fn()
+	}
+	return nil
+}
+
+// retryConfig - return transaction retry parameters
+func (db *DB) retryConfig() (retryCount int, backoff time.Duration, maxBackoff time.Duration) {
+	retryCount, backoff, maxBackoff = defaultTxRetryCount, defaultTxRetryBackoff, defaultTxRetryMaxBackoff
+	if db.cfg != nil {
+		if db.cfg.TxRetryCount >= 0 {
+			retryCount = db.cfg.TxRetryCount
+		}
+		if db.cfg.TxRetryBackoff > 0 {
+			backoff = time.Duration(db.cfg.TxRetryBackoff) * time.Millisecond
+		}
+		if db.cfg.TxRetryMaxBackoff > 0 {
+			maxBackoff = time.Duration(db.cfg.TxRetryMaxBackoff) * time.Millisecond
+		}
+	}
+	return retryCount, backoff, maxBackoff
+}
+
+// SQLState - extract SQLSTATE from DB driver error or its cause
+func SQLState(err error) string {
+	for err != nil {
+		switch e := err.(type) {
+		case pgx.PgError:
+			return e.Code
+		case *pgx.PgError:
+			return e.Code
+		case *pq.Error:
+			return string(e.Code)
+		case pq.Error:
+			return string(e.Code)
+		case *myerror.Error:
+			err = e.CauseErr
+		default:
+			return ""
+		}
+	}
+	return ""
+}
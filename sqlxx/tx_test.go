@@ -0,0 +1,350 @@
+package sqlxx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	myerror "github.com/romapres2010/httpserver/error"
+)
+
+// fakeConn represent database/sql connection, which records executed commands instead of sending them to DB
+type fakeConn struct {
+	mx         sync.Mutex
+	log        []string         // выполненные команды, в том числе BEGIN, COMMIT и ROLLBACK
+	commitErrs []error          // ошибки последовательных COMMIT, nil - успешная фиксация
+	execErrs   map[string]error // ошибки выполнения команд по префиксу текста
+}
+
+func (c *fakeConn) record(cmd string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.log = append(c.log, cmd)
+}
+
+// commands return executed commands
+func (c *fakeConn) commands() []string {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return append([]string(nil), c.log...)
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.record("PREPARE " + query)
+	for prefix, err := range c.execErrs {
+		if strings.HasPrefix(query, prefix) {
+			return nil, err
+		}
+	}
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.record("BEGIN")
+	return &fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query)
+	for prefix, err := range c.execErrs {
+		if strings.HasPrefix(query, prefix) {
+			return nil, err
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query)
+	return fakeRows{}, nil
+}
+
+// fakeRows represent empty result of query
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string { return []string{"version"} }
+
+func (fakeRows) Close() error { return nil }
+
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+// fakeStmt represent prepared statement of fakeConn, only preparation and closing are recorded
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	s.conn.record("CLOSE " + s.query)
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("exec of prepared statement is not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("query of prepared statement is not supported")
+}
+
+// fakeTx represent transaction of fakeConn
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.conn.record("COMMIT")
+	tx.conn.mx.Lock()
+	defer tx.conn.mx.Unlock()
+	if len(tx.conn.commitErrs) > 0 {
+		err := tx.conn.commitErrs[0]
+		tx.conn.commitErrs = tx.conn.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.record("ROLLBACK")
+	return nil
+}
+
+// fakeConnector represent driver.Connector, which always returns the same connection
+type fakeConnector struct {
+	conn *fakeConn
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) { return c.conn, nil }
+
+func (c *fakeConnector) Driver() driver.Driver { return nil }
+
+// newFakeDB create DB over fake connection
+func newFakeDB(cfg *Config) (*DB, *fakeConn) {
+	conn := &fakeConn{}
+	sqlDB := sql.OpenDB(&fakeConnector{conn: conn})
+	sqlDB.SetMaxOpenConns(1)
+	return &DB{DB: sqlx.NewDb(sqlDB, "postgres"), cfg: cfg, stmsUsed: &sync.WaitGroup{}}, conn
+}
+
+// count return number of cmd in commands
+func count(commands []string, cmd string) int {
+	n := 0
+	for _, c := range commands {
+		if c == cmd {
+			n++
+		}
+	}
+	return n
+}
+
+func errCode(err error) string {
+	if myerr, ok := err.(*myerror.Error); ok {
+		return myerr.Code
+	}
+	return ""
+}
+
+func TestSQLState(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"other", errors.New("error"), ""},
+		{"pgx", pgx.PgError{Code: "40001"}, "40001"},
+		{"pq", &pq.Error{Code: "40P01"}, "40P01"},
+		{"wrapped", myerror.WithCause("4008", "commit", myerror.WithCause("4005", "exec", pgx.PgError{Code: "40P01"})), "40P01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SQLState(tt.err); got != tt.want {
+				t.Errorf("SQLState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTxRetry(t *testing.T) {
+	serializationFailure := &pq.Error{Code: "40001"}
+	tests := []struct {
+		name       string
+		retryCount int
+		fnErrs     []error // ошибки последовательных вызовов fn, далее fn выполняется успешно
+		commitErrs []error
+		wantErr    bool
+		wantState  string
+		attempts   int
+		commits    int
+	}{
+		{"success", 3, nil, nil, false, "", 1, 1},
+		{"retry until success", 3, []error{serializationFailure, pgx.PgError{Code: "40P01"}}, nil, false, "", 3, 1},
+		{"retry commit", 3, nil, []error{serializationFailure}, false, "", 2, 1},
+		{"retry limit", 2, []error{serializationFailure, serializationFailure, serializationFailure, serializationFailure}, nil, true, "40001", 3, 0},
+		{"no retry", 0, []error{serializationFailure}, nil, true, "40001", 1, 0},
+		{"not retryable", 3, []error{&pq.Error{Code: "23505"}}, nil, true, "23505", 1, 0},
+		{"rollback", 3, []error{ErrRollback}, nil, false, "", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, conn := newFakeDB(&Config{TxRetryCount: tt.retryCount, TxRetryBackoff: 1, TxRetryMaxBackoff: 2})
+			conn.commitErrs = tt.commitErrs
+
+			attempts := 0
+			err := db.RunTx(context.Background(), nil, func(tx *Tx) error {
+				attempts++
+				if attempts <= len(tt.fnErrs) {
+					return tt.fnErrs[attempts-1]
+				}
+				return nil
+			})
+
+			if (err != nil) != tt.wantErr || SQLState(err) != tt.wantState {
+				t.Errorf("RunTx() error = %v, want SQLSTATE %q", err, tt.wantState)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("RunTx() attempts = %v, want %v", attempts, tt.attempts)
+			}
+			if commits := count(conn.commands(), "COMMIT") - len(tt.commitErrs); commits != tt.commits {
+				t.Errorf("RunTx() successful commits = %v, want %v", commits, tt.commits)
+			}
+		})
+	}
+}
+
+func TestRunTxRetryCanceled(t *testing.T) {
+	// задержка перед повтором больше времени теста - повтор прерывается только отменой контекста
+	db, _ := newFakeDB(&Config{TxRetryCount: 3, TxRetryBackoff: 60000, TxRetryMaxBackoff: 60000})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := 0
+	err := db.RunTx(ctx, nil, func(tx *Tx) error {
+		attempts++
+		cancel()
+		return &pq.Error{Code: "40001"}
+	})
+	if errCode(err) != ErrCodeCanceled || attempts != 1 {
+		t.Errorf("RunTx() = %v, attempts %v, want error %s after 1 attempt", err, attempts, ErrCodeCanceled)
+	}
+}
+
+func TestInTxSavepoint(t *testing.T) {
+	db, conn := newFakeDB(&Config{})
+	ctx := context.Background()
+	failed := errors.New("failed")
+
+	var ran []string
+	afterCommit := func(ctx context.Context, name string) {
+		FromContextTx(ctx).AfterCommit(func() { ran = append(ran, name) })
+	}
+
+	var errs []error
+	err := db.InTx(ctx, nil, func(ctx context.Context) error {
+		afterCommit(ctx, "outer")
+
+		// ошибка откатывает только точку сохранения
+		errs = append(errs, db.InTx(ctx, nil, func(ctx context.Context) error {
+			afterCommit(ctx, "failed")
+			return failed
+		}))
+
+		// ErrRollback откатывает точку сохранения вместе с вложенными без ошибки
+		errs = append(errs, db.InTx(ctx, nil, func(ctx context.Context) error {
+			afterCommit(ctx, "rolled back")
+			_ = db.InTx(ctx, nil, func(ctx context.Context) error {
+				afterCommit(ctx, "nested rolled back")
+				return nil
+			})
+			return ErrRollback
+		}))
+
+		// вложенные точки сохранения освобождаются
+		errs = append(errs, db.InTx(ctx, nil, func(ctx context.Context) error {
+			_ = db.InTx(ctx, nil, func(ctx context.Context) error {
+				afterCommit(ctx, "nested")
+				return nil
+			})
+			afterCommit(ctx, "released")
+			return nil
+		}))
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("InTx() error = %v", err)
+	}
+	if len(errs) != 3 || errs[0] != failed || errs[1] != nil || errs[2] != nil {
+		t.Errorf("InTx() savepoint errors = %v, want [%v <nil> <nil>]", errs, failed)
+	}
+	want := []string{
+		"BEGIN",
+		"SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1",
+		"SAVEPOINT sp_2", "SAVEPOINT sp_3", "RELEASE SAVEPOINT sp_3", "ROLLBACK TO SAVEPOINT sp_2",
+		"SAVEPOINT sp_4", "SAVEPOINT sp_5", "RELEASE SAVEPOINT sp_5", "RELEASE SAVEPOINT sp_4",
+		"COMMIT",
+	}
+	if got := conn.commands(); strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("InTx() commands = %v, want %v", got, want)
+	}
+	if got, want := strings.Join(ran, ", "), "outer, nested, released"; got != want {
+		t.Errorf("InTx() after commit = %v, want %v", got, want)
+	}
+}
+
+func TestInTxRollback(t *testing.T) {
+	tests := []struct {
+		name     string
+		execErrs map[string]error
+		inner    error
+		outer    error
+		wantCode string
+		wantErr  bool
+		want     string
+	}{
+		{"ErrRollback", nil, nil, ErrRollback, "", false, "BEGIN; SAVEPOINT sp_1; RELEASE SAVEPOINT sp_1; ROLLBACK"},
+		{"error", nil, nil, errors.New("failed"), "", true, "BEGIN; SAVEPOINT sp_1; RELEASE SAVEPOINT sp_1; ROLLBACK"},
+		{"inner error is returned", nil, errors.New("failed"), nil, "", true, "BEGIN; SAVEPOINT sp_1; ROLLBACK TO SAVEPOINT sp_1; ROLLBACK"},
+		{"savepoint rollback failure", map[string]error{"ROLLBACK TO": errors.New("connection lost")}, ErrRollback, nil, "4008", true,
+			"BEGIN; SAVEPOINT sp_1; ROLLBACK TO SAVEPOINT sp_1; ROLLBACK"},
+		{"savepoint failure", map[string]error{"SAVEPOINT": errors.New("connection lost")}, nil, nil, "4005", true, "BEGIN; SAVEPOINT sp_1; ROLLBACK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, conn := newFakeDB(&Config{})
+			conn.execErrs = tt.execErrs
+
+			ran := false
+			err := db.InTx(context.Background(), nil, func(ctx context.Context) error {
+				FromContextTx(ctx).AfterCommit(func() { ran = true })
+				if err := db.InTx(ctx, nil, func(ctx context.Context) error { return tt.inner }); err != nil {
+					return err // внешняя транзакция откатывается целиком
+				}
+				return tt.outer
+			})
+
+			if (err != nil) != tt.wantErr || (tt.wantCode != "" && errCode(err) != tt.wantCode) {
+				t.Errorf("InTx() error = %v, want error %v, code %q", err, tt.wantErr, tt.wantCode)
+			}
+			if ran {
+				t.Errorf("InTx() after commit functions are run for rolled back transaction")
+			}
+			if got := strings.Join(conn.commands(), "; "); got != tt.want {
+				t.Errorf("InTx() commands = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
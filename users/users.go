@@ -0,0 +1,189 @@
+package users
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	myerror "github.com/romapres2010/httpserver/error"
+	mylog "github.com/romapres2010/httpserver/log"
+)
+
+// Параметры хеширования паролей
+const (
+	hashScheme     = "pbkdf2-sha256"
+	hashIterations = 100000
+	hashSaltLen    = 16
+	hashKeyLen     = 32
+)
+
+// Store represent file based store of users for INTERNAL authentication
+//
+// Формат файла - по одному пользователю в строке:
+//
+//	username:$pbkdf2-sha256$iterations$salt$hash
+//
+// строки, начинающиеся с '#', являются комментариями
+type Store struct {
+	mx       sync.RWMutex
+	fileName string            // файл с пользователями
+	users    map[string]string // пользователь - хеш пароля
+}
+
+// Load load users from file, if file does not exist empty store is returned
+func Load(fileName string) (*Store, error) {
+	if fileName == "" {
+		return nil, myerror.New("6040", "Users file name is null").PrintfInfo()
+	}
+
+	store := &Store{
+		fileName: fileName,
+		users:    make(map[string]string),
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			mylog.PrintfInfoMsg("Users file does not exist, created empty store: FileName", fileName)
+			return store, nil
+		}
+		return nil, myerror.WithCause("6041", "Error open users file: FileName", err, fileName).PrintfInfo()
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, ":")
+		if idx <= 0 {
+			return nil, myerror.New("6042", "Incorrect users file format: FileName, Line", fileName, lineNum).PrintfInfo()
+		}
+		store.users[line[:idx]] = line[idx+1:]
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, myerror.WithCause("6041", "Error read users file: FileName", err, fileName).PrintfInfo()
+	}
+
+	mylog.PrintfInfoMsg("Users are loaded: FileName, Count", fileName, len(store.users))
+	return store, nil
+}
+
+// Add add new user or replace password of existing one
+func (s *Store) Add(username string, password string, replace bool) error {
+	if username == "" || strings.ContainsAny(username, ":\n\r") {
+		return myerror.New("6043", "Incorrect user name, it must not be empty or contain ':': username", username).PrintfInfo()
+	}
+	if password == "" {
+		return myerror.New("6043", "User password is null: username", username).PrintfInfo()
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return myerror.WithCause("6044", "Error hash user password: username", err, username).PrintfInfo()
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.users[username]; ok && !replace {
+		return myerror.New("6045", "User already exists: username", username).PrintfInfo()
+	}
+	s.users[username] = hash
+	return nil
+}
+
+// Save write all users into file
+func (s *Store) Save() error {
+	s.mx.RLock()
+	names := make([]string, 0, len(s.users))
+	for name := range s.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("# HTTP Server users: username:$" + hashScheme + "$iterations$salt$hash\n")
+	for _, name := range names {
+		b.WriteString(name + ":" + s.users[name] + "\n")
+	}
+	s.mx.RUnlock()
+
+	if err := ioutil.WriteFile(s.fileName, []byte(b.String()), 0600); err != nil {
+		return myerror.WithCause("6041", "Error write users file: FileName", err, s.fileName).PrintfInfo()
+	}
+	return nil
+}
+
+// Check check user name and password
+func (s *Store) Check(username string, password string) bool {
+	s.mx.RLock()
+	hash, ok := s.users[username]
+	s.mx.RUnlock()
+
+	if !ok {
+		return false
+	}
+	return checkPassword(hash, password)
+}
+
+// Len return number of users
+func (s *Store) Len() int {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return len(s.users)
+}
+
+// hashPassword create salted password hash
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, hashSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(password), salt, hashIterations, hashKeyLen, sha256.New)
+
+	return fmt.Sprintf("$%s$%d$%s$%s", hashScheme, hashIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// checkPassword compare password with salted hash
+func checkPassword(hash string, password string) bool {
+	// "$pbkdf2-sha256$iterations$salt$hash" разбивается на 5 частей, первая пустая
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 || parts[1] != hashScheme {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[2])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+
+	got := pbkdf2.Key([]byte(password), salt, iterations, len(want), sha256.New)
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
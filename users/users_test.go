@@ -0,0 +1,192 @@
+package users
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	myerror "github.com/romapres2010/httpserver/error"
+)
+
+// errCode return code of catalogued error
+func errCode(err error) string {
+	if myerr, ok := err.(*myerror.Error); ok {
+		return myerr.Code
+	}
+	return ""
+}
+
+// testHash create hash of password with fixed salt and small number of iterations
+func testHash(password string, iterations int) string {
+	salt := []byte("0123456789abcdef")
+	key := pbkdf2.Key([]byte(password), salt, iterations, hashKeyLen, sha256.New)
+	return fmt.Sprintf("$%s$%d$%s$%s", hashScheme, iterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash := testHash("secret", 10)
+	salt := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef"))
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+	}{
+		{"valid", hash, "secret", true},
+		{"wrong password", hash, "Secret", false},
+		{"empty password", hash, "", false},
+		{"other iterations", testHash("secret", 11), "secret", true},
+		{"other scheme", "$pbkdf2-sha1$10$" + salt + "$AAAA", "secret", false},
+		{"plain text", "secret", "secret", false},
+		{"missing part", "$" + hashScheme + "$10$" + salt, "secret", false},
+		{"invalid iterations", "$" + hashScheme + "$ten$" + salt + "$AAAA", "secret", false},
+		{"zero iterations", "$" + hashScheme + "$0$" + salt + "$AAAA", "secret", false},
+		{"invalid salt", "$" + hashScheme + "$10$!!!$AAAA", "secret", false},
+		{"invalid key", "$" + hashScheme + "$10$" + salt + "$!!!", "secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPassword(tt.hash, tt.password); got != tt.want {
+				t.Errorf("checkPassword(%q, %q) = %v, want %v", tt.hash, tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "users")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		content  string // содержимое файла, пусто - файл не создается
+		wantCode string
+		wantLen  int
+	}{
+		{"missing file", "", "", 0},
+		{"valid", "# comment\n\nalice:" + testHash("alice-pass", 10) + "\n  bob:" + testHash("bob-pass", 10) + "  \n", "", 2},
+		{"colon in hash", "alice:" + testHash("alice-pass", 10) + ":tail\n", "", 1},
+		{"without colon", "alice:" + testHash("alice-pass", 10) + "\nbob\n", "6042", 0},
+		{"empty username", ":" + testHash("alice-pass", 10) + "\n", "6042", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(dir, tt.name+".users")
+			if tt.content != "" {
+				if err := ioutil.WriteFile(fileName, []byte(tt.content), 0600); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+
+			store, err := Load(fileName)
+			if tt.wantCode != "" {
+				if errCode(err) != tt.wantCode {
+					t.Fatalf("Load() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if store.Len() != tt.wantLen {
+				t.Errorf("Load() Len = %v, want %v", store.Len(), tt.wantLen)
+			}
+		})
+	}
+
+	if _, err := Load(""); errCode(err) != "6040" {
+		t.Errorf("Load(\"\") error = %v, want code 6040", err)
+	}
+}
+
+func TestStoreCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "users")
+	if err != nil {
+		t.Fatalf("TempDir() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "httpserver.users")
+
+	content := "alice:" + testHash("alice-pass", 10) + "\nbob:" + testHash("bob-pass", 10) + "\ncarol:plain\n"
+	if err = ioutil.WriteFile(fileName, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	store, err := Load(fileName)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// пользователь, добавленный с хешем по умолчанию, сохраняется и загружается вместе с остальными
+	if err = store.Add("dave", "dave-pass", false); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err = store.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if store, err = Load(fileName); err != nil {
+		t.Fatalf("Load() after Save error = %v", err)
+	}
+
+	tests := []struct {
+		username string
+		password string
+		want     bool
+	}{
+		{"alice", "alice-pass", true},
+		{"alice", "bob-pass", false},
+		{"bob", "bob-pass", true},
+		{"bob", "", false},
+		{"carol", "plain", false},
+		{"dave", "dave-pass", true},
+		{"dave", "dave-pass ", false},
+		{"eve", "alice-pass", false},
+	}
+	for _, tt := range tests {
+		if got := store.Check(tt.username, tt.password); got != tt.want {
+			t.Errorf("Check(%q, %q) = %v, want %v", tt.username, tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestStoreAdd(t *testing.T) {
+	store := &Store{users: make(map[string]string)}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		replace  bool
+		wantCode string
+	}{
+		{"new", "alice", "alice-pass", false, ""},
+		{"exists", "alice", "other-pass", false, "6045"},
+		{"replace", "alice", "new-pass", true, ""},
+		{"empty username", "", "pass", false, "6043"},
+		{"colon in username", "al:ice", "pass", false, "6043"},
+		{"newline in username", "al\nice", "pass", false, "6043"},
+		{"empty password", "bob", "", false, "6043"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Add(tt.username, tt.password, tt.replace); errCode(err) != tt.wantCode {
+				t.Errorf("Add() error = %v, want code %q", err, tt.wantCode)
+			}
+		})
+	}
+
+	if store.Len() != 1 || !store.Check("alice", "new-pass") || store.Check("alice", "alice-pass") {
+		t.Errorf("Add() users = %v, want alice with replaced password", store.users)
+	}
+}